@@ -1,19 +1,31 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
 	"github.com/romanzh1/master-english-srs/internal/handler"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
 	"github.com/romanzh1/master-english-srs/internal/repository"
 	"github.com/romanzh1/master-english-srs/internal/service"
 	"github.com/romanzh1/master-english-srs/pkg/onenote"
+	"github.com/romanzh1/master-english-srs/pkg/utils"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run поднимает зависимости, работает до SIGINT/SIGTERM и освобождает ресурсы в обратном порядке:
+// сначала бот перестаёт принимать обновления и дожидается обработчиков, затем закрывается пул БД и сбрасывается лог.
+// Возвращает код выхода процесса.
+func run() int {
 	config := zap.NewDevelopmentConfig()
 	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	config.EncoderConfig.TimeKey = "timestamp"
@@ -22,11 +34,20 @@ func main() {
 	if err != nil {
 		panic(fmt.Errorf("init logger: %w", err))
 	}
-	defer logger.Sync()
+	defer func() {
+		// Sync для stdout/stderr возвращает ошибку на некоторых платформах, её игнорируем
+		_ = logger.Sync()
+	}()
 
 	zap.ReplaceGlobals(logger)
 	zap.S().Info("logger initialized")
 
+	// Ключ, которого нет в одном из языков, показал бы пользователю запасной текст — не запускаемся с таким каталогом
+	if err := i18n.Validate(); err != nil {
+		zap.S().Error("validate message catalog", zap.Error(err))
+		return 1
+	}
+
 	if err := godotenv.Load(); err != nil {
 		zap.S().Debug("load .env file", zap.Error(err))
 	}
@@ -42,35 +63,84 @@ func main() {
 	azureRedirectURI := os.Getenv("AZURE_REDIRECT_URI")
 
 	if telegramToken == "" || postgresHost == "" {
-		zap.S().Fatal("missing required environment variables")
+		zap.S().Error("missing required environment variables")
+		return 1
 	}
 
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		postgresHost, postgresPort, postgresUser, postgresPassword, postgresDB)
 
-	repo, err := repository.NewDB(dsn, 10, 20)
+	// Все компоненты берут текущее время из одних часов
+	clock := utils.SystemClock{}
+
+	repo, err := repository.NewDB(dsn, 10, 20, clock)
 	if err != nil {
 		zap.S().Error("connect to PostgreSQL", zap.Error(err), zap.String("host", postgresHost))
-		os.Exit(1)
+		return 1
 	}
-	defer repo.Close()
+	defer func() {
+		if err := repo.Close(); err != nil {
+			zap.S().Error("close database", zap.Error(err))
+		}
+		zap.S().Info("database closed")
+	}()
 
 	if err = repo.Up("migrations"); err != nil {
 		zap.S().Error("run migrations", zap.Error(err))
-		os.Exit(1)
+		return 1
 	}
 
 	scopes := []string{"Notes.Read", "offline_access"}
 	authService := onenote.NewAuthService(azureClientID, azureClientSecret, azureRedirectURI, scopes)
 	oneNoteClient := onenote.NewClient()
 
-	svc := service.NewService(repo, authService, oneNoteClient)
+	svc := service.NewService(repo, authService, oneNoteClient, clock, utils.SystemRand{})
 
-	bot, err := handler.NewTelegramHandler(telegramToken, svc)
+	bot, err := handler.NewTelegramHandler(telegramToken, svc, clock)
 	if err != nil {
 		zap.S().Error("create telegram handler", zap.Error(err))
-		os.Exit(1)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Метрики обработчиков (expvar) отдаются отдельным сервером, только если задан адрес
+	if metricsAddr := os.Getenv("METRICS_ADDR"); metricsAddr != "" {
+		go func() {
+			if err := handler.ServeMetrics(ctx, metricsAddr); err != nil {
+				zap.S().Error("serve metrics", zap.Error(err))
+			}
+		}()
+	}
+
+	// Режим получения обновлений: polling (по умолчанию) или webhook
+	switch mode := os.Getenv("TELEGRAM_MODE"); mode {
+	case "", "polling":
+		err = bot.Start(ctx)
+	case "webhook":
+		webhookConfig := handler.WebhookConfig{
+			URL:               os.Getenv("WEBHOOK_URL"),
+			ListenAddr:        os.Getenv("WEBHOOK_LISTEN_ADDR"),
+			SecretToken:       os.Getenv("WEBHOOK_SECRET_TOKEN"),
+			CertFile:          os.Getenv("WEBHOOK_TLS_CERT"),
+			KeyFile:           os.Getenv("WEBHOOK_TLS_KEY"),
+			UploadCertificate: os.Getenv("WEBHOOK_UPLOAD_CERT") == "true",
+		}
+		if webhookConfig.ListenAddr == "" {
+			webhookConfig.ListenAddr = ":8080"
+		}
+
+		err = bot.StartWebhook(ctx, webhookConfig)
+	default:
+		err = fmt.Errorf("unknown TELEGRAM_MODE %q, expected polling or webhook", mode)
+	}
+
+	if err != nil {
+		zap.S().Error("run bot", zap.Error(err))
+		return 1
 	}
 
-	bot.Start()
+	zap.S().Info("bot stopped")
+	return 0
 }
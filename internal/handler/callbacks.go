@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// callbackTokenPrefix — префикс callback data кнопок, ссылающихся на токен.
+// Токен занимает 11 символов, поэтому callback data всегда укладывается в лимит Telegram в 64 байта,
+// какой бы длины ни был ID страницы OneNote. Сами действия хранятся в базе (callback_tokens),
+// поэтому кнопки работают после перезапуска бота и на любой реплике.
+const callbackTokenPrefix = "t_"
+
+// callbackBatch собирает токены кнопок одного сообщения, чтобы сохранить их одним запросом перед отправкой
+type callbackBatch struct {
+	tokens []*models.CallbackToken
+}
+
+// issue создаёт токен и возвращает готовую callback data для кнопки
+func (b *callbackBatch) issue(action, pageID string) string {
+	return b.add(&models.CallbackToken{PageID: pageID, Action: action})
+}
+
+// issueGrade создаёт токен кнопки оценки вместе с версией прогресса, показанной пользователю
+func (b *callbackBatch) issueGrade(pageID string, grade, version int) string {
+	return b.add(&models.CallbackToken{PageID: pageID, Action: models.CallbackActionGrade, Grade: grade, Version: version})
+}
+
+// issueUndo создаёт токен кнопки отмены оценки с ключом reviewKey
+func (b *callbackBatch) issueUndo(pageID, reviewKey string) string {
+	return b.add(&models.CallbackToken{PageID: pageID, Action: models.CallbackActionUndo, ReviewKey: reviewKey})
+}
+
+func (b *callbackBatch) add(token *models.CallbackToken) string {
+	token.Token = newCallbackToken()
+	b.tokens = append(b.tokens, token)
+	return callbackTokenPrefix + token.Token
+}
+
+// saveCallbacks сохраняет токены кнопок; без сохранённых токенов кнопки отправлять нельзя — они сразу устареют
+func (h *TelegramHandler) saveCallbacks(ctx context.Context, userID int64, batch *callbackBatch) error {
+	return h.service.SaveCallbackTokens(ctx, userID, batch.tokens)
+}
+
+// callbackTokenFromData достаёт токен из callback data кнопки
+func callbackTokenFromData(data string) (string, bool) {
+	token, ok := strings.CutPrefix(data, callbackTokenPrefix)
+	return token, ok && token != ""
+}
+
+func newCallbackToken() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"go.uber.org/zap"
+)
+
+// Callback data плана возвращения: comeback_plan_<N> пересчитывает план с темпом N страниц в день,
+// comeback_accept_<N>_<отпечаток> применяет показанный план, comeback_keep оставляет все повторения на сегодня
+const (
+	comebackPrefix       = "comeback_"
+	comebackPlanPrefix   = comebackPrefix + "plan_"
+	comebackAcceptPrefix = comebackPrefix + "accept_"
+	comebackKeep         = comebackPrefix + "keep"
+)
+
+// comebackVisibleDays — сколько дней плана расписывается по страницам, остальные только пересчитываются
+const comebackVisibleDays = 7
+
+// handleComeback показывает план возвращения по запросу, даже если перерыва не было
+func (h *TelegramHandler) handleComeback(ctx context.Context, req *request) error {
+	plan, err := h.service.GetComebackPlan(ctx, req.userID, 0)
+	if err != nil {
+		return withReply(fmt.Errorf("get comeback plan: %w", err), req.loc.T("error.short"))
+	}
+
+	if plan == nil {
+		h.sendMessage(req.chatID, req.loc.T("comeback.nothing"))
+		return nil
+	}
+
+	text, keyboard := renderComebackPlan(req.loc, plan)
+	h.sendMessageWithKeyboard(req.chatID, text, keyboard)
+	return nil
+}
+
+// suggestComebackPlan предлагает план вернувшемуся после перерыва пользователю. Возвращает true, если план показан.
+func (h *TelegramHandler) suggestComebackPlan(ctx context.Context, req *request) (bool, error) {
+	plan, err := h.service.SuggestComebackPlan(ctx, req.userID)
+	if err != nil {
+		return false, err
+	}
+
+	if plan == nil {
+		return false, nil
+	}
+
+	text, keyboard := renderComebackPlan(req.loc, plan)
+	h.sendMessageWithKeyboard(req.chatID, text, keyboard)
+	return true, nil
+}
+
+// handleComebackCallback меняет темп плана, применяет его или отказывается от него, редактируя сообщение с планом
+func (h *TelegramHandler) handleComebackCallback(ctx context.Context, req *request) error {
+	data := req.callback.Data
+	messageID := req.callback.Message.MessageID
+
+	if data == comebackKeep {
+		if err := h.service.DismissComebackPlan(ctx, req.userID); err != nil {
+			return withReply(fmt.Errorf("dismiss comeback plan: %w", err), req.loc.T("error.generic"))
+		}
+		h.editMessage(req.chatID, messageID, req.loc.T("comeback.kept"), nil)
+		return nil
+	}
+
+	var action, value, fingerprint string
+	switch {
+	case strings.HasPrefix(data, comebackPlanPrefix):
+		action = comebackPlanPrefix
+		value = strings.TrimPrefix(data, comebackPlanPrefix)
+	case strings.HasPrefix(data, comebackAcceptPrefix):
+		action = comebackAcceptPrefix
+		value, fingerprint, _ = strings.Cut(strings.TrimPrefix(data, comebackAcceptPrefix), "_")
+	default:
+		zap.S().Warn("unknown comeback action", zap.String("data", data), zap.Int64("telegram_id", req.userID))
+		return nil
+	}
+
+	pagesPerDay, err := strconv.Atoi(value)
+	if err != nil || pagesPerDay < 1 || pagesPerDay > service.MaxComebackPagesPerDay {
+		return withReply(fmt.Errorf("invalid comeback callback %q", data), req.loc.T("error.generic"))
+	}
+
+	if action == comebackAcceptPrefix {
+		plan, err := h.service.ApplyComebackPlan(ctx, req.userID, pagesPerDay, fingerprint)
+		if errors.Is(err, service.ErrComebackPlanChanged) {
+			// Показанный план устарел: вместо него показывается новый, принять его можно той же кнопкой
+			return h.editComebackPlan(ctx, req, pagesPerDay, req.loc.T("comeback.changed"))
+		}
+		if err != nil {
+			return withReply(fmt.Errorf("apply comeback plan: %w", err), req.loc.T("error.generic"))
+		}
+
+		text := req.loc.T("comeback.kept")
+		if plan != nil {
+			days := len(plan.Days)
+			text = req.loc.N("comeback.accepted", plan.PagesPerDay, plan.PagesPerDay, req.loc.N("interval.days", days, days))
+		}
+		h.editMessage(req.chatID, messageID, text, nil)
+		return nil
+	}
+
+	return h.editComebackPlan(ctx, req, pagesPerDay, "")
+}
+
+// editComebackPlan заменяет сообщение с планом планом с темпом pagesPerDay, notice — строка перед планом
+func (h *TelegramHandler) editComebackPlan(ctx context.Context, req *request, pagesPerDay int, notice string) error {
+	messageID := req.callback.Message.MessageID
+
+	plan, err := h.service.GetComebackPlan(ctx, req.userID, pagesPerDay)
+	if err != nil {
+		return withReply(fmt.Errorf("get comeback plan: %w", err), req.loc.T("error.short"))
+	}
+
+	if plan == nil {
+		h.editMessage(req.chatID, messageID, req.loc.T("comeback.nothing"), nil)
+		return nil
+	}
+
+	text, keyboard := renderComebackPlan(req.loc, plan)
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	h.editMessage(req.chatID, messageID, text, &keyboard)
+	return nil
+}
+
+// renderComebackPlan описывает план по дням и кнопки изменения темпа, принятия и отказа
+func renderComebackPlan(loc i18n.Localizer, plan *models.ComebackPlan) (string, tgbotapi.InlineKeyboardMarkup) {
+	total := 0
+	for _, day := range plan.Days {
+		total += len(day.Pages)
+	}
+
+	lines := []string{
+		loc.N("comeback.title", total, total),
+		loc.T("comeback.pace", plan.PagesPerDay, loc.N("interval.days", len(plan.Days), len(plan.Days))),
+		"",
+	}
+
+	for i, day := range plan.Days {
+		if i == comebackVisibleDays {
+			more := len(plan.Days) - comebackVisibleDays
+			lines = append(lines, loc.N("comeback.more_days", more, more))
+			break
+		}
+
+		label := day.Date.Format("02.01")
+		if i == 0 {
+			label = loc.T("comeback.today")
+		}
+
+		titles := make([]string, 0, len(day.Pages))
+		for _, pwp := range day.Pages {
+			titles = append(titles, escapeHTML(pwp.Page.Title))
+		}
+		lines = append(lines, fmt.Sprintf("<b>%s</b>: %s", label, strings.Join(titles, ", ")))
+	}
+
+	lines = append(lines, "", loc.T("comeback.hint"))
+
+	var paceButtons []tgbotapi.InlineKeyboardButton
+	if plan.PagesPerDay > 1 {
+		paceButtons = append(paceButtons, tgbotapi.NewInlineKeyboardButtonData(loc.T("comeback.button.slower"), comebackPlanPrefix+strconv.Itoa(plan.PagesPerDay-1)))
+	}
+	if plan.PagesPerDay < service.MaxComebackPagesPerDay {
+		paceButtons = append(paceButtons, tgbotapi.NewInlineKeyboardButtonData(loc.T("comeback.button.faster"), comebackPlanPrefix+strconv.Itoa(plan.PagesPerDay+1)))
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(loc.T("comeback.button.accept"), comebackAcceptPrefix+strconv.Itoa(plan.PagesPerDay)+"_"+plan.Fingerprint())),
+	}
+	if len(paceButtons) > 0 {
+		rows = append(rows, paceButtons)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(loc.T("comeback.button.keep"), comebackKeep)))
+
+	return strings.Join(lines, "\n"), tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"go.uber.org/zap"
+)
+
+// onboardingSteps — порядок шагов регистрации: язык → уровень → лимит страниц → таймзона → OneNote → секция
+var onboardingSteps = []string{
+	models.ChatStateOnboardingLanguage,
+	models.ChatStateOnboardingLevel,
+	models.ChatStateOnboardingMaxPages,
+	models.ChatStateOnboardingTimezone,
+	models.ChatStateOnboardingAuthCode,
+	models.ChatStateOnboardingSection,
+}
+
+// chatStateKeyPageID — ключ данных состояния с ID страницы, на которую ждём ответ
+const chatStateKeyPageID = "page_id"
+
+// chatStateKeyVersion — ключ данных состояния с версией прогресса страницы на момент её показа
+const chatStateKeyVersion = "version"
+
+// chatStateKeyShownAt — ключ данных состояния со временем показа страницы, от него считается время ответа
+const chatStateKeyShownAt = "shown_at"
+
+// chatStateKeyLanguage — ключ данных состояния с языком, выбранным до регистрации
+const chatStateKeyLanguage = "language"
+
+func isOnboardingState(state string) bool {
+	for _, step := range onboardingSteps {
+		if step == state {
+			return true
+		}
+	}
+	return false
+}
+
+// nextOnboardingState возвращает шаг, следующий за текущим, после последнего шага — ChatStateIdle
+func nextOnboardingState(state string) string {
+	for i, step := range onboardingSteps {
+		if step == state && i+1 < len(onboardingSteps) {
+			return onboardingSteps[i+1]
+		}
+	}
+	return models.ChatStateIdle
+}
+
+// chatState возвращает состояние диалога в чате, при ошибке чтения считаем чат свободным
+func (h *TelegramHandler) chatState(ctx context.Context, chatID int64) *models.ChatState {
+	state, err := h.service.GetChatState(ctx, chatID)
+	if err != nil {
+		zap.S().Error("get chat state", zap.Error(err), zap.Int64("chat_id", chatID))
+		return &models.ChatState{ChatID: chatID, State: models.ChatStateIdle}
+	}
+	return state
+}
+
+func (h *TelegramHandler) setChatState(ctx context.Context, chatID, userID int64, state string, data map[string]string) {
+	if err := h.service.SetChatState(ctx, chatID, userID, state, data); err != nil {
+		zap.S().Error("set chat state", zap.Error(err), zap.Int64("chat_id", chatID), zap.Int64("telegram_id", userID), zap.String("state", state))
+	}
+}
+
+// advanceOnboarding переводит чат на следующий шаг регистрации, если он сейчас на шаге from,
+// и отправляет подсказку для нового шага. Возвращает false, если чат не на этом шаге регистрации.
+// Данные состояния переносятся на следующий шаг.
+func (h *TelegramHandler) advanceOnboarding(ctx context.Context, loc i18n.Localizer, chatID, userID int64, from string) (bool, error) {
+	state := h.chatState(ctx, chatID)
+	if state.State != from {
+		return false, nil
+	}
+
+	next := nextOnboardingState(from)
+	h.setChatState(ctx, chatID, userID, next, state.Data)
+
+	return true, h.promptOnboarding(ctx, loc, chatID, userID, next)
+}
+
+// promptOnboarding отправляет сообщение, с которого начинается шаг регистрации
+func (h *TelegramHandler) promptOnboarding(ctx context.Context, loc i18n.Localizer, chatID, userID int64, state string) error {
+	switch state {
+	case models.ChatStateOnboardingLanguage:
+		h.showLanguageSelector(loc, chatID)
+	case models.ChatStateOnboardingLevel:
+		h.showLevelSelector(loc, chatID)
+	case models.ChatStateOnboardingMaxPages:
+		h.sendMessage(chatID, loc.T("onboarding.max_pages"))
+		h.showMaxPagesSelector(loc, chatID)
+	case models.ChatStateOnboardingTimezone:
+		h.sendMessage(chatID, loc.T("onboarding.timezone"))
+		h.showTimezoneSelector(loc, chatID)
+	case models.ChatStateOnboardingAuthCode:
+		h.sendAuthLink(loc, chatID, userID)
+	case models.ChatStateOnboardingSection:
+		h.sendMessage(chatID, loc.T("onboarding.section"))
+		return h.showPicker(ctx, loc, userID, chatID, nil)
+	}
+	return nil
+}
+
+func (h *TelegramHandler) sendAuthLink(loc i18n.Localizer, chatID, userID int64) {
+	h.sendMessage(chatID, loc.T("auth.link", h.service.GetAuthURL(userID)))
+}
+
+// handleTextMessage направляет свободный текст по состоянию диалога в чате
+func (h *TelegramHandler) handleTextMessage(ctx context.Context, req *request) error {
+	state := h.chatState(ctx, req.chatID)
+
+	switch state.State {
+	case models.ChatStateOnboardingAuthCode, models.ChatStateAwaitingAuthCode:
+		return h.handleAuthCodeInput(ctx, req, state)
+	case models.ChatStateAwaitingManualPage:
+		return h.handleManualPageInput(ctx, req)
+	case models.ChatStateAwaitingAnswer:
+		return h.handleAnswerInput(ctx, req, state)
+	case models.ChatStateOnboardingLanguage, models.ChatStateOnboardingLevel, models.ChatStateOnboardingMaxPages, models.ChatStateOnboardingTimezone, models.ChatStateOnboardingSection:
+		return reply(req.loc.T("onboarding.use_buttons"))
+	default:
+		return reply(req.loc.T("text.unknown"))
+	}
+}
+
+func (h *TelegramHandler) handleAuthCodeInput(ctx context.Context, req *request, state *models.ChatState) error {
+	if err := h.authorize(ctx, req, requireRegistered); err != nil {
+		return err
+	}
+
+	code := strings.TrimSpace(req.message.Text)
+	if err := h.service.ExchangeAuthCode(ctx, req.userID, code); err != nil {
+		return withReply(fmt.Errorf("exchange auth code: %w", err), req.loc.T("auth.code_failed"))
+	}
+
+	if state.State == models.ChatStateOnboardingAuthCode {
+		h.sendMessage(req.chatID, req.loc.T("auth.success"))
+		_, err := h.advanceOnboarding(ctx, req.loc, req.chatID, req.userID, models.ChatStateOnboardingAuthCode)
+		return err
+	}
+
+	h.setChatState(ctx, req.chatID, req.userID, models.ChatStateIdle, nil)
+	h.sendMessage(req.chatID, req.loc.T("auth.updated"))
+	return nil
+}
+
+func (h *TelegramHandler) handleManualPageInput(ctx context.Context, req *request) error {
+	done, err := h.addPageManually(ctx, req.loc, req.userID, req.chatID, req.message.Text)
+	if done {
+		h.setChatState(ctx, req.chatID, req.userID, models.ChatStateIdle, nil)
+	}
+	return err
+}
+
+// addPageManually добавляет страницу по запросу пользователя. done == false — запрос нужно повторить,
+// при ошибке ожидание запроса тоже завершается
+func (h *TelegramHandler) addPageManually(ctx context.Context, loc i18n.Localizer, userID, chatID int64, query string) (done bool, err error) {
+	page, err := h.service.AddPageManually(ctx, userID, query)
+	switch {
+	case err == nil:
+		h.sendMessage(chatID, loc.T("add_page.added", escapeHTML(page.Title)))
+		return true, nil
+	case errors.Is(err, service.ErrPageNotFound):
+		h.sendMessage(chatID, loc.T("add_page.not_found"))
+		return false, nil
+	case errors.Is(err, service.ErrPageAlreadyInProgress):
+		h.sendMessage(chatID, loc.T("add_page.already"))
+		return false, nil
+	default:
+		return true, withReply(fmt.Errorf("add page manually: %w", err), loc.T("add_page.failed"))
+	}
+}
+
+// handleAnswerInput принимает результат повторения, набранный текстом: процент от 0 до 100
+func (h *TelegramHandler) handleAnswerInput(ctx context.Context, req *request, state *models.ChatState) error {
+	userID := req.userID
+	chatID := req.chatID
+
+	pageID := state.Data[chatStateKeyPageID]
+	if pageID == "" {
+		h.setChatState(ctx, chatID, userID, models.ChatStateIdle, nil)
+		return reply(req.loc.T("text.unknown"))
+	}
+
+	text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(req.message.Text), "%"))
+	grade, err := strconv.Atoi(text)
+	if err != nil || grade < 0 || grade > 100 {
+		return reply(req.loc.T("answer.invalid"))
+	}
+
+	// Состояние, сохранённое до появления версий, не знает версию, показанную пользователю, — берём текущую
+	version, err := strconv.Atoi(state.Data[chatStateKeyVersion])
+	if err != nil {
+		progress, err := h.service.GetProgress(ctx, userID, pageID)
+		if err != nil {
+			h.setChatState(ctx, chatID, userID, models.ChatStateIdle, nil)
+			return withReply(fmt.Errorf("get progress %s: %w", pageID, err), req.loc.T("review.failed"))
+		}
+		version = progress.Version
+	}
+
+	submission := models.ReviewSubmission{
+		PageID:  pageID,
+		Grade:   grade,
+		Version: version,
+		// ID сообщения служит ключом идемпотентности: повторная доставка того же сообщения не оценит страницу второй раз
+		Key:    fmt.Sprintf("msg_%d", req.message.MessageID),
+		Source: models.ReviewSourceTyped,
+	}
+	if shownAt, err := time.Parse(time.RFC3339Nano, state.Data[chatStateKeyShownAt]); err == nil {
+		submission.ShownAt = &shownAt
+	}
+
+	h.setChatState(ctx, chatID, userID, models.ChatStateIdle, nil)
+	return h.updateReviewProgress(ctx, req.loc, userID, chatID, submission)
+}
+
+func (h *TelegramHandler) handleAddPage(ctx context.Context, req *request) error {
+	if query := strings.TrimSpace(req.message.CommandArguments()); query != "" {
+		done, err := h.addPageManually(ctx, req.loc, req.userID, req.chatID, query)
+		if !done {
+			h.setChatState(ctx, req.chatID, req.userID, models.ChatStateAwaitingManualPage, nil)
+		}
+		return err
+	}
+
+	h.setChatState(ctx, req.chatID, req.userID, models.ChatStateAwaitingManualPage, nil)
+	h.sendMessage(req.chatID, req.loc.T("add_page.prompt"))
+	return nil
+}
+
+func (h *TelegramHandler) handleCancel(ctx context.Context, req *request) error {
+	state := h.chatState(ctx, req.chatID)
+	if state.State == models.ChatStateIdle {
+		return reply(req.loc.T("cancel.nothing"))
+	}
+
+	h.setChatState(ctx, req.chatID, req.userID, models.ChatStateIdle, nil)
+
+	if isOnboardingState(state.State) {
+		h.sendMessage(req.chatID, req.loc.T("cancel.onboarding"))
+		return nil
+	}
+
+	h.sendMessage(req.chatID, req.loc.T("cancel.done"))
+	return nil
+}
+
+// resetInputState сбрасывает ожидание свободного текста, когда пользователь переходит к другой команде.
+// Шаги регистрации не сбрасываются, их прерывает только /cancel.
+func (h *TelegramHandler) resetInputState(ctx context.Context, chatID, userID int64) {
+	state := h.chatState(ctx, chatID)
+	switch state.State {
+	case models.ChatStateAwaitingAuthCode, models.ChatStateAwaitingManualPage, models.ChatStateAwaitingAnswer:
+		h.setChatState(ctx, chatID, userID, models.ChatStateIdle, nil)
+	}
+}
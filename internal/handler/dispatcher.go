@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// updateTimeout — сколько может обрабатываться одно обновление, включая запросы к OneNote
+const updateTimeout = 2 * time.Minute
+
+// maxConcurrentUsers — сколько пользователей обрабатываются одновременно
+const maxConcurrentUsers = 16
+
+// maxQueuedUpdates — сколько обновлений одного пользователя может ждать обработки. Обновления сверх этого
+// отбрасываются, чтобы пользователь, засыпающий бота сообщениями, не копил очередь без ограничений.
+const maxQueuedUpdates = 50
+
+// updateDispatcher обрабатывает обновления разных пользователей параллельно,
+// а обновления одного пользователя — строго по очереди в порядке получения.
+// Для каждого пользователя с непустой очередью работает одна горутина, число одновременно
+// работающих горутин ограничено семафором, а длина очереди пользователя — queueLimit.
+type updateDispatcher struct {
+	handle  func(ctx context.Context, update tgbotapi.Update)
+	onPanic func(update tgbotapi.Update, panicValue any)
+	timeout time.Duration
+
+	// ctx — родительский контекст всех обновлений, отменяется, если обработчики не успели завершиться при остановке
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	sem        chan struct{}
+	queueLimit int
+	mu         sync.Mutex
+	queues     map[int64][]tgbotapi.Update
+	wg         sync.WaitGroup
+}
+
+func newUpdateDispatcher(
+	workers, queueLimit int,
+	timeout time.Duration,
+	handle func(ctx context.Context, update tgbotapi.Update),
+	onPanic func(update tgbotapi.Update, panicValue any),
+) *updateDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &updateDispatcher{
+		ctx:        ctx,
+		cancel:     cancel,
+		handle:     handle,
+		onPanic:    onPanic,
+		timeout:    timeout,
+		sem:        make(chan struct{}, workers),
+		queueLimit: queueLimit,
+		queues:     make(map[int64][]tgbotapi.Update),
+	}
+}
+
+// dispatch ставит обновление в очередь пользователя и запускает её обработку, если она ещё не идёт.
+// Возвращает false, если очередь пользователя заполнена и обновление отброшено.
+func (d *updateDispatcher) dispatch(update tgbotapi.Update) bool {
+	userID := updateUserID(update)
+
+	d.mu.Lock()
+	queue, running := d.queues[userID]
+	if len(queue) >= d.queueLimit {
+		d.mu.Unlock()
+		metricUpdatesDropped.Add(1)
+		zap.S().Warn("user update queue is full, update dropped", zap.Int64("telegram_id", userID), zap.Int("update_id", update.UpdateID))
+		return false
+	}
+	d.queues[userID] = append(queue, update)
+	d.mu.Unlock()
+
+	if running {
+		return true
+	}
+
+	d.wg.Add(1)
+	go d.run(userID)
+	return true
+}
+
+// wait дожидается обработки всех поставленных в очередь обновлений
+func (d *updateDispatcher) wait() {
+	d.wg.Wait()
+}
+
+// stop отменяет контексты обновлений, которые ещё обрабатываются
+func (d *updateDispatcher) stop() {
+	d.cancel()
+}
+
+func (d *updateDispatcher) run(userID int64) {
+	defer d.wg.Done()
+
+	d.sem <- struct{}{}
+	defer func() { <-d.sem }()
+
+	for {
+		d.mu.Lock()
+		queue := d.queues[userID]
+		if len(queue) == 0 {
+			// Очередь удаляется под той же блокировкой, что и проверка в dispatch, поэтому обновления не теряются
+			delete(d.queues, userID)
+			d.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		d.queues[userID] = queue[1:]
+		d.mu.Unlock()
+
+		d.process(update)
+	}
+}
+
+func (d *updateDispatcher) process(update tgbotapi.Update) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			zap.S().Error("panic while handling update",
+				zap.Any("panic", r),
+				zap.Int("update_id", update.UpdateID),
+				zap.Int64("telegram_id", updateUserID(update)),
+				zap.Stack("stack"))
+			d.onPanic(update, r)
+		}
+	}()
+
+	d.handle(ctx, update)
+}
+
+// updateUserID возвращает ID пользователя, от которого пришло обновление, или 0, если его нет
+func updateUserID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil && update.Message.From != nil:
+		return update.Message.From.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.From != nil:
+		return update.CallbackQuery.From.ID
+	}
+	return 0
+}
+
+// updateChatID возвращает чат, в который нужно отвечать на обновление, или 0, если его нет
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	}
+	return 0
+}
+
+// updateLanguageCode возвращает язык клиента Telegram отправителя обновления
+func updateLanguageCode(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil && update.Message.From != nil:
+		return update.Message.From.LanguageCode
+	case update.CallbackQuery != nil && update.CallbackQuery.From != nil:
+		return update.CallbackQuery.From.LanguageCode
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/handler/messengertest"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+func newTestUpdateWithID(userID int64, updateID int) tgbotapi.Update {
+	update := newTestUpdate(userID)
+	update.UpdateID = updateID
+	return update
+}
+
+func TestDispatcherKeepsUserOrder(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[int64][]int)
+
+	d := newUpdateDispatcher(4, maxQueuedUpdates, time.Minute, func(_ context.Context, update tgbotapi.Update) {
+		// Разная длительность обработки перемешала бы порядок, если бы обновления одного пользователя шли параллельно
+		time.Sleep(time.Duration(update.UpdateID%3) * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		userID := updateUserID(update)
+		handled[userID] = append(handled[userID], update.UpdateID)
+	}, func(tgbotapi.Update, any) {})
+
+	for i := range 20 {
+		for userID := int64(1); userID <= 3; userID++ {
+			d.dispatch(newTestUpdateWithID(userID, i))
+		}
+	}
+	d.wait()
+
+	for userID := int64(1); userID <= 3; userID++ {
+		got := handled[userID]
+		if len(got) != 20 || !slices.IsSorted(got) {
+			t.Errorf("user %d updates handled in order %v, want 0..19", userID, got)
+		}
+	}
+}
+
+func TestDispatcherHandlesUsersInParallel(t *testing.T) {
+	started := make(chan int64, 2)
+	release := make(chan struct{})
+
+	d := newUpdateDispatcher(2, maxQueuedUpdates, time.Minute, func(_ context.Context, update tgbotapi.Update) {
+		started <- updateUserID(update)
+		<-release
+	}, func(tgbotapi.Update, any) {})
+
+	d.dispatch(newTestUpdate(1))
+	d.dispatch(newTestUpdate(2))
+
+	// Оба обработчика должны начаться, пока ни один не завершился
+	timeout := time.After(time.Second)
+	for range 2 {
+		select {
+		case <-started:
+		case <-timeout:
+			close(release)
+			t.Fatal("updates of different users are not handled in parallel")
+		}
+	}
+
+	close(release)
+	d.wait()
+}
+
+func TestDispatcherDropsUpdatesOverQueueLimit(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []int
+
+	d := newUpdateDispatcher(1, 2, time.Minute, func(_ context.Context, update tgbotapi.Update) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, update.UpdateID)
+	}, func(tgbotapi.Update, any) {})
+
+	// Первое обновление уходит в обработку, два следующих ждут в очереди, остальные отбрасываются
+	accepted := []bool{d.dispatch(newTestUpdateWithID(1, 0))}
+	<-started
+	for i := 1; i < 5; i++ {
+		accepted = append(accepted, d.dispatch(newTestUpdateWithID(1, i)))
+	}
+
+	// Очередь другого пользователя не зависит от переполненной
+	otherAccepted := d.dispatch(newTestUpdateWithID(2, 10))
+
+	close(release)
+	d.wait()
+
+	if want := []bool{true, true, true, false, false}; !slices.Equal(accepted, want) {
+		t.Errorf("accepted = %v, want %v", accepted, want)
+	}
+	if !otherAccepted {
+		t.Error("update of another user dropped")
+	}
+	slices.Sort(handled)
+	if want := []int{0, 1, 2, 10}; !slices.Equal(handled, want) {
+		t.Errorf("handled updates %v, want %v", handled, want)
+	}
+}
+
+func TestDispatcherRecoversFromPanic(t *testing.T) {
+	svc := newTestService()
+	svc.GetStatsFunc = func(context.Context, int64) (*models.UserStats, error) {
+		panic("stats exploded")
+	}
+	recorder := messengertest.NewRecorder()
+	h := NewTelegramHandlerWithMessenger(recorder, svc, clocktest.NewManualClock(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)))
+
+	h.dispatcher.dispatch(newCommandUpdate("/stats"))
+	// Паника в обработке одного обновления не останавливает очередь пользователя
+	h.dispatcher.dispatch(newCommandUpdate("/get_max_pages"))
+	h.dispatcher.wait()
+
+	texts := recorder.Texts()
+	loc := i18n.New("ru")
+	if !slices.Contains(texts, loc.T("error.generic")) {
+		t.Errorf("user was not told about the failure, sent: %q", texts)
+	}
+	if len(texts) < 2 {
+		t.Errorf("update after the panic was not handled, sent: %q", texts)
+	}
+}
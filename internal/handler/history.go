@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"github.com/romanzh1/master-english-srs/internal/service/srs"
+	"github.com/romanzh1/master-english-srs/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// gradeButtons — кнопки оценки страницы. Середины диапазонов: 80-100 → 90, 60-80 → 70, 40-60 → 50, 0-40 → 30
+var gradeButtons = []struct {
+	key   string
+	grade int
+}{
+	{key: "grade.easy", grade: 90},
+	{key: "grade.normal", grade: 70},
+	{key: "grade.hard", grade: 50},
+	{key: "grade.forgot", grade: 30},
+}
+
+// historyVisibleEvents — сколько последних оценок показывается в истории страницы
+const historyVisibleEvents = 15
+
+// historyVisibleWords — сколько слов страницы показывается в истории страницы
+const historyVisibleWords = 20
+
+// handlePageHistory показывает историю страницы: оценки, интервалы, срывы, переход в AI режим и прогноз для кнопок оценки
+func (h *TelegramHandler) handlePageHistory(ctx context.Context, req *request, pageID string) error {
+	grades := make([]int, 0, len(gradeButtons))
+	for _, button := range gradeButtons {
+		grades = append(grades, button.grade)
+	}
+
+	history, err := h.service.GetPageHistory(ctx, req.userID, pageID, grades)
+	if errors.Is(err, service.ErrPageNotFound) {
+		return reply(req.loc.T("history.not_found"))
+	}
+	if err != nil {
+		return withReply(fmt.Errorf("get page history %s: %w", pageID, err), req.loc.T("error.short"))
+	}
+
+	timezone := "UTC"
+	if req.user.Timezone != nil && *req.user.Timezone != "" {
+		timezone = *req.user.Timezone
+	}
+
+	// Слова извлекаются при синхронизации, без них история всё равно полезна
+	items, err := h.service.GetPageItems(ctx, req.userID, pageID)
+	if err != nil {
+		zap.S().Error("get page items", zap.Error(err), zap.Int64("telegram_id", req.userID), zap.String("page_id", pageID))
+	}
+
+	h.sendLongMessageWithKeyboard(req.chatID, renderPageHistory(req.loc, history, items, timezone), nil)
+	return nil
+}
+
+// renderPageHistory описывает историю страницы: текущее состояние, оценки от старых к новым, слова страницы и прогноз
+func renderPageHistory(loc i18n.Localizer, history *models.PageHistory, items []*models.PageItem, timezone string) string {
+	progress := history.Progress
+
+	lines := []string{
+		loc.T("history.title", escapeHTML(history.Page.Title)),
+		"",
+	}
+
+	if progress.Passed {
+		lines = append(lines, loc.T("history.passed"))
+	} else {
+		lines = append(lines,
+			formatIntervalProgress(loc, progress.IntervalDays),
+			loc.T("pages.next_review", formatUserDate(progress.NextReviewDate, timezone)),
+		)
+	}
+
+	lines = append(lines, loc.T("history.summary", loc.N("count.grades", len(history.Events), len(history.Events)), loc.N("count.lapses", history.Lapses, history.Lapses)))
+	if history.AIModeSince != nil {
+		lines = append(lines, loc.T("history.ai_since", formatUserDate(*history.AIModeSince, timezone)))
+	} else if progress.IntervalDays == 0 {
+		lines = append(lines, loc.T("history.reading_mode"))
+	}
+
+	lines = append(lines, "", loc.T("history.events.title"))
+	if len(history.Events) == 0 {
+		lines = append(lines, loc.T("history.events.empty"))
+	}
+
+	events := history.Events
+	if hidden := len(events) - historyVisibleEvents; hidden > 0 {
+		lines = append(lines, loc.N("history.events.more", hidden, hidden))
+		events = events[hidden:]
+	}
+	for _, event := range events {
+		lines = append(lines, renderHistoryEvent(loc, event, timezone))
+	}
+
+	if len(items) > 0 {
+		lines = append(lines, "", loc.N("history.words.title", len(items), len(items)))
+		for _, item := range items[:min(len(items), historyVisibleWords)] {
+			lines = append(lines, loc.T("history.words.line", escapeHTML(item.Word), escapeHTML(item.Translation)))
+		}
+		if hidden := len(items) - historyVisibleWords; hidden > 0 {
+			lines = append(lines, loc.N("history.words.more", hidden, hidden))
+		}
+	}
+
+	if len(history.Forecast) > 0 {
+		lines = append(lines, "", loc.T("history.forecast.title"))
+		// Прогноз идёт в порядке gradeButtons
+		for i, forecast := range history.Forecast {
+			name := loc.T(gradeButtons[i].key)
+			if forecast.Passed {
+				lines = append(lines, loc.T("history.forecast.passed", name))
+				continue
+			}
+			lines = append(lines, loc.T("history.forecast.line", name, historyInterval(loc, forecast.IntervalDays), forecast.NextReviewDate.Format("02.01.2006")))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderHistoryEvent описывает одну оценку: дату, результат, изменение интервала и отметку срыва
+func renderHistoryEvent(loc i18n.Localizer, event *models.ProgressHistory, timezone string) string {
+	line := fmt.Sprintf("%s %s %d%%", formatUserDate(event.Date, timezone), gradeEmoji(event.Score), event.Score)
+
+	if event.Before != nil && event.After != nil {
+		line += " · " + loc.T("history.interval", historyInterval(loc, event.Before.IntervalDays), historyInterval(loc, event.After.IntervalDays))
+	} else if event.Mode == srs.ModeReading {
+		line += " · " + loc.T("history.mode.reading")
+	}
+
+	status := srs.ConvertGradeToStatus(event.Score)
+	if event.Mode == srs.ModeReading && status.Remembered() {
+		line += " " + loc.T("history.mark.ai")
+	}
+	if event.Mode == srs.ModeStandard && status == srs.Forgot {
+		line += " " + loc.T("history.mark.lapse")
+	}
+	return line
+}
+
+// historyInterval — интервал для истории: 0 показывается как режим чтения
+func historyInterval(loc i18n.Localizer, intervalDays int) string {
+	if intervalDays == 0 {
+		return loc.T("history.mode.reading")
+	}
+	return loc.N("interval.days", intervalDays, intervalDays)
+}
+
+// formatUserDate показывает дату момента t в таймзоне пользователя
+func formatUserDate(t time.Time, timezone string) string {
+	local, err := utils.ToUserTimezone(t, timezone)
+	if err != nil {
+		zap.S().Warn("failed to convert date to user timezone", zap.Error(err), zap.String("timezone", timezone))
+		local = t
+	}
+	return local.Format("02.01.2006")
+}
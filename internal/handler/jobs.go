@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"go.uber.org/zap"
+)
+
+const (
+	// jobPollInterval — как часто планировщик ищет задачи, время которых наступило
+	jobPollInterval = time.Minute
+	// jobHistoryPruneInterval — как часто удаляется старая история запусков задач
+	jobHistoryPruneInterval = time.Hour
+)
+
+// startJobScheduler выполняет задачи из таблицы jobs при старте и затем каждую минуту, пока ctx не отменён.
+// Несколько реплик делят задачи между собой: каждую задачу занимает один процесс.
+func (h *TelegramHandler) startJobScheduler(ctx context.Context) {
+	h.pruneJobHistory(ctx)
+	h.runDueJobs(ctx)
+
+	pollTicker := time.NewTicker(jobPollInterval)
+	defer pollTicker.Stop()
+
+	pruneTicker := time.NewTicker(jobHistoryPruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pollTicker.C:
+			h.runDueJobs(ctx)
+		case <-pruneTicker.C:
+			h.pruneJobHistory(ctx)
+		}
+	}
+}
+
+// runDueJobs выполняет наступившие задачи пачками, пока они не кончатся или ctx не будет отменён.
+// Занятая задача не прерывается отменой ctx, остановка дожидается её завершения. Каждая задача выполняется
+// не дольше service.JobTimeout и заканчивается до конца своей аренды, поэтому её не займёт второй процесс.
+func (h *TelegramHandler) runDueJobs(ctx context.Context) {
+	runCtx := context.WithoutCancel(ctx)
+
+	for ctx.Err() == nil {
+		jobs, err := h.service.ClaimJobs(runCtx, h.clock.Now())
+		if err != nil {
+			zap.S().Error("claim jobs", zap.Error(err))
+			return
+		}
+
+		if len(jobs) == 0 {
+			return
+		}
+
+		for _, job := range jobs {
+			startedAt := h.clock.Now()
+
+			timeout := min(service.JobTimeout, job.LockedUntil.Sub(startedAt)-service.JobLeaseMargin)
+			if timeout <= 0 {
+				// Задача не освобождается: после конца аренды её займёт следующий проход
+				zap.S().Warn("job lease is about to expire, skip the job", zap.Int64("telegram_id", job.UserID), zap.String("job_type", job.JobType))
+				continue
+			}
+
+			jobCtx, cancel := context.WithTimeout(runCtx, timeout)
+			runErr := h.runJob(jobCtx, job)
+			cancel()
+			if runErr != nil {
+				zap.S().Warn("run job", zap.Error(runErr), zap.Int64("telegram_id", job.UserID), zap.String("job_type", job.JobType), zap.Int("attempt", job.Attempts+1))
+			}
+
+			if err := h.service.CompleteJob(runCtx, job, startedAt, runErr); err != nil {
+				zap.S().Error("complete job", zap.Error(err), zap.Int64("telegram_id", job.UserID), zap.String("job_type", job.JobType))
+			}
+		}
+	}
+}
+
+func (h *TelegramHandler) runJob(ctx context.Context, job *models.Job) error {
+	switch job.JobType {
+	case models.JobRollover:
+		return h.service.RunRollover(ctx, job.UserID)
+	case models.JobReminder:
+		return h.sendReminders(ctx, job.UserID)
+	case models.JobInactivity:
+		return h.service.CheckInactivity(ctx, job.UserID)
+	default:
+		return fmt.Errorf("unknown job type %q", job.JobType)
+	}
+}
+
+func (h *TelegramHandler) pruneJobHistory(ctx context.Context) {
+	if err := h.service.PruneJobHistory(context.WithoutCancel(ctx)); err != nil {
+		zap.S().Error("prune job history", zap.Error(err))
+	}
+}
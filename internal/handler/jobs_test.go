@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/handler/messengertest"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+func TestRunDueJobsKeepsJobsWithinLease(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	leased := now.Add(10 * time.Minute)
+	expiring := now.Add(service.JobLeaseMargin / 2)
+
+	svc := newTestService()
+	claimed := false
+	svc.ClaimJobsFunc = func(context.Context, time.Time) ([]*models.Job, error) {
+		if claimed {
+			return nil, nil
+		}
+		claimed = true
+		return []*models.Job{
+			{ID: 1, UserID: 1, JobType: models.JobInactivity, LockedUntil: &leased},
+			{ID: 2, UserID: 2, JobType: models.JobInactivity, LockedUntil: &expiring},
+		}, nil
+	}
+
+	var ran []int64
+	svc.CheckInactivityFunc = func(ctx context.Context, telegramID int64) error {
+		ran = append(ran, telegramID)
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Error("job runs without a deadline")
+		} else if time.Until(deadline) > service.JobTimeout {
+			t.Errorf("job deadline in %v, want at most %v", time.Until(deadline), service.JobTimeout)
+		}
+		return nil
+	}
+
+	var completed []int64
+	svc.CompleteJobFunc = func(_ context.Context, job *models.Job, _ time.Time, _ error) error {
+		completed = append(completed, job.ID)
+		return nil
+	}
+
+	h := NewTelegramHandlerWithMessenger(messengertest.NewRecorder(), svc, clocktest.NewManualClock(now))
+	h.runDueJobs(context.Background())
+
+	if len(ran) != 1 || ran[0] != 1 {
+		t.Fatalf("ran jobs of users %v, want only the job with enough lease left", ran)
+	}
+	if len(completed) != 1 || completed[0] != 1 {
+		t.Fatalf("completed jobs %v, want only the job that ran", completed)
+	}
+}
+
+func TestSendRemindersMarksSentAfterDelivery(t *testing.T) {
+	tests := []struct {
+		name    string
+		sendErr error
+		want    string
+		wantErr bool
+	}{
+		{name: "sent", want: "MarkRemindersSent"},
+		{name: "send failed", sendErr: errors.New("telegram is down"), want: "ReleaseReminder", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService()
+			svc.ClaimDueRemindersFunc = func(context.Context, int64, time.Time) ([]*models.DueReminder, error) {
+				return []*models.DueReminder{{UserID: 1, ReminderTime: "09:00", Language: "en"}}, nil
+			}
+			svc.GetDuePagesTodayFunc = func(context.Context, int64) ([]*models.PageWithProgress, error) {
+				return []*models.PageWithProgress{{}}, nil
+			}
+
+			recorder := messengertest.NewRecorder()
+			recorder.Err = tt.sendErr
+			h := NewTelegramHandlerWithMessenger(recorder, svc, clocktest.NewManualClock(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)))
+
+			if err := h.sendReminders(context.Background(), 1); (err != nil) != tt.wantErr {
+				t.Fatalf("sendReminders error = %v, want error %v", err, tt.wantErr)
+			}
+
+			calls := svc.Calls()
+			if !slices.Contains(calls, tt.want) {
+				t.Errorf("service.%s not called, calls: %v", tt.want, calls)
+			}
+			if slices.Contains(calls, "MarkRemindersSent") && slices.Contains(calls, "ReleaseReminder") {
+				t.Errorf("reminder both marked sent and released, calls: %v", calls)
+			}
+		})
+	}
+}
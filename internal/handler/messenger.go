@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Messenger — всё, что обработчикам нужно от Telegram для ответа пользователю.
+// Тексты отправляются в формате Telegram HTML.
+type Messenger interface {
+	// Send отправляет сообщение и возвращает его ID. keyboard == nil — без клавиатуры.
+	Send(chatID int64, text string, keyboard any) (int, error)
+	// Edit заменяет текст и клавиатуру отправленного сообщения, keyboard == nil убирает клавиатуру
+	Edit(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error
+	// AnswerCallback отвечает на нажатие кнопки, убирая индикатор загрузки; непустой text показывается всплывающим уведомлением
+	AnswerCallback(callbackID, text string) error
+	// SendDocument отправляет файл с подписью
+	SendDocument(chatID int64, name string, data []byte, caption string) error
+	// SendPhoto отправляет изображение с подписью
+	SendPhoto(chatID int64, name string, data []byte, caption string) error
+}
+
+// botMessenger отправляет сообщения через Telegram Bot API
+type botMessenger struct {
+	api *tgbotapi.BotAPI
+}
+
+func (m botMessenger) Send(chatID int64, text string, keyboard any) (int, error) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	// Используем HTML для форматирования текста (жирный шрифт через <b>текст</b>)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = keyboard
+
+	sent, err := m.api.Send(msg)
+	if err != nil {
+		return 0, fmt.Errorf("send message (chat_id: %d): %w", chatID, err)
+	}
+
+	return sent.MessageID, nil
+}
+
+func (m botMessenger) Edit(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	msg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = keyboard
+
+	if _, err := m.api.Send(msg); err != nil {
+		return fmt.Errorf("edit message (chat_id: %d, message_id: %d): %w", chatID, messageID, err)
+	}
+
+	return nil
+}
+
+func (m botMessenger) AnswerCallback(callbackID, text string) error {
+	if _, err := m.api.Request(tgbotapi.NewCallback(callbackID, text)); err != nil {
+		return fmt.Errorf("answer callback (callback_id: %s): %w", callbackID, err)
+	}
+
+	return nil
+}
+
+func (m botMessenger) SendDocument(chatID int64, name string, data []byte, caption string) error {
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	doc.Caption = caption
+	doc.ParseMode = tgbotapi.ModeHTML
+
+	if _, err := m.api.Send(doc); err != nil {
+		return fmt.Errorf("send document %s (chat_id: %d): %w", name, chatID, err)
+	}
+
+	return nil
+}
+
+func (m botMessenger) SendPhoto(chatID int64, name string, data []byte, caption string) error {
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	photo.Caption = caption
+	photo.ParseMode = tgbotapi.ModeHTML
+
+	if _, err := m.api.Send(photo); err != nil {
+		return fmt.Errorf("send photo %s (chat_id: %d): %w", name, chatID, err)
+	}
+
+	return nil
+}
+
+// newBotAPI создаёт клиент Bot API без запроса к Telegram. tgbotapi.NewBotAPI сразу вызывает getMe,
+// поэтому токен проверяется позже, при запуске бота.
+func newBotAPI(token string) (*tgbotapi.BotAPI, error) {
+	if token == "" {
+		return nil, errors.New("empty bot token")
+	}
+
+	api := &tgbotapi.BotAPI{
+		Token:  token,
+		Client: &http.Client{},
+		Buffer: 100,
+	}
+	api.SetAPIEndpoint(tgbotapi.APIEndpoint)
+
+	return api, nil
+}
@@ -0,0 +1,135 @@
+// Package messengertest содержит записывающую реализацию handler.Messenger для тестов обработчиков
+package messengertest
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SentMessage — сообщение, отправленное через Send
+type SentMessage struct {
+	ChatID    int64
+	MessageID int
+	Text      string
+	Keyboard  any
+}
+
+// EditedMessage — изменение сообщения через Edit
+type EditedMessage struct {
+	ChatID    int64
+	MessageID int
+	Text      string
+	Keyboard  *tgbotapi.InlineKeyboardMarkup
+}
+
+// CallbackAnswer — ответ на нажатие кнопки через AnswerCallback
+type CallbackAnswer struct {
+	CallbackID string
+	Text       string
+}
+
+// SentDocument — файл, отправленный через SendDocument
+type SentDocument struct {
+	ChatID  int64
+	Name    string
+	Data    []byte
+	Caption string
+}
+
+// SentPhoto — изображение, отправленное через SendPhoto
+type SentPhoto struct {
+	ChatID  int64
+	Name    string
+	Data    []byte
+	Caption string
+}
+
+// Recorder запоминает все вызовы вместо обращения к Telegram.
+// Если Err задан, каждый вызов записывается и возвращает эту ошибку.
+type Recorder struct {
+	mu sync.Mutex
+
+	Err error
+
+	Sent      []SentMessage
+	Edited    []EditedMessage
+	Answers   []CallbackAnswer
+	Documents []SentDocument
+	Photos    []SentPhoto
+
+	lastMessageID int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Send(chatID int64, text string, keyboard any) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastMessageID++
+	r.Sent = append(r.Sent, SentMessage{ChatID: chatID, MessageID: r.lastMessageID, Text: text, Keyboard: keyboard})
+
+	if r.Err != nil {
+		return 0, r.Err
+	}
+	return r.lastMessageID, nil
+}
+
+func (r *Recorder) Edit(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Edited = append(r.Edited, EditedMessage{ChatID: chatID, MessageID: messageID, Text: text, Keyboard: keyboard})
+	return r.Err
+}
+
+func (r *Recorder) AnswerCallback(callbackID, text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Answers = append(r.Answers, CallbackAnswer{CallbackID: callbackID, Text: text})
+	return r.Err
+}
+
+func (r *Recorder) SendDocument(chatID int64, name string, data []byte, caption string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Documents = append(r.Documents, SentDocument{ChatID: chatID, Name: name, Data: data, Caption: caption})
+	return r.Err
+}
+
+func (r *Recorder) SendPhoto(chatID int64, name string, data []byte, caption string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Photos = append(r.Photos, SentPhoto{ChatID: chatID, Name: name, Data: data, Caption: caption})
+	return r.Err
+}
+
+// Texts возвращает тексты отправленных сообщений в порядке отправки
+func (r *Recorder) Texts() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	texts := make([]string, 0, len(r.Sent))
+	for _, msg := range r.Sent {
+		texts = append(texts, msg.Text)
+	}
+	return texts
+}
+
+// Reset забывает все записанные вызовы
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Sent = nil
+	r.Edited = nil
+	r.Answers = nil
+	r.Documents = nil
+	r.Photos = nil
+}
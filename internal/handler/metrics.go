@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Метрики обработчиков по маршрутам, публикуются через expvar на /debug/vars
+var (
+	metricRequests    = expvar.NewMap("bot_requests")
+	metricFailures    = expvar.NewMap("bot_request_failures")
+	metricRateLimited = expvar.NewMap("bot_requests_rate_limited")
+	metricDurationMs  = expvar.NewMap("bot_request_duration_ms")
+)
+
+// metricUpdatesDropped — обновления, отброшенные из-за переполненной очереди пользователя
+var metricUpdatesDropped = expvar.NewInt("bot_updates_dropped")
+
+// collectMetrics считает запросы, ошибки и суммарное время обработки по маршрутам
+func collectMetrics(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) error {
+		start := time.Now()
+		err := next(ctx, req)
+
+		metricRequests.Add(req.route, 1)
+		metricDurationMs.Add(req.route, time.Since(start).Milliseconds())
+		if isFailure(err) {
+			metricFailures.Add(req.route, 1)
+		}
+
+		return err
+	}
+}
+
+// ServeMetrics отдаёт метрики expvar на addr по пути /debug/vars, пока ctx не отменён
+func ServeMetrics(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	zap.S().Info("metrics server started", zap.String("addr", addr))
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("serve metrics: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("shutdown metrics server: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"go.uber.org/zap"
+)
+
+// pickerPageSize — количество элементов на одной странице выбора секции
+const pickerPageSize = 8
+
+const (
+	pickerKindNotebook = "notebook"
+	pickerKindGroup    = "group"
+	pickerKindSection  = "section"
+)
+
+// pickerItem — книга, группа секций или секция в списке выбора
+type pickerItem struct {
+	kind string
+	id   string
+	name string
+}
+
+// sectionPicker хранит состояние навигации пользователя по книгам и группам секций.
+// Кнопки ссылаются на элементы закэшированного списка текущего уровня, а version
+// позволяет распознать кнопки из старых сообщений выбора.
+type sectionPicker struct {
+	version int
+	path    []pickerItem
+	items   []pickerItem
+	page    int
+}
+
+func (p *sectionPicker) breadcrumb(loc i18n.Localizer) string {
+	parts := []string{loc.T("picker.root")}
+	for _, level := range p.path {
+		parts = append(parts, escapeHTML(level.name))
+	}
+	return strings.Join(parts, " › ")
+}
+
+// startPicker создаёт новое состояние выбора для пользователя, старые кнопки выбора становятся недействительными
+func (h *TelegramHandler) startPicker(userID int64, path []pickerItem) *sectionPicker {
+	h.pickersMu.Lock()
+	defer h.pickersMu.Unlock()
+
+	version := 1
+	if old, ok := h.pickers[userID]; ok {
+		version = old.version + 1
+	}
+
+	picker := &sectionPicker{version: version, path: path}
+	h.pickers[userID] = picker
+	return picker
+}
+
+func (h *TelegramHandler) getPicker(userID int64, version int) (*sectionPicker, bool) {
+	h.pickersMu.Lock()
+	defer h.pickersMu.Unlock()
+
+	picker, ok := h.pickers[userID]
+	if !ok || picker.version != version {
+		return nil, false
+	}
+	return picker, true
+}
+
+func (h *TelegramHandler) dropPicker(userID int64) {
+	h.pickersMu.Lock()
+	defer h.pickersMu.Unlock()
+
+	delete(h.pickers, userID)
+}
+
+// loadPickerLevel загружает содержимое текущего уровня: список книг, содержимое книги или группы секций
+func (h *TelegramHandler) loadPickerLevel(ctx context.Context, userID int64, picker *sectionPicker) error {
+	var items []pickerItem
+
+	if len(picker.path) == 0 {
+		notebooks, err := h.service.GetOneNoteNotebooks(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, notebook := range notebooks {
+			items = append(items, pickerItem{kind: pickerKindNotebook, id: notebook.ID, name: notebook.DisplayName})
+		}
+	} else {
+		current := picker.path[len(picker.path)-1]
+
+		var err error
+		var groupItems, sectionItems []pickerItem
+		if current.kind == pickerKindNotebook {
+			groups, sections, loadErr := h.service.GetOneNoteNotebookContents(ctx, userID, current.id)
+			err = loadErr
+			for _, group := range groups {
+				groupItems = append(groupItems, pickerItem{kind: pickerKindGroup, id: group.ID, name: group.DisplayName})
+			}
+			for _, section := range sections {
+				sectionItems = append(sectionItems, pickerItem{kind: pickerKindSection, id: section.ID, name: section.DisplayName})
+			}
+		} else {
+			groups, sections, loadErr := h.service.GetOneNoteSectionGroupContents(ctx, userID, current.id)
+			err = loadErr
+			for _, group := range groups {
+				groupItems = append(groupItems, pickerItem{kind: pickerKindGroup, id: group.ID, name: group.DisplayName})
+			}
+			for _, section := range sections {
+				sectionItems = append(sectionItems, pickerItem{kind: pickerKindSection, id: section.ID, name: section.DisplayName})
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		items = append(groupItems, sectionItems...)
+	}
+
+	picker.items = items
+	picker.page = 0
+	return nil
+}
+
+// renderPicker формирует текст с хлебными крошками и клавиатуру текущей страницы списка
+func renderPicker(loc i18n.Localizer, picker *sectionPicker) (string, tgbotapi.InlineKeyboardMarkup) {
+	text := picker.breadcrumb(loc) + "\n\n"
+	if len(picker.path) == 0 {
+		text += loc.T("picker.choose_notebook")
+	} else {
+		text += loc.T("picker.choose_section")
+	}
+
+	if len(picker.items) == 0 {
+		text += "\n\n" + loc.T("picker.empty")
+	}
+
+	totalPages := max(1, (len(picker.items)+pickerPageSize-1)/pickerPageSize)
+	page := min(max(picker.page, 0), totalPages-1)
+
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	start := page * pickerPageSize
+	end := min(start+pickerPageSize, len(picker.items))
+	for i := start; i < end; i++ {
+		item := picker.items[i]
+
+		icon := "📑"
+		switch item.kind {
+		case pickerKindNotebook:
+			icon = "📓"
+		case pickerKindGroup:
+			icon = "📁"
+		}
+
+		callbackData := fmt.Sprintf("pick_%d_o_%d", picker.version, i)
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(icon+" "+item.name, callbackData),
+		))
+	}
+
+	if totalPages > 1 {
+		var row []tgbotapi.InlineKeyboardButton
+		if page > 0 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️", fmt.Sprintf("pick_%d_p_%d", picker.version, page-1)))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, totalPages), fmt.Sprintf("pick_%d_p_%d", picker.version, page)))
+		if page < totalPages-1 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("▶️", fmt.Sprintf("pick_%d_p_%d", picker.version, page+1)))
+		}
+		buttons = append(buttons, row)
+	}
+
+	if len(picker.path) > 0 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(loc.T("button.back"), fmt.Sprintf("pick_%d_b", picker.version)),
+		))
+	}
+
+	return text, tgbotapi.NewInlineKeyboardMarkup(buttons...)
+}
+
+// showPicker начинает выбор секции с указанного уровня и отправляет новое сообщение выбора
+func (h *TelegramHandler) showPicker(ctx context.Context, loc i18n.Localizer, userID, chatID int64, path []pickerItem) error {
+	picker := h.startPicker(userID, path)
+
+	if err := h.loadPickerLevel(ctx, userID, picker); err != nil {
+		return withReply(fmt.Errorf("load picker level: %w", err), loc.T("picker.load_failed"))
+	}
+
+	if len(path) == 0 && len(picker.items) == 0 {
+		return reply(loc.T("picker.no_notebooks"))
+	}
+
+	text, keyboard := renderPicker(loc, picker)
+	h.sendMessageWithKeyboard(chatID, text, keyboard)
+	return nil
+}
+
+func (h *TelegramHandler) handlePickerCallback(ctx context.Context, req *request) error {
+	callback := req.callback
+	userID := req.userID
+	chatID := req.chatID
+	messageID := callback.Message.MessageID
+
+	parts := strings.Split(strings.TrimPrefix(callback.Data, "pick_"), "_")
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) < 2 {
+		zap.S().Error("invalid picker callback", zap.String("data", callback.Data), zap.Int64("telegram_id", userID))
+		return nil
+	}
+
+	picker, ok := h.getPicker(userID, version)
+	if !ok {
+		h.editMessage(chatID, messageID, req.loc.T("picker.stale"), nil)
+		return nil
+	}
+
+	arg := -1
+	if len(parts) > 2 {
+		if arg, err = strconv.Atoi(parts[2]); err != nil {
+			zap.S().Error("invalid picker callback argument", zap.String("data", callback.Data), zap.Int64("telegram_id", userID))
+			return nil
+		}
+	}
+
+	switch parts[1] {
+	case "p":
+		picker.page = arg
+	case "b":
+		if len(picker.path) > 0 {
+			picker.path = picker.path[:len(picker.path)-1]
+		}
+		if err := h.loadPickerLevel(ctx, userID, picker); err != nil {
+			return withReply(fmt.Errorf("load picker level: %w", err), req.loc.T("picker.load_failed"))
+		}
+	case "o":
+		if arg < 0 || arg >= len(picker.items) {
+			zap.S().Error("invalid picker item", zap.String("data", callback.Data), zap.Int64("telegram_id", userID))
+			return nil
+		}
+
+		item := picker.items[arg]
+		if item.kind == pickerKindSection {
+			return h.selectPickerSection(ctx, req, picker, item)
+		}
+
+		if item.kind == pickerKindNotebook {
+			h.saveSelectedNotebook(ctx, userID, item.id)
+		}
+
+		picker.path = append(picker.path, item)
+		if err := h.loadPickerLevel(ctx, userID, picker); err != nil {
+			return withReply(fmt.Errorf("load picker level: %w", err), req.loc.T("picker.load_failed"))
+		}
+	default:
+		zap.S().Warn("unknown picker action", zap.String("data", callback.Data), zap.Int64("telegram_id", userID))
+		return nil
+	}
+
+	text, keyboard := renderPicker(req.loc, picker)
+	h.editMessage(chatID, messageID, text, &keyboard)
+	return nil
+}
+
+// saveSelectedNotebook запоминает открытую книгу как текущую, чтобы /select_section открывался с неё
+func (h *TelegramHandler) saveSelectedNotebook(ctx context.Context, userID int64, notebookID string) {
+	user, err := h.service.GetUser(ctx, userID)
+	if err != nil {
+		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
+		return
+	}
+
+	sectionID := ""
+	if user.SectionID != nil {
+		sectionID = *user.SectionID
+	}
+
+	if err := h.service.SaveOneNoteConfig(ctx, userID, notebookID, sectionID); err != nil {
+		zap.S().Error("save notebook config", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("notebook_id", notebookID))
+	}
+}
+
+func (h *TelegramHandler) selectPickerSection(ctx context.Context, req *request, picker *sectionPicker, section pickerItem) error {
+	userID := req.userID
+	chatID := req.chatID
+
+	if len(picker.path) == 0 || picker.path[0].kind != pickerKindNotebook {
+		zap.S().Error("section selected outside of notebook", zap.Int64("telegram_id", userID))
+		return nil
+	}
+
+	notebook := picker.path[0]
+
+	// Имя секции включает путь групп секций, чтобы в /sources было видно, откуда она
+	names := make([]string, 0, len(picker.path))
+	for _, level := range picker.path[1:] {
+		names = append(names, level.name)
+	}
+	names = append(names, section.name)
+	sectionName := strings.Join(names, " / ")
+
+	if err := h.service.SaveOneNoteConfig(ctx, userID, notebook.id, section.id); err != nil {
+		return withReply(fmt.Errorf("save section config %s: %w", section.id, err), req.loc.T("picker.save_failed"))
+	}
+
+	// Секция добавляется к уже подключённым, старые секции и их страницы остаются в обучении
+	if err := h.service.AddSource(ctx, userID, notebook.id, notebook.name, section.id, sectionName); err != nil {
+		return withReply(fmt.Errorf("add source %s: %w", section.id, err), req.loc.T("picker.save_failed"))
+	}
+
+	h.dropPicker(userID)
+
+	selected := picker.breadcrumb(req.loc) + " › " + escapeHTML(section.name)
+	h.editMessage(chatID, req.callback.Message.MessageID, req.loc.T("picker.section_added", selected), nil)
+
+	// Выбор секции завершает регистрацию, если она шла
+	if h.chatState(ctx, chatID).State == models.ChatStateOnboardingSection {
+		h.setChatState(ctx, chatID, userID, models.ChatStateIdle, nil)
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(req.loc.T("button.yes"), "start_today_yes"),
+			tgbotapi.NewInlineKeyboardButtonData(req.loc.T("button.no"), "start_today_no"),
+		),
+	)
+	h.sendMessageWithKeyboard(chatID, req.loc.T("picker.setup_done"), keyboard)
+	return nil
+}
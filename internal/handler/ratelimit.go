@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/pkg/utils"
+)
+
+const (
+	// rateLimitBurst — сколько запросов пользователь может отправить подряд
+	rateLimitBurst = 10
+	// rateLimitPerSecond — с какой скоростью восстанавливается запас запросов
+	rateLimitPerSecond = 1
+)
+
+// errRateLimited — запрос отброшен, потому что пользователь превысил лимит
+var errRateLimited = errors.New("rate limited")
+
+// tokenBucket — запас запросов одного пользователя
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// warned — пользователь уже получил предупреждение о лимите и ещё не восстановил запас
+	warned bool
+}
+
+// rateLimiter ограничивает частоту запросов каждого пользователя алгоритмом token bucket
+type rateLimiter struct {
+	mu        sync.Mutex
+	clock     utils.Clock
+	rate      float64
+	burst     float64
+	buckets   map[int64]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(clock utils.Clock, rate, burst float64) *rateLimiter {
+	return &rateLimiter{
+		clock:   clock,
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[int64]*tokenBucket),
+	}
+}
+
+// allow забирает запрос из запаса пользователя. warn == true, если запрос отброшен
+// и пользователя нужно предупредить: предупреждение отправляется один раз за период превышения.
+func (l *rateLimiter) allow(userID int64) (allowed, warn bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[userID]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updatedAt: now}
+		l.buckets[userID] = bucket
+	}
+
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*l.rate)
+	bucket.updatedAt = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.warned = false
+		return true, false
+	}
+
+	warn = !bucket.warned
+	bucket.warned = true
+	return false, warn
+}
+
+// sweep удаляет запасы, которые успели полностью восстановиться, не чаще раза в минуту
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for userID, bucket := range l.buckets {
+		if now.Sub(bucket.updatedAt) > full {
+			delete(l.buckets, userID)
+		}
+	}
+}
+
+// limitRate отбрасывает запросы пользователя сверх лимита
+func (h *TelegramHandler) limitRate(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) error {
+		allowed, warn := h.limiter.allow(req.userID)
+		if allowed {
+			return next(ctx, req)
+		}
+
+		metricRateLimited.Add(req.route, 1)
+		if warn {
+			h.sendMessage(req.chatID, req.loc.T("rate_limited"))
+		}
+
+		return errRateLimited
+	}
+}
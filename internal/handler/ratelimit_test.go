@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+func TestRateLimiterRefillsByClock(t *testing.T) {
+	clock := clocktest.NewManualClock(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC))
+	limiter := newRateLimiter(clock, 1, 2)
+
+	for i := range 2 {
+		if allowed, _ := limiter.allow(testUserID); !allowed {
+			t.Fatalf("request %d rejected within burst", i+1)
+		}
+	}
+	if allowed, warn := limiter.allow(testUserID); allowed || !warn {
+		t.Fatalf("allow over burst = %v, %v, want rejected with a warning", allowed, warn)
+	}
+	if _, warn := limiter.allow(testUserID); warn {
+		t.Fatal("warned twice in one period over the limit")
+	}
+
+	clock.Advance(time.Second)
+	if allowed, _ := limiter.allow(testUserID); !allowed {
+		t.Fatal("request rejected after the bucket refilled")
+	}
+	if allowed, warn := limiter.allow(testUserID); allowed || !warn {
+		t.Fatalf("allow after refill = %v, %v, want rejected with a new warning", allowed, warn)
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"go.uber.org/zap"
+)
+
+// sendReminders отправляет наступившие напоминания пользователя, занятые в reminder_log, и отмечает их отправленными.
+// Если отправить не удалось, напоминание возвращается, а ошибка приводит к повтору задачи. Напоминание, которое
+// не нужно отправлять (страниц на сегодня нет или нужна авторизация), тоже отмечается: на сегодня оно обработано.
+func (h *TelegramHandler) sendReminders(ctx context.Context, userID int64) error {
+	reminders, err := h.service.ClaimDueReminders(ctx, userID, h.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	duePages, err := h.service.GetDuePagesToday(ctx, userID)
+	if err != nil {
+		var authErr *service.AuthRequiredError
+		if errors.As(err, &authErr) {
+			zap.S().Warn("authentication required for reminder", zap.Int64("telegram_id", userID))
+			return h.service.MarkRemindersSent(ctx, reminders)
+		}
+		h.releaseReminders(ctx, reminders)
+		return fmt.Errorf("get due pages for reminder: %w", err)
+	}
+
+	if len(duePages) == 0 {
+		return h.service.MarkRemindersSent(ctx, reminders)
+	}
+
+	// Несколько напоминаний, наступивших одновременно (например, после тихих часов), объединяются в одно сообщение
+	loc := i18n.New(reminders[0].Language)
+	if _, err := h.messenger.Send(userID, loc.N("reminder.due", len(duePages), len(duePages)), nil); err != nil {
+		h.releaseReminders(ctx, reminders)
+		return fmt.Errorf("send reminder: %w", err)
+	}
+
+	return h.service.MarkRemindersSent(ctx, reminders)
+}
+
+func (h *TelegramHandler) releaseReminders(ctx context.Context, reminders []*models.DueReminder) {
+	for _, reminder := range reminders {
+		h.releaseReminder(ctx, reminder)
+	}
+}
+
+func (h *TelegramHandler) releaseReminder(ctx context.Context, reminder *models.DueReminder) {
+	if err := h.service.ReleaseReminder(ctx, reminder); err != nil {
+		zap.S().Error("release reminder", zap.Error(err), zap.Int64("telegram_id", reminder.UserID), zap.String("reminder_time", reminder.ReminderTime))
+	}
+}
+
+// handleSetReminder настраивает напоминания:
+// /set_reminder 09:00 20:00, /set_reminder on|off, /set_reminder quiet 22:00-08:00|off
+func (h *TelegramHandler) handleSetReminder(ctx context.Context, req *request) error {
+	args := strings.Fields(req.message.Text)[1:]
+	if len(args) == 0 {
+		return h.showReminders(ctx, req)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		return h.switchReminders(ctx, req, true)
+	case "off":
+		return h.switchReminders(ctx, req, false)
+	case "quiet":
+		return h.setQuietHours(ctx, req, args[1:])
+	}
+
+	if err := h.service.SetReminderTimes(ctx, req.userID, args); err != nil {
+		if errors.Is(err, service.ErrInvalidSetting) {
+			return reply(req.loc.T("reminder.invalid", service.MaxReminderTimes))
+		}
+		return withReply(fmt.Errorf("set reminder times %v: %w", args, err), req.loc.T("settings.update_failed"))
+	}
+
+	// Новое время без включения напоминаний ничего бы не изменило для пользователя
+	if !req.user.RemindersEnabled {
+		return h.switchReminders(ctx, req, true)
+	}
+
+	times, err := h.service.GetReminderTimes(ctx, req.userID)
+	if err != nil {
+		return err
+	}
+
+	h.sendMessage(req.chatID, req.loc.T("reminder.times_set", strings.Join(times, ", ")))
+	return nil
+}
+
+// showReminders показывает текущие напоминания и справку по команде
+func (h *TelegramHandler) showReminders(ctx context.Context, req *request) error {
+	times, err := h.service.GetReminderTimes(ctx, req.userID)
+	if err != nil {
+		return err
+	}
+
+	lines := reminderLines(req.loc, req.user, times)
+	lines = append(lines, "", req.loc.T("reminder.usage", service.MaxReminderTimes))
+	h.sendMessage(req.chatID, strings.Join(lines, "\n"))
+	return nil
+}
+
+func (h *TelegramHandler) switchReminders(ctx context.Context, req *request, enabled bool) error {
+	if err := h.service.SetRemindersEnabled(ctx, req.userID, enabled); err != nil {
+		return withReply(fmt.Errorf("set reminders enabled to %v: %w", enabled, err), req.loc.T("settings.update_failed"))
+	}
+
+	if !enabled {
+		h.sendMessage(req.chatID, req.loc.T("reminder.disabled"))
+		return nil
+	}
+
+	times, err := h.service.GetReminderTimes(ctx, req.userID)
+	if err != nil {
+		return err
+	}
+
+	h.sendMessage(req.chatID, req.loc.T("reminder.enabled", strings.Join(times, ", ")))
+	return nil
+}
+
+// setQuietHours принимает интервал "22:00-08:00" или "off"
+func (h *TelegramHandler) setQuietHours(ctx context.Context, req *request, args []string) error {
+	if len(args) != 1 {
+		return reply(req.loc.T("reminder.quiet_usage"))
+	}
+
+	if strings.EqualFold(args[0], "off") {
+		if err := h.service.DisableQuietHours(ctx, req.userID); err != nil {
+			return withReply(fmt.Errorf("disable quiet hours: %w", err), req.loc.T("settings.update_failed"))
+		}
+		h.sendMessage(req.chatID, req.loc.T("reminder.quiet_off"))
+		return nil
+	}
+
+	start, end, ok := strings.Cut(args[0], "-")
+	if !ok {
+		return reply(req.loc.T("reminder.quiet_usage"))
+	}
+
+	if err := h.service.SetQuietHours(ctx, req.userID, start, end); err != nil {
+		if errors.Is(err, service.ErrInvalidSetting) {
+			return reply(req.loc.T("reminder.quiet_usage"))
+		}
+		return withReply(fmt.Errorf("set quiet hours %s: %w", args[0], err), req.loc.T("settings.update_failed"))
+	}
+
+	user, err := h.service.GetUser(ctx, req.userID)
+	if err != nil {
+		return err
+	}
+
+	h.sendMessage(req.chatID, req.loc.T("reminder.quiet_set", *user.QuietHoursStart, *user.QuietHoursEnd))
+	return nil
+}
+
+// reminderLines описывает напоминания пользователя: время или "выключены" и тихие часы, если они заданы
+func reminderLines(loc i18n.Localizer, user *models.User, times []string) []string {
+	status := strings.Join(times, ", ")
+	if !user.RemindersEnabled || len(times) == 0 {
+		status = loc.T("reminder.off")
+	}
+
+	lines := []string{loc.T("settings.reminder", status)}
+	if user.QuietHoursStart != nil && user.QuietHoursEnd != nil {
+		lines = append(lines, loc.T("settings.quiet_hours", *user.QuietHoursStart, *user.QuietHoursEnd))
+	}
+
+	return lines
+}
@@ -0,0 +1,369 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"go.uber.org/zap"
+)
+
+// requirement — условие, которое должно выполняться, прежде чем обработчик будет вызван.
+// Каждое следующее условие включает предыдущие.
+type requirement int
+
+const (
+	// requireNone — обработчик доступен всем, включая незарегистрированных пользователей
+	requireNone requirement = iota
+	// requireRegistered — пользователь зарегистрирован, request.user заполнен
+	requireRegistered
+	// requireOneNote — подключён OneNote
+	requireOneNote
+	// requireNotebook — выбрана книга OneNote
+	requireNotebook
+	// requireSection — выбрана секция OneNote
+	requireSection
+)
+
+// request — входящая команда, callback или текст вместе с данными, которые заполняют middleware
+type request struct {
+	// route — имя маршрута для логов и метрик
+	route    string
+	userID   int64
+	chatID   int64
+	message  *tgbotapi.Message
+	callback *tgbotapi.CallbackQuery
+	// languageCode — язык клиента Telegram, по нему выбирается язык незарегистрированного пользователя
+	languageCode string
+	// user заполняется, если маршрут требует регистрации
+	user *models.User
+	// loc переводит ответы на язык пользователя
+	loc i18n.Localizer
+}
+
+// handlerFunc обрабатывает запрос. Возвращённая ошибка превращается в ответ пользователю в replyErrors.
+type handlerFunc func(ctx context.Context, req *request) error
+
+type middleware func(next handlerFunc) handlerFunc
+
+type route struct {
+	name    string
+	require requirement
+	handle  handlerFunc
+}
+
+type callbackRoute struct {
+	prefix string
+	route
+}
+
+// router сопоставляет команды и callback data с обработчиками и оборачивает их в общую цепочку middleware
+type router struct {
+	commands  map[string]route
+	callbacks []callbackRoute
+	chain     []middleware
+}
+
+// replyError — ошибка с текстом, который нужно показать пользователю.
+// Без err это ожидаемый отказ (например, пользователь не зарегистрирован), он не логируется как ошибка.
+type replyError struct {
+	text string
+	err  error
+}
+
+func (e *replyError) Error() string {
+	if e.err == nil {
+		return e.text
+	}
+	return e.err.Error()
+}
+
+func (e *replyError) Unwrap() error {
+	return e.err
+}
+
+// reply завершает обработку ответом пользователю без ошибки
+func reply(text string) error {
+	return &replyError{text: text}
+}
+
+// withReply прикладывает к ошибке текст ответа пользователю вместо общего сообщения об ошибке
+func withReply(err error, text string) error {
+	return &replyError{text: text, err: err}
+}
+
+// isFailure сообщает, является ли результат обработчика настоящей ошибкой, а не ожидаемым отказом
+func isFailure(err error) bool {
+	if errors.Is(err, errRateLimited) {
+		return false
+	}
+
+	var replyErr *replyError
+	if errors.As(err, &replyErr) {
+		return replyErr.err != nil
+	}
+	return err != nil
+}
+
+// newRouter описывает все команды и callback бота вместе с их требованиями
+func (h *TelegramHandler) newRouter() *router {
+	r := &router{
+		commands: map[string]route{
+			"start":             {require: requireNone, handle: h.handleStart},
+			"connect_onenote":   {require: requireRegistered, handle: h.handleConnectOneNote},
+			"select_notebook":   {require: requireOneNote, handle: h.handleSelectNotebook},
+			"select_section":    {require: requireOneNote, handle: h.handleSelectSection},
+			"sources":           {require: requireRegistered, handle: h.handleSources},
+			"today":             {require: requireRegistered, handle: h.handleToday},
+			"comeback":          {require: requireRegistered, handle: h.handleComeback},
+			"pages":             {require: requireRegistered, handle: h.handlePages},
+			"stats":             {require: requireRegistered, handle: h.handleStats},
+			"set_max_pages":     {require: requireRegistered, handle: h.handleSetMaxPages},
+			"get_max_pages":     {require: requireRegistered, handle: h.handleGetMaxPages},
+			"prepare_materials": {require: requireNotebook, handle: h.handlePrepareMaterials},
+			"sync":              {require: requireSection, handle: h.handleSync},
+			"add_page":          {require: requireRegistered, handle: h.handleAddPage},
+			"cancel":            {require: requireNone, handle: h.handleCancel},
+			"set_timezone":      {require: requireRegistered, handle: h.handleSetTimezone},
+			"set_reminder":      {require: requireRegistered, handle: h.handleSetReminder},
+			"vacation":          {require: requireRegistered, handle: h.handleVacation},
+			"language":          {require: requireRegistered, handle: h.handleLanguage},
+			"settings":          {require: requireRegistered, handle: h.handleSettings},
+			"help":              {require: requireNone, handle: h.handleHelp},
+		},
+		// Маршруты проверяются по порядку, побеждает первый подходящий префикс
+		callbacks: []callbackRoute{
+			{"lang_", route{name: "language", require: requireNone, handle: h.handleLanguageSelection}},
+			{"level_", route{name: "level", require: requireNone, handle: h.handleLevelSelection}},
+			{"pick_", route{name: "pick", require: requireOneNote, handle: h.handlePickerCallback}},
+			{"source_", route{name: "source", require: requireRegistered, handle: h.handleSourceAction}},
+			{callbackTokenPrefix, route{name: "token", require: requireRegistered, handle: h.handleTokenCallback}},
+			{"skip_all", route{name: "skip_all", require: requireNone, handle: h.handleSkipAll}},
+			{"start_today_yes", route{name: "start_today_yes", require: requireNotebook, handle: h.handleStartTodayYes}},
+			{"start_today_no", route{name: "start_today_no", require: requireNone, handle: h.handleStartTodayNo}},
+			{"timezone_", route{name: "timezone", require: requireRegistered, handle: h.handleTimezoneSelection}},
+			{"max_pages_", route{name: "max_pages", require: requireRegistered, handle: h.handleMaxPagesSelection}},
+			{settingsPrefix, route{name: "settings", require: requireRegistered, handle: h.handleSettingsCallback}},
+			{comebackPrefix, route{name: "comeback", require: requireRegistered, handle: h.handleComebackCallback}},
+			{sessionNext, route{name: "session", require: requireRegistered, handle: h.handleSessionNext}},
+			// Кнопки старого формата ссылались на позицию в списке, который мог измениться
+			{"notebook_", route{name: "legacy_picker", require: requireNone, handle: h.handleStalePicker}},
+			{"section_", route{name: "legacy_picker", require: requireNone, handle: h.handleStalePicker}},
+			{"show_", route{name: "legacy_page", require: requireNone, handle: h.handleStaleButton}},
+			{"grade_", route{name: "legacy_page", require: requireNone, handle: h.handleStaleButton}},
+			{"skip_page", route{name: "legacy_page", require: requireNone, handle: h.handleStaleButton}},
+			{"success_", route{name: "legacy_page", require: requireNone, handle: h.handleStaleButton}},
+			{"failure_", route{name: "legacy_page", require: requireNone, handle: h.handleStaleButton}},
+		},
+		// Первый middleware в списке — внешний
+		chain: []middleware{
+			h.logRequests,
+			collectMetrics,
+			h.limitRate,
+			h.localize,
+			h.resetInput,
+			h.replyErrors,
+		},
+	}
+
+	for name, cmd := range r.commands {
+		cmd.name = name
+		r.commands[name] = cmd
+	}
+
+	return r
+}
+
+// command возвращает маршрут команды
+func (r *router) command(name string) (route, bool) {
+	rt, ok := r.commands[name]
+	return rt, ok
+}
+
+// callback возвращает маршрут callback data
+func (r *router) callback(data string) (route, bool) {
+	for _, cb := range r.callbacks {
+		if strings.HasPrefix(data, cb.prefix) {
+			return cb.route, true
+		}
+	}
+	return route{}, false
+}
+
+// serve выполняет маршрут: проверка требований оказывается внутри цепочки,
+// поэтому её отказы и ошибки проходят через те же логирование, метрики и ответы.
+// До localize запрос переводится на язык клиента Telegram, чтобы отброшенные лимитом запросы не ходили в базу
+func (h *TelegramHandler) serve(ctx context.Context, rt route, req *request) {
+	req.route = rt.name
+	req.loc = i18n.New(i18n.Match(req.languageCode))
+
+	handle := h.authorized(rt.require, rt.handle)
+	for i := len(h.router.chain) - 1; i >= 0; i-- {
+		handle = h.router.chain[i](handle)
+	}
+
+	_ = handle(ctx, req)
+}
+
+// localize выбирает язык ответов пользователю; стоит после limitRate, потому что обращается к базе
+func (h *TelegramHandler) localize(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) error {
+		req.loc = h.localizer(ctx, req.userID, req.chatID, req.languageCode)
+		return next(ctx, req)
+	}
+}
+
+// resetInput сбрасывает ожидание свободного текста, когда пользователь вместо ответа отправил другую команду
+func (h *TelegramHandler) resetInput(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) error {
+		if req.message != nil && req.message.IsCommand() && req.message.Command() != "cancel" {
+			h.resetInputState(ctx, req.chatID, req.userID)
+		}
+		return next(ctx, req)
+	}
+}
+
+// localizer выбирает язык ответов: язык из профиля пользователя, язык, выбранный на шаге регистрации
+// до создания пользователя, язык клиента Telegram, иначе язык по умолчанию
+func (h *TelegramHandler) localizer(ctx context.Context, userID, chatID int64, languageCode string) i18n.Localizer {
+	lang, err := h.service.GetUserLanguage(ctx, userID)
+	if err != nil {
+		zap.S().Error("get user language", zap.Error(err), zap.Int64("telegram_id", userID))
+	}
+
+	if lang == "" {
+		lang = h.chatState(ctx, chatID).Data[chatStateKeyLanguage]
+	}
+	if lang == "" {
+		lang = i18n.Match(languageCode)
+	}
+
+	return i18n.New(lang)
+}
+
+// authorized проверяет требование маршрута и заполняет request.user
+func (h *TelegramHandler) authorized(require requirement, next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) error {
+		if err := h.authorize(ctx, req, require); err != nil {
+			return err
+		}
+		return next(ctx, req)
+	}
+}
+
+// authorize проверяет, что пользователь выполнил требование, и загружает его в req.user
+func (h *TelegramHandler) authorize(ctx context.Context, req *request, require requirement) error {
+	if require == requireNone {
+		return nil
+	}
+
+	exists, err := h.service.UserExists(ctx, req.userID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return reply(req.loc.T("require.registered"))
+	}
+
+	user, err := h.service.GetUser(ctx, req.userID)
+	if err != nil {
+		return err
+	}
+	req.user = user
+
+	if require >= requireOneNote && (user.AccessToken == nil || user.RefreshToken == nil) {
+		return reply(req.loc.T("require.onenote"))
+	}
+
+	if require >= requireNotebook && (user.NotebookID == nil || *user.NotebookID == "") {
+		return reply(req.loc.T("require.notebook"))
+	}
+
+	if require >= requireSection && user.OneNoteConfig == nil {
+		return reply(req.loc.T("require.section"))
+	}
+
+	return nil
+}
+
+// logRequests логирует каждый запрос с его маршрутом, длительностью и ошибкой
+func (h *TelegramHandler) logRequests(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) error {
+		start := time.Now()
+		err := next(ctx, req)
+
+		route := zap.String("route", req.route)
+		userID := zap.Int64("telegram_id", req.userID)
+		duration := zap.Duration("duration", time.Since(start))
+
+		var authErr *service.AuthRequiredError
+		switch {
+		case errors.As(err, &authErr):
+			zap.S().Warn("authentication required", route, userID, duration)
+		case errors.Is(err, errRateLimited):
+			zap.S().Warn("rate limited", route, userID, duration)
+		case isFailure(err):
+			zap.S().Error("handle request", zap.Error(err), route, userID, duration)
+		default:
+			zap.S().Debug("handle request", route, userID, duration)
+		}
+
+		return err
+	}
+}
+
+// replyErrors отвечает пользователю на ошибку обработчика: просит заново авторизоваться в OneNote,
+// показывает текст replyError или общее сообщение об ошибке
+func (h *TelegramHandler) replyErrors(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req *request) error {
+		err := next(ctx, req)
+		if err == nil {
+			return nil
+		}
+
+		var authErr *service.AuthRequiredError
+		var replyErr *replyError
+		switch {
+		case errors.As(err, &authErr):
+			h.sendAuthRequired(req.loc, req.userID, req.chatID)
+		case errors.As(err, &replyErr):
+			h.sendMessage(req.chatID, replyErr.text)
+		default:
+			h.sendMessage(req.chatID, req.loc.T("error.generic"))
+		}
+
+		return err
+	}
+}
+
+// sendAuthRequired просит пользователя заново авторизоваться в OneNote
+func (h *TelegramHandler) sendAuthRequired(loc i18n.Localizer, userID, chatID int64) {
+	h.sendMessage(chatID, loc.T("auth.expired", h.service.GetAuthURL(userID)))
+}
+
+// handleUnknownCommand отвечает на команды, которых нет в маршрутизаторе
+func (h *TelegramHandler) handleUnknownCommand(_ context.Context, req *request) error {
+	return reply(req.loc.T("command.unknown"))
+}
+
+// handleUnknownCallback отвечает на callback data, которой не подходит ни один префикс
+func (h *TelegramHandler) handleUnknownCallback(_ context.Context, req *request) error {
+	zap.S().Warn("unknown callback data", zap.String("data", req.callback.Data), zap.Int64("user_id", req.userID))
+	return reply(req.loc.T("callback.unknown"))
+}
+
+// handleStaleButton отвечает на кнопки страниц старого формата, ссылавшиеся на позицию в списке
+func (h *TelegramHandler) handleStaleButton(_ context.Context, req *request) error {
+	return reply(req.loc.T("button.stale"))
+}
+
+// handleStalePicker отвечает на кнопки выбора книги и секции старого формата
+func (h *TelegramHandler) handleStalePicker(_ context.Context, req *request) error {
+	return reply(req.loc.T("picker.stale"))
+}
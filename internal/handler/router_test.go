@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/handler/messengertest"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+func TestServeLimitsRateBeforeLoadingLanguage(t *testing.T) {
+	svc := newTestService()
+	recorder := messengertest.NewRecorder()
+
+	h := NewTelegramHandlerWithMessenger(recorder, svc, clocktest.NewManualClock(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)))
+	// Без восстановления запаса: проходит только первый запрос
+	h.limiter = newRateLimiter(h.clock, 0, 1)
+
+	var handled []string
+	rt := route{name: "test", require: requireNone, handle: func(_ context.Context, req *request) error {
+		handled = append(handled, req.loc.T("command.unknown"))
+		return nil
+	}}
+
+	msg := &tgbotapi.Message{From: &tgbotapi.User{ID: 7, LanguageCode: "en"}, Chat: &tgbotapi.Chat{ID: 7}, Text: "hello"}
+	for range 3 {
+		h.serve(context.Background(), rt, &request{userID: 7, chatID: 7, message: msg, languageCode: "en"})
+	}
+
+	if got := svc.Count("GetUserLanguage"); got != 1 {
+		t.Fatalf("GetUserLanguage called %d times, want 1: limited requests must not reach the database", got)
+	}
+
+	if len(handled) != 1 || handled[0] != i18n.New("ru").T("command.unknown") {
+		t.Fatalf("handled = %q, want one request answered in the user's language", handled)
+	}
+
+	// Предупреждение о лимите отправляется один раз и на языке клиента Telegram
+	texts := recorder.Texts()
+	if len(texts) != 1 || texts[0] != i18n.New("en").T("rate_limited") {
+		t.Fatalf("sent %q, want a single rate limit warning in the client language", texts)
+	}
+}
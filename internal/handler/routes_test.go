@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/handler/messengertest"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/models/modelstest"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+const testUserID = 7
+
+// newTestService возвращает сервис зарегистрированного пользователя с подключённым OneNote и выбранной секцией
+func newTestService() *modelstest.Service {
+	token := "token"
+	notebookID := "notebook"
+	sectionID := "section"
+	timezone := "Europe/Moscow"
+
+	svc := modelstest.NewService()
+	svc.UserExistsFunc = func(context.Context, int64) (bool, error) {
+		return true, nil
+	}
+	svc.GetUserFunc = func(_ context.Context, telegramID int64) (*models.User, error) {
+		return &models.User{
+			TelegramID:    telegramID,
+			Level:         "B1",
+			Language:      "ru",
+			AccessToken:   &token,
+			RefreshToken:  &token,
+			NotebookID:    &notebookID,
+			SectionID:     &sectionID,
+			OneNoteConfig: &models.OneNoteConfig{NotebookID: notebookID, SectionID: sectionID},
+			Timezone:      &timezone,
+		}, nil
+	}
+	svc.GetUserLanguageFunc = func(context.Context, int64) (string, error) {
+		return "ru", nil
+	}
+	svc.GetChatStateFunc = func(_ context.Context, chatID int64) (*models.ChatState, error) {
+		return &models.ChatState{ChatID: chatID, State: models.ChatStateIdle}, nil
+	}
+	svc.GetStatsFunc = func(context.Context, int64) (*models.UserStats, error) {
+		return &models.UserStats{}, nil
+	}
+	svc.SyncPagesFunc = func(context.Context, int64) (*models.SyncReport, error) {
+		return &models.SyncReport{}, nil
+	}
+	return svc
+}
+
+func newCommandUpdate(text string) tgbotapi.Update {
+	command, _, _ := strings.Cut(text, " ")
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: testUserID, LanguageCode: "ru"},
+		Chat:      &tgbotapi.Chat{ID: testUserID},
+		Text:      text,
+		Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}}
+}
+
+func newCallbackUpdate(data string) tgbotapi.Update {
+	return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "callback",
+		From:    &tgbotapi.User{ID: testUserID, LanguageCode: "ru"},
+		Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: testUserID}},
+		Data:    data,
+	}}
+}
+
+// withToken настраивает сервис так, будто кнопка с токеном "token" выдана для действия над страницей "page"
+func withToken(action string) func(*modelstest.Service) {
+	return func(svc *modelstest.Service) {
+		token := &models.CallbackToken{Token: "token", UserID: testUserID, PageID: "page", Action: action, Grade: 4, Version: 1}
+		svc.GetCallbackTokenFunc = func(context.Context, int64, string) (*models.CallbackToken, error) {
+			return token, nil
+		}
+		svc.ConsumeCallbackTokenFunc = func(context.Context, int64, string) (*models.CallbackToken, error) {
+			return token, nil
+		}
+	}
+}
+
+// routeTest — запрос к маршруту и его видимый результат: вызовы сервиса и ответ пользователю
+type routeTest struct {
+	name   string
+	update tgbotapi.Update
+	setup  func(svc *modelstest.Service)
+	// calls — методы сервиса, которые маршрут должен вызвать
+	calls []string
+	// reply — ключ i18n ответа; ищется текст до первого параметра формата
+	reply string
+}
+
+var routeTests = []routeTest{
+	// Команды
+	{name: "start", update: newCommandUpdate("/start"), reply: "start.welcome_back"},
+	{
+		name:   "start new user",
+		update: newCommandUpdate("/start"),
+		setup: func(svc *modelstest.Service) {
+			svc.UserExistsFunc = func(context.Context, int64) (bool, error) { return false, nil }
+		},
+		calls: []string{"SetChatState"},
+	},
+	{name: "connect_onenote", update: newCommandUpdate("/connect_onenote"), calls: []string{"SetChatState", "GetAuthURL"}, reply: "auth.link"},
+	{name: "select_notebook", update: newCommandUpdate("/select_notebook"), calls: []string{"GetOneNoteNotebooks"}},
+	{name: "select_section", update: newCommandUpdate("/select_section"), calls: []string{"GetOneNoteNotebooks"}},
+	{name: "sources", update: newCommandUpdate("/sources"), calls: []string{"GetSources"}, reply: "sources.empty"},
+	{name: "today", update: newCommandUpdate("/today"), calls: []string{"SuggestComebackPlan", "StartReviewSession"}, reply: "today.empty"},
+	{name: "comeback", update: newCommandUpdate("/comeback"), calls: []string{"GetComebackPlan"}},
+	{name: "pages", update: newCommandUpdate("/pages"), calls: []string{"GetUserAllPagesInProgress"}, reply: "pages.empty"},
+	{name: "stats", update: newCommandUpdate("/stats"), calls: []string{"GetStats"}, reply: "stats.empty"},
+	{name: "set_max_pages", update: newCommandUpdate("/set_max_pages 5"), calls: []string{"UpdateMaxPagesPerDay"}, reply: "max_pages.set"},
+	{name: "set_max_pages without value", update: newCommandUpdate("/set_max_pages"), reply: "max_pages.usage"},
+	{name: "get_max_pages", update: newCommandUpdate("/get_max_pages"), reply: "max_pages.current"},
+	{name: "prepare_materials", update: newCommandUpdate("/prepare_materials"), calls: []string{"PrepareMaterials"}, reply: "prepare.done"},
+	{name: "sync", update: newCommandUpdate("/sync"), calls: []string{"SyncPages"}, reply: "sync.no_changes"},
+	{name: "add_page", update: newCommandUpdate("/add_page"), calls: []string{"SetChatState"}, reply: "add_page.prompt"},
+	{name: "cancel", update: newCommandUpdate("/cancel"), reply: "cancel.nothing"},
+	{name: "set_timezone", update: newCommandUpdate("/set_timezone"), reply: "timezone.choose"},
+	{name: "set_reminder", update: newCommandUpdate("/set_reminder 09:00"), calls: []string{"SetReminderTimes"}},
+	{name: "set_reminder off", update: newCommandUpdate("/set_reminder off"), calls: []string{"SetRemindersEnabled"}, reply: "reminder.disabled"},
+	{name: "vacation", update: newCommandUpdate("/vacation"), reply: "vacation.none"},
+	{name: "vacation off", update: newCommandUpdate("/vacation off"), calls: []string{"CancelVacation"}, reply: "vacation.cancelled"},
+	{name: "language", update: newCommandUpdate("/language"), reply: "language.choose"},
+	{name: "settings", update: newCommandUpdate("/settings"), calls: []string{"GetReminderTimes"}, reply: "settings.title"},
+	{name: "help", update: newCommandUpdate("/help"), reply: "help"},
+	{name: "unknown command", update: newCommandUpdate("/unknown"), reply: "command.unknown"},
+	{
+		name:   "unregistered user",
+		update: newCommandUpdate("/today"),
+		setup: func(svc *modelstest.Service) {
+			svc.UserExistsFunc = func(context.Context, int64) (bool, error) { return false, nil }
+		},
+		reply: "require.registered",
+	},
+
+	// Callback
+	{name: "lang_", update: newCallbackUpdate("lang_en"), calls: []string{"UpdateUserLanguage"}},
+	{name: "level_", update: newCallbackUpdate("level_B2"), calls: []string{"UpdateUserLevel"}, reply: "level.updated"},
+	{name: "pick_", update: newCallbackUpdate("pick_1_o_0"), reply: "picker.stale"},
+	{name: "source_", update: newCallbackUpdate("source_toggle_1"), calls: []string{"ToggleSource", "GetSources"}},
+	{name: "source_ invalid", update: newCallbackUpdate("source_toggle_x"), reply: "sources.invalid"},
+	{name: "t_ show", update: newCallbackUpdate("t_token"), setup: withToken(models.CallbackActionShowPage), calls: []string{"GetCallbackToken", "GetDuePagesToday"}},
+	{
+		name:   "t_ history",
+		update: newCallbackUpdate("t_token"),
+		setup: func(svc *modelstest.Service) {
+			withToken(models.CallbackActionHistory)(svc)
+			svc.GetPageHistoryFunc = func(context.Context, int64, string, []int) (*models.PageHistory, error) {
+				return &models.PageHistory{Page: &models.PageReference{Title: "Page"}, Progress: &models.UserProgress{}}, nil
+			}
+		},
+		calls: []string{"GetCallbackToken", "GetPageHistory", "GetPageItems"},
+		reply: "history.title",
+	},
+	{name: "t_ grade", update: newCallbackUpdate("t_token"), setup: withToken(models.CallbackActionGrade), calls: []string{"GetCallbackToken", "UpdateReviewProgress"}},
+	{
+		// Оценка сохранена, но прогресс после неё прочитать не удалось: ответ без интервала, а не паника
+		name:   "t_ grade progress read fails",
+		update: newCallbackUpdate("t_token"),
+		setup: func(svc *modelstest.Service) {
+			token := &models.CallbackToken{Token: "token", UserID: testUserID, PageID: "page", Action: models.CallbackActionGrade, Grade: 90, Version: 1}
+			svc.GetCallbackTokenFunc = func(context.Context, int64, string) (*models.CallbackToken, error) {
+				return token, nil
+			}
+			svc.GetProgressFunc = func(context.Context, int64, string) (*models.UserProgress, error) {
+				return nil, errors.New("connection reset")
+			}
+		},
+		calls: []string{"UpdateReviewProgress", "GetProgress"},
+		reply: "review.saved",
+	},
+	{name: "t_ skip", update: newCallbackUpdate("t_token"), setup: withToken(models.CallbackActionSkipPage), calls: []string{"ConsumeCallbackToken", "SkipPage"}},
+	{name: "t_ undo", update: newCallbackUpdate("t_token"), setup: withToken(models.CallbackActionUndo), calls: []string{"ConsumeCallbackToken", "UndoLastGrade"}},
+	{
+		name:   "t_ undo after a later grade",
+		update: newCallbackUpdate("t_token"),
+		setup: func(svc *modelstest.Service) {
+			withToken(models.CallbackActionUndo)(svc)
+			svc.UndoLastGradeFunc = func(context.Context, int64, string, string) error {
+				return service.ErrNothingToUndo
+			}
+		},
+		calls: []string{"ConsumeCallbackToken", "UndoLastGrade"},
+		reply: "undo.nothing",
+	},
+	{name: "t_ stale", update: newCallbackUpdate("t_token"), calls: []string{"GetCallbackToken"}, reply: "button.stale"},
+	{name: "skip_all", update: newCallbackUpdate("skip_all"), reply: "skip_all.done"},
+	{name: "start_today_yes", update: newCallbackUpdate("start_today_yes"), calls: []string{"PrepareMaterials"}, reply: "prepare.done_today"},
+	{name: "start_today_no", update: newCallbackUpdate("start_today_no"), reply: "prepare.later"},
+	{name: "timezone_", update: newCallbackUpdate("timezone_Europe/Berlin"), calls: []string{"UpdateUserTimezone"}},
+	{name: "max_pages_", update: newCallbackUpdate("max_pages_3"), calls: []string{"UpdateMaxPagesPerDay"}},
+	{name: "settings_open", update: newCallbackUpdate(settingsOpenPrefix + settingMaxPages), calls: []string{"GetReminderTimes"}},
+	{name: "settings_set", update: newCallbackUpdate(settingsSetPrefix + settingMaxPages + "_4"), calls: []string{"UpdateMaxPagesPerDay"}},
+	{name: "settings_back", update: newCallbackUpdate(settingsBack), calls: []string{"GetReminderTimes"}, reply: "settings.title"},
+	{name: "comeback_plan", update: newCallbackUpdate(comebackPlanPrefix + "5"), calls: []string{"GetComebackPlan"}},
+	{name: "comeback_accept", update: newCallbackUpdate(comebackAcceptPrefix + "5_fingerprint"), calls: []string{"ApplyComebackPlan"}, reply: "comeback.kept"},
+	{
+		name:   "comeback_accept changed plan",
+		update: newCallbackUpdate(comebackAcceptPrefix + "5_stale"),
+		setup: func(svc *modelstest.Service) {
+			svc.ApplyComebackPlanFunc = func(context.Context, int64, int, string) (*models.ComebackPlan, error) {
+				return nil, service.ErrComebackPlanChanged
+			}
+		},
+		calls: []string{"ApplyComebackPlan", "GetComebackPlan"},
+		reply: "comeback.nothing",
+	},
+	{name: "comeback_keep", update: newCallbackUpdate(comebackKeep), calls: []string{"DismissComebackPlan"}, reply: "comeback.kept"},
+	{name: "session_next", update: newCallbackUpdate(sessionNext), calls: []string{"StartReviewSession"}, reply: "today.empty"},
+	{name: "notebook_", update: newCallbackUpdate("notebook_0"), reply: "picker.stale"},
+	{name: "section_", update: newCallbackUpdate("section_0"), reply: "picker.stale"},
+	{name: "show_", update: newCallbackUpdate("show_0"), reply: "button.stale"},
+	{name: "grade_", update: newCallbackUpdate("grade_0_4"), reply: "button.stale"},
+	{name: "skip_page", update: newCallbackUpdate("skip_page_0"), reply: "button.stale"},
+	{name: "success_", update: newCallbackUpdate("success_0"), reply: "button.stale"},
+	{name: "failure_", update: newCallbackUpdate("failure_0"), reply: "button.stale"},
+	{name: "unknown callback", update: newCallbackUpdate("unknown"), reply: "callback.unknown"},
+}
+
+func TestRoutes(t *testing.T) {
+	loc := i18n.New("ru")
+	generic := loc.T("error.generic")
+
+	for _, tt := range routeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService()
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			recorder := messengertest.NewRecorder()
+			h := NewTelegramHandlerWithMessenger(recorder, svc, clocktest.NewManualClock(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)))
+
+			h.handleUpdate(context.Background(), tt.update)
+
+			calls := svc.Calls()
+			for _, call := range tt.calls {
+				if !slices.Contains(calls, call) {
+					t.Errorf("service.%s not called, calls: %v", call, calls)
+				}
+			}
+
+			texts := recorder.Texts()
+			for _, edited := range recorder.Edited {
+				texts = append(texts, edited.Text)
+			}
+
+			if slices.Contains(texts, generic) {
+				t.Errorf("route answered with the generic error, calls: %v", calls)
+			}
+
+			if tt.reply != "" {
+				format := loc.T(tt.reply)
+				if format == tt.reply {
+					t.Fatalf("unknown i18n key %q", tt.reply)
+				}
+				want, _, _ := strings.Cut(format, "%")
+				if !slices.ContainsFunc(texts, func(text string) bool { return strings.Contains(text, want) }) {
+					t.Errorf("no reply %q in %q", tt.reply, texts)
+				}
+			}
+
+			if tt.update.CallbackQuery != nil && len(recorder.Answers) != 1 {
+				t.Errorf("callback answered %d times, want 1", len(recorder.Answers))
+			}
+		})
+	}
+}
+
+// TestRoutesCovered не даёт добавить команду или префикс callback без случая в routeTests
+func TestRoutesCovered(t *testing.T) {
+	h := NewTelegramHandlerWithMessenger(messengertest.NewRecorder(), newTestService(), clocktest.NewManualClock(time.Now()))
+
+	var commands, callbacks []string
+	for _, tt := range routeTests {
+		switch {
+		case tt.update.Message != nil:
+			commands = append(commands, tt.update.Message.Command())
+		case tt.update.CallbackQuery != nil:
+			callbacks = append(callbacks, tt.update.CallbackQuery.Data)
+		}
+	}
+
+	for name := range h.router.commands {
+		if !slices.Contains(commands, name) {
+			t.Errorf("command /%s has no route test", name)
+		}
+	}
+
+	for _, cb := range h.router.callbacks {
+		covered := slices.ContainsFunc(callbacks, func(data string) bool {
+			rt, ok := h.router.callback(data)
+			return ok && strings.HasPrefix(data, cb.prefix) && rt.name == cb.name
+		})
+		if !covered {
+			t.Errorf("callback prefix %q has no route test", cb.prefix)
+		}
+	}
+}
+
+// TestUndoButtonCarriesReviewKey проверяет, что кнопка отмены отменяет именно ту оценку, под которой показана
+func TestUndoButtonCarriesReviewKey(t *testing.T) {
+	svc := newTestService()
+	withToken(models.CallbackActionGrade)(svc)
+	var undoToken *models.CallbackToken
+	svc.SaveCallbackTokensFunc = func(_ context.Context, _ int64, tokens []*models.CallbackToken) error {
+		for _, token := range tokens {
+			if token.Action == models.CallbackActionUndo {
+				undoToken = token
+			}
+		}
+		return nil
+	}
+	var undoneKey string
+	svc.UndoLastGradeFunc = func(_ context.Context, _ int64, _ string, reviewKey string) error {
+		undoneKey = reviewKey
+		return nil
+	}
+	h := NewTelegramHandlerWithMessenger(messengertest.NewRecorder(), svc, clocktest.NewManualClock(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)))
+
+	h.handleUpdate(context.Background(), newCallbackUpdate("t_token"))
+	if undoToken == nil {
+		t.Fatal("grade result has no undo button")
+	}
+	if undoToken.ReviewKey != "t_token" {
+		t.Fatalf("undo button review key = %q, want the grade button data %q", undoToken.ReviewKey, "t_token")
+	}
+
+	svc.GetCallbackTokenFunc = func(context.Context, int64, string) (*models.CallbackToken, error) {
+		return undoToken, nil
+	}
+	svc.ConsumeCallbackTokenFunc = svc.GetCallbackTokenFunc
+	h.handleUpdate(context.Background(), newCallbackUpdate("t_undo"))
+	if undoneKey != "t_token" {
+		t.Errorf("undo review key = %q, want %q", undoneKey, "t_token")
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"go.uber.org/zap"
+)
+
+// sessionNext — callback кнопки, которая показывает следующую непройденную страницу сессии повторения
+const sessionNext = "session_next"
+
+// handleSessionNext показывает следующую страницу сегодняшней сессии, а если все пройдены — итог сессии
+func (h *TelegramHandler) handleSessionNext(ctx context.Context, req *request) error {
+	session, _, err := h.service.StartReviewSession(ctx, req.userID)
+	if err != nil {
+		return withReply(fmt.Errorf("start review session: %w", err), req.loc.T("error.short"))
+	}
+
+	if session == nil {
+		h.sendMessage(req.chatID, req.loc.T("today.empty"))
+		return nil
+	}
+
+	item := session.Next()
+	if item == nil {
+		h.sendMessage(req.chatID, renderSessionSummary(req.loc, session))
+		return nil
+	}
+
+	return h.handleShowPage(ctx, req, item.PageID)
+}
+
+// sessionPageHeader возвращает строку «страница k из N», если страница входит в сегодняшнюю сессию
+func (h *TelegramHandler) sessionPageHeader(ctx context.Context, loc i18n.Localizer, userID int64, pageID string) string {
+	session, err := h.service.GetReviewSession(ctx, userID)
+	if err != nil {
+		zap.S().Error("get review session", zap.Error(err), zap.Int64("telegram_id", userID))
+		return ""
+	}
+
+	if session == nil {
+		return ""
+	}
+
+	for i, item := range session.Items {
+		if item.PageID == pageID {
+			return loc.T("session.page", i+1, len(session.Items))
+		}
+	}
+	return ""
+}
+
+// sendSessionStep отправляет результат страницы вместе с прогрессом сессии и кнопкой следующей страницы.
+// После последней страницы сессии к результату добавляется итог, для страниц вне сессии — только результат.
+// rows — кнопки самого результата, они идут перед кнопкой следующей страницы.
+func (h *TelegramHandler) sendSessionStep(ctx context.Context, loc i18n.Localizer, userID, chatID int64, pageID, resultText string, rows ...[]tgbotapi.InlineKeyboardButton) {
+	session, err := h.service.GetReviewSession(ctx, userID)
+	if err != nil {
+		zap.S().Error("get review session", zap.Error(err), zap.Int64("telegram_id", userID))
+	}
+
+	text := resultText
+	if session != nil && session.Item(pageID) != nil {
+		if session.Next() == nil {
+			text += "\n\n" + renderSessionSummary(loc, session)
+		} else {
+			text += "\n\n" + loc.T("session.progress", session.Done(), len(session.Items))
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(loc.T("session.button.next"), sessionNext)))
+		}
+	}
+
+	if len(rows) == 0 {
+		h.sendMessage(chatID, text)
+		return
+	}
+	h.sendMessageWithKeyboard(chatID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// sessionButton — кнопка начала или продолжения сессии для списка на сегодня
+func sessionButton(loc i18n.Localizer, session *models.ReviewSession) tgbotapi.InlineKeyboardButton {
+	text := loc.T("session.button.start")
+	if done := session.Done(); done > 0 {
+		text = loc.T("session.button.continue", done, len(session.Items))
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, sessionNext)
+}
+
+// renderSessionSummary описывает итог сессии: сколько страниц оценено и пропущено и средний результат
+func renderSessionSummary(loc i18n.Localizer, session *models.ReviewSession) string {
+	graded, skipped, total := 0, 0, 0
+	for _, item := range session.Items {
+		if item.Result == nil {
+			continue
+		}
+		switch *item.Result {
+		case models.ReviewResultGraded:
+			graded++
+			if item.Grade != nil {
+				total += *item.Grade
+			}
+		case models.ReviewResultSkipped:
+			skipped++
+		}
+	}
+
+	lines := []string{
+		loc.T("session.summary.title"),
+		"",
+		loc.T("session.summary.graded", graded, len(session.Items)),
+	}
+	if skipped > 0 {
+		lines = append(lines, loc.N("session.summary.skipped", skipped, skipped))
+	}
+	if graded > 0 {
+		lines = append(lines, loc.T("session.summary.average", total/graded))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/handler/messengertest"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+// newTestSession собирает сессию из результатов страниц: "" — страница не пройдена, иначе результат и оценка
+func newTestSession(results ...string) *models.ReviewSession {
+	session := &models.ReviewSession{ID: 1, Status: models.ReviewSessionActive}
+	for i, result := range results {
+		item := &models.ReviewSessionItem{SessionID: 1, Position: i, PageID: "page-" + string(rune('a'+i))}
+		if result != "" {
+			kind, grade, _ := strings.Cut(result, ":")
+			item.Result = &kind
+			if value, err := strconv.Atoi(grade); err == nil {
+				item.Grade = &value
+			}
+		}
+		session.Items = append(session.Items, item)
+	}
+	return session
+}
+
+func TestRenderSessionSummary(t *testing.T) {
+	loc := i18n.New("ru")
+
+	tests := []struct {
+		name    string
+		session *models.ReviewSession
+		want    []string
+		// absent — строки, которых в итоге быть не должно
+		absent []string
+	}{
+		{
+			name:    "graded and skipped",
+			session: newTestSession("graded:90", "skipped", "graded:61"),
+			want:    []string{loc.T("session.summary.graded", 2, 3), loc.N("session.summary.skipped", 1, 1), loc.T("session.summary.average", 75)},
+		},
+		{
+			// Без оценок средний результат не показывается
+			name:    "all skipped",
+			session: newTestSession("skipped", "skipped"),
+			want:    []string{loc.T("session.summary.graded", 0, 2), loc.N("session.summary.skipped", 2, 2)},
+			absent:  []string{"📈"},
+		},
+		{
+			name:    "all graded",
+			session: newTestSession("graded:100", "graded:40"),
+			want:    []string{loc.T("session.summary.graded", 2, 2), loc.T("session.summary.average", 70)},
+			absent:  []string{"↩️"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := renderSessionSummary(loc, tt.session)
+			if !strings.HasPrefix(summary, loc.T("session.summary.title")) {
+				t.Errorf("summary %q does not start with the title", summary)
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(summary, line) {
+					t.Errorf("summary %q has no line %q", summary, line)
+				}
+			}
+			for _, text := range tt.absent {
+				if strings.Contains(summary, text) {
+					t.Errorf("summary %q contains %q", summary, text)
+				}
+			}
+		})
+	}
+}
+
+// TestSessionNext проверяет, что кнопка следующей страницы открывает первую непройденную страницу, а после последней — итог
+func TestSessionNext(t *testing.T) {
+	loc := i18n.New("ru")
+
+	tests := []struct {
+		name    string
+		session *models.ReviewSession
+		// wantPage — страница, которая открывается, "" — вместо страницы показывается итог
+		wantPage string
+	}{
+		{
+			name:     "next page",
+			session:  newTestSession("graded:90", "", ""),
+			wantPage: "page-b",
+		},
+		{
+			name:    "summary",
+			session: newTestSession("graded:90", "skipped"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService()
+			svc.StartReviewSessionFunc = func(context.Context, int64) (*models.ReviewSession, []*models.PageWithProgress, error) {
+				return tt.session, nil, nil
+			}
+			svc.GetDuePagesTodayFunc = func(context.Context, int64) ([]*models.PageWithProgress, error) {
+				var due []*models.PageWithProgress
+				for _, item := range tt.session.Items {
+					due = append(due, &models.PageWithProgress{Page: models.PageReference{PageID: item.PageID, Title: "1 Topic"}, Progress: &models.UserProgress{PageID: item.PageID}})
+				}
+				return due, nil
+			}
+			var shown string
+			svc.GetPageContentFunc = func(_ context.Context, _ int64, pageID string) (string, error) {
+				shown = pageID
+				return "content", nil
+			}
+			recorder := messengertest.NewRecorder()
+			h := NewTelegramHandlerWithMessenger(recorder, svc, clocktest.NewManualClock(time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)))
+
+			h.handleUpdate(context.Background(), newCallbackUpdate(sessionNext))
+
+			if shown != tt.wantPage {
+				t.Errorf("page %q shown, want %q", shown, tt.wantPage)
+			}
+			summary := slices.ContainsFunc(recorder.Texts(), func(text string) bool {
+				return strings.HasPrefix(text, loc.T("session.summary.title"))
+			})
+			if summary != (tt.wantPage == "") {
+				t.Errorf("summary sent = %v, sent: %q", summary, recorder.Texts())
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service"
+	"go.uber.org/zap"
+)
+
+// Callback data сообщения настроек: settings_open_<поле> открывает выбор значения,
+// settings_set_<поле>_<значение> сохраняет его, settings_back возвращает к списку настроек
+const (
+	settingsPrefix     = "settings_"
+	settingsOpenPrefix = settingsPrefix + "open_"
+	settingsSetPrefix  = settingsPrefix + "set_"
+	settingsBack       = settingsPrefix + "back"
+)
+
+// Поля настроек в callback data, без подчёркиваний: значение отделяется от поля первым "_"
+const (
+	settingLevel    = "level"
+	settingMaxPages = "pages"
+	settingTimezone = "tz"
+	settingReminder = "reminder"
+	// settingReminders включает и выключает напоминания целиком, значения "on" и "off"
+	settingReminders = "reminders"
+	settingLanguage  = "lang"
+)
+
+// reminderTimeOptions — время напоминания, которое можно выбрать кнопкой, вместе с уже выбранным командой /set_reminder
+var reminderTimeOptions = []string{"07:00", "08:00", "09:00", "10:00", "12:00", "18:00", "20:00", "21:00"}
+
+func (h *TelegramHandler) handleSettings(ctx context.Context, req *request) error {
+	times, err := h.service.GetReminderTimes(ctx, req.userID)
+	if err != nil {
+		return err
+	}
+
+	text, keyboard := renderSettings(req.loc, req.user, times, false)
+	h.sendMessageWithKeyboard(req.chatID, text, keyboard)
+	return nil
+}
+
+// renderSettings формирует сообщение с текущими настройками и кнопками для изменения каждой из них
+func renderSettings(loc i18n.Localizer, user *models.User, reminderTimes []string, saved bool) (string, tgbotapi.InlineKeyboardMarkup) {
+	maxPages := uint(service.MinMaxPagesPerDay)
+	if user.MaxPagesPerDay != nil {
+		maxPages = *user.MaxPagesPerDay
+	}
+
+	timezone := "UTC"
+	if user.Timezone != nil && *user.Timezone != "" {
+		timezone = *user.Timezone
+	}
+
+	lines := []string{
+		loc.T("settings.title"),
+		"",
+		loc.T("settings.level", user.Level),
+		loc.T("settings.max_pages", maxPages),
+		loc.T("settings.timezone", timezone),
+	}
+	lines = append(lines, reminderLines(loc, user, reminderTimes)...)
+	lines = append(lines, loc.T("settings.language", i18n.Name(user.Language)))
+	if saved {
+		lines = append(lines, "", loc.T("settings.saved"))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(loc.T("settings.button.level"), settingsOpenPrefix+settingLevel),
+			tgbotapi.NewInlineKeyboardButtonData(loc.T("settings.button.max_pages"), settingsOpenPrefix+settingMaxPages),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(loc.T("settings.button.timezone"), settingsOpenPrefix+settingTimezone),
+			tgbotapi.NewInlineKeyboardButtonData(loc.T("settings.button.reminder"), settingsOpenPrefix+settingReminder),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(loc.T("settings.button.language"), settingsOpenPrefix+settingLanguage),
+		),
+	)
+
+	return strings.Join(lines, "\n"), keyboard
+}
+
+// renderSettingOptions формирует выбор значения одной настройки с кнопкой возврата к списку
+func renderSettingOptions(loc i18n.Localizer, field string, user *models.User, reminderTimes []string) (string, tgbotapi.InlineKeyboardMarkup, bool) {
+	prefix := settingsSetPrefix + field + "_"
+
+	var text string
+	var buttons [][]tgbotapi.InlineKeyboardButton
+	switch field {
+	case settingLevel:
+		text, buttons = loc.T("onboarding.level"), levelButtons(prefix)
+	case settingMaxPages:
+		text, buttons = loc.T("max_pages.choose"), maxPagesButtons(prefix)
+	case settingTimezone:
+		text, buttons = loc.T("timezone.choose"), timezoneButtons(loc, prefix)
+	case settingReminder:
+		text, buttons = loc.T("settings.choose_reminder", service.MaxReminderTimes), reminderButtons(loc, prefix, user, reminderTimes)
+	case settingLanguage:
+		text, buttons = loc.T("language.choose"), languageButtons(prefix)
+	default:
+		return "", tgbotapi.InlineKeyboardMarkup{}, false
+	}
+
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(loc.T("button.back"), settingsBack),
+	))
+
+	return text, tgbotapi.NewInlineKeyboardMarkup(buttons...), true
+}
+
+// reminderButtons — время напоминаний, выбранное отмечено и снимается повторным нажатием,
+// и кнопка включения или выключения напоминаний
+func reminderButtons(loc i18n.Localizer, prefix string, user *models.User, reminderTimes []string) [][]tgbotapi.InlineKeyboardButton {
+	options := slices.Clone(reminderTimeOptions)
+	for _, reminderTime := range reminderTimes {
+		if !slices.Contains(options, reminderTime) {
+			options = append(options, reminderTime)
+		}
+	}
+	slices.Sort(options)
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, option := range options {
+		label := option
+		if slices.Contains(reminderTimes, option) {
+			label = "✅ " + option
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(label, prefix+option))
+	}
+
+	switchButton := tgbotapi.NewInlineKeyboardButtonData(loc.T("reminder.button.off"), settingsSetPrefix+settingReminders+"_off")
+	if !user.RemindersEnabled {
+		switchButton = tgbotapi.NewInlineKeyboardButtonData(loc.T("reminder.button.on"), settingsSetPrefix+settingReminders+"_on")
+	}
+
+	return append(buttonRows(buttons, 4), tgbotapi.NewInlineKeyboardRow(switchButton))
+}
+
+// handleSettingsCallback переключает сообщение настроек между списком и выбором значения
+// и сохраняет выбранное значение, всё редактированием того же сообщения
+func (h *TelegramHandler) handleSettingsCallback(ctx context.Context, req *request) error {
+	data := req.callback.Data
+	messageID := req.callback.Message.MessageID
+
+	switch {
+	case data == settingsBack:
+		times, err := h.service.GetReminderTimes(ctx, req.userID)
+		if err != nil {
+			return err
+		}
+
+		text, keyboard := renderSettings(req.loc, req.user, times, false)
+		h.editMessage(req.chatID, messageID, text, &keyboard)
+		return nil
+
+	case strings.HasPrefix(data, settingsOpenPrefix):
+		times, err := h.service.GetReminderTimes(ctx, req.userID)
+		if err != nil {
+			return err
+		}
+
+		text, keyboard, ok := renderSettingOptions(req.loc, strings.TrimPrefix(data, settingsOpenPrefix), req.user, times)
+		if !ok {
+			zap.S().Warn("unknown settings field", zap.String("data", data), zap.Int64("telegram_id", req.userID))
+			return nil
+		}
+		h.editMessage(req.chatID, messageID, text, &keyboard)
+		return nil
+
+	case strings.HasPrefix(data, settingsSetPrefix):
+		field, value, ok := strings.Cut(strings.TrimPrefix(data, settingsSetPrefix), "_")
+		if !ok {
+			return withReply(fmt.Errorf("invalid settings callback %q", data), req.loc.T("settings.invalid"))
+		}
+		return h.saveSetting(ctx, req, field, value)
+
+	default:
+		zap.S().Warn("unknown settings action", zap.String("data", data), zap.Int64("telegram_id", req.userID))
+		return nil
+	}
+}
+
+// saveSetting сохраняет значение через сервис, который проверяет его, и показывает обновлённый список настроек.
+// Время напоминаний выбирается по одному, поэтому после него остаётся открытым выбор напоминаний.
+func (h *TelegramHandler) saveSetting(ctx context.Context, req *request, field, value string) error {
+	var err error
+	switch field {
+	case settingLevel:
+		err = h.service.UpdateUserLevel(ctx, req.userID, value)
+	case settingMaxPages:
+		var maxPages uint64
+		if maxPages, err = strconv.ParseUint(value, 10, 0); err != nil {
+			err = fmt.Errorf("%w: max pages per day %q", service.ErrInvalidSetting, value)
+			break
+		}
+		err = h.service.UpdateMaxPagesPerDay(ctx, req.userID, uint(maxPages))
+	case settingTimezone:
+		err = h.service.UpdateUserTimezone(ctx, req.userID, value)
+	case settingReminder:
+		err = h.service.ToggleReminderTime(ctx, req.userID, value)
+	case settingReminders:
+		err = h.service.SetRemindersEnabled(ctx, req.userID, value == "on")
+	case settingLanguage:
+		err = h.service.UpdateUserLanguage(ctx, req.userID, value)
+	default:
+		return withReply(fmt.Errorf("unknown settings field %q", field), req.loc.T("settings.invalid"))
+	}
+
+	if err != nil {
+		if errors.Is(err, service.ErrNoReminderTimes) {
+			return withReply(err, req.loc.T("reminder.last_time"))
+		}
+		if errors.Is(err, service.ErrInvalidSetting) || errors.Is(err, i18n.ErrUnsupportedLanguage) {
+			return withReply(err, req.loc.T("settings.invalid"))
+		}
+		return withReply(fmt.Errorf("update setting %s to %q: %w", field, value, err), req.loc.T("settings.update_failed"))
+	}
+
+	user, err := h.service.GetUser(ctx, req.userID)
+	if err != nil {
+		return withReply(fmt.Errorf("get user: %w", err), req.loc.T("error.generic"))
+	}
+
+	times, err := h.service.GetReminderTimes(ctx, req.userID)
+	if err != nil {
+		return withReply(fmt.Errorf("get reminder times: %w", err), req.loc.T("error.generic"))
+	}
+
+	// После смены языка список настроек показывается уже на новом языке
+	loc := i18n.New(user.Language)
+	text, keyboard := renderSettings(loc, user, times, true)
+	if field == settingReminder || field == settingReminders {
+		text, keyboard, _ = renderSettingOptions(loc, settingReminder, user, times)
+	}
+	h.editMessage(req.chatID, req.callback.Message.MessageID, text, &keyboard)
+	return nil
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/romanzh1/master-english-srs/internal/i18n"
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/chart"
+	"go.uber.org/zap"
+)
+
+// handleStats показывает статистику обучения текстом и графиками
+func (h *TelegramHandler) handleStats(ctx context.Context, req *request) error {
+	stats, err := h.service.GetStats(ctx, req.userID)
+	if err != nil {
+		return withReply(fmt.Errorf("get stats: %w", err), req.loc.T("error.short"))
+	}
+
+	if stats.TotalReviews == 0 && stats.PassedPages == 0 && len(stats.PagesPerInterval) == 0 {
+		h.sendMessage(req.chatID, req.loc.T("stats.empty"))
+		return nil
+	}
+
+	steps := statsSteps(stats)
+	h.sendMessage(req.chatID, renderStats(req.loc, stats, steps))
+
+	png, err := chart.Render(statsPanels(stats, steps))
+	if err != nil {
+		zap.S().Error("render stats chart", zap.Error(err), zap.Int64("telegram_id", req.userID))
+		return nil
+	}
+
+	if err := h.messenger.SendPhoto(req.chatID, "stats.png", png, req.loc.T("stats.chart.caption")); err != nil {
+		zap.S().Error("send stats chart", zap.Error(err), zap.Int64("telegram_id", req.userID))
+	}
+	return nil
+}
+
+// statsStep — страницы и вспоминаемость на одном шаге SRS, шаг 0 — режим чтения
+type statsStep struct {
+	step         int
+	intervalDays int
+	pages        int
+	reviews      int
+	remembered   int
+}
+
+// statsSteps сводит интервалы статистики к шагам SRS так же, как они показываются у страниц
+func statsSteps(stats *models.UserStats) []*statsStep {
+	var steps []*statsStep
+	byStep := make(map[int]*statsStep)
+	stepOf := func(intervalDays int) *statsStep {
+		displayIntervalDays, stepNumber, _ := getIntervalStepInfo(intervalDays)
+		if stepNumber == 0 {
+			displayIntervalDays = 0
+		}
+		step, ok := byStep[stepNumber]
+		if !ok {
+			step = &statsStep{step: stepNumber, intervalDays: displayIntervalDays}
+			byStep[stepNumber] = step
+			steps = append(steps, step)
+		}
+		return step
+	}
+
+	for _, pages := range stats.PagesPerInterval {
+		stepOf(pages.IntervalDays).pages += pages.Pages
+	}
+	for _, retention := range stats.Retention {
+		step := stepOf(retention.IntervalDays)
+		step.reviews += retention.Reviews
+		step.remembered += retention.Remembered
+	}
+
+	slices.SortFunc(steps, func(a, b *statsStep) int {
+		return cmp.Compare(a.step, b.step)
+	})
+	return steps
+}
+
+// renderStats описывает статистику текстом: серия, итоги, шаги SRS, динамика оценки и нагрузка
+func renderStats(loc i18n.Localizer, stats *models.UserStats, steps []*statsStep) string {
+	inProgress := 0
+	for _, step := range steps {
+		inProgress += step.pages
+	}
+
+	recentReviews, activeDays := 0, 0
+	for _, day := range stats.ReviewsPerDay {
+		recentReviews += day.Reviews
+		if day.Reviews > 0 {
+			activeDays++
+		}
+	}
+
+	lines := []string{
+		loc.T("stats.title"),
+		"",
+		loc.N("stats.streak", stats.Streak, stats.Streak),
+		loc.N("stats.total", stats.TotalReviews, stats.TotalReviews),
+		loc.T("stats.recent",
+			loc.N("interval.days", len(stats.ReviewsPerDay), len(stats.ReviewsPerDay)),
+			loc.N("count.reviews", recentReviews, recentReviews),
+			loc.N("count.active_days", activeDays, activeDays),
+		),
+		loc.T("stats.pages", inProgress, stats.PassedPages),
+	}
+
+	if len(steps) > 0 {
+		lines = append(lines, "", loc.T("stats.steps.title"))
+		for _, step := range steps {
+			name := loc.T("stats.step.reading")
+			if step.step > 0 {
+				name = loc.T("stats.step", step.step, loc.N("interval.days", step.intervalDays, step.intervalDays))
+			}
+
+			retention := loc.T("stats.retention.none")
+			if step.reviews > 0 {
+				retention = loc.T("stats.retention", step.remembered*100/step.reviews, step.remembered, step.reviews)
+			}
+			lines = append(lines, loc.T("stats.step.line", name, loc.N("count.pages", step.pages, step.pages), retention))
+		}
+	}
+
+	lines = append(lines, "", loc.T("stats.trend.title"))
+	for _, week := range stats.ScoreTrend {
+		period := week.WeekStart.Format("02.01") + "–" + week.WeekStart.AddDate(0, 0, 6).Format("02.01")
+		if week.Reviews == 0 {
+			lines = append(lines, loc.T("stats.trend.empty", period))
+			continue
+		}
+		lines = append(lines, loc.T("stats.trend.week", period, week.AverageScore, loc.N("count.reviews", week.Reviews, week.Reviews)))
+	}
+
+	upcoming := make([]string, 0, len(stats.Upcoming))
+	for i, day := range stats.Upcoming {
+		label := day.Date.Format("02.01")
+		if i == 0 {
+			label = loc.T("comeback.today")
+		}
+		upcoming = append(upcoming, fmt.Sprintf("%s: %d", label, day.Pages))
+	}
+	lines = append(lines, "", loc.T("stats.upcoming.title"), strings.Join(upcoming, ", "))
+
+	return strings.Join(lines, "\n")
+}
+
+// statsPanels строит панели графика в порядке, описанном в подписи stats.chart.caption
+func statsPanels(stats *models.UserStats, steps []*statsStep) []chart.Panel {
+	reviews := chart.Panel{Color: chart.Blue}
+	for _, day := range stats.ReviewsPerDay {
+		reviews.Labels = append(reviews.Labels, day.Date.Format("02"))
+		reviews.Values = append(reviews.Values, float64(day.Reviews))
+		reviews.ValueLabels = append(reviews.ValueLabels, countLabel(day.Reviews))
+	}
+
+	scores := chart.Panel{Color: chart.Purple, Max: 100}
+	for _, week := range stats.ScoreTrend {
+		scores.Labels = append(scores.Labels, week.WeekStart.Format("02.01"))
+		scores.Values = append(scores.Values, float64(week.AverageScore))
+		label := ""
+		if week.Reviews > 0 {
+			label = strconv.Itoa(week.AverageScore) + "%"
+		}
+		scores.ValueLabels = append(scores.ValueLabels, label)
+	}
+
+	retention := chart.Panel{Color: chart.Orange, Max: 100}
+	for _, step := range steps {
+		if step.reviews == 0 {
+			continue
+		}
+		percent := step.remembered * 100 / step.reviews
+		retention.Labels = append(retention.Labels, strconv.Itoa(step.intervalDays))
+		retention.Values = append(retention.Values, float64(percent))
+		retention.ValueLabels = append(retention.ValueLabels, strconv.Itoa(percent)+"%")
+	}
+
+	upcoming := chart.Panel{Color: chart.Green}
+	for _, day := range stats.Upcoming {
+		upcoming.Labels = append(upcoming.Labels, day.Date.Format("02"))
+		upcoming.Values = append(upcoming.Values, float64(day.Pages))
+		upcoming.ValueLabels = append(upcoming.ValueLabels, countLabel(day.Pages))
+	}
+
+	return []chart.Panel{reviews, scores, retention, upcoming}
+}
+
+// countLabel — подпись столбца с количеством, у пустого столбца подписи нет
+func countLabel(count int) string {
+	if count == 0 {
+		return ""
+	}
+	return strconv.Itoa(count)
+}
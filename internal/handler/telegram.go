@@ -2,87 +2,232 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/romanzh1/master-english-srs/internal/i18n"
 	"github.com/romanzh1/master-english-srs/internal/models"
 	"github.com/romanzh1/master-english-srs/internal/service"
+	"github.com/romanzh1/master-english-srs/pkg/onenote"
 	"github.com/romanzh1/master-english-srs/pkg/utils"
 	"go.uber.org/zap"
 )
 
+// telegramMessageLimit — максимальная длина сообщения Telegram
+const telegramMessageLimit = 4096
+
 type TelegramHandler struct {
-	api     *tgbotapi.BotAPI
-	service models.Service
+	// api получает обновления, messenger отправляет ответы. У обработчика, созданного
+	// через NewTelegramHandlerWithMessenger, api нет и запустить получение обновлений нельзя.
+	api       *tgbotapi.BotAPI
+	messenger Messenger
+	service   models.Service
+	// clock — источник текущего времени, в тестах его можно подменить
+	clock utils.Clock
+
+	pickersMu sync.Mutex
+	pickers   map[int64]*sectionPicker
+
+	dispatcher *updateDispatcher
+	router     *router
+	limiter    *rateLimiter
+
+	// background отслеживает планировщик фоновых задач
+	background sync.WaitGroup
 }
 
-func NewTelegramHandler(token string, service models.Service) (*TelegramHandler, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+// NewTelegramHandler создаёт обработчик бота. Конструктор не обращается к Telegram,
+// токен проверяется при запуске через Start или StartWebhook.
+func NewTelegramHandler(token string, service models.Service, clock utils.Clock) (*TelegramHandler, error) {
+	api, err := newBotAPI(token)
 	if err != nil {
 		return nil, fmt.Errorf("create bot API: %w", err)
 	}
 
-	return &TelegramHandler{
-		api:     api,
-		service: service,
-	}, nil
+	h := NewTelegramHandlerWithMessenger(botMessenger{api: api}, service, clock)
+	h.api = api
+
+	return h, nil
+}
+
+// NewTelegramHandlerWithMessenger создаёт обработчик, отвечающий через messenger, например через записывающий фейк в тестах
+func NewTelegramHandlerWithMessenger(messenger Messenger, service models.Service, clock utils.Clock) *TelegramHandler {
+	h := &TelegramHandler{
+		messenger: messenger,
+		service:   service,
+		clock:     clock,
+		pickers:   make(map[int64]*sectionPicker),
+		limiter:   newRateLimiter(clock, rateLimitPerSecond, rateLimitBurst),
+	}
+	h.router = h.newRouter()
+	h.dispatcher = newUpdateDispatcher(maxConcurrentUsers, maxQueuedUpdates, updateTimeout, h.handleUpdate, h.handlePanic)
+
+	return h
+}
+
+// connect проверяет токен запросом getMe перед началом получения обновлений
+func (h *TelegramHandler) connect() error {
+	if h.api == nil {
+		return errors.New("bot API is not configured")
+	}
+
+	self, err := h.api.GetMe()
+	if err != nil {
+		return fmt.Errorf("get bot info: %w", err)
+	}
+	h.api.Self = self
+
+	zap.S().Info("authorized on account", zap.String("username", self.UserName))
+	return nil
 }
 
 func (h *TelegramHandler) handleCommand(ctx context.Context, update tgbotapi.Update) {
-	switch update.Message.Command() {
-	case "start":
-		h.handleStart(ctx, update)
-	case "connect_onenote":
-		h.handleConnectOneNote(ctx, update)
-	case "select_notebook":
-		h.handleSelectNotebook(ctx, update)
-	case "select_section":
-		h.handleSelectSection(ctx, update)
-	case "today":
-		h.handleToday(ctx, update)
-	case "pages":
-		h.handlePages(ctx, update)
-	case "set_max_pages":
-		h.handleSetMaxPages(ctx, update)
-	case "get_max_pages":
-		h.handleGetMaxPages(ctx, update)
-	case "prepare_materials":
-		h.handlePrepareMaterials(ctx, update)
-	case "set_timezone":
-		h.handleSetTimezone(ctx, update)
-	case "help":
-		h.handleHelp(ctx, update)
-	default:
-		h.sendMessage(update.Message.Chat.ID, "Неизвестная команда. Используй /help")
+	msg := update.Message
+
+	rt, ok := h.router.command(msg.Command())
+	if !ok {
+		rt = route{name: "unknown_command", require: requireNone, handle: h.handleUnknownCommand}
 	}
+
+	h.serve(ctx, rt, &request{userID: msg.From.ID, chatID: msg.Chat.ID, message: msg, languageCode: msg.From.LanguageCode})
 }
 
-func (h *TelegramHandler) Start() {
+// shutdownTimeout — сколько при остановке ждать завершения обработчиков и фоновых задач
+const shutdownTimeout = 25 * time.Second
+
+// shutdownGrace — сколько после отмены контекстов ждать, пока обработчики освободят ресурсы.
+// Вместе с shutdownTimeout укладывается в stop_grace_period в docker-compose.yml
+const shutdownGrace = 3 * time.Second
+
+// Start получает обновления через long polling, пока ctx не отменён
+func (h *TelegramHandler) Start(ctx context.Context) error {
+	if err := h.connect(); err != nil {
+		return err
+	}
+
+	// Пока у бота установлен вебхук, getUpdates не работает, поэтому при переходе с вебхука на polling удаляем его
+	if _, err := h.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		zap.S().Warn("delete webhook before polling", zap.Error(err))
+	}
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
 	updates := h.api.GetUpdatesChan(u)
 
-	zap.S().Info("bot started")
+	zap.S().Info("bot started", zap.String("mode", "polling"))
 
-	go h.startReminderScheduler()
-	go h.startDailyCron()
+	// tgbotapi подтверждает полученные обновления следующим запросом getUpdates, поэтому всё, что уже лежит
+	// в канале, после перезапуска не придёт снова. При остановке сначала прекращаем опрос, затем обрабатываем
+	// оставшееся в канале. Придут снова только обновления последнего запроса, который ещё не подтверждён.
+	return h.processUpdates(ctx, updates, h.api.StopReceivingUpdates)
+}
 
-	for update := range updates {
-		if update.Message == nil && update.CallbackQuery == nil {
-			continue
+// processUpdates запускает фоновые задачи и обрабатывает обновления, пока ctx не отменён или канал не закрыт.
+// При отмене ctx вызывает stopReceiving, передаёт в обработку обновления, уже лежащие в канале,
+// затем дожидается обработчиков и фоновых задач
+func (h *TelegramHandler) processUpdates(ctx context.Context, updates <-chan tgbotapi.Update, stopReceiving func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	h.background.Add(1)
+	go func() {
+		defer h.background.Done()
+		h.startJobScheduler(ctx)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			stopReceiving()
+			h.dispatchBuffered(updates)
+			return h.drain(cancel, shutdownTimeout, shutdownGrace)
+		case update, ok := <-updates:
+			if !ok {
+				return h.drain(cancel, shutdownTimeout, shutdownGrace)
+			}
+
+			h.dispatchUpdate(update)
 		}
+	}
+}
 
-		h.handleUpdate(update)
+// dispatchUpdate передаёт обновление в обработку, остальные типы обновлений пропускаются
+func (h *TelegramHandler) dispatchUpdate(update tgbotapi.Update) {
+	if update.Message == nil && update.CallbackQuery == nil {
+		return
+	}
+
+	h.dispatcher.dispatch(update)
+}
+
+// dispatchBuffered передаёт в обработку обновления, уже полученные, но ещё не прочитанные из канала
+func (h *TelegramHandler) dispatchBuffered(updates <-chan tgbotapi.Update) {
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			h.dispatchUpdate(update)
+		default:
+			return
+		}
 	}
 }
 
-func (h *TelegramHandler) handleUpdate(update tgbotapi.Update) {
-	ctx := context.Background()
+// drain останавливает планировщики и ждёт завершения обработчиков не дольше timeout,
+// по истечении времени отменяет контексты оставшихся обработчиков и ждёт ещё не дольше grace,
+// чтобы отменённые обработчики успели вернуть соединения с БД и дописать логи до выхода процесса
+func (h *TelegramHandler) drain(stopBackground context.CancelFunc, timeout, grace time.Duration) error {
+	zap.S().Info("stopping bot, waiting for handlers")
+	stopBackground()
+
+	done := make(chan struct{})
+	go func() {
+		h.dispatcher.wait()
+		h.background.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	}
+
+	h.dispatcher.stop()
+
+	graceTimer := time.NewTimer(grace)
+	defer graceTimer.Stop()
+
+	select {
+	case <-done:
+		return fmt.Errorf("handlers did not finish in %s and were cancelled", timeout)
+	case <-graceTimer.C:
+		return fmt.Errorf("handlers did not finish in %s and did not stop %s after cancellation", timeout, grace)
+	}
+}
+
+// handlePanic отвечает пользователю, если обработка его обновления упала с паникой.
+// Язык берётся из клиента Telegram без обращения к базе: паника могла случиться именно в ней.
+func (h *TelegramHandler) handlePanic(update tgbotapi.Update, _ any) {
+	if chatID := updateChatID(update); chatID != 0 {
+		loc := i18n.New(i18n.Match(updateLanguageCode(update)))
+		h.sendMessage(chatID, loc.T("error.generic"))
+	}
+}
+
+func (h *TelegramHandler) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 
 	if update.Message != nil && update.Message.IsCommand() {
 		// Проверяем, что сообщение от пользователя (не от канала или группы)
@@ -98,7 +243,8 @@ func (h *TelegramHandler) handleUpdate(update tgbotapi.Update) {
 			return
 		}
 		// Обрабатываем текстовые сообщения (например, код авторизации)
-		h.handleTextMessage(ctx, update)
+		msg := update.Message
+		h.serve(ctx, route{name: "text", handle: h.handleTextMessage}, &request{userID: msg.From.ID, chatID: msg.Chat.ID, message: msg, languageCode: msg.From.LanguageCode})
 	} else if update.CallbackQuery != nil {
 		// Проверяем, что callback от пользователя
 		if update.CallbackQuery.From == nil {
@@ -109,307 +255,279 @@ func (h *TelegramHandler) handleUpdate(update tgbotapi.Update) {
 	}
 }
 
-func (h *TelegramHandler) handleStart(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-
-	exists, err := h.service.UserExists(ctx, userID)
+func (h *TelegramHandler) handleStart(ctx context.Context, req *request) error {
+	exists, err := h.service.UserExists(ctx, req.userID)
 	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(update.Message.Chat.ID, "Произошла ошибка. Попробуй позже.")
-		return
+		return err
 	}
 
 	if exists {
-		h.sendMessage(update.Message.Chat.ID, "С возвращением! Используй /today для начала занятий.")
-		return
-	}
+		// Незаконченная регистрация продолжается с того шага, на котором прервалась
+		if state := h.chatState(ctx, req.chatID); isOnboardingState(state.State) {
+			return h.promptOnboarding(ctx, req.loc, req.chatID, req.userID, state.State)
+		}
 
-	text := `Привет! 👋
+		h.sendMessage(req.chatID, req.loc.T("start.welcome_back"))
+		return nil
+	}
 
-		Я помогу тебе изучать английский по системе интервальных повторений (SRS).
+	h.sendMessage(req.chatID, req.loc.T("start.greeting"))
+	h.setChatState(ctx, req.chatID, req.userID, models.ChatStateOnboardingLanguage, nil)
+	return h.promptOnboarding(ctx, req.loc, req.chatID, req.userID, models.ChatStateOnboardingLanguage)
+}
 
-		Выбери свой уровень:`
+// showLanguageSelector показывает кнопки для выбора языка интерфейса
+func (h *TelegramHandler) showLanguageSelector(loc i18n.Localizer, chatID int64) {
+	h.sendMessageWithKeyboard(chatID, loc.T("language.choose"), tgbotapi.NewInlineKeyboardMarkup(languageButtons("lang_")...))
+}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("A1", "level_A1"),
-			tgbotapi.NewInlineKeyboardButtonData("A2", "level_A2"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("B1", "level_B1"),
-			tgbotapi.NewInlineKeyboardButtonData("B2", "level_B2"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("C1", "level_C1"),
-		),
-	)
+// languageButtons возвращает кнопки поддерживаемых языков в один ряд, callback data — prefix и код языка
+func languageButtons(prefix string) [][]tgbotapi.InlineKeyboardButton {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, lang := range i18n.Languages {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(i18n.Name(lang), prefix+lang))
+	}
+	return [][]tgbotapi.InlineKeyboardButton{row}
+}
 
-	h.sendMessageWithKeyboard(update.Message.Chat.ID, text, keyboard)
+func (h *TelegramHandler) handleLanguage(_ context.Context, req *request) error {
+	h.showLanguageSelector(req.loc, req.chatID)
+	return nil
 }
 
-func (h *TelegramHandler) handleConnectOneNote(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
+// handleLanguageSelection сохраняет выбранный язык: во время регистрации — в состоянии диалога до создания
+// пользователя, после регистрации — в профиле пользователя
+func (h *TelegramHandler) handleLanguageSelection(ctx context.Context, req *request) error {
+	userID := req.userID
+	chatID := req.chatID
+	lang := strings.TrimPrefix(req.callback.Data, "lang_")
 
-	exists, err := h.service.UserExists(ctx, userID)
-	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+	if !i18n.Supported(lang) {
+		return withReply(fmt.Errorf("unsupported language %q", lang), req.loc.T("language.failed"))
 	}
+	loc := i18n.New(lang)
 
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
+	state := h.chatState(ctx, chatID)
+	if state.State == models.ChatStateOnboardingLanguage {
+		data := map[string]string{chatStateKeyLanguage: lang}
+		h.setChatState(ctx, chatID, userID, state.State, data)
+		h.editMessage(chatID, req.callback.Message.MessageID, loc.T("language.set", i18n.Name(lang)), nil)
+
+		_, err := h.advanceOnboarding(ctx, loc, chatID, userID, models.ChatStateOnboardingLanguage)
+		return err
+	}
+
+	if err := h.authorize(ctx, req, requireRegistered); err != nil {
+		return err
 	}
 
-	authURL := h.service.GetAuthURL(userID)
+	if err := h.service.UpdateUserLanguage(ctx, userID, lang); err != nil {
+		return withReply(fmt.Errorf("update user language to %s: %w", lang, err), req.loc.T("language.failed"))
+	}
 
-	text := fmt.Sprintf("Для подключения OneNote перейди по ссылке:\n\n%s\n\nПосле авторизации отправь мне полученный код.", authURL)
-	h.sendMessage(chatID, text)
+	h.editMessage(chatID, req.callback.Message.MessageID, loc.T("language.set", i18n.Name(lang)), nil)
+	return nil
 }
 
-func (h *TelegramHandler) handleSelectNotebook(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
+// showLevelSelector показывает кнопки для выбора уровня языка
+func (h *TelegramHandler) showLevelSelector(loc i18n.Localizer, chatID int64) {
+	h.sendMessageWithKeyboard(chatID, loc.T("onboarding.level"), tgbotapi.NewInlineKeyboardMarkup(levelButtons("level_")...))
+}
 
-	exists, err := h.service.UserExists(ctx, userID)
-	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+// levelButtons возвращает кнопки уровней по два в ряд, callback data — prefix и уровень
+func levelButtons(prefix string) [][]tgbotapi.InlineKeyboardButton {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, level := range service.Levels {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(level, prefix+level))
 	}
+	return buttonRows(buttons, 2)
+}
 
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
+// buttonRows раскладывает кнопки по рядам из perRow штук
+func buttonRows(buttons []tgbotapi.InlineKeyboardButton, perRow int) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for start := 0; start < len(buttons); start += perRow {
+		rows = append(rows, buttons[start:min(start+perRow, len(buttons))])
 	}
+	return rows
+}
 
-	user, err := h.service.GetUser(ctx, userID)
-	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+func (h *TelegramHandler) handleConnectOneNote(ctx context.Context, req *request) error {
+	// Во время регистрации код ждёт шаг регистрации, иначе — отдельное ожидание кода
+	state := h.chatState(ctx, req.chatID)
+	if !isOnboardingState(state.State) {
+		h.setChatState(ctx, req.chatID, req.userID, models.ChatStateAwaitingAuthCode, nil)
 	}
 
-	// Проверяем, что пользователь авторизован
-	if user.AccessToken == nil || user.RefreshToken == nil {
-		h.sendMessage(chatID, "Сначала подключи OneNote с помощью команды /connect_onenote")
-		return
-	}
+	h.sendAuthLink(req.loc, req.chatID, req.userID)
+	return nil
+}
 
-	notebooks, err := h.service.GetOneNoteNotebooks(ctx, userID)
-	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
-		}
-		zap.S().Error("get notebooks", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Не удалось получить список книг OneNote. Попробуй позже.")
-		return
-	}
+func (h *TelegramHandler) handleSelectNotebook(ctx context.Context, req *request) error {
+	return h.showPicker(ctx, req.loc, req.userID, req.chatID, nil)
+}
 
-	if len(notebooks) == 0 {
-		h.sendMessage(chatID, "У тебя нет доступных книг OneNote.")
-		return
+func (h *TelegramHandler) handleSelectSection(ctx context.Context, req *request) error {
+	// Если книга уже выбрана, начинаем выбор с неё, иначе со списка книг
+	if req.user.NotebookID == nil || *req.user.NotebookID == "" {
+		return h.showPicker(ctx, req.loc, req.userID, req.chatID, nil)
 	}
 
-	text := "📚 Выбери книгу OneNote для синхронизации:\n\n"
-	var buttons [][]tgbotapi.InlineKeyboardButton
+	notebooks, err := h.service.GetOneNoteNotebooks(ctx, req.userID)
+	if err != nil {
+		return withReply(fmt.Errorf("get notebooks: %w", err), req.loc.T("notebooks.load_failed"))
+	}
 
-	for i, notebook := range notebooks {
-		callbackData := fmt.Sprintf("notebook_%d", i)
-		button := tgbotapi.NewInlineKeyboardButtonData(
-			notebook.DisplayName,
-			callbackData,
-		)
-		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(button))
+	var path []pickerItem
+	for _, notebook := range notebooks {
+		if notebook.ID == *req.user.NotebookID {
+			path = append(path, pickerItem{kind: pickerKindNotebook, id: notebook.ID, name: notebook.DisplayName})
+			break
+		}
 	}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-	h.sendMessageWithKeyboard(chatID, text, keyboard)
+	return h.showPicker(ctx, req.loc, req.userID, req.chatID, path)
 }
 
-func (h *TelegramHandler) handleSelectSection(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
-
-	exists, err := h.service.UserExists(ctx, userID)
+func (h *TelegramHandler) handleSources(ctx context.Context, req *request) error {
+	text, keyboard, err := h.buildSourcesMessage(ctx, req.loc, req.userID)
 	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+		return fmt.Errorf("get sources: %w", err)
 	}
 
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
+	if keyboard == nil {
+		h.sendMessage(req.chatID, text)
+		return nil
 	}
 
-	user, err := h.service.GetUser(ctx, userID)
+	h.sendMessageWithKeyboard(req.chatID, text, *keyboard)
+	return nil
+}
+
+// buildSourcesMessage формирует список подключённых секций с кнопками управления
+func (h *TelegramHandler) buildSourcesMessage(ctx context.Context, loc i18n.Localizer, userID int64) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	sources, err := h.service.GetSources(ctx, userID)
 	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+		return "", nil, err
 	}
 
-	// Проверяем, что пользователь авторизован
-	if user.AccessToken == nil || user.RefreshToken == nil {
-		h.sendMessage(chatID, "Сначала подключи OneNote с помощью команды /connect_onenote")
-		return
+	if len(sources) == 0 {
+		return loc.T("sources.empty"), nil, nil
 	}
 
-	// Проверяем, что выбран notebook
-	if user.NotebookID == nil || *user.NotebookID == "" {
-		h.sendMessage(chatID, "Сначала выбери книгу OneNote с помощью команды /select_notebook")
-		return
-	}
+	text := loc.T("sources.title") + "\n\n"
+	var buttons [][]tgbotapi.InlineKeyboardButton
 
-	sections, err := h.service.GetOneNoteSections(ctx, userID, *user.NotebookID)
-	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
+	for i, source := range sources {
+		status := "✅"
+		toggleText := loc.T("sources.disable")
+		if !source.Enabled {
+			status = "⏸"
+			toggleText = loc.T("sources.enable")
 		}
-		zap.S().Error("get sections", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Не удалось получить список секций OneNote. Попробуй позже.")
-		return
-	}
 
-	if len(sections) == 0 {
-		h.sendMessage(chatID, "В выбранной книге нет доступных секций.")
-		return
-	}
+		name := source.SectionName
+		if name == "" {
+			name = source.SectionID
+		}
+		if source.NotebookName != "" {
+			name = source.NotebookName + " / " + name
+		}
 
-	text := "📑 Выбери секцию OneNote для синхронизации:\n\n"
-	var buttons [][]tgbotapi.InlineKeyboardButton
+		text += fmt.Sprintf("%d. %s %s\n", i+1, status, escapeHTML(name))
 
-	for i, section := range sections {
-		callbackData := fmt.Sprintf("section_%d", i)
-		button := tgbotapi.NewInlineKeyboardButtonData(
-			section.DisplayName,
-			callbackData,
-		)
-		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(button))
+		row := []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d: %s", i+1, toggleText), fmt.Sprintf("source_toggle_%d", source.ID)),
+		}
+		if i > 0 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("⬆️", fmt.Sprintf("source_up_%d", source.ID)))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("🗑", fmt.Sprintf("source_remove_%d", source.ID)))
+		buttons = append(buttons, row)
 	}
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-	h.sendMessageWithKeyboard(chatID, text, keyboard)
+	return text, &keyboard, nil
 }
 
-func (h *TelegramHandler) handleTextMessage(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	text := strings.TrimSpace(update.Message.Text)
-	chatID := update.Message.Chat.ID
+func (h *TelegramHandler) handleSourceAction(ctx context.Context, req *request) error {
+	callback := req.callback
+	data := strings.TrimPrefix(callback.Data, "source_")
 
-	// Проверяем, может ли это быть код авторизации (длина от 20 до 200 символов)
-	if len(text) < 20 || len(text) >= 200 {
-		// Это не код авторизации - отправляем подсказку пользователю
-		h.sendMessage(chatID, "Я не понимаю эту команду. Используй /help для списка доступных команд.")
-		return
-	}
-
-	// Проверяем, существует ли пользователь
-	exists, err := h.service.UserExists(ctx, userID)
-	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+	action, idStr, ok := strings.Cut(data, "_")
+	sourceID, err := strconv.ParseInt(idStr, 10, 64)
+	if !ok || err != nil {
+		return withReply(fmt.Errorf("invalid source callback %q", callback.Data), req.loc.T("sources.invalid"))
 	}
 
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
+	switch action {
+	case "toggle":
+		err = h.service.ToggleSource(ctx, req.userID, sourceID)
+	case "up":
+		err = h.service.MoveSourceUp(ctx, req.userID, sourceID)
+	case "remove":
+		err = h.service.RemoveSource(ctx, req.userID, sourceID)
+	default:
+		zap.S().Warn("unknown source action", zap.String("action", action), zap.Int64("telegram_id", req.userID))
+		return nil
 	}
 
-	user, err := h.service.GetUser(ctx, userID)
 	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+		return withReply(fmt.Errorf("update source %d (%s): %w", sourceID, action, err), req.loc.T("sources.update_failed"))
 	}
 
-	// Проверяем, была ли уже авторизация
-	wasAuthorized := user.AccessToken != nil && user.RefreshToken != nil
-
-	// Пытаемся обменять код на токены (работает для новой и обновлённой авторизации)
-	err = h.service.ExchangeAuthCode(ctx, userID, text)
+	text, keyboard, err := h.buildSourcesMessage(ctx, req.loc, req.userID)
 	if err != nil {
-		// Если пользователь не авторизован, показываем ошибку
-		if !wasAuthorized {
-			zap.S().Error("exchange auth code", zap.Error(err), zap.Int64("telegram_id", userID))
-			h.sendMessage(chatID, "❌ Не удалось обработать код авторизации. Убедись, что код правильный и не истёк. Попробуй получить новый код через /connect_onenote")
-		} else {
-			// Если пользователь уже авторизован и код не подошёл, это не код авторизации
-			h.sendMessage(chatID, "Не удалось обработать код. Убедись, что код правильный и не истёк. Попробуй получить новый код через /connect_onenote")
-		}
-		return
+		zap.S().Error("get sources", zap.Error(err), zap.Int64("telegram_id", req.userID))
+		return nil
 	}
 
-	// После успешного обмена кода отправляем соответствующее сообщение
-	if wasAuthorized {
-		h.sendMessage(chatID, "✅ Авторизация обновлена!")
-	} else {
-		text := "✅ Авторизация успешна!\n\nТеперь выбери книгу OneNote с помощью /select_notebook, а затем секцию с помощью /select_section."
-		h.sendMessage(chatID, text)
-	}
+	h.editMessage(req.chatID, callback.Message.MessageID, text, keyboard)
+	return nil
 }
 
-// handleAuthError обрабатывает ошибку авторизации и отправляет пользователю сообщение с запросом повторной авторизации
-func (h *TelegramHandler) handleAuthError(err error, userID, chatID int64) bool {
-	authErr, ok := err.(*service.AuthRequiredError)
-	if !ok {
-		return false
-	}
+func (h *TelegramHandler) handleToday(ctx context.Context, req *request) error {
+	userID := req.userID
+	chatID := req.chatID
 
-	zap.S().Warn("authentication required", zap.Int64("telegram_id", authErr.TelegramID))
-	authURL := h.service.GetAuthURL(userID)
-	text := fmt.Sprintf("❌ Требуется повторная авторизация. Твой токен истёк.\n\nПерейди по ссылке для авторизации:\n\n%s\n\nПосле авторизации отправь мне полученный код.", authURL)
-	h.sendMessage(chatID, text)
-	return true
-}
-
-func (h *TelegramHandler) handleToday(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
-
-	// Проверяем, существует ли пользователь
-	exists, err := h.service.UserExists(ctx, userID)
+	// Вернувшемуся после перерыва пользователю сначала предлагается распределить накопившиеся повторения по дням
+	suggested, err := h.suggestComebackPlan(ctx, req)
 	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+		return withReply(fmt.Errorf("suggest comeback plan: %w", err), req.loc.T("error.short"))
 	}
-
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
+	if suggested {
+		return nil
 	}
 
-	duePages, err := h.service.GetDuePagesToday(ctx, userID)
+	session, duePages, err := h.service.StartReviewSession(ctx, userID)
 	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
-		}
-		zap.S().Error("get due pages today", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка.")
-		return
+		return withReply(fmt.Errorf("start review session: %w", err), req.loc.T("error.short"))
 	}
 
-	if len(duePages) == 0 {
-		h.sendMessage(chatID, "🎉 Сегодня нет страниц для повторения!")
-		return
+	if session == nil {
+		h.sendMessage(chatID, req.loc.T("today.empty"))
+		return nil
 	}
 
-	text := "📚 <b>Сегодня на повторение:</b>\n\n"
-	var buttons [][]tgbotapi.InlineKeyboardButton
+	// Все страницы сегодняшней сессии пройдены — вместо пустого списка показываем итог
+	if session.Next() == nil {
+		h.sendMessage(chatID, renderSessionSummary(req.loc, session))
+		return nil
+	}
+
+	text := req.loc.T("today.title") + "\n" + req.loc.T("session.progress", session.Done(), len(session.Items)) + "\n\n"
+	buttons := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(sessionButton(req.loc, session)),
+	}
 	counter := 0
+	callbacks := &callbackBatch{}
 
-	nowUTC := utils.NowUTC()
-	for i, pwp := range duePages {
+	nowUTC := h.clock.Now()
+	for _, pwp := range duePages {
 		daysSince := int(nowUTC.Sub(pwp.Progress.LastReviewDate).Hours() / 24)
 		escapedTitle := escapeHTML(pwp.Page.Title)
 
-		displayIntervalDays, stepNumber, totalSteps := getIntervalStepInfo(pwp.Progress.IntervalDays)
-		intervalWord := formatDaysRu(displayIntervalDays)
+		progressLine := formatIntervalProgress(req.loc, pwp.Progress.IntervalDays)
 
 		pageNumber := extractPageNumberFromTitle(pwp.Page.Title)
 		shouldNumber := pageNumber == 999999
@@ -419,77 +537,58 @@ func (h *TelegramHandler) handleToday(ctx context.Context, update tgbotapi.Updat
 		if shouldNumber {
 			counter++
 			prefix = fmt.Sprintf("%d. ", counter)
-			buttonText = fmt.Sprintf("Показать страницу %d", counter)
+			buttonText = req.loc.T("today.show_page", counter)
 		} else {
 			prefix = ""
-			buttonText = fmt.Sprintf("Показать страницу %d", pageNumber)
+			buttonText = req.loc.T("today.show_page", pageNumber)
 		}
 
-		if pwp.Progress.IntervalDays == 0 {
-			text += fmt.Sprintf("%s%s\n   📅 Новая страница\n   📊 Прогресс: интервал %d %s (шаг %d из %d)\n\n",
-				prefix, escapedTitle, displayIntervalDays, intervalWord, stepNumber, totalSteps)
-		} else {
-			text += fmt.Sprintf("%s%s\n   📅 Последнее повторение: %d дней назад\n   📊 Прогресс: интервал %d %s (шаг %d из %d)\n\n",
-				prefix, escapedTitle, daysSince, displayIntervalDays, intervalWord, stepNumber, totalSteps)
+		dateLine := req.loc.T("today.new_page")
+		if pwp.Progress.IntervalDays != 0 {
+			dateLine = req.loc.N("today.last_review", daysSince, daysSince)
 		}
+		text += fmt.Sprintf("%s%s\n   %s\n   %s\n\n", prefix, escapedTitle, dateLine, progressLine)
 
-		callbackData := fmt.Sprintf("show_%d", i)
+		callbackData := callbacks.issue(models.CallbackActionShowPage, pwp.Page.PageID)
 		button := tgbotapi.NewInlineKeyboardButtonData(buttonText, callbackData)
 		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(button))
 	}
 
 	buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("Пропустить всё", "skip_all"),
+		tgbotapi.NewInlineKeyboardButtonData(req.loc.T("today.skip_all"), "skip_all"),
 	))
 
+	if err := h.saveCallbacks(ctx, userID, callbacks); err != nil {
+		return withReply(fmt.Errorf("save callback tokens: %w", err), req.loc.T("error.short"))
+	}
+
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
 	h.sendMessageWithKeyboard(chatID, text, keyboard)
+	return nil
 }
 
-func (h *TelegramHandler) handlePages(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
-
-	exists, err := h.service.UserExists(ctx, userID)
-	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
-
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
-	}
-
-	user, err := h.service.GetUser(ctx, userID)
-	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
+func (h *TelegramHandler) handlePages(ctx context.Context, req *request) error {
+	userID := req.userID
+	chatID := req.chatID
 
 	timezone := "UTC"
-	if user.Timezone != nil && *user.Timezone != "" {
-		timezone = *user.Timezone
+	if req.user.Timezone != nil && *req.user.Timezone != "" {
+		timezone = *req.user.Timezone
 	}
 
 	pages, err := h.service.GetUserAllPagesInProgress(ctx, userID)
 	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
-		}
-		zap.S().Error("get user pages", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка.")
-		return
+		return withReply(fmt.Errorf("get user pages: %w", err), req.loc.T("error.short"))
 	}
 
 	if len(pages) == 0 {
-		h.sendMessage(chatID, "У тебя пока нет страниц, приходи завтра или используй /prepare_materials.")
-		return
+		h.sendMessage(chatID, req.loc.T("pages.empty"))
+		return nil
 	}
 
-	text := "📖 <b>Твои страницы:</b>\n\n"
+	text := req.loc.T("pages.title") + "\n\n"
+	var buttons []tgbotapi.InlineKeyboardButton
+	callbacks := &callbackBatch{}
 	counter := 0
 	for _, page := range pages {
 		progress, err := h.service.GetProgress(ctx, userID, page.PageID)
@@ -498,8 +597,7 @@ func (h *TelegramHandler) handlePages(ctx context.Context, update tgbotapi.Updat
 			continue
 		}
 
-		displayIntervalDays, stepNumber, totalSteps := getIntervalStepInfo(progress.IntervalDays)
-		intervalWord := formatDaysRu(displayIntervalDays)
+		progressLine := formatIntervalProgress(req.loc, progress.IntervalDays)
 
 		lastScore, err := h.service.GetLastReviewScore(ctx, userID, page.PageID)
 		if err != nil {
@@ -507,17 +605,9 @@ func (h *TelegramHandler) handlePages(ctx context.Context, update tgbotapi.Updat
 			lastScore = 0
 		}
 
-		var scoreEmoji string
-		if lastScore > 80 {
-			scoreEmoji = "✅"
-		} else if lastScore > 60 {
-			scoreEmoji = "🟢"
-		} else if lastScore >= 40 {
-			scoreEmoji = "🟡"
-		} else if lastScore > 0 {
-			scoreEmoji = "🔴"
-		} else {
-			scoreEmoji = ""
+		scoreEmoji := ""
+		if lastScore > 0 {
+			scoreEmoji = gradeEmoji(lastScore)
 		}
 
 		escapedTitle := escapeHTML(page.Title)
@@ -526,12 +616,16 @@ func (h *TelegramHandler) handlePages(ctx context.Context, update tgbotapi.Updat
 		shouldNumber := pageNumber == 999999
 
 		var prefix string
+		var buttonText string
 		if shouldNumber {
 			counter++
 			prefix = fmt.Sprintf("%d. ", counter)
+			buttonText = req.loc.T("pages.history_button", counter)
 		} else {
 			prefix = ""
+			buttonText = req.loc.T("pages.history_button", pageNumber)
 		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(buttonText, callbacks.issue(models.CallbackActionHistory, page.PageID)))
 
 		// Convert NextReviewDate to user's timezone for display
 		nextReviewInTz, err := utils.ToUserTimezone(progress.NextReviewDate, timezone)
@@ -543,7 +637,7 @@ func (h *TelegramHandler) handlePages(ctx context.Context, update tgbotapi.Updat
 
 		reviewedTodayStr := ""
 		if progress.ReviewedToday {
-			reviewedTodayStr = " | ✅ Повторено сегодня"
+			reviewedTodayStr = " | " + req.loc.T("pages.reviewed_today")
 		}
 
 		var scoreStr string
@@ -557,11 +651,36 @@ func (h *TelegramHandler) handlePages(ctx context.Context, update tgbotapi.Updat
 			scoreStr = ""
 		}
 
-		text += fmt.Sprintf("%s%s\n   📅 Следующее повторение: %s\n   📊 Прогресс: интервал %d %s (шаг %d из %d)%s%s\n\n",
-			prefix, escapedTitle, nextReviewStr, displayIntervalDays, intervalWord, stepNumber, totalSteps, reviewedTodayStr, scoreStr)
+		text += fmt.Sprintf("%s%s\n   %s\n   %s%s%s\n\n",
+			prefix, escapedTitle, req.loc.T("pages.next_review", nextReviewStr), progressLine, reviewedTodayStr, scoreStr)
 	}
 
-	h.sendMessage(chatID, text)
+	if len(buttons) == 0 {
+		h.sendMessage(chatID, text)
+		return nil
+	}
+
+	if err := h.saveCallbacks(ctx, userID, callbacks); err != nil {
+		return withReply(fmt.Errorf("save callback tokens: %w", err), req.loc.T("error.short"))
+	}
+
+	text += req.loc.T("pages.history_hint")
+	h.sendLongMessageWithKeyboard(chatID, text, tgbotapi.NewInlineKeyboardMarkup(buttonRows(buttons, 4)...))
+	return nil
+}
+
+// gradeEmoji — значок результата по тем же границам, что и кнопки оценки
+func gradeEmoji(score int) string {
+	switch {
+	case score > 80:
+		return "✅"
+	case score > 60:
+		return "🟢"
+	case score >= 40:
+		return "🟡"
+	default:
+		return "🔴"
+	}
 }
 
 // extractPageNumberFromTitle извлекает первое число из начала заголовка страницы
@@ -588,539 +707,355 @@ func extractPageNumberFromTitle(title string) int {
 	return num
 }
 
-func (h *TelegramHandler) handleHelp(ctx context.Context, update tgbotapi.Update) {
-	text := `📚 <b>Master English SRS</b>
-
-		Доступные команды:
-
-		/start - Начать работу с ботом
-		/connect_onenote - Подключить OneNote
-		/select_notebook - Выбрать книгу OneNote для синхронизации
-		/select_section - Выбрать секцию OneNote для синхронизации
-
-		/today - Показать страницы на сегодня
-		/pages - Список всех страниц
-		/set_max_pages - Установить максимальное количество страниц в день на повторение
-		/get_max_pages - Показать текущее максимальное количество страниц в день для повторения
-		/prepare_materials - Подгрузить дополнительную страницу на сегодня
-		/set_timezone - Установить таймзону (например, /set_timezone Europe/Moscow)
-
-		/help - Справка`
-
-	h.sendMessage(update.Message.Chat.ID, text)
+func (h *TelegramHandler) handleHelp(_ context.Context, req *request) error {
+	h.sendMessage(req.chatID, req.loc.T("help"))
+	return nil
 }
 
 func (h *TelegramHandler) handleCallback(ctx context.Context, update tgbotapi.Update) {
 	callback := update.CallbackQuery
-	data := callback.Data
-	chatID := callback.Message.Chat.ID
-
-	if strings.HasPrefix(data, "level_") {
-		h.handleLevelSelection(ctx, callback)
-	} else if strings.HasPrefix(data, "notebook_") {
-		h.handleNotebookSelection(ctx, callback)
-	} else if strings.HasPrefix(data, "section_") {
-		h.handleSectionSelection(ctx, callback)
-	} else if strings.HasPrefix(data, "show_") {
-		h.handleShowPage(ctx, callback)
-	} else if strings.HasPrefix(data, "grade_80_100_") {
-		h.handleGradeReview(ctx, callback, 90) // Use 90 as middle value for 80-100 range
-	} else if strings.HasPrefix(data, "grade_60_80_") {
-		h.handleGradeReview(ctx, callback, 70) // Use 70 as middle value for 60-80 range
-	} else if strings.HasPrefix(data, "grade_40_60_") {
-		h.handleGradeReview(ctx, callback, 50) // Use 50 as middle value for 40-60 range
-	} else if strings.HasPrefix(data, "grade_0_40_") {
-		h.handleGradeReview(ctx, callback, 30) // Use 30 as middle value for 0-40 range
-	} else if strings.HasPrefix(data, "success_") {
-		// Legacy support - treat as easy (80+)
-		h.handleGradeReview(ctx, callback, 90)
-	} else if strings.HasPrefix(data, "failure_") {
-		// Legacy support - treat as forgot (<40)
-		h.handleGradeReview(ctx, callback, 30)
-	} else if data == "skip_page" {
-		h.handleSkipPage(ctx, callback)
-	} else if data == "skip_all" {
-		h.handleSkipAll(ctx, callback)
-	} else if data == "start_today_yes" {
-		h.handleStartTodayYes(ctx, callback)
-	} else if data == "start_today_no" {
-		h.handleStartTodayNo(ctx, callback)
-	} else if strings.HasPrefix(data, "timezone_") {
-		h.handleTimezoneSelection(ctx, callback)
-	} else if strings.HasPrefix(data, "max_pages_") {
-		h.handleMaxPagesSelection(ctx, callback)
-	} else {
-		// Неизвестный callback - отправляем уведомление пользователю
-		zap.S().Warn("unknown callback data", zap.String("data", data), zap.Int64("user_id", callback.From.ID))
-		h.sendMessage(chatID, "Неизвестная команда. Используй /help для списка доступных команд.")
+
+	rt, ok := h.router.callback(callback.Data)
+	if !ok {
+		rt = route{name: "unknown_callback", require: requireNone, handle: h.handleUnknownCallback}
 	}
+	h.serve(ctx, rt, &request{userID: callback.From.ID, chatID: callback.Message.Chat.ID, callback: callback, languageCode: callback.From.LanguageCode})
 
 	// Всегда отвечаем на callback, чтобы убрать индикатор загрузки
-	callbackConfig := tgbotapi.NewCallback(callback.ID, "")
-	if _, err := h.api.Request(callbackConfig); err != nil {
+	if err := h.messenger.AnswerCallback(callback.ID, ""); err != nil {
 		zap.S().Error("send callback answer", zap.Error(err), zap.String("callback_id", callback.ID))
 	}
 }
 
-func (h *TelegramHandler) handleLevelSelection(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	userID := callback.From.ID
-	username := callback.From.UserName
-	level := strings.TrimPrefix(callback.Data, "level_")
-	chatID := callback.Message.Chat.ID
+func (h *TelegramHandler) handleLevelSelection(ctx context.Context, req *request) error {
+	userID := req.userID
+	chatID := req.chatID
+	username := req.callback.From.UserName
+	level := strings.TrimPrefix(req.callback.Data, "level_")
 
 	exists, err := h.service.UserExists(ctx, userID)
 	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+		return err
 	}
 
-	if !exists {
-		// Регистрируем нового пользователя с выбранным уровнем
-		if err := h.service.RegisterUser(ctx, userID, username, level); err != nil {
-			zap.S().Error("register user", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("username", username), zap.String("level", level))
-			h.sendMessage(chatID, "Произошла ошибка при регистрации. Попробуй позже.")
-			return
-		}
-		text := fmt.Sprintf("✅ Регистрация завершена! Уровень установлен: %s\n\nВыбери максимальное количество страниц в день для повторения:", level)
-		h.sendMessage(chatID, text)
-		// Показываем выбор max_pages после регистрации
-		h.showMaxPagesSelector(chatID)
-	} else {
+	if exists {
 		// Обновляем уровень существующего пользователя
 		if err := h.service.UpdateUserLevel(ctx, userID, level); err != nil {
-			zap.S().Error("update user level", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("level", level))
-			h.sendMessage(chatID, "Произошла ошибка при обновлении уровня. Попробуй позже.")
-			return
+			if errors.Is(err, service.ErrInvalidSetting) {
+				return withReply(err, req.loc.T("settings.invalid"))
+			}
+			return withReply(fmt.Errorf("update user level %s: %w", level, err), req.loc.T("level.update_failed"))
 		}
-		text := fmt.Sprintf("✅ Уровень обновлён: %s\n\nТеперь подключи OneNote с помощью /connect_onenote", level)
-		h.sendMessage(chatID, text)
-	}
-}
-
-func (h *TelegramHandler) handleNotebookSelection(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	userID := callback.From.ID
-	indexStr := strings.TrimPrefix(callback.Data, "notebook_")
-	chatID := callback.Message.Chat.ID
-
-	// Получаем пользователя, чтобы узнать текущий sectionID (если есть)
-	user, err := h.service.GetUser(ctx, userID)
-	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
-
-	// Проверяем, что пользователь авторизован
-	if user.AccessToken == nil || user.RefreshToken == nil {
-		h.sendMessage(chatID, "Сначала подключи OneNote с помощью команды /connect_onenote")
-		return
+		h.sendMessage(chatID, req.loc.T("level.updated", level))
+		return nil
 	}
 
-	// Получаем список notebooks и находим нужный по индексу
-	notebooks, err := h.service.GetOneNoteNotebooks(ctx, userID)
-	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
+	// Регистрируем нового пользователя с выбранным уровнем на языке, в котором с ним уже говорит бот
+	if err := h.service.RegisterUser(ctx, userID, username, level, req.loc.Language()); err != nil {
+		if errors.Is(err, service.ErrInvalidSetting) {
+			return withReply(err, req.loc.T("settings.invalid"))
 		}
-		zap.S().Error("get notebooks", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Не удалось получить список книг. Попробуй позже.")
-		return
-	}
-
-	index, err := strconv.Atoi(indexStr)
-	if err != nil || index < 0 || index >= len(notebooks) {
-		zap.S().Error("invalid notebook index", zap.String("index", indexStr), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Неверный выбор. Попробуй заново через /select_notebook")
-		return
-	}
-
-	notebookID := notebooks[index].ID
-
-	// Сохраняем только notebookID, sectionID оставляем как есть (или nil, если его нет)
-	sectionID := ""
-	if user.SectionID != nil {
-		sectionID = *user.SectionID
+		return withReply(fmt.Errorf("register user %q with level %s: %w", username, level, err), req.loc.T("register.failed"))
 	}
+	h.sendMessage(chatID, req.loc.T("register.done", level))
 
-	if err := h.service.SaveOneNoteConfig(ctx, userID, notebookID, sectionID); err != nil {
-		zap.S().Error("save notebook config", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("notebook_id", notebookID))
-		h.sendMessage(chatID, "Не удалось сохранить выбранную книгу. Попробуй позже.")
-		return
+	advanced, err := h.advanceOnboarding(ctx, req.loc, chatID, userID, models.ChatStateOnboardingLevel)
+	if err != nil || advanced {
+		return err
 	}
 
-	text := "✅ Книга OneNote выбрана!\n\nТеперь выбери секцию с помощью команды /select_section"
-	h.sendMessage(chatID, text)
+	// Кнопка уровня из сообщения, отправленного до появления состояний, — продолжаем регистрацию со следующего шага
+	h.setChatState(ctx, chatID, userID, models.ChatStateOnboardingMaxPages, nil)
+	return h.promptOnboarding(ctx, req.loc, chatID, userID, models.ChatStateOnboardingMaxPages)
 }
 
-func (h *TelegramHandler) handleSectionSelection(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	userID := callback.From.ID
-	indexStr := strings.TrimPrefix(callback.Data, "section_")
-	chatID := callback.Message.Chat.ID
-
-	// Получаем пользователя, чтобы узнать текущий notebookID
-	user, err := h.service.GetUser(ctx, userID)
-	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
-
-	// Проверяем, что выбран notebook
-	if user.NotebookID == nil || *user.NotebookID == "" {
-		h.sendMessage(chatID, "Сначала выбери книгу OneNote с помощью команды /select_notebook")
-		return
-	}
+// handleTokenCallback выполняет действие кнопки по её токену
+func (h *TelegramHandler) handleTokenCallback(ctx context.Context, req *request) error {
+	userID := req.userID
+	chatID := req.chatID
 
-	// Проверяем, что пользователь авторизован
-	if user.AccessToken == nil || user.RefreshToken == nil {
-		h.sendMessage(chatID, "Сначала подключи OneNote с помощью команды /connect_onenote")
-		return
+	tokenID, ok := callbackTokenFromData(req.callback.Data)
+	if !ok {
+		return reply(req.loc.T("button.stale"))
 	}
 
-	// Получаем список sections и находим нужную по индексу
-	sections, err := h.service.GetOneNoteSections(ctx, userID, *user.NotebookID)
+	token, err := h.service.GetCallbackToken(ctx, userID, tokenID)
 	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
-		}
-		zap.S().Error("get sections", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Не удалось получить список секций. Попробуй позже.")
-		return
+		return withReply(fmt.Errorf("get callback token: %w", err), req.loc.T("error.short"))
+	}
+	if token == nil {
+		return reply(req.loc.T("button.stale"))
+	}
+
+	switch token.Action {
+	case models.CallbackActionShowPage:
+		return h.handleShowPage(ctx, req, token.PageID)
+	case models.CallbackActionHistory:
+		return h.handlePageHistory(ctx, req, token.PageID)
+	case models.CallbackActionGrade:
+		h.leaveAwaitingAnswer(ctx, chatID, userID, token.PageID)
+		// Callback data кнопки служит ключом идемпотентности: повторное нажатие той же кнопки сервис распознает как повтор
+		// Кнопки оценки выдаются вместе с показом страницы, поэтому время выдачи токена — время показа
+		return h.updateReviewProgress(ctx, req.loc, userID, chatID, models.ReviewSubmission{
+			PageID:  token.PageID,
+			Grade:   token.Grade,
+			Version: token.Version,
+			Key:     req.callback.Data,
+			Source:  models.ReviewSourceButton,
+			ShownAt: &token.IssuedAt,
+		})
+	}
+
+	// Пропуск и отмена одноразовые: повторное нажатие не должно выполнить их второй раз
+	token, err = h.service.ConsumeCallbackToken(ctx, userID, tokenID)
+	if err != nil {
+		return withReply(fmt.Errorf("consume callback token: %w", err), req.loc.T("error.short"))
 	}
-
-	index, err := strconv.Atoi(indexStr)
-	if err != nil || index < 0 || index >= len(sections) {
-		zap.S().Error("invalid section index", zap.String("index", indexStr), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Неверный выбор. Попробуй заново через /select_section")
-		return
+	if token == nil {
+		return reply(req.loc.T("button.stale"))
 	}
 
-	sectionID := sections[index].ID
+	h.leaveAwaitingAnswer(ctx, chatID, userID, token.PageID)
 
-	if err := h.service.SaveOneNoteConfig(ctx, userID, *user.NotebookID, sectionID); err != nil {
-		zap.S().Error("save section config", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("section_id", sectionID))
-		h.sendMessage(chatID, "Не удалось сохранить выбранную секцию. Попробуй позже.")
-		return
+	switch token.Action {
+	case models.CallbackActionSkipPage:
+		return h.handleSkipPage(ctx, req, token.PageID)
+	case models.CallbackActionUndo:
+		return h.handleUndoGrade(ctx, req, token.PageID, token.ReviewKey)
+	default:
+		zap.S().Warn("unknown callback token action", zap.String("action", token.Action), zap.Int64("telegram_id", userID))
+		return reply(req.loc.T("button.stale"))
 	}
+}
 
-	text := "✅ Секция OneNote выбрана!\n\nТеперь OneNote настроен.\n\nХочешь начать повторять уже сегодня?"
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Да", "start_today_yes"),
-			tgbotapi.NewInlineKeyboardButtonData("Нет", "start_today_no"),
-		),
-	)
-	h.sendMessageWithKeyboard(chatID, text, keyboard)
+// leaveAwaitingAnswer сбрасывает ожидание набранного результата, если оно относится к странице pageID
+func (h *TelegramHandler) leaveAwaitingAnswer(ctx context.Context, chatID, userID int64, pageID string) {
+	if state := h.chatState(ctx, chatID); state.State == models.ChatStateAwaitingAnswer && state.Data[chatStateKeyPageID] == pageID {
+		h.setChatState(ctx, chatID, userID, models.ChatStateIdle, nil)
+	}
 }
 
-func (h *TelegramHandler) handleShowPage(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	indexStr := strings.TrimPrefix(callback.Data, "show_")
-	userID := callback.From.ID
-	chatID := callback.Message.Chat.ID
+func (h *TelegramHandler) handleShowPage(ctx context.Context, req *request, pageID string) error {
+	userID := req.userID
+	chatID := req.chatID
 
-	// Получаем список страниц для повторения и находим нужную по индексу
+	// Проверяем, что страница всё ещё в списке на сегодня, и берём её прогресс
 	duePages, err := h.service.GetDuePagesToday(ctx, userID)
 	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
-		}
-		zap.S().Error("get due pages today", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Не удалось получить список страниц. Попробуй заново через /today")
-		return
+		return withReply(fmt.Errorf("get due pages today: %w", err), req.loc.T("page.list_failed"))
 	}
 
-	index, err := strconv.Atoi(indexStr)
-	if err != nil || index < 0 || index >= len(duePages) {
-		zap.S().Error("invalid page index", zap.String("index", indexStr), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Неверный выбор. Попробуй заново через /today")
-		return
+	var due *models.PageWithProgress
+	for _, pwp := range duePages {
+		if pwp.Page.PageID == pageID {
+			due = pwp
+			break
+		}
 	}
 
-	pageID := duePages[index].Page.PageID
+	if due == nil {
+		return reply(req.loc.T("page.not_due"))
+	}
 
 	content, err := h.service.GetPageContent(ctx, userID, pageID)
 	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
-		}
-		zap.S().Error("get page content", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("page_id", pageID))
-		h.sendMessage(chatID, "Не удалось получить содержимое страницы.")
-		return
+		return withReply(fmt.Errorf("get page content %s: %w", pageID, err), req.loc.T("page.content_failed"))
 	}
 
-	// Экранируем содержимое страницы для безопасной вставки в HTML
-	escapedContent := escapeHTML(content)
-	text := fmt.Sprintf("📄 <b>Страница</b>\n\n━━━━━━━━━━━━━━━━━━━━━━\n\n[TOPIC:%s;LEVEL:B1;MODE:STANDART]\n\n%s\n\n━━━━━━━━━━━━━━━━━━━━━━\n\n", duePages[index].Page.Title, escapedContent)
+	// Содержимое страницы уже отформатировано в Telegram HTML и экранировано при рендеринге
+	text := fmt.Sprintf("%s\n\n━━━━━━━━━━━━━━━━━━━━━━\n\n[TOPIC:%s;LEVEL:B1;MODE:STANDART]\n\n%s\n\n━━━━━━━━━━━━━━━━━━━━━━\n\n", req.loc.T("page.header"), escapeHTML(due.Page.Title), content)
 
 	// Проверяем режим: чтение (IntervalDays == 0) или AI (IntervalDays >= 1)
-	isReadingMode := duePages[index].Progress.IntervalDays == 0
+	isReadingMode := due.Progress.IntervalDays == 0
 	if isReadingMode {
-		text += "📖 Прочитай слова и оцени насколько хорошо их помнишь:"
+		text += req.loc.T("page.reading_prompt")
 	} else {
-		text += "💡 Скопируй эту страницу и отправь в бота Poe для генерации задания.\n\n"
-		text += "После прохождения задания отметь результат:"
+		text += req.loc.T("page.practice_prompt")
 	}
 
-	// Передаём индекс страницы в кнопки оценки
-	callbackData1 := fmt.Sprintf("grade_80_100_%d", index)
-	callbackData2 := fmt.Sprintf("grade_60_80_%d", index)
-	callbackData3 := fmt.Sprintf("grade_40_60_%d", index)
-	callbackData4 := fmt.Sprintf("grade_0_40_%d", index)
+	// Кнопки несут версию прогресса: если страницу успеют оценить в другом сообщении, эти кнопки не сработают
+	version := due.Progress.Version
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Easy (>80%)", callbackData1),
-			tgbotapi.NewInlineKeyboardButtonData("🟢 Normal (>60%)", callbackData2),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🟡 Hard (>40%)", callbackData3),
-			tgbotapi.NewInlineKeyboardButtonData("🔴 Forgot (<40%)", callbackData4),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("↩️ Пропустить", "skip_page"),
-		),
-	)
-
-	h.sendMessageWithKeyboard(callback.Message.Chat.ID, text, keyboard)
-}
-
-func (h *TelegramHandler) handleGradeReview(ctx context.Context, callback *tgbotapi.CallbackQuery, grade int) {
-	// Extract page index from callback data
-	data := callback.Data
-	userID := callback.From.ID
-	chatID := callback.Message.Chat.ID
-
-	var indexStr string
-	if strings.HasPrefix(data, "grade_80_100_") {
-		indexStr = strings.TrimPrefix(data, "grade_80_100_")
-	} else if strings.HasPrefix(data, "grade_60_80_") {
-		indexStr = strings.TrimPrefix(data, "grade_60_80_")
-	} else if strings.HasPrefix(data, "grade_40_60_") {
-		indexStr = strings.TrimPrefix(data, "grade_40_60_")
-	} else if strings.HasPrefix(data, "grade_0_40_") {
-		indexStr = strings.TrimPrefix(data, "grade_0_40_")
-	} else if strings.HasPrefix(data, "success_") || strings.HasPrefix(data, "failure_") {
-		// Legacy support - старые callback могут содержать обрезанный pageID
-		// Пытаемся восстановить по префиксу (для обратной совместимости)
-		pageIDPrefix := ""
-		if strings.HasPrefix(data, "success_") {
-			pageIDPrefix = strings.TrimPrefix(data, "success_")
-			grade = 90
-		} else {
-			pageIDPrefix = strings.TrimPrefix(data, "failure_")
-			grade = 30
-		}
-
-		// Восстанавливаем pageID из списка страниц по префиксу
-		duePages, err := h.service.GetDuePagesToday(ctx, userID)
-		if err != nil {
-			if h.handleAuthError(err, userID, chatID) {
-				return
-			}
-			zap.S().Error("get due pages today for legacy callback", zap.Error(err), zap.Int64("telegram_id", userID))
-			h.sendMessage(chatID, "Не удалось найти страницу. Попробуй заново через /today")
-			return
-		}
-
-		var pageID string
-		found := false
-		for _, pwp := range duePages {
-			if strings.HasPrefix(pwp.Page.PageID, pageIDPrefix) {
-				pageID = pwp.Page.PageID
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			zap.S().Warn("page ID not found for legacy callback prefix", zap.String("prefix", pageIDPrefix), zap.Int64("telegram_id", userID))
-			h.sendMessage(chatID, "Не удалось найти страницу. Попробуй заново через /today")
-			return
-		}
-
-		h.updateReviewProgress(ctx, userID, chatID, pageID, grade)
-		return
-	} else {
-		zap.S().Warn("unknown grade callback format", zap.String("data", data))
-		return
+	callbacks := &callbackBatch{}
+	grades := make([]tgbotapi.InlineKeyboardButton, 0, len(gradeButtons))
+	for _, button := range gradeButtons {
+		grades = append(grades, tgbotapi.NewInlineKeyboardButtonData(req.loc.T(button.key), callbacks.issueGrade(pageID, button.grade, version)))
 	}
+	rows := buttonRows(grades, 2)
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(req.loc.T("page.skip"), callbacks.issue(models.CallbackActionSkipPage, pageID)),
+		tgbotapi.NewInlineKeyboardButtonData(req.loc.T("history.button"), callbacks.issue(models.CallbackActionHistory, pageID)),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
-	// Получаем список страниц для повторения и находим нужную по индексу
-	duePages, err := h.service.GetDuePagesToday(ctx, userID)
-	if err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
-		}
-		zap.S().Error("get due pages today", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Не удалось получить список страниц. Попробуй заново через /today")
-		return
+	if err := h.saveCallbacks(ctx, userID, callbacks); err != nil {
+		return withReply(fmt.Errorf("save callback tokens: %w", err), req.loc.T("page.content_failed"))
 	}
 
-	index, err := strconv.Atoi(indexStr)
-	if err != nil || index < 0 || index >= len(duePages) {
-		zap.S().Error("invalid page index", zap.String("index", indexStr), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Неверный выбор. Попробуй заново через /today")
-		return
+	// Результат можно не только выбрать кнопкой, но и отправить текстом
+	h.setChatState(ctx, chatID, userID, models.ChatStateAwaitingAnswer, map[string]string{
+		chatStateKeyPageID:  pageID,
+		chatStateKeyVersion: strconv.Itoa(version),
+		chatStateKeyShownAt: h.clock.Now().Format(time.RFC3339Nano),
+	})
+
+	if header := h.sessionPageHeader(ctx, req.loc, userID, pageID); header != "" {
+		text = header + "\n" + text
 	}
 
-	pageID := duePages[index].Page.PageID
-	h.updateReviewProgress(ctx, userID, chatID, pageID, grade)
+	h.sendLongMessageWithKeyboard(chatID, text, keyboard)
+	return nil
 }
 
-func (h *TelegramHandler) updateReviewProgress(ctx context.Context, userID int64, chatID int64, pageID string, grade int) {
-	if err := h.service.UpdateReviewProgress(ctx, userID, pageID, grade); err != nil {
-		zap.S().Error("update review progress", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("page_id", pageID), zap.Int("grade", grade))
-		h.sendMessage(chatID, "Ошибка при обновлении прогресса.")
-		return
-	}
+func (h *TelegramHandler) updateReviewProgress(ctx context.Context, loc i18n.Localizer, userID int64, chatID int64, submission models.ReviewSubmission) error {
+	pageID := submission.PageID
+	grade := submission.Grade
 
-	progress, _ := h.service.GetProgress(ctx, userID, pageID)
-
-	var statusText string
+	err := h.service.UpdateReviewProgress(ctx, userID, submission)
 	switch {
-	case grade > 80:
-		statusText = fmt.Sprintf("✅ Easy! Следующее повторение через %d дней.", progress.IntervalDays)
-	case grade > 60:
-		statusText = fmt.Sprintf("🟢 Normal! Следующее повторение через %d дней.", progress.IntervalDays)
-	case grade > 40:
-		statusText = fmt.Sprintf("🟡 Hard! Следующее повторение через %d дней.", progress.IntervalDays)
-	default:
-		statusText = "🔴 Forgot! Повторим завтра."
+	case errors.Is(err, service.ErrDuplicateReview):
+		return reply(loc.T("review.duplicate"))
+	case errors.Is(err, service.ErrReviewConflict):
+		return reply(loc.T("review.conflict"))
+	case err != nil:
+		return withReply(fmt.Errorf("update review progress %s (grade %d): %w", pageID, grade, err), loc.T("review.failed"))
+	}
+
+	statusText := loc.T("review.forgot")
+	if grade > 40 {
+		// Оценка уже сохранена, поэтому без прогресса ответ показывается без интервала до следующего повторения
+		progress, err := h.service.GetProgress(ctx, userID, pageID)
+		switch {
+		case err != nil:
+			zap.S().Error("get progress after review", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("page_id", pageID))
+			statusText = loc.T("review.saved")
+		case grade > 80:
+			statusText = loc.N("review.easy", progress.IntervalDays, progress.IntervalDays)
+		case grade > 60:
+			statusText = loc.N("review.normal", progress.IntervalDays, progress.IntervalDays)
+		default:
+			statusText = loc.N("review.hard", progress.IntervalDays, progress.IntervalDays)
+		}
 	}
 
-	h.sendMessage(chatID, statusText)
+	// Ошибочную оценку можно отменить в течение service.UndoWindow; кнопка отменяет только эту оценку.
+	// Оценка уже сохранена, поэтому без сохранённого токена результат показывается без кнопки отмены
+	callbacks := &callbackBatch{}
+	undoRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(loc.T("undo.button"), callbacks.issueUndo(pageID, submission.Key)),
+	)
+	if err := h.saveCallbacks(ctx, userID, callbacks); err != nil {
+		zap.S().Error("save undo button", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("page_id", pageID))
+		h.sendSessionStep(ctx, loc, userID, chatID, pageID, statusText)
+		return nil
+	}
+	h.sendSessionStep(ctx, loc, userID, chatID, pageID, statusText, undoRow)
+	return nil
 }
 
-func (h *TelegramHandler) handleSkipPage(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-
-	userID := callback.From.ID
-	pageID := strings.TrimPrefix(callback.Data, "skip_page_")
-
-	if err := h.service.SkipPage(ctx, userID, pageID); err != nil {
-		zap.S().Error("skip page", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("page_id", pageID))
-		h.sendMessage(callback.Message.Chat.ID, "Не удалось пропустить страницу. Попробуй позже.")
-		return
+func (h *TelegramHandler) handleSkipPage(ctx context.Context, req *request, pageID string) error {
+	if err := h.service.SkipPage(ctx, req.userID, pageID); err != nil {
+		return withReply(fmt.Errorf("skip page %s: %w", pageID, err), req.loc.T("skip.failed"))
 	}
 
-	h.sendMessage(callback.Message.Chat.ID, "Хорошо, пропустим её на сегодня")
+	h.sendSessionStep(ctx, req.loc, req.userID, req.chatID, pageID, req.loc.T("skip.done"))
+	return nil
 }
 
-func (h *TelegramHandler) handleSkipAll(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	h.sendMessage(callback.Message.Chat.ID, "Хорошо, пропускаем на сегодня. Увидимся завтра! 👋")
+// handleUndoGrade отменяет оценку страницы с ключом reviewKey, если она последняя, и заменяет сообщение с результатом кнопкой, открывающей страницу заново
+func (h *TelegramHandler) handleUndoGrade(ctx context.Context, req *request, pageID, reviewKey string) error {
+	err := h.service.UndoLastGrade(ctx, req.userID, pageID, reviewKey)
+	switch {
+	case errors.Is(err, service.ErrNothingToUndo):
+		return reply(req.loc.T("undo.nothing"))
+	case errors.Is(err, service.ErrReviewConflict):
+		return reply(req.loc.T("review.conflict"))
+	case errors.Is(err, service.ErrUndoExpired):
+		minutes := int(service.UndoWindow.Minutes())
+		return reply(req.loc.N("undo.expired", minutes, minutes))
+	case err != nil:
+		return withReply(fmt.Errorf("undo last grade %s: %w", pageID, err), req.loc.T("undo.failed"))
+	}
+
+	callbacks := &callbackBatch{}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(req.loc.T("undo.regrade"), callbacks.issue(models.CallbackActionShowPage, pageID)),
+		),
+	)
+	// Оценка уже отменена, поэтому без сохранённого токена сообщение просто остаётся без кнопки
+	if err := h.saveCallbacks(ctx, req.userID, callbacks); err != nil {
+		zap.S().Error("save regrade button", zap.Error(err), zap.Int64("telegram_id", req.userID), zap.String("page_id", pageID))
+		h.editMessage(req.chatID, req.callback.Message.MessageID, req.loc.T("undo.done"), nil)
+		return nil
+	}
+	h.editMessage(req.chatID, req.callback.Message.MessageID, req.loc.T("undo.done"), &keyboard)
+	return nil
 }
 
-func (h *TelegramHandler) handleStartTodayYes(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	userID := callback.From.ID
-	chatID := callback.Message.Chat.ID
-
-	exists, err := h.service.UserExists(ctx, userID)
-	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
-
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
-	}
-
-	user, err := h.service.GetUser(ctx, userID)
-	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
-
-	if user.AccessToken == nil || user.RefreshToken == nil {
-		h.sendMessage(chatID, "Сначала подключи OneNote с помощью команды /connect_onenote")
-		return
-	}
-
-	if user.NotebookID == nil || *user.NotebookID == "" {
-		h.sendMessage(chatID, "Сначала выбери книгу OneNote с помощью команды /select_notebook")
-		return
-	}
+func (h *TelegramHandler) handleSkipAll(_ context.Context, req *request) error {
+	h.sendMessage(req.chatID, req.loc.T("skip_all.done"))
+	return nil
+}
 
-	h.sendMessage(chatID, "Подготавливаю материалы...")
+func (h *TelegramHandler) handleStartTodayYes(ctx context.Context, req *request) error {
+	h.sendMessage(req.chatID, req.loc.T("prepare.in_progress"))
 
-	if err := h.service.PrepareMaterials(ctx, userID); err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
-		}
-		zap.S().Error("prepare materials", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Не удалось подготовить материалы. Попробуй позже.")
-		return
+	if err := h.service.PrepareMaterials(ctx, req.userID); err != nil {
+		return withReply(fmt.Errorf("prepare materials: %w", err), req.loc.T("prepare.failed"))
 	}
 
-	h.sendMessage(chatID, "✅ Материалы успешно подготовлены! Используй /today для начала занятий.")
+	h.sendMessage(req.chatID, req.loc.T("prepare.done_today"))
+	return nil
 }
 
-func (h *TelegramHandler) handleStartTodayNo(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	h.sendMessage(callback.Message.Chat.ID, "Хорошо, используй /today когда будешь готов начать занятия.")
+func (h *TelegramHandler) handleStartTodayNo(_ context.Context, req *request) error {
+	h.sendMessage(req.chatID, req.loc.T("prepare.later"))
+	return nil
 }
 
-func (h *TelegramHandler) handleMaxPagesSelection(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	userID := callback.From.ID
-	maxPagesStr := strings.TrimPrefix(callback.Data, "max_pages_")
-	chatID := callback.Message.Chat.ID
+func (h *TelegramHandler) handleMaxPagesSelection(ctx context.Context, req *request) error {
+	userID := req.userID
+	chatID := req.chatID
+	maxPagesStr := strings.TrimPrefix(req.callback.Data, "max_pages_")
 
-	maxPagesInt, err := strconv.Atoi(maxPagesStr)
-	if err != nil || maxPagesInt < 2 || maxPagesInt > 4 {
-		zap.S().Error("invalid max pages value", zap.String("max_pages", maxPagesStr), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "❌ Некорректное значение. Попробуй ещё раз.")
-		return
+	maxPages, err := strconv.ParseUint(maxPagesStr, 10, 0)
+	if err != nil {
+		return withReply(fmt.Errorf("invalid max pages value %q", maxPagesStr), req.loc.T("max_pages.invalid_button"))
 	}
 
-	maxPages := uint(maxPagesInt)
-	if err := h.service.UpdateMaxPagesPerDay(ctx, userID, maxPages); err != nil {
-		zap.S().Error("update max pages per day", zap.Error(err), zap.Int64("telegram_id", userID), zap.Uint("max_pages", maxPages))
-		h.sendMessage(chatID, "Ошибка при обновлении настроек. Попробуй позже.")
-		return
+	if err := h.service.UpdateMaxPagesPerDay(ctx, userID, uint(maxPages)); err != nil {
+		if errors.Is(err, service.ErrInvalidSetting) {
+			return withReply(err, req.loc.T("max_pages.invalid_button"))
+		}
+		return withReply(fmt.Errorf("update max pages per day to %d: %w", maxPages, err), req.loc.T("settings.update_failed"))
 	}
 
-	text := fmt.Sprintf("✅ Максимальное количество страниц в день установлено: %d\n\nТеперь выбери свой город для установки таймзоны:", maxPages)
-	h.sendMessage(chatID, text)
-	// Показываем выбор таймзоны после установки max_pages
-	h.showTimezoneSelector(chatID)
+	h.sendMessage(chatID, req.loc.T("max_pages.set", maxPages))
+	_, err = h.advanceOnboarding(ctx, req.loc, chatID, userID, models.ChatStateOnboardingMaxPages)
+	return err
 }
 
-func (h *TelegramHandler) handleTimezoneSelection(ctx context.Context, callback *tgbotapi.CallbackQuery) {
-	userID := callback.From.ID
-	timezoneStr := strings.TrimPrefix(callback.Data, "timezone_")
-	chatID := callback.Message.Chat.ID
-
-	// Validate timezone by trying to load it
-	_, err := time.LoadLocation(timezoneStr)
-	if err != nil {
-		zap.S().Error("invalid timezone", zap.Error(err), zap.String("timezone", timezoneStr), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, fmt.Sprintf("❌ Некорректная таймзона: %s", timezoneStr))
-		return
-	}
+func (h *TelegramHandler) handleTimezoneSelection(ctx context.Context, req *request) error {
+	userID := req.userID
+	chatID := req.chatID
+	timezoneStr := strings.TrimPrefix(req.callback.Data, "timezone_")
 
 	if err := h.service.UpdateUserTimezone(ctx, userID, timezoneStr); err != nil {
-		zap.S().Error("update user timezone", zap.Error(err), zap.Int64("telegram_id", userID), zap.String("timezone", timezoneStr))
-		h.sendMessage(chatID, "Ошибка при обновлении таймзоны. Попробуй позже.")
-		return
-	}
-
-	// Получаем информацию о пользователе, чтобы понять, был ли это этап регистрации
-	user, err := h.service.GetUser(ctx, userID)
-	isNewUser := false
-	if err == nil && user != nil {
-		// Проверяем, есть ли у пользователя настройки OneNote
-		// Если нет, значит это этап регистрации
-		if user.NotebookID == nil || *user.NotebookID == "" {
-			isNewUser = true
+		if errors.Is(err, service.ErrInvalidSetting) {
+			return withReply(err, req.loc.T("timezone.invalid", timezoneStr))
 		}
+		return withReply(fmt.Errorf("update user timezone to %s: %w", timezoneStr, err), req.loc.T("timezone.update_failed"))
 	}
 
-	if isNewUser {
-		h.sendMessage(chatID, fmt.Sprintf("✅ Таймзона установлена: %s\n\nТеперь подключи OneNote с помощью /connect_onenote", timezoneStr))
-	} else {
-		h.sendMessage(chatID, fmt.Sprintf("✅ Таймзона установлена: %s\n\nНовые материалы будут добавляться автоматически в 00:00 каждый день по твоему местному времени.", timezoneStr))
+	if h.chatState(ctx, chatID).State == models.ChatStateOnboardingTimezone {
+		h.sendMessage(chatID, req.loc.T("timezone.set", timezoneStr))
+		_, err := h.advanceOnboarding(ctx, req.loc, chatID, userID, models.ChatStateOnboardingTimezone)
+		return err
 	}
+
+	h.sendMessage(chatID, req.loc.T("timezone.set_details", timezoneStr))
+	return nil
 }
 
 // getIntervalStepInfo возвращает отображаемый интервал (в днях), номер шага и общее количество шагов SRS
@@ -1149,221 +1084,165 @@ func getIntervalStepInfo(intervalDays int) (displayIntervalDays int, stepNumber
 	return intervalDays, 1, totalSteps
 }
 
-// formatDaysRu подбирает правильное склонение слова "день" в русском языке
-func formatDaysRu(n int) string {
-	nAbs := n % 100
-	if nAbs >= 11 && nAbs <= 14 {
-		return "дней"
-	}
-
-	last := n % 10
-	switch last {
-	case 1:
-		return "день"
-	case 2, 3, 4:
-		return "дня"
-	default:
-		return "дней"
-	}
+// formatIntervalProgress формирует строку прогресса страницы: интервал и номер шага SRS
+func formatIntervalProgress(loc i18n.Localizer, intervalDays int) string {
+	displayIntervalDays, stepNumber, totalSteps := getIntervalStepInfo(intervalDays)
+	interval := loc.N("interval.days", displayIntervalDays, displayIntervalDays)
+	return loc.T("progress.interval", interval, stepNumber, totalSteps)
 }
 
-func (h *TelegramHandler) handleSetMaxPages(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
-
-	exists, err := h.service.UserExists(ctx, userID)
-	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
-
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
-	}
-
+func (h *TelegramHandler) handleSetMaxPages(ctx context.Context, req *request) error {
 	// Parse number from message text after command
-	parts := strings.Fields(update.Message.Text)
+	parts := strings.Fields(req.message.Text)
 	if len(parts) < 2 {
-		h.sendMessage(chatID, "Использование: /set_max_pages <b>число</b>\n\nНапример: /set_max_pages 3\n\nРекомендуемые значения: 2, 3 или 4")
-		return
-	}
-
-	maxPagesInt, err := strconv.Atoi(parts[1])
-	if err != nil || maxPagesInt < 2 || maxPagesInt > 4 {
-		h.sendMessage(chatID, "Некорректное значение. Используй число от 2 до 4.\n\n2 страницы в день → добавляется 1 страница\n3 страницы в день → добавляется 1 (60%) или 2 (40%)\n4 страницы в день → добавляется 2 страницы")
-		return
-	}
-
-	maxPages := uint(maxPagesInt)
-	if err := h.service.UpdateMaxPagesPerDay(ctx, userID, maxPages); err != nil {
-		zap.S().Error("update max pages per day", zap.Error(err), zap.Int64("telegram_id", userID), zap.Uint("max_pages", maxPages))
-		h.sendMessage(chatID, "Ошибка при обновлении настроек. Попробуй позже.")
-		return
+		return reply(req.loc.T("max_pages.usage"))
 	}
 
-	h.sendMessage(chatID, fmt.Sprintf("✅ Максимальное количество страниц в день установлено: %d", maxPages))
-}
-
-func (h *TelegramHandler) handleGetMaxPages(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
-
-	exists, err := h.service.UserExists(ctx, userID)
+	maxPages, err := strconv.ParseUint(parts[1], 10, 0)
 	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+		return reply(req.loc.T("max_pages.invalid"))
 	}
 
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
+	if err := h.service.UpdateMaxPagesPerDay(ctx, req.userID, uint(maxPages)); err != nil {
+		if errors.Is(err, service.ErrInvalidSetting) {
+			return reply(req.loc.T("max_pages.invalid"))
+		}
+		return withReply(fmt.Errorf("update max pages per day to %d: %w", maxPages, err), req.loc.T("settings.update_failed"))
 	}
 
-	user, err := h.service.GetUser(ctx, userID)
-	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
+	h.sendMessage(req.chatID, req.loc.T("max_pages.set", maxPages))
+	return nil
+}
 
+func (h *TelegramHandler) handleGetMaxPages(_ context.Context, req *request) error {
 	maxPages := uint(2) // default
-	if user.MaxPagesPerDay != nil {
-		maxPages = *user.MaxPagesPerDay
+	if req.user.MaxPagesPerDay != nil {
+		maxPages = *req.user.MaxPagesPerDay
 	}
 
-	h.sendMessage(chatID, fmt.Sprintf("📊 Текущее максимальное количество страниц в день: %d", maxPages))
+	h.sendMessage(req.chatID, req.loc.T("max_pages.current", maxPages))
+	return nil
 }
 
-func (h *TelegramHandler) handlePrepareMaterials(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
+func (h *TelegramHandler) handlePrepareMaterials(ctx context.Context, req *request) error {
+	h.sendMessage(req.chatID, req.loc.T("prepare.warning"))
 
-	exists, err := h.service.UserExists(ctx, userID)
-	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+	if err := h.service.PrepareMaterials(ctx, req.userID); err != nil {
+		return withReply(fmt.Errorf("prepare materials: %w", err), req.loc.T("prepare.failed"))
 	}
 
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
-	}
+	h.sendMessage(req.chatID, req.loc.T("prepare.done"))
+	return nil
+}
 
-	user, err := h.service.GetUser(ctx, userID)
+// syncReportTitlesLimit ограничивает число заголовков в каждой группе отчёта синхронизации
+const syncReportTitlesLimit = 10
+
+func (h *TelegramHandler) handleSync(ctx context.Context, req *request) error {
+	report, err := h.service.SyncPages(ctx, req.userID)
 	if err != nil {
-		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
+		return withReply(fmt.Errorf("sync pages: %w", err), req.loc.T("sync.failed"))
 	}
 
-	if user.AccessToken == nil || user.RefreshToken == nil {
-		h.sendMessage(chatID, "Сначала подключи OneNote с помощью команды /connect_onenote")
-		return
-	}
+	h.sendLongMessageWithKeyboard(req.chatID, formatSyncReport(req.loc, report), nil)
+	return nil
+}
 
-	if user.NotebookID == nil || *user.NotebookID == "" {
-		h.sendMessage(chatID, "Сначала выбери книгу OneNote с помощью команды /select_notebook")
-		return
+// formatSyncReport формирует текст отчёта синхронизации с количеством и первыми заголовками каждой группы
+func formatSyncReport(loc i18n.Localizer, report *models.SyncReport) string {
+	groups := []struct {
+		title  string
+		titles []string
+	}{
+		{loc.T("sync.added"), report.Added},
+		{loc.T("sync.changed"), report.Changed},
+		{loc.T("sync.moved"), report.Moved},
+		{loc.T("sync.removed"), report.Removed},
 	}
 
-	warningMsg := "⚠️ Внимание! Эта команда добавляет материалы для повторения.\n" +
-		"Не рекомендуется использовать её часто, иначе материалы будут накапливаться и в будущем придётся повторять слишком много за один день.\n\n" +
-		"Обычно материалы подготавливаются автоматически в 00:00 каждый день.\n\n" +
-		"Подготавливаю материалы..."
+	var b strings.Builder
+	b.WriteString(loc.T("sync.title") + "\n")
 
-	h.sendMessage(chatID, warningMsg)
+	empty := true
+	for _, group := range groups {
+		if len(group.titles) == 0 {
+			continue
+		}
+		empty = false
 
-	if err := h.service.PrepareMaterials(ctx, userID); err != nil {
-		if h.handleAuthError(err, userID, chatID) {
-			return
+		b.WriteString(fmt.Sprintf("\n<b>%s: %d</b>\n", group.title, len(group.titles)))
+		for i, title := range group.titles {
+			if i == syncReportTitlesLimit {
+				b.WriteString(loc.T("sync.more", len(group.titles)-syncReportTitlesLimit) + "\n")
+				break
+			}
+			b.WriteString("• " + escapeHTML(title) + "\n")
 		}
-		zap.S().Error("prepare materials", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Не удалось подготовить материалы. Попробуй позже.")
-		return
 	}
 
-	h.sendMessage(chatID, "✅ Материалы успешно подготовлены!")
+	if empty {
+		b.WriteString("\n" + loc.T("sync.no_changes"))
+	}
+
+	return strings.TrimRight(b.String(), "\n")
 }
 
 // showMaxPagesSelector показывает кнопки для выбора максимального количества страниц в день
-func (h *TelegramHandler) showMaxPagesSelector(chatID int64) {
-	text := "📊 Выбери максимальное количество страниц в день:\n\n2 страницы в день → добавляется 1 страница\n3 страницы в день → добавляется 1 (60%) или 2 (40%)\n4 страницы в день → добавляется 2 страницы"
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("2", "max_pages_2"),
-			tgbotapi.NewInlineKeyboardButtonData("3", "max_pages_3"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("4", "max_pages_4"),
-		),
-	)
-
-	h.sendMessageWithKeyboard(chatID, text, keyboard)
+func (h *TelegramHandler) showMaxPagesSelector(loc i18n.Localizer, chatID int64) {
+	h.sendMessageWithKeyboard(chatID, loc.T("max_pages.choose"), tgbotapi.NewInlineKeyboardMarkup(maxPagesButtons("max_pages_")...))
 }
 
-// showTimezoneSelector показывает кнопки с популярными городами для выбора таймзоны
-func (h *TelegramHandler) showTimezoneSelector(chatID int64) {
-	text := "🌍 Выбери свой город для установки таймзоны:"
-
-	// Популярные города с их таймзонами
-	cities := []struct {
-		name     string
-		timezone string
-		offset   int // Смещение относительно UTC в часах
-	}{
-		{"Москва", "Europe/Moscow", 3},
-		{"Санкт-Петербург", "Europe/Moscow", 3},
-		{"Киев", "Europe/Kyiv", 2},
-		{"Минск", "Europe/Minsk", 3},
-		{"Лондон", "Europe/London", 0},
-		{"Париж", "Europe/Paris", 1},
-		{"Берлин", "Europe/Berlin", 1},
-		{"Рим", "Europe/Rome", 1},
-		{"Нью-Йорк", "America/New_York", -5},
-		{"Лос-Анджелес", "America/Los_Angeles", -8},
-		{"Чикаго", "America/Chicago", -6},
-		{"Торонто", "America/Toronto", -5},
-		{"Токио", "Asia/Tokyo", 9},
-		{"Пекин", "Asia/Shanghai", 8},
-		{"Дубай", "Asia/Dubai", 4},
-		{"Тегеран", "Asia/Tehran", 3},
-		{"Дели", "Asia/Kolkata", 5},
-		{"Сидней", "Australia/Sydney", 10},
-		{"Сан-Паулу", "America/Sao_Paulo", -3},
-		{"Буэнос-Айрес", "America/Argentina/Buenos_Aires", -3},
-		{"Каир", "Africa/Cairo", 2},
+// maxPagesButtons возвращает кнопки допустимых лимитов страниц по два в ряд, callback data — prefix и лимит
+func maxPagesButtons(prefix string) [][]tgbotapi.InlineKeyboardButton {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for n := service.MinMaxPagesPerDay; n <= service.MaxMaxPagesPerDay; n++ {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(n), fmt.Sprintf("%s%d", prefix, n)))
 	}
+	return buttonRows(buttons, 2)
+}
 
-	var buttons [][]tgbotapi.InlineKeyboardButton
+// showTimezoneSelector показывает кнопки с популярными городами для выбора таймзоны
+func (h *TelegramHandler) showTimezoneSelector(loc i18n.Localizer, chatID int64) {
+	h.sendMessageWithKeyboard(chatID, loc.T("timezone.choose"), tgbotapi.NewInlineKeyboardMarkup(timezoneButtons(loc, "timezone_")...))
+}
 
-	// Группируем города по 2 в ряд
-	for i := 0; i < len(cities); i += 2 {
-		var row []tgbotapi.InlineKeyboardButton
-
-		// Первая кнопка в ряду
-		offsetStr := formatTimezoneOffset(cities[i].offset)
-		buttonText := fmt.Sprintf("%s %s", cities[i].name, offsetStr)
-		callbackData := fmt.Sprintf("timezone_%s", cities[i].timezone)
-		row = append(row, tgbotapi.NewInlineKeyboardButtonData(buttonText, callbackData))
-
-		// Вторая кнопка в ряду (если есть)
-		if i+1 < len(cities) {
-			offsetStr2 := formatTimezoneOffset(cities[i+1].offset)
-			buttonText2 := fmt.Sprintf("%s %s", cities[i+1].name, offsetStr2)
-			callbackData2 := fmt.Sprintf("timezone_%s", cities[i+1].timezone)
-			row = append(row, tgbotapi.NewInlineKeyboardButtonData(buttonText2, callbackData2))
-		}
+// timezoneCities — популярные города с их таймзонами, name — ключ каталога сообщений
+var timezoneCities = []struct {
+	name     string
+	timezone string
+	offset   int // Смещение относительно UTC в часах
+}{
+	{"city.moscow", "Europe/Moscow", 3},
+	{"city.saint_petersburg", "Europe/Moscow", 3},
+	{"city.kyiv", "Europe/Kyiv", 2},
+	{"city.minsk", "Europe/Minsk", 3},
+	{"city.london", "Europe/London", 0},
+	{"city.paris", "Europe/Paris", 1},
+	{"city.berlin", "Europe/Berlin", 1},
+	{"city.rome", "Europe/Rome", 1},
+	{"city.new_york", "America/New_York", -5},
+	{"city.los_angeles", "America/Los_Angeles", -8},
+	{"city.chicago", "America/Chicago", -6},
+	{"city.toronto", "America/Toronto", -5},
+	{"city.tokyo", "Asia/Tokyo", 9},
+	{"city.beijing", "Asia/Shanghai", 8},
+	{"city.dubai", "Asia/Dubai", 4},
+	{"city.tehran", "Asia/Tehran", 3},
+	{"city.delhi", "Asia/Kolkata", 5},
+	{"city.sydney", "Australia/Sydney", 10},
+	{"city.sao_paulo", "America/Sao_Paulo", -3},
+	{"city.buenos_aires", "America/Argentina/Buenos_Aires", -3},
+	{"city.cairo", "Africa/Cairo", 2},
+}
 
-		buttons = append(buttons, row)
+// timezoneButtons возвращает кнопки городов по два в ряд, callback data — prefix и таймзона
+func timezoneButtons(loc i18n.Localizer, prefix string) [][]tgbotapi.InlineKeyboardButton {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, city := range timezoneCities {
+		buttonText := fmt.Sprintf("%s %s", loc.T(city.name), formatTimezoneOffset(city.offset))
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(buttonText, prefix+city.timezone))
 	}
-
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons...)
-	h.sendMessageWithKeyboard(chatID, text, keyboard)
+	return buttonRows(buttons, 2)
 }
 
 // formatTimezoneOffset форматирует смещение таймзоны в строку типа "UTC+3" или "UTC-5"
@@ -1377,40 +1256,10 @@ func formatTimezoneOffset(offset int) string {
 	return fmt.Sprintf("UTC%d", offset) // Для отрицательных значений fmt.Sprintf автоматически добавит минус
 }
 
-func (h *TelegramHandler) handleSetTimezone(ctx context.Context, update tgbotapi.Update) {
-	userID := update.Message.From.ID
-	chatID := update.Message.Chat.ID
-
-	exists, err := h.service.UserExists(ctx, userID)
-	if err != nil {
-		zap.S().Error("check user exists", zap.Error(err), zap.Int64("telegram_id", userID))
-		h.sendMessage(chatID, "Произошла ошибка. Попробуй позже.")
-		return
-	}
-
-	if !exists {
-		h.sendMessage(chatID, "Сначала зарегистрируйся с помощью команды /start")
-		return
-	}
-
+func (h *TelegramHandler) handleSetTimezone(_ context.Context, req *request) error {
 	// Показываем кнопки для выбора таймзоны
-	h.showTimezoneSelector(chatID)
-}
-
-func (h *TelegramHandler) startDailyCron() {
-	ctx := context.Background()
-	if err := h.service.RunDailyCron(ctx); err != nil {
-		zap.S().Error("run daily cron on startup", zap.Error(err))
-	}
-
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		if err := h.service.RunDailyCron(ctx); err != nil {
-			zap.S().Error("run daily cron", zap.Error(err))
-		}
-	}
+	h.showTimezoneSelector(req.loc, req.chatID)
+	return nil
 }
 
 // escapeHTML экранирует специальные символы HTML для безопасной вставки в HTML-текст
@@ -1424,92 +1273,30 @@ func escapeHTML(text string) string {
 }
 
 func (h *TelegramHandler) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	// Используем HTML для форматирования текста (жирный шрифт через <b>текст</b>)
-	msg.ParseMode = tgbotapi.ModeHTML
-	if _, err := h.api.Send(msg); err != nil {
+	if _, err := h.messenger.Send(chatID, text, nil); err != nil {
 		zap.S().Error("send message", zap.Error(err), zap.Int64("chat_id", chatID))
 	}
 }
 
 func (h *TelegramHandler) sendMessageWithKeyboard(chatID int64, text string, keyboard interface{}) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	// Используем HTML для форматирования текста (жирный шрифт через <b>текст</b>)
-	msg.ParseMode = tgbotapi.ModeHTML
-	msg.ReplyMarkup = keyboard
-	if _, err := h.api.Send(msg); err != nil {
+	if _, err := h.messenger.Send(chatID, text, keyboard); err != nil {
 		zap.S().Error("send message with keyboard", zap.Error(err), zap.Int64("chat_id", chatID))
 	}
 }
 
-func (h *TelegramHandler) startReminderScheduler() {
-	ticker := time.NewTicker(30 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		h.checkAndSendReminders()
-	}
-}
-
-func (h *TelegramHandler) checkAndSendReminders() {
-	ctx := context.Background()
-
-	users, err := h.service.GetAllUsersForReminders(ctx)
-	if err != nil {
-		zap.S().Error("get all users for reminders", zap.Error(err))
-		return
+// sendLongMessageWithKeyboard отправляет текст, разбитый на части по лимиту Telegram, клавиатура прикрепляется к последней части
+func (h *TelegramHandler) sendLongMessageWithKeyboard(chatID int64, text string, keyboard interface{}) {
+	chunks := onenote.SplitHTML(text, telegramMessageLimit)
+	for _, chunk := range chunks[:len(chunks)-1] {
+		h.sendMessage(chatID, chunk)
 	}
 
-	for _, user := range users {
-		reminder, err := time.Parse("15:04", user.ReminderTime)
-		if err != nil {
-			zap.S().Error("parse reminder time", zap.Error(err), zap.Int64("telegram_id", user.TelegramID), zap.String("reminder_time", user.ReminderTime))
-			continue
-		}
-
-		// Get current time in user's timezone
-		timezone := "UTC"
-		if user.Timezone != nil && *user.Timezone != "" {
-			timezone = *user.Timezone
-		}
-
-		nowUTC := utils.NowUTC()
-		now, err := utils.ToUserTimezone(nowUTC, timezone)
-		if err != nil {
-			zap.S().Warn("failed to convert to user timezone", zap.Error(err), zap.Int64("telegram_id", user.TelegramID), zap.String("timezone", timezone))
-			continue
-		}
-
-		if reminder.Hour() != now.Hour() || reminder.Minute() != now.Minute() {
-			continue
-		}
-
-		duePages, err := h.service.GetDuePagesToday(ctx, user.TelegramID)
-		if err != nil {
-			if _, ok := err.(*service.AuthRequiredError); ok {
-				zap.S().Warn("authentication required for reminder", zap.Int64("telegram_id", user.TelegramID))
-				continue
-			}
-			zap.S().Error("get due pages for reminder", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
-			continue
-		}
-
-		if len(duePages) > 0 {
-			text := h.formatReminderMessage(len(duePages))
-			h.sendMessage(user.TelegramID, text)
-		}
-	}
+	h.sendMessageWithKeyboard(chatID, chunks[len(chunks)-1], keyboard)
 }
 
-func (h *TelegramHandler) formatReminderMessage(count int) string {
-	var pageWord string
-	if count == 1 {
-		pageWord = "страница"
-	} else if count >= 2 && count <= 4 {
-		pageWord = "страницы"
-	} else {
-		pageWord = "страниц"
+// editMessage заменяет текст и клавиатуру уже отправленного сообщения, keyboard == nil убирает клавиатуру
+func (h *TelegramHandler) editMessage(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	if err := h.messenger.Edit(chatID, messageID, text, keyboard); err != nil {
+		zap.S().Error("edit message", zap.Error(err), zap.Int64("chat_id", chatID), zap.Int("message_id", messageID))
 	}
-
-	return fmt.Sprintf("🔔 Доброе утро! У тебя %d %s на повторение сегодня.\nИспользуй /today для начала.", count, pageWord)
 }
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func newTestUpdate(userID int64) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: userID}}}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name string
+		// handle — обработчик обновления; release закрывается в конце теста, чтобы не оставлять горутины
+		handle       func(ctx context.Context, release <-chan struct{})
+		wantErr      string
+		wantFinished bool
+	}{
+		{
+			name:         "handlers finish in time",
+			handle:       func(context.Context, <-chan struct{}) {},
+			wantFinished: true,
+		},
+		{
+			name: "cancelled handler stops within grace",
+			handle: func(ctx context.Context, _ <-chan struct{}) {
+				<-ctx.Done()
+			},
+			wantErr:      "were cancelled",
+			wantFinished: true,
+		},
+		{
+			name: "handler ignores cancellation",
+			handle: func(_ context.Context, release <-chan struct{}) {
+				<-release
+			},
+			wantErr: "did not stop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+
+			var finished atomic.Bool
+			h := &TelegramHandler{}
+			h.dispatcher = newUpdateDispatcher(1, maxQueuedUpdates, time.Minute, func(ctx context.Context, _ tgbotapi.Update) {
+				tt.handle(ctx, release)
+				finished.Store(true)
+			}, func(tgbotapi.Update, any) {})
+
+			h.dispatcher.dispatch(newTestUpdate(1))
+
+			stopped := false
+			err := h.drain(func() { stopped = true }, 20*time.Millisecond, time.Second/2)
+
+			if !stopped {
+				t.Fatal("background tasks were not stopped")
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("drain: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("drain error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if finished.Load() != tt.wantFinished {
+				t.Fatalf("handler finished = %v, want %v", finished.Load(), tt.wantFinished)
+			}
+		})
+	}
+}
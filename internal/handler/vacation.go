@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/romanzh1/master-english-srs/internal/service"
+)
+
+// handleVacation управляет отпуском: /vacation 2026-07-01 2026-07-14 [spread], /vacation off
+func (h *TelegramHandler) handleVacation(ctx context.Context, req *request) error {
+	args := strings.Fields(req.message.Text)[1:]
+
+	if len(args) == 0 {
+		return h.showVacation(req)
+	}
+
+	if len(args) == 1 && strings.EqualFold(args[0], "off") {
+		if err := h.service.CancelVacation(ctx, req.userID); err != nil {
+			return withReply(fmt.Errorf("cancel vacation: %w", err), req.loc.T("settings.update_failed"))
+		}
+		h.sendMessage(req.chatID, req.loc.T("vacation.cancelled"))
+		return nil
+	}
+
+	spread := len(args) == 3 && strings.EqualFold(args[2], "spread")
+	if len(args) != 2 && !spread {
+		return reply(req.loc.T("vacation.usage", service.MaxVacationDays))
+	}
+
+	start, err := service.ParseVacationDate(args[0])
+	if err != nil {
+		return reply(req.loc.T("vacation.usage", service.MaxVacationDays))
+	}
+
+	end, err := service.ParseVacationDate(args[1])
+	if err != nil {
+		return reply(req.loc.T("vacation.usage", service.MaxVacationDays))
+	}
+
+	shifted, err := h.service.SetVacation(ctx, req.userID, start, end, spread)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSetting) {
+			return reply(req.loc.T("vacation.invalid", service.MaxVacationDays))
+		}
+		return withReply(fmt.Errorf("set vacation %s-%s: %w", args[0], args[1], err), req.loc.T("settings.update_failed"))
+	}
+
+	lines := []string{req.loc.T("vacation.set", start.Format(service.VacationDateLayout), end.Format(service.VacationDateLayout))}
+	if shifted > 0 {
+		key := "vacation.shifted"
+		if spread {
+			key = "vacation.spread"
+		}
+		lines = append(lines, req.loc.N(key, shifted, shifted))
+	}
+
+	h.sendMessage(req.chatID, strings.Join(lines, "\n"))
+	return nil
+}
+
+// showVacation показывает запланированный или идущий отпуск и справку по команде
+func (h *TelegramHandler) showVacation(req *request) error {
+	status := req.loc.T("vacation.none")
+	if user := req.user; service.VacationPlanned(user, h.clock.Now()) {
+		status = req.loc.T("vacation.current", user.VacationStart.Format(service.VacationDateLayout), user.VacationEnd.Format(service.VacationDateLayout))
+	}
+
+	h.sendMessage(req.chatID, status+"\n\n"+req.loc.T("vacation.usage", service.MaxVacationDays))
+	return nil
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// webhookSecretHeader — заголовок, в котором Telegram передаёт secret_token, указанный в setWebhook
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookMaxBodySize ограничивает размер тела запроса с обновлением
+const webhookMaxBodySize = 1 << 20
+
+// WebhookConfig — параметры приёма обновлений через вебхук
+type WebhookConfig struct {
+	// URL — публичный адрес вебхука, который регистрируется в Telegram, путь из него обслуживает HTTP-сервер
+	URL string
+	// ListenAddr — адрес, на котором слушает HTTP-сервер, например ":8080"
+	ListenAddr string
+	// SecretToken проверяется в каждом запросе, чтобы обновления мог присылать только Telegram
+	SecretToken string
+	// CertFile и KeyFile включают TLS на самом сервере. Без них сервер слушает HTTP,
+	// а TLS завершается на reverse proxy перед ботом
+	CertFile string
+	KeyFile  string
+	// UploadCertificate отправляет CertFile в setWebhook, нужен для самоподписанного сертификата
+	UploadCertificate bool
+}
+
+// StartWebhook регистрирует вебхук в Telegram и обрабатывает обновления, приходящие на HTTP-сервер,
+// пока ctx не отменён или сервер не упал. При остановке сервер завершается и вебхук удаляется.
+func (h *TelegramHandler) StartWebhook(ctx context.Context, cfg WebhookConfig) error {
+	webhookURL, err := url.Parse(cfg.URL)
+	if err != nil || webhookURL.Scheme != "https" || webhookURL.Host == "" {
+		return fmt.Errorf("invalid webhook URL (url: %s): must be an absolute https URL", cfg.URL)
+	}
+
+	if cfg.SecretToken == "" {
+		return fmt.Errorf("webhook secret token is required")
+	}
+
+	if err := h.connect(); err != nil {
+		return err
+	}
+
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	// Ошибка сервера останавливает обработку так же, как сигнал завершения
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	updates := make(chan tgbotapi.Update, h.api.Buffer)
+
+	mux := http.NewServeMux()
+	mux.Handle(path, newWebhookHandler(cfg.SecretToken, updates, runCtx.Done()))
+
+	server := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		var err error
+		if cfg.CertFile != "" && cfg.KeyFile != "" {
+			err = server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+
+		if !errors.Is(err, http.ErrServerClosed) {
+			cancel(fmt.Errorf("serve webhook (listen_addr: %s): %w", cfg.ListenAddr, err))
+		}
+	}()
+
+	if err := h.setWebhook(cfg); err != nil {
+		_ = server.Close()
+		return err
+	}
+
+	zap.S().Info("bot started", zap.String("mode", "webhook"), zap.String("listen_addr", cfg.ListenAddr), zap.String("path", path))
+
+	// Обновления, на которые Telegram уже получил 200, повторно не придут, поэтому сервер останавливается
+	// до разбора канала: новые запросы получают 503, а всё принятое до остановки обрабатывается
+	stopServer := func() {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			zap.S().Error("shutdown webhook server", zap.Error(err))
+		}
+	}
+
+	processErr := h.processUpdates(runCtx, updates, stopServer)
+
+	h.deleteWebhook()
+
+	// Отмена родительского ctx — штатная остановка, ошибка сервера — нет
+	if cause := context.Cause(runCtx); ctx.Err() == nil && cause != nil {
+		return cause
+	}
+
+	return processErr
+}
+
+// setWebhook регистрирует вебхук. Используется прямой вызов API, потому что
+// tgbotapi.WebhookConfig не умеет передавать secret_token.
+func (h *TelegramHandler) setWebhook(cfg WebhookConfig) error {
+	params := tgbotapi.Params{
+		"url":          cfg.URL,
+		"secret_token": cfg.SecretToken,
+	}
+	if err := params.AddInterface("allowed_updates", []string{"message", "callback_query"}); err != nil {
+		return fmt.Errorf("build setWebhook params: %w", err)
+	}
+
+	var err error
+	if cfg.UploadCertificate && cfg.CertFile != "" {
+		_, err = h.api.UploadFiles("setWebhook", params, []tgbotapi.RequestFile{{
+			Name: "certificate",
+			Data: tgbotapi.FilePath(cfg.CertFile),
+		}})
+	} else {
+		_, err = h.api.MakeRequest("setWebhook", params)
+	}
+
+	if err != nil {
+		return fmt.Errorf("set webhook (url: %s): %w", cfg.URL, err)
+	}
+
+	return nil
+}
+
+func (h *TelegramHandler) deleteWebhook() {
+	if _, err := h.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		zap.S().Error("delete webhook", zap.Error(err))
+	}
+}
+
+// newWebhookHandler принимает обновления от Telegram: проверяет метод и secret token,
+// разбирает обновление и передаёт его в общий цикл обработки. После закрытия stopped
+// обновления не принимаются, и Telegram повторит их доставку позже. Обновление, на которое
+// ответили 200, уже лежит в updates и будет обработано даже при остановке.
+func newWebhookHandler(secretToken string, updates chan<- tgbotapi.Update, stopped <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.Header.Get(webhookSecretHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secretToken)) != 1 {
+			zap.S().Warn("webhook request with invalid secret token", zap.String("remote_addr", r.RemoteAddr))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, webhookMaxBodySize)).Decode(&update); err != nil {
+			zap.S().Warn("decode webhook update", zap.Error(err))
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		// Остановка проверяется отдельно: select с двумя готовыми ветками выбирает случайную,
+		// и обновление могло бы попасть в канал, который уже разобран при остановке
+		select {
+		case <-stopped:
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		default:
+		}
+
+		select {
+		case updates <- update:
+			w.WriteHeader(http.StatusOK)
+		case <-stopped:
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		case <-r.Context().Done():
+			// Telegram повторит доставку обновления, если не получил ответ
+			http.Error(w, "timeout", http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testWebhookSecret = "secret"
+
+const testWebhookUpdate = `{"update_id": 42, "message": {"message_id": 1, "from": {"id": 7}, "chat": {"id": 7}, "text": "/today"}}`
+
+func newWebhookRequest(secret, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set(webhookSecretHeader, secret)
+	}
+	return req
+}
+
+func TestWebhookHandlerRejectsRequests(t *testing.T) {
+	stopped := make(chan struct{})
+	close(stopped)
+
+	tests := []struct {
+		name    string
+		req     *http.Request
+		stopped <-chan struct{}
+		want    int
+	}{
+		{
+			name: "wrong secret",
+			req:  newWebhookRequest("wrong", testWebhookUpdate),
+			want: http.StatusForbidden,
+		},
+		{
+			name: "missing secret",
+			req:  newWebhookRequest("", testWebhookUpdate),
+			want: http.StatusForbidden,
+		},
+		{
+			name: "bad json",
+			req:  newWebhookRequest(testWebhookSecret, `{"update_id":`),
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "wrong method",
+			req:  httptest.NewRequest(http.MethodGet, "/webhook", nil),
+			want: http.StatusMethodNotAllowed,
+		},
+		{
+			name:    "shutting down",
+			req:     newWebhookRequest(testWebhookSecret, testWebhookUpdate),
+			stopped: stopped,
+			want:    http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Буфер есть, поэтому 503 при остановке не объясняется заполненным каналом
+			updates := make(chan tgbotapi.Update, 1)
+			running := tt.stopped
+			if running == nil {
+				running = make(chan struct{})
+			}
+
+			rec := httptest.NewRecorder()
+			newWebhookHandler(testWebhookSecret, updates, running).ServeHTTP(rec, tt.req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if len(updates) != 0 {
+				t.Fatalf("rejected request put %d updates into the channel", len(updates))
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerAcceptsUpdate(t *testing.T) {
+	updates := make(chan tgbotapi.Update, 1)
+
+	rec := httptest.NewRecorder()
+	newWebhookHandler(testWebhookSecret, updates, make(chan struct{})).ServeHTTP(rec, newWebhookRequest(testWebhookSecret, testWebhookUpdate))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	select {
+	case update := <-updates:
+		if update.UpdateID != 42 || update.Message == nil || update.Message.Text != "/today" {
+			t.Fatalf("unexpected update: %+v", update)
+		}
+	default:
+		t.Fatal("accepted update was not passed on")
+	}
+}
+
+func TestWebhookHandlerStopsWaitingOnShutdown(t *testing.T) {
+	// Канал без буфера и без читателя: запрос ждёт, пока не начнётся остановка
+	updates := make(chan tgbotapi.Update)
+	stopped := make(chan struct{})
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		newWebhookHandler(testWebhookSecret, updates, stopped).ServeHTTP(rec, newWebhookRequest(testWebhookSecret, testWebhookUpdate))
+		done <- rec.Code
+	}()
+
+	close(stopped)
+
+	select {
+	case code := <-done:
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d, want %d", code, http.StatusServiceUnavailable)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler kept waiting after shutdown")
+	}
+}
+
+// TestDispatchBufferedHandlesAcceptedUpdates проверяет, что обновления, на которые вебхук уже ответил 200,
+// обрабатываются при остановке, а после остановки новые обновления не принимаются
+func TestDispatchBufferedHandlesAcceptedUpdates(t *testing.T) {
+	var mu sync.Mutex
+	var handled []int
+
+	h := &TelegramHandler{}
+	h.dispatcher = newUpdateDispatcher(2, maxQueuedUpdates, time.Second, func(_ context.Context, update tgbotapi.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, update.UpdateID)
+	}, func(tgbotapi.Update, any) {})
+
+	updates := make(chan tgbotapi.Update, 4)
+	stopped := make(chan struct{})
+	server := newWebhookHandler(testWebhookSecret, updates, stopped)
+
+	send := func(id string) int {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, newWebhookRequest(testWebhookSecret, strings.Replace(testWebhookUpdate, "42", id, 1)))
+		return rec.Code
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		if code := send(id); code != http.StatusOK {
+			t.Fatalf("update %s: status = %d, want %d", id, code, http.StatusOK)
+		}
+	}
+
+	close(stopped)
+	if code := send("4"); code != http.StatusServiceUnavailable {
+		t.Fatalf("update after stop: status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	h.dispatchBuffered(updates)
+	h.dispatcher.wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 3 {
+		t.Fatalf("handled %v, want the 3 accepted updates", handled)
+	}
+	if len(updates) != 0 {
+		t.Fatalf("%d updates left in the channel", len(updates))
+	}
+}
@@ -0,0 +1,387 @@
+package i18n
+
+var english = &catalog{
+	name:       "English",
+	plural:     pluralEnglish,
+	categories: []PluralCategory{PluralOne, PluralOther},
+	messages: map[string]string{
+		"error.generic":    "Something went wrong. Please try again later.",
+		"error.short":      "Something went wrong.",
+		"command.unknown":  "Unknown command. Use /help",
+		"callback.unknown": "Unknown command. Use /help to see the available commands.",
+		"text.unknown":     "I don't understand this command. Use /help to see the available commands.",
+		"rate_limited":     "Too many requests. Please wait a moment and try again.",
+		"button.stale":     "This button has expired. Open the list again with /today",
+		"button.yes":       "Yes",
+		"button.no":        "No",
+		"button.back":      "⬅️ Back",
+
+		"require.registered": "Please register first with /start",
+		"require.onenote":    "Please connect OneNote first with /connect_onenote",
+		"require.notebook":   "Please choose a OneNote notebook first with /select_notebook",
+		"require.section":    "Please choose a OneNote section first with /select_section",
+
+		"auth.link":        "To connect OneNote, open this link:\n\n%s\n\nAfter signing in, send me the code you receive.",
+		"auth.expired":     "❌ Please sign in again. Your token has expired.\n\nOpen this link to sign in:\n\n%s\n\nAfter signing in, send me the code you receive.",
+		"auth.code_failed": "❌ Could not process the authorization code. Make sure the code is correct and has not expired, then send it again or get a new one with /connect_onenote",
+		"auth.success":     "✅ Signed in successfully!",
+		"auth.updated":     "✅ Authorization updated!",
+
+		"start.greeting":     "Hi! 👋\n\nI will help you learn English with spaced repetition (SRS).",
+		"start.welcome_back": "Welcome back! Use /today to start studying.",
+
+		"language.choose": "🌐 Choose the interface language:",
+		"language.set":    "✅ Interface language: %s",
+		"language.failed": "Could not change the language. Please try again later.",
+
+		"onboarding.level":       "Choose your level:",
+		"onboarding.max_pages":   "Choose the maximum number of pages to review per day:",
+		"onboarding.timezone":    "Now choose your city to set your time zone:",
+		"onboarding.section":     "Finally, choose the OneNote section to take pages from:",
+		"onboarding.use_buttons": "Choose an option with the buttons in the message above. To stop, use /cancel",
+
+		"register.done":       "✅ Registration complete! Level set: %s",
+		"register.failed":     "Registration failed. Please try again later.",
+		"level.updated":       "✅ Level updated: %s\n\nNow connect OneNote with /connect_onenote",
+		"level.update_failed": "Could not update your level. Please try again later.",
+
+		"add_page.prompt":    "Send the page number or part of its title and I will add it to your studies. To cancel, use /cancel",
+		"add_page.added":     "✅ Page added to your studies: %s\n\nIt will appear in /today",
+		"add_page.not_found": "I could not find this page in the connected sections. Send the page number or part of the title again, or /cancel",
+		"add_page.already":   "You are already studying this page. Send another page or /cancel",
+		"add_page.failed":    "Could not add the page. Please try again later.",
+
+		"answer.invalid": "Send your result as a number from 0 to 100 (percentage of correct answers) or rate it with a button under the page",
+
+		"cancel.nothing":    "Nothing to cancel.",
+		"cancel.onboarding": "Setup interrupted. You can continue with /connect_onenote, /select_notebook and /select_section.",
+		"cancel.done":       "Cancelled.",
+
+		"picker.root":            "📚 Notebooks",
+		"picker.choose_notebook": "Choose a OneNote notebook:",
+		"picker.choose_section":  "Choose a section to sync or open a section group:",
+		"picker.empty":           "Nothing here.",
+		"picker.no_notebooks":    "You have no OneNote notebooks available.",
+		"picker.load_failed":     "Could not load OneNote notebooks and sections. Please try again later.",
+		"picker.stale":           "This list has expired. Open it again with /select_notebook or /select_section",
+		"picker.save_failed":     "Could not save the selected section. Please try again later.",
+		"picker.section_added":   "✅ Section added:\n%s",
+		"picker.setup_done":      "✅ OneNote section added!\n\nOneNote is now set up. You can see all connected sections with /sources.\n\nWould you like to start reviewing today?",
+		"notebooks.load_failed":  "Could not load OneNote notebooks. Please try again later.",
+
+		"sources.empty":         "You have no connected sections yet. Choose a notebook with /select_notebook and add a section with /select_section.",
+		"sources.title":         "📚 <b>Connected sections</b>\n\nNew pages are taken from sections higher in the list first.",
+		"sources.disable":       "Disable",
+		"sources.enable":        "Enable",
+		"sources.invalid":       "Invalid choice. Try again with /sources",
+		"sources.update_failed": "Could not update the section. Try again with /sources",
+
+		"progress.interval": "📊 Progress: interval %s (step %d of %d)",
+
+		"today.empty":     "🎉 No pages to review today!",
+		"today.title":     "📚 <b>Today's review:</b>",
+		"today.new_page":  "📅 New page",
+		"today.show_page": "Show page %d",
+		"today.skip_all":  "Skip all",
+
+		"session.progress":        "📊 Done %d of %d",
+		"session.page":            "📖 Page %d of %d",
+		"session.button.start":    "▶️ Start review",
+		"session.button.continue": "▶️ Continue (%d of %d)",
+		"session.button.next":     "▶️ Next page",
+		"session.summary.title":   "🏁 <b>Today's review is complete!</b>",
+		"session.summary.graded":  "✅ Pages graded: %d of %d",
+		"session.summary.average": "📈 Average score: %d%%",
+
+		"stats.title":          "📊 <b>Statistics</b>",
+		"stats.empty":          "📊 No statistics yet: start reviewing pages with /today.",
+		"stats.recent":         "📅 Last %s: %s, %s",
+		"stats.pages":          "📚 In progress: %d, fully learned: %d",
+		"stats.steps.title":    "<b>SRS steps</b> (pages, retention)",
+		"stats.step":           "Step %d, %s",
+		"stats.step.reading":   "Reading",
+		"stats.step.line":      "• %s: %s, %s",
+		"stats.retention":      "%d%% (%d of %d)",
+		"stats.retention.none": "no grades yet",
+		"stats.trend.title":    "<b>Average score by week</b>",
+		"stats.trend.week":     "%s: %d%% (%s)",
+		"stats.trend.empty":    "%s: —",
+		"stats.upcoming.title": "<b>Load for the week</b>",
+		"stats.chart.caption":  "🟦 reviews per day · 🟪 average score by week · 🟧 retention by interval in days · 🟩 reviews for the coming days",
+
+		"history.button":          "📈 History",
+		"history.not_found":       "This page is no longer in your studies.",
+		"history.title":           "📈 <b>Page history</b>\n%s",
+		"history.passed":          "🎓 Page learned",
+		"history.summary":         "📝 %s, %s",
+		"history.ai_since":        "🤖 AI mode since %s",
+		"history.reading_mode":    "📖 Still in reading mode",
+		"history.events.title":    "<b>Grades</b>",
+		"history.events.empty":    "The page has not been graded yet.",
+		"history.interval":        "%s → %s",
+		"history.mode.reading":    "reading",
+		"history.mark.ai":         "🤖 switched to AI mode",
+		"history.mark.lapse":      "⚠️ lapse",
+		"history.forecast.title":  "<b>What each grade will do</b>",
+		"history.forecast.line":   "%s: %s, review on %s",
+		"history.forecast.passed": "%s: the page will be learned",
+		"history.words.line":      "• %s — %s",
+
+		"pages.empty":          "You have no pages yet. Come back tomorrow or use /prepare_materials.",
+		"pages.title":          "📖 <b>Your pages:</b>",
+		"pages.next_review":    "📅 Next review: %s",
+		"pages.reviewed_today": "✅ Reviewed today",
+		"pages.history_button": "📈 %d",
+		"pages.history_hint":   "Tap 📈 with a page number to see its history and interval forecast.",
+
+		"page.list_failed":     "Could not load the list of pages. Try again with /today",
+		"page.not_due":         "This page is no longer in today's list. Open the current list with /today",
+		"page.content_failed":  "Could not load the page content.",
+		"page.header":          "📄 <b>Page</b>",
+		"page.reading_prompt":  "📖 Read the words and rate how well you remember them with a button, or send a percentage from 0 to 100:",
+		"page.practice_prompt": "💡 Copy this page and send it to the Poe bot to generate an exercise.\n\nAfter completing the exercise, rate your result with a button or send the percentage of correct answers from 0 to 100:",
+		"page.skip":            "↩️ Skip",
+
+		"grade.easy":   "✅ Easy (>80%)",
+		"grade.normal": "🟢 Normal (>60%)",
+		"grade.hard":   "🟡 Hard (>40%)",
+		"grade.forgot": "🔴 Forgot (<40%)",
+
+		"review.failed":    "Could not update your progress.",
+		"review.forgot":    "🔴 Forgot! We'll review it tomorrow.",
+		"review.saved":     "✅ Grade saved.",
+		"review.duplicate": "✅ This grade has already been recorded.",
+		"review.conflict":  "This page has already been graded in another message, the grade was not applied. Open the current list with /today",
+
+		"undo.button":  "↩️ Undo grade",
+		"undo.done":    "↩️ Grade undone, the page progress is restored. The page is waiting for review today again.",
+		"undo.regrade": "📖 Grade again",
+		"undo.nothing": "This grade can no longer be undone: it was already undone or the page was graded again.",
+		"undo.failed":  "Failed to undo the grade. Please try again.",
+
+		"skip.failed":   "Could not skip the page. Please try again later.",
+		"skip.done":     "OK, we'll skip it today",
+		"skip_all.done": "OK, skipping today. See you tomorrow! 👋",
+
+		"prepare.warning":     "⚠️ Warning! This command adds review materials.\nUsing it often is not recommended: materials will pile up and you will have too much to review in a single day later.\n\nMaterials are normally prepared automatically at 00:00 every day.\n\nPreparing materials...",
+		"prepare.in_progress": "Preparing materials...",
+		"prepare.failed":      "Could not prepare materials. Please try again later.",
+		"prepare.done":        "✅ Materials prepared!",
+		"prepare.done_today":  "✅ Materials prepared! Use /today to start studying.",
+		"prepare.later":       "OK, use /today when you are ready to start studying.",
+
+		"settings.update_failed":    "Could not update the settings. Please try again later.",
+		"settings.invalid":          "❌ Invalid value. Please try again.",
+		"settings.title":            "⚙️ <b>Settings</b>",
+		"settings.level":            "🎓 Level: %s",
+		"settings.max_pages":        "📊 Pages per day: %d",
+		"settings.timezone":         "🌍 Time zone: %s",
+		"settings.reminder":         "⏰ Reminders: %s",
+		"settings.quiet_hours":      "🌙 Quiet hours: %s–%s",
+		"settings.language":         "🌐 Language: %s",
+		"settings.saved":            "✅ Saved",
+		"settings.choose_reminder":  "⏰ Tick the reminder times, up to %d per day.\nSet quiet hours with /set_reminder quiet 22:00-08:00",
+		"settings.button.level":     "🎓 Level",
+		"settings.button.max_pages": "📊 Pages per day",
+		"settings.button.timezone":  "🌍 Time zone",
+		"settings.button.reminder":  "⏰ Reminders",
+		"settings.button.language":  "🌐 Language",
+
+		"max_pages.choose":         "📊 Choose the maximum number of pages per day:\n\n2 pages per day → 1 new page is added\n3 pages per day → 1 (60%) or 2 (40%) new pages are added\n4 pages per day → 2 new pages are added",
+		"max_pages.usage":          "Usage: /set_max_pages <b>number</b>\n\nFor example: /set_max_pages 3\n\nRecommended values: 2, 3 or 4",
+		"max_pages.invalid":        "Invalid value. Use a number from 2 to 4.\n\n2 pages per day → 1 new page is added\n3 pages per day → 1 (60%) or 2 (40%) new pages are added\n4 pages per day → 2 new pages are added",
+		"max_pages.invalid_button": "❌ Invalid value. Please try again.",
+		"max_pages.set":            "✅ Maximum pages per day set to: %d",
+		"max_pages.current":        "📊 Current maximum pages per day: %d",
+
+		"timezone.choose":        "🌍 Choose your city to set your time zone:",
+		"timezone.invalid":       "❌ Invalid time zone: %s",
+		"timezone.update_failed": "Could not update the time zone. Please try again later.",
+		"timezone.set":           "✅ Time zone set: %s",
+		"timezone.set_details":   "✅ Time zone set: %s\n\nNew materials will be added automatically at 00:00 every day in your local time.",
+
+		"city.moscow":           "Moscow",
+		"city.saint_petersburg": "Saint Petersburg",
+		"city.kyiv":             "Kyiv",
+		"city.minsk":            "Minsk",
+		"city.london":           "London",
+		"city.paris":            "Paris",
+		"city.berlin":           "Berlin",
+		"city.rome":             "Rome",
+		"city.new_york":         "New York",
+		"city.los_angeles":      "Los Angeles",
+		"city.chicago":          "Chicago",
+		"city.toronto":          "Toronto",
+		"city.tokyo":            "Tokyo",
+		"city.beijing":          "Beijing",
+		"city.dubai":            "Dubai",
+		"city.tehran":           "Tehran",
+		"city.delhi":            "Delhi",
+		"city.sydney":           "Sydney",
+		"city.sao_paulo":        "São Paulo",
+		"city.buenos_aires":     "Buenos Aires",
+		"city.cairo":            "Cairo",
+
+		"reminder.off":         "off",
+		"reminder.usage":       "Usage:\n/set_reminder 09:00 20:00 — reminder times, up to %d per day\n/set_reminder off — turn reminders off\n/set_reminder on — turn them on\n/set_reminder quiet 22:00-08:00 — quiet hours without reminders\n/set_reminder quiet off — remove quiet hours",
+		"reminder.invalid":     "❌ Invalid time. Give 1 to %d values as HH:MM, for example: /set_reminder 09:00 20:00",
+		"reminder.last_time":   "❌ Keep at least one time. To stop reminders, press “Turn off”.",
+		"reminder.quiet_usage": "❌ Invalid quiet hours. Example: /set_reminder quiet 22:00-08:00",
+		"reminder.times_set":   "✅ Reminders: %s",
+		"reminder.enabled":     "🔔 Reminders are on: %s",
+		"reminder.disabled":    "🔕 Reminders are off. Turn them on with /set_reminder on",
+		"reminder.quiet_set":   "🌙 Quiet hours: %s–%s. No reminders during this time.",
+		"reminder.quiet_off":   "🌙 Quiet hours removed.",
+		"reminder.button.on":   "🔔 Turn reminders on",
+		"reminder.button.off":  "🔕 Turn reminders off",
+
+		"vacation.usage":     "Usage:\n/vacation 2026-07-01 2026-07-14 — vacation from the first date to the second inclusive, up to %d days\n/vacation 2026-07-01 2026-07-14 spread — the same, but each postponed review moves forward by the length of the vacation, keeping the gaps between reviews\n/vacation off — cancel the vacation\n\nDuring a vacation there are no reminders, no new pages and no interval resets for inactivity. Reviews falling on the vacation are moved to the days after it, no more than your daily page limit per day together with reviews already planned.",
+		"vacation.invalid":   "❌ Invalid vacation: dates can't be in the past, the end can't be before the start, and it can't be longer than %d days.",
+		"vacation.none":      "🏖 No vacation planned.",
+		"vacation.current":   "🏖 Vacation: %s – %s",
+		"vacation.set":       "🏖 Vacation from %s to %s. Have a good rest!",
+		"vacation.cancelled": "🏖 Vacation cancelled. Postponed reviews stay on their new dates.",
+
+		"comeback.pace":          "📅 Plan: %d per day over %s",
+		"comeback.today":         "Today",
+		"comeback.hint":          "Pages you are most likely to forget come first: the most overdue ones, with low scores and short intervals. Intervals are kept, only review dates change.",
+		"comeback.nothing":       "✅ Nothing has piled up, everything fits into today.",
+		"comeback.changed":       "⚠️ The plan has changed since it was shown. Here is the updated one:",
+		"comeback.kept":          "👌 All reviews stay on today. Start with /today",
+		"comeback.button.accept": "✅ Accept the plan",
+		"comeback.button.slower": "➖ Slower",
+		"comeback.button.faster": "➕ Faster",
+		"comeback.button.keep":   "Keep everything for today",
+
+		"sync.failed":     "Could not sync pages. Please try again later.",
+		"sync.title":      "🔄 <b>Sync complete</b>",
+		"sync.added":      "➕ New",
+		"sync.changed":    "✏️ Changed",
+		"sync.moved":      "🔀 Moved",
+		"sync.removed":    "🗑 Removed",
+		"sync.more":       "… and %d more",
+		"sync.no_changes": "No changes, all pages are up to date.",
+
+		"help": `📚 <b>Master English SRS</b>
+
+Available commands:
+
+/start - Start using the bot
+/connect_onenote - Connect OneNote
+/select_notebook - Choose a OneNote notebook to sync
+/select_section - Add a OneNote section to sync
+/sources - Connected sections: enable, priority, remove
+
+/today - Show today's pages
+/comeback - Spread piled-up reviews over several days
+/pages - List all pages
+/stats - Learning statistics and charts
+/set_max_pages - Set the maximum number of pages to review per day
+/get_max_pages - Show the current maximum number of pages per day
+/prepare_materials - Load an extra page for today
+/add_page - Add a page to your studies by number or title
+/sync - Sync pages with OneNote and show the changes
+/set_timezone - Set your time zone
+/set_reminder - Reminder times, quiet hours, on and off
+/vacation - Vacation: no reminders or new pages, reviews are postponed
+/language - Change the interface language
+/settings - All settings: level, pages per day, time zone, reminders, language
+
+/cancel - Cancel the current action
+/help - Help`,
+	},
+	plurals: map[string]map[PluralCategory]string{
+		"interval.days": {
+			PluralOne:   "%d day",
+			PluralOther: "%d days",
+		},
+		"today.last_review": {
+			PluralOne:   "📅 Last review: %d day ago",
+			PluralOther: "📅 Last review: %d days ago",
+		},
+		"review.easy": {
+			PluralOne:   "✅ Easy! Next review in %d day.",
+			PluralOther: "✅ Easy! Next review in %d days.",
+		},
+		"review.normal": {
+			PluralOne:   "🟢 Normal! Next review in %d day.",
+			PluralOther: "🟢 Normal! Next review in %d days.",
+		},
+		"review.hard": {
+			PluralOne:   "🟡 Hard! Next review in %d day.",
+			PluralOther: "🟡 Hard! Next review in %d days.",
+		},
+		"reminder.due": {
+			PluralOne:   "🔔 Time to review! You have %d page to review today.\nUse /today to start.",
+			PluralOther: "🔔 Time to review! You have %d pages to review today.\nUse /today to start.",
+		},
+		"undo.expired": {
+			PluralOne:   "A grade can only be undone within %d minute.",
+			PluralOther: "A grade can only be undone within %d minutes.",
+		},
+		"stats.streak": {
+			PluralOne:   "🔥 Streak: %d day in a row",
+			PluralOther: "🔥 Streak: %d days in a row",
+		},
+		"history.words.title": {
+			PluralOne:   "<b>Page words</b>: %d word",
+			PluralOther: "<b>Page words</b>: %d words",
+		},
+		"count.reviews": {
+			PluralOne:   "%d review",
+			PluralOther: "%d reviews",
+		},
+		"count.active_days": {
+			PluralOne:   "%d active day",
+			PluralOther: "%d active days",
+		},
+		"count.pages": {
+			PluralOne:   "%d page",
+			PluralOther: "%d pages",
+		},
+		"count.grades": {
+			PluralOne:   "%d grade",
+			PluralOther: "%d grades",
+		},
+		"count.lapses": {
+			PluralOne:   "%d lapse",
+			PluralOther: "%d lapses",
+		},
+		"stats.total": {
+			PluralOne:   "📝 %d review this year",
+			PluralOther: "📝 %d reviews this year",
+		},
+		"history.events.more": {
+			PluralOne:   "… %d earlier grade",
+			PluralOther: "… %d earlier grades",
+		},
+		"history.words.more": {
+			PluralOne:   "… and %d more word",
+			PluralOther: "… and %d more words",
+		},
+		"session.summary.skipped": {
+			PluralOne:   "↩️ %d page skipped",
+			PluralOther: "↩️ %d pages skipped",
+		},
+		"vacation.shifted": {
+			PluralOne:   "📅 %d review moved to the first days after the vacation",
+			PluralOther: "📅 %d reviews moved to the first days after the vacation",
+		},
+		"vacation.spread": {
+			PluralOne:   "📅 %d review moved forward by the length of the vacation",
+			PluralOther: "📅 %d reviews moved forward by the length of the vacation",
+		},
+		"comeback.title": {
+			PluralOne:   "👋 Welcome back! %d review piled up.",
+			PluralOther: "👋 Welcome back! %d reviews piled up.",
+		},
+		"comeback.more_days": {
+			PluralOne:   "… and %d more day",
+			PluralOther: "… and %d more days",
+		},
+		"comeback.accepted": {
+			PluralOne:   "✅ Plan accepted: %d page per day over %s. Start with /today",
+			PluralOther: "✅ Plan accepted: %d pages per day over %s. Start with /today",
+		},
+	},
+}
@@ -0,0 +1,167 @@
+// Package i18n содержит каталог сообщений бота на поддерживаемых языках
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	Russian = "ru"
+	English = "en"
+
+	// DefaultLanguage — язык пользователей, не выбравших язык, и язык запасных сообщений
+	DefaultLanguage = Russian
+)
+
+// Languages — поддерживаемые языки в порядке показа в /language
+var Languages = []string{Russian, English}
+
+// ErrUnsupportedLanguage — язык не поддерживается ботом
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// catalog — сообщения одного языка. Обычные сообщения — форматные строки fmt,
+// сообщения с числом хранят форму для каждой категории множественного числа языка.
+type catalog struct {
+	// name — название языка на нём самом, для кнопок выбора языка
+	name       string
+	plural     pluralRule
+	categories []PluralCategory
+	messages   map[string]string
+	plurals    map[string]map[PluralCategory]string
+}
+
+var catalogs = map[string]*catalog{
+	Russian: russian,
+	English: english,
+}
+
+// Supported сообщает, поддерживается ли язык
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Name возвращает название языка на нём самом
+func Name(lang string) string {
+	if c, ok := catalogs[lang]; ok {
+		return c.name
+	}
+	return lang
+}
+
+// Match подбирает поддерживаемый язык по коду языка Telegram (например, "en-US"), пустая строка — не подошёл ни один
+func Match(languageCode string) string {
+	base, _, _ := strings.Cut(strings.ToLower(languageCode), "-")
+	if Supported(base) {
+		return base
+	}
+	return ""
+}
+
+// Localizer переводит сообщения на язык пользователя
+type Localizer struct {
+	lang string
+}
+
+// New возвращает Localizer для языка, неподдерживаемый язык заменяется на DefaultLanguage
+func New(lang string) Localizer {
+	if !Supported(lang) {
+		lang = DefaultLanguage
+	}
+	return Localizer{lang: lang}
+}
+
+// Language возвращает язык Localizer
+func (l Localizer) Language() string {
+	if l.lang == "" {
+		return DefaultLanguage
+	}
+	return l.lang
+}
+
+// T возвращает сообщение по ключу, подставляя args как в fmt.Sprintf.
+// Если ключа нет в языке пользователя, берётся сообщение на DefaultLanguage, если нет и там — сам ключ.
+func (l Localizer) T(key string, args ...any) string {
+	format, ok := catalogs[l.Language()].messages[key]
+	if !ok {
+		format, ok = catalogs[DefaultLanguage].messages[key]
+	}
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// N возвращает форму сообщения для числа n по правилам множественного числа языка, подставляя args как в fmt.Sprintf
+func (l Localizer) N(key string, n int, args ...any) string {
+	c := catalogs[l.Language()]
+	forms, ok := c.plurals[key]
+	if !ok {
+		c = catalogs[DefaultLanguage]
+		forms, ok = c.plurals[key]
+	}
+	if !ok {
+		return key
+	}
+
+	format, ok := forms[c.plural(n)]
+	if !ok {
+		format = forms[PluralOther]
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Validate проверяет, что каждый ключ есть во всех языках, а у сообщений с числом заданы все категории языка
+func Validate() error {
+	// Ключ, которого нет в языке, может встретиться в нескольких других языках — сообщаем о нём один раз
+	problems := make(map[string]struct{})
+
+	for lang, c := range catalogs {
+		for other, oc := range catalogs {
+			if other == lang {
+				continue
+			}
+			for key := range oc.messages {
+				if _, ok := c.messages[key]; !ok {
+					problems[fmt.Sprintf("%s: missing message %q", lang, key)] = struct{}{}
+				}
+			}
+			for key := range oc.plurals {
+				if _, ok := c.plurals[key]; !ok {
+					problems[fmt.Sprintf("%s: missing plural message %q", lang, key)] = struct{}{}
+				}
+			}
+		}
+
+		for key, forms := range c.plurals {
+			for _, category := range c.categories {
+				if _, ok := forms[category]; !ok {
+					problems[fmt.Sprintf("%s: plural message %q has no %s form", lang, key, category)] = struct{}{}
+				}
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	list := make([]string, 0, len(problems))
+	for problem := range problems {
+		list = append(list, problem)
+	}
+	sort.Strings(list)
+
+	return fmt.Errorf("invalid message catalog:\n%s", strings.Join(list, "\n"))
+}
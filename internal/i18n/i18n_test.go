@@ -0,0 +1,33 @@
+package i18n
+
+import "testing"
+
+func TestCatalogsValid(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestN(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{lang: "ru", n: 1, want: "1 повторение"},
+		{lang: "ru", n: 3, want: "3 повторения"},
+		{lang: "ru", n: 5, want: "5 повторений"},
+		{lang: "ru", n: 11, want: "11 повторений"},
+		{lang: "ru", n: 21, want: "21 повторение"},
+		{lang: "ru", n: 0, want: "0 повторений"},
+		{lang: "en", n: 1, want: "1 review"},
+		{lang: "en", n: 2, want: "2 reviews"},
+		{lang: "en", n: 0, want: "0 reviews"},
+	}
+
+	for _, tt := range tests {
+		if got := New(tt.lang).N("count.reviews", tt.n, tt.n); got != tt.want {
+			t.Errorf("%s N(%d) = %q, want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package i18n
+
+// PluralCategory — категория множественного числа CLDR
+type PluralCategory string
+
+const (
+	PluralOne   PluralCategory = "one"
+	PluralFew   PluralCategory = "few"
+	PluralMany  PluralCategory = "many"
+	PluralOther PluralCategory = "other"
+)
+
+// pluralRule выбирает категорию CLDR для целого числа
+type pluralRule func(n int) PluralCategory
+
+// pluralRussian — правило CLDR для русского языка (целые числа):
+// one — 1, 21, 101; few — 2-4, 22-24; many — 0, 5-20, 25-30, 11-14 в любой сотне
+func pluralRussian(n int) PluralCategory {
+	if n < 0 {
+		n = -n
+	}
+
+	mod10 := n % 10
+	mod100 := n % 100
+
+	switch {
+	case mod10 == 1 && mod100 != 11:
+		return PluralOne
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return PluralFew
+	default:
+		return PluralMany
+	}
+}
+
+// pluralEnglish — правило CLDR для английского языка (целые числа): one — 1, other — остальные
+func pluralEnglish(n int) PluralCategory {
+	if n == 1 || n == -1 {
+		return PluralOne
+	}
+	return PluralOther
+}
@@ -0,0 +1,410 @@
+package i18n
+
+var russian = &catalog{
+	name:       "Русский",
+	plural:     pluralRussian,
+	categories: []PluralCategory{PluralOne, PluralFew, PluralMany},
+	messages: map[string]string{
+		"error.generic":    "Произошла ошибка. Попробуй позже.",
+		"error.short":      "Произошла ошибка.",
+		"command.unknown":  "Неизвестная команда. Используй /help",
+		"callback.unknown": "Неизвестная команда. Используй /help для списка доступных команд.",
+		"text.unknown":     "Я не понимаю эту команду. Используй /help для списка доступных команд.",
+		"rate_limited":     "Слишком много запросов. Подожди немного и попробуй снова.",
+		"button.stale":     "Эта кнопка устарела. Открой список заново через /today",
+		"button.yes":       "Да",
+		"button.no":        "Нет",
+		"button.back":      "⬅️ Назад",
+
+		"require.registered": "Сначала зарегистрируйся с помощью команды /start",
+		"require.onenote":    "Сначала подключи OneNote с помощью команды /connect_onenote",
+		"require.notebook":   "Сначала выбери книгу OneNote с помощью команды /select_notebook",
+		"require.section":    "Сначала выбери секцию OneNote с помощью команды /select_section",
+
+		"auth.link":        "Для подключения OneNote перейди по ссылке:\n\n%s\n\nПосле авторизации отправь мне полученный код.",
+		"auth.expired":     "❌ Требуется повторная авторизация. Твой токен истёк.\n\nПерейди по ссылке для авторизации:\n\n%s\n\nПосле авторизации отправь мне полученный код.",
+		"auth.code_failed": "❌ Не удалось обработать код авторизации. Убедись, что код правильный и не истёк, и отправь его ещё раз или получи новый через /connect_onenote",
+		"auth.success":     "✅ Авторизация успешна!",
+		"auth.updated":     "✅ Авторизация обновлена!",
+
+		"start.greeting":     "Привет! 👋\n\nЯ помогу тебе изучать английский по системе интервальных повторений (SRS).",
+		"start.welcome_back": "С возвращением! Используй /today для начала занятий.",
+
+		"language.choose": "🌐 Выбери язык интерфейса:",
+		"language.set":    "✅ Язык интерфейса: %s",
+		"language.failed": "Не удалось сменить язык. Попробуй позже.",
+
+		"onboarding.level":       "Выбери свой уровень:",
+		"onboarding.max_pages":   "Выбери максимальное количество страниц в день для повторения:",
+		"onboarding.timezone":    "Теперь выбери свой город для установки таймзоны:",
+		"onboarding.section":     "Осталось выбрать секцию OneNote, из которой брать страницы:",
+		"onboarding.use_buttons": "Выбери вариант кнопкой в сообщении выше. Чтобы прервать, используй /cancel",
+
+		"register.done":       "✅ Регистрация завершена! Уровень установлен: %s",
+		"register.failed":     "Произошла ошибка при регистрации. Попробуй позже.",
+		"level.updated":       "✅ Уровень обновлён: %s\n\nТеперь подключи OneNote с помощью /connect_onenote",
+		"level.update_failed": "Произошла ошибка при обновлении уровня. Попробуй позже.",
+
+		"add_page.prompt":    "Отправь номер страницы или часть её заголовка, и я добавлю её в изучение. Чтобы отменить, используй /cancel",
+		"add_page.added":     "✅ Страница добавлена в изучение: %s\n\nОна появится в /today",
+		"add_page.not_found": "Не нашёл такую страницу в подключённых секциях. Отправь номер страницы или часть заголовка ещё раз или /cancel",
+		"add_page.already":   "Эта страница уже изучается. Отправь другую страницу или /cancel",
+		"add_page.failed":    "Не удалось добавить страницу. Попробуй позже.",
+
+		"answer.invalid": "Отправь результат числом от 0 до 100 (процент правильных ответов) или оцени кнопкой под страницей",
+
+		"cancel.nothing":    "Нечего отменять.",
+		"cancel.onboarding": "Настройка прервана. Продолжить можно командами /connect_onenote, /select_notebook и /select_section.",
+		"cancel.done":       "Отменено.",
+
+		"picker.root":            "📚 Книги",
+		"picker.choose_notebook": "Выбери книгу OneNote:",
+		"picker.choose_section":  "Выбери секцию для синхронизации или открой группу секций:",
+		"picker.empty":           "Здесь пусто.",
+		"picker.no_notebooks":    "У тебя нет доступных книг OneNote.",
+		"picker.load_failed":     "Не удалось получить список книг и секций OneNote. Попробуй позже.",
+		"picker.stale":           "Этот список устарел. Открой выбор заново через /select_notebook или /select_section",
+		"picker.save_failed":     "Не удалось сохранить выбранную секцию. Попробуй позже.",
+		"picker.section_added":   "✅ Секция добавлена:\n%s",
+		"picker.setup_done":      "✅ Секция OneNote добавлена!\n\nТеперь OneNote настроен. Все подключённые секции можно посмотреть через /sources.\n\nХочешь начать повторять уже сегодня?",
+		"notebooks.load_failed":  "Не удалось получить список книг OneNote. Попробуй позже.",
+
+		"sources.empty":         "У тебя пока нет подключённых секций. Выбери книгу через /select_notebook и добавь секцию через /select_section.",
+		"sources.title":         "📚 <b>Подключённые секции</b>\n\nНовые страницы берутся сначала из секций выше по списку.",
+		"sources.disable":       "Выключить",
+		"sources.enable":        "Включить",
+		"sources.invalid":       "Неверный выбор. Попробуй заново через /sources",
+		"sources.update_failed": "Не удалось изменить секцию. Попробуй заново через /sources",
+
+		"progress.interval": "📊 Прогресс: интервал %s (шаг %d из %d)",
+
+		"today.empty":     "🎉 Сегодня нет страниц для повторения!",
+		"today.title":     "📚 <b>Сегодня на повторение:</b>",
+		"today.new_page":  "📅 Новая страница",
+		"today.show_page": "Показать страницу %d",
+		"today.skip_all":  "Пропустить всё",
+
+		"session.progress":        "📊 Пройдено %d из %d",
+		"session.page":            "📖 Страница %d из %d",
+		"session.button.start":    "▶️ Начать повторение",
+		"session.button.continue": "▶️ Продолжить (%d из %d)",
+		"session.button.next":     "▶️ Следующая страница",
+		"session.summary.title":   "🏁 <b>Повторение на сегодня завершено!</b>",
+		"session.summary.graded":  "✅ Оценено страниц: %d из %d",
+		"session.summary.average": "📈 Средний результат: %d%%",
+
+		"stats.title":          "📊 <b>Статистика</b>",
+		"stats.empty":          "📊 Статистики пока нет: начни повторять страницы с /today.",
+		"stats.recent":         "📅 За %s: %s, %s",
+		"stats.pages":          "📚 В изучении: %d, изучено полностью: %d",
+		"stats.steps.title":    "<b>Шаги SRS</b> (страницы, вспоминаемость)",
+		"stats.step":           "Шаг %d, %s",
+		"stats.step.reading":   "Чтение",
+		"stats.step.line":      "• %s: %s, %s",
+		"stats.retention":      "%d%% (%d из %d)",
+		"stats.retention.none": "оценок ещё не было",
+		"stats.trend.title":    "<b>Средняя оценка по неделям</b>",
+		"stats.trend.week":     "%s: %d%% (%s)",
+		"stats.trend.empty":    "%s: —",
+		"stats.upcoming.title": "<b>Нагрузка на неделю</b>",
+		"stats.chart.caption":  "🟦 повторения по дням · 🟪 средняя оценка по неделям · 🟧 вспоминаемость по интервалу в днях · 🟩 повторения на ближайшие дни",
+
+		"history.button":          "📈 История",
+		"history.not_found":       "Эта страница больше не изучается.",
+		"history.title":           "📈 <b>История страницы</b>\n%s",
+		"history.passed":          "🎓 Страница изучена",
+		"history.summary":         "📝 %s, %s",
+		"history.ai_since":        "🤖 AI режим с %s",
+		"history.reading_mode":    "📖 Пока в режиме чтения",
+		"history.events.title":    "<b>Оценки</b>",
+		"history.events.empty":    "Страницу ещё не оценивали.",
+		"history.interval":        "%s → %s",
+		"history.mode.reading":    "чтение",
+		"history.mark.ai":         "🤖 переход в AI режим",
+		"history.mark.lapse":      "⚠️ срыв",
+		"history.forecast.title":  "<b>Что будет после оценки</b>",
+		"history.forecast.line":   "%s: %s, повторение %s",
+		"history.forecast.passed": "%s: страница будет изучена",
+		"history.words.line":      "• %s — %s",
+
+		"pages.empty":          "У тебя пока нет страниц, приходи завтра или используй /prepare_materials.",
+		"pages.title":          "📖 <b>Твои страницы:</b>",
+		"pages.next_review":    "📅 Следующее повторение: %s",
+		"pages.reviewed_today": "✅ Повторено сегодня",
+		"pages.history_button": "📈 %d",
+		"pages.history_hint":   "Нажми 📈 с номером страницы, чтобы посмотреть её историю и прогноз интервалов.",
+
+		"page.list_failed":     "Не удалось получить список страниц. Попробуй заново через /today",
+		"page.not_due":         "Эта страница уже не в списке на сегодня. Открой актуальный список через /today",
+		"page.content_failed":  "Не удалось получить содержимое страницы.",
+		"page.header":          "📄 <b>Страница</b>",
+		"page.reading_prompt":  "📖 Прочитай слова и оцени насколько хорошо их помнишь кнопкой или отправь процент от 0 до 100:",
+		"page.practice_prompt": "💡 Скопируй эту страницу и отправь в бота Poe для генерации задания.\n\nПосле прохождения задания отметь результат кнопкой или отправь процент правильных ответов от 0 до 100:",
+		"page.skip":            "↩️ Пропустить",
+
+		"grade.easy":   "✅ Easy (>80%)",
+		"grade.normal": "🟢 Normal (>60%)",
+		"grade.hard":   "🟡 Hard (>40%)",
+		"grade.forgot": "🔴 Forgot (<40%)",
+
+		"review.failed":    "Ошибка при обновлении прогресса.",
+		"review.forgot":    "🔴 Forgot! Повторим завтра.",
+		"review.saved":     "✅ Оценка сохранена.",
+		"review.duplicate": "✅ Эта оценка уже засчитана.",
+		"review.conflict":  "Эту страницу уже оценили в другом сообщении, оценка не применена. Открой актуальный список через /today",
+
+		"undo.button":  "↩️ Отменить оценку",
+		"undo.done":    "↩️ Оценка отменена, прогресс страницы восстановлен. Страница снова ждёт повторения сегодня.",
+		"undo.regrade": "📖 Оценить заново",
+		"undo.nothing": "Эту оценку уже не отменить: она уже отменена или страницу оценили снова.",
+		"undo.failed":  "Не удалось отменить оценку. Попробуй ещё раз.",
+
+		"skip.failed":   "Не удалось пропустить страницу. Попробуй позже.",
+		"skip.done":     "Хорошо, пропустим её на сегодня",
+		"skip_all.done": "Хорошо, пропускаем на сегодня. Увидимся завтра! 👋",
+
+		"prepare.warning":     "⚠️ Внимание! Эта команда добавляет материалы для повторения.\nНе рекомендуется использовать её часто, иначе материалы будут накапливаться и в будущем придётся повторять слишком много за один день.\n\nОбычно материалы подготавливаются автоматически в 00:00 каждый день.\n\nПодготавливаю материалы...",
+		"prepare.in_progress": "Подготавливаю материалы...",
+		"prepare.failed":      "Не удалось подготовить материалы. Попробуй позже.",
+		"prepare.done":        "✅ Материалы успешно подготовлены!",
+		"prepare.done_today":  "✅ Материалы успешно подготовлены! Используй /today для начала занятий.",
+		"prepare.later":       "Хорошо, используй /today когда будешь готов начать занятия.",
+
+		"settings.update_failed":    "Ошибка при обновлении настроек. Попробуй позже.",
+		"settings.invalid":          "❌ Некорректное значение. Попробуй ещё раз.",
+		"settings.title":            "⚙️ <b>Настройки</b>",
+		"settings.level":            "🎓 Уровень: %s",
+		"settings.max_pages":        "📊 Страниц в день: %d",
+		"settings.timezone":         "🌍 Таймзона: %s",
+		"settings.reminder":         "⏰ Напоминания: %s",
+		"settings.quiet_hours":      "🌙 Тихие часы: %s–%s",
+		"settings.language":         "🌐 Язык: %s",
+		"settings.saved":            "✅ Сохранено",
+		"settings.choose_reminder":  "⏰ Отметь время напоминаний, можно выбрать до %d в день.\nТихие часы настраиваются командой /set_reminder quiet 22:00-08:00",
+		"settings.button.level":     "🎓 Уровень",
+		"settings.button.max_pages": "📊 Страниц в день",
+		"settings.button.timezone":  "🌍 Таймзона",
+		"settings.button.reminder":  "⏰ Напоминания",
+		"settings.button.language":  "🌐 Язык",
+
+		"max_pages.choose":         "📊 Выбери максимальное количество страниц в день:\n\n2 страницы в день → добавляется 1 страница\n3 страницы в день → добавляется 1 (60%) или 2 (40%)\n4 страницы в день → добавляется 2 страницы",
+		"max_pages.usage":          "Использование: /set_max_pages <b>число</b>\n\nНапример: /set_max_pages 3\n\nРекомендуемые значения: 2, 3 или 4",
+		"max_pages.invalid":        "Некорректное значение. Используй число от 2 до 4.\n\n2 страницы в день → добавляется 1 страница\n3 страницы в день → добавляется 1 (60%) или 2 (40%)\n4 страницы в день → добавляется 2 страницы",
+		"max_pages.invalid_button": "❌ Некорректное значение. Попробуй ещё раз.",
+		"max_pages.set":            "✅ Максимальное количество страниц в день установлено: %d",
+		"max_pages.current":        "📊 Текущее максимальное количество страниц в день: %d",
+
+		"timezone.choose":        "🌍 Выбери свой город для установки таймзоны:",
+		"timezone.invalid":       "❌ Некорректная таймзона: %s",
+		"timezone.update_failed": "Ошибка при обновлении таймзоны. Попробуй позже.",
+		"timezone.set":           "✅ Таймзона установлена: %s",
+		"timezone.set_details":   "✅ Таймзона установлена: %s\n\nНовые материалы будут добавляться автоматически в 00:00 каждый день по твоему местному времени.",
+
+		"city.moscow":           "Москва",
+		"city.saint_petersburg": "Санкт-Петербург",
+		"city.kyiv":             "Киев",
+		"city.minsk":            "Минск",
+		"city.london":           "Лондон",
+		"city.paris":            "Париж",
+		"city.berlin":           "Берлин",
+		"city.rome":             "Рим",
+		"city.new_york":         "Нью-Йорк",
+		"city.los_angeles":      "Лос-Анджелес",
+		"city.chicago":          "Чикаго",
+		"city.toronto":          "Торонто",
+		"city.tokyo":            "Токио",
+		"city.beijing":          "Пекин",
+		"city.dubai":            "Дубай",
+		"city.tehran":           "Тегеран",
+		"city.delhi":            "Дели",
+		"city.sydney":           "Сидней",
+		"city.sao_paulo":        "Сан-Паулу",
+		"city.buenos_aires":     "Буэнос-Айрес",
+		"city.cairo":            "Каир",
+
+		"reminder.off":         "выключены",
+		"reminder.usage":       "Использование:\n/set_reminder 09:00 20:00 — время напоминаний, до %d в день\n/set_reminder off — выключить напоминания\n/set_reminder on — включить\n/set_reminder quiet 22:00-08:00 — тихие часы, в которые напоминания не приходят\n/set_reminder quiet off — убрать тихие часы",
+		"reminder.invalid":     "❌ Некорректное время. Укажи от 1 до %d значений в формате ЧЧ:ММ, например: /set_reminder 09:00 20:00",
+		"reminder.last_time":   "❌ Нужно оставить хотя бы одно время. Чтобы отключить напоминания, нажми «Выключить».",
+		"reminder.quiet_usage": "❌ Некорректные тихие часы. Пример: /set_reminder quiet 22:00-08:00",
+		"reminder.times_set":   "✅ Напоминания: %s",
+		"reminder.enabled":     "🔔 Напоминания включены: %s",
+		"reminder.disabled":    "🔕 Напоминания выключены. Включить: /set_reminder on",
+		"reminder.quiet_set":   "🌙 Тихие часы: %s–%s. В это время напоминания не приходят.",
+		"reminder.quiet_off":   "🌙 Тихие часы отключены.",
+		"reminder.button.on":   "🔔 Включить напоминания",
+		"reminder.button.off":  "🔕 Выключить напоминания",
+
+		"vacation.usage":     "Использование:\n/vacation 2026-07-01 2026-07-14 — отпуск с первой даты по вторую включительно, не дольше %d дней\n/vacation 2026-07-01 2026-07-14 spread — то же, но каждое повторение сдвигается на длину отпуска, промежутки между повторениями сохраняются\n/vacation off — отменить отпуск\n\nВо время отпуска не приходят напоминания, не добавляются новые страницы и не сбрасываются интервалы за неактивность. Повторения, выпадающие на отпуск, переносятся на дни после него — вместе с уже назначенными не больше лимита страниц в день.",
+		"vacation.invalid":   "❌ Некорректный отпуск: даты должны быть не раньше сегодняшней, конец — не раньше начала, длина — не больше %d дней.",
+		"vacation.none":      "🏖 Отпуск не запланирован.",
+		"vacation.current":   "🏖 Отпуск: %s – %s",
+		"vacation.set":       "🏖 Отпуск с %s по %s. Хорошего отдыха!",
+		"vacation.cancelled": "🏖 Отпуск отменён. Перенесённые повторения остаются на новых датах.",
+
+		"comeback.pace":          "📅 План: по %d в день, %s",
+		"comeback.today":         "Сегодня",
+		"comeback.hint":          "Первыми идут страницы, которые легче всего забыть: дольше всего просроченные, с низкой оценкой и коротким интервалом. Интервалы не сбрасываются, меняются только даты повторения.",
+		"comeback.nothing":       "✅ Накопившихся повторений нет, всё помещается в сегодняшний день.",
+		"comeback.changed":       "⚠️ План изменился с тех пор, как его показали. Вот обновлённый:",
+		"comeback.kept":          "👌 Все повторения остаются на сегодня. Начни с /today",
+		"comeback.button.accept": "✅ Принять план",
+		"comeback.button.slower": "➖ Медленнее",
+		"comeback.button.faster": "➕ Быстрее",
+		"comeback.button.keep":   "Оставить всё на сегодня",
+
+		"sync.failed":     "Не удалось синхронизировать страницы. Попробуй позже.",
+		"sync.title":      "🔄 <b>Синхронизация завершена</b>",
+		"sync.added":      "➕ Новые",
+		"sync.changed":    "✏️ Изменённые",
+		"sync.moved":      "🔀 Перенесённые",
+		"sync.removed":    "🗑 Удалённые",
+		"sync.more":       "… и ещё %d",
+		"sync.no_changes": "Изменений нет, все страницы актуальны.",
+
+		"help": `📚 <b>Master English SRS</b>
+
+Доступные команды:
+
+/start - Начать работу с ботом
+/connect_onenote - Подключить OneNote
+/select_notebook - Выбрать книгу OneNote для синхронизации
+/select_section - Добавить секцию OneNote для синхронизации
+/sources - Подключённые секции: включение, приоритет, удаление
+
+/today - Показать страницы на сегодня
+/comeback - Распределить накопившиеся повторения по дням
+/pages - Список всех страниц
+/stats - Статистика обучения и графики
+/set_max_pages - Установить максимальное количество страниц в день на повторение
+/get_max_pages - Показать текущее максимальное количество страниц в день для повторения
+/prepare_materials - Подгрузить дополнительную страницу на сегодня
+/add_page - Добавить в изучение страницу по номеру или заголовку
+/sync - Синхронизировать страницы с OneNote и показать изменения
+/set_timezone - Установить таймзону (например, /set_timezone Europe/Moscow)
+/set_reminder - Время напоминаний, тихие часы, включение и выключение
+/vacation - Отпуск: без напоминаний и новых страниц, повторения переносятся
+/language - Сменить язык интерфейса
+/settings - Все настройки: уровень, лимит страниц, таймзона, напоминания, язык
+
+/cancel - Отменить текущее действие
+/help - Справка`,
+	},
+	plurals: map[string]map[PluralCategory]string{
+		"interval.days": {
+			PluralOne:  "%d день",
+			PluralFew:  "%d дня",
+			PluralMany: "%d дней",
+		},
+		"today.last_review": {
+			PluralOne:  "📅 Последнее повторение: %d день назад",
+			PluralFew:  "📅 Последнее повторение: %d дня назад",
+			PluralMany: "📅 Последнее повторение: %d дней назад",
+		},
+		"review.easy": {
+			PluralOne:  "✅ Easy! Следующее повторение через %d день.",
+			PluralFew:  "✅ Easy! Следующее повторение через %d дня.",
+			PluralMany: "✅ Easy! Следующее повторение через %d дней.",
+		},
+		"review.normal": {
+			PluralOne:  "🟢 Normal! Следующее повторение через %d день.",
+			PluralFew:  "🟢 Normal! Следующее повторение через %d дня.",
+			PluralMany: "🟢 Normal! Следующее повторение через %d дней.",
+		},
+		"review.hard": {
+			PluralOne:  "🟡 Hard! Следующее повторение через %d день.",
+			PluralFew:  "🟡 Hard! Следующее повторение через %d дня.",
+			PluralMany: "🟡 Hard! Следующее повторение через %d дней.",
+		},
+		"reminder.due": {
+			PluralOne:  "🔔 Пора повторить! У тебя %d страница на повторение сегодня.\nИспользуй /today для начала.",
+			PluralFew:  "🔔 Пора повторить! У тебя %d страницы на повторение сегодня.\nИспользуй /today для начала.",
+			PluralMany: "🔔 Пора повторить! У тебя %d страниц на повторение сегодня.\nИспользуй /today для начала.",
+		},
+		"undo.expired": {
+			PluralOne:  "Оценку можно отменить только в течение %d минуты.",
+			PluralFew:  "Оценку можно отменить только в течение %d минут.",
+			PluralMany: "Оценку можно отменить только в течение %d минут.",
+		},
+		"stats.streak": {
+			PluralOne:  "🔥 Серия: %d день подряд",
+			PluralFew:  "🔥 Серия: %d дня подряд",
+			PluralMany: "🔥 Серия: %d дней подряд",
+		},
+		"history.words.title": {
+			PluralOne:  "<b>Слова страницы</b>: %d слово",
+			PluralFew:  "<b>Слова страницы</b>: %d слова",
+			PluralMany: "<b>Слова страницы</b>: %d слов",
+		},
+		"count.reviews": {
+			PluralOne:  "%d повторение",
+			PluralFew:  "%d повторения",
+			PluralMany: "%d повторений",
+		},
+		"count.active_days": {
+			PluralOne:  "%d активный день",
+			PluralFew:  "%d активных дня",
+			PluralMany: "%d активных дней",
+		},
+		"count.pages": {
+			PluralOne:  "%d страница",
+			PluralFew:  "%d страницы",
+			PluralMany: "%d страниц",
+		},
+		"count.grades": {
+			PluralOne:  "%d оценка",
+			PluralFew:  "%d оценки",
+			PluralMany: "%d оценок",
+		},
+		"count.lapses": {
+			PluralOne:  "%d срыв",
+			PluralFew:  "%d срыва",
+			PluralMany: "%d срывов",
+		},
+		"stats.total": {
+			PluralOne:  "📝 За год %d повторение",
+			PluralFew:  "📝 За год %d повторения",
+			PluralMany: "📝 За год %d повторений",
+		},
+		"history.events.more": {
+			PluralOne:  "… и ещё %d более ранняя оценка",
+			PluralFew:  "… и ещё %d более ранние оценки",
+			PluralMany: "… и ещё %d более ранних оценок",
+		},
+		"history.words.more": {
+			PluralOne:  "… и ещё %d слово",
+			PluralFew:  "… и ещё %d слова",
+			PluralMany: "… и ещё %d слов",
+		},
+		"session.summary.skipped": {
+			PluralOne:  "↩️ Пропущена %d страница",
+			PluralFew:  "↩️ Пропущено %d страницы",
+			PluralMany: "↩️ Пропущено %d страниц",
+		},
+		"vacation.shifted": {
+			PluralOne:  "📅 %d повторение перенесено на первые дни после отпуска",
+			PluralFew:  "📅 %d повторения перенесены на первые дни после отпуска",
+			PluralMany: "📅 %d повторений перенесено на первые дни после отпуска",
+		},
+		"vacation.spread": {
+			PluralOne:  "📅 %d повторение сдвинуто на длину отпуска",
+			PluralFew:  "📅 %d повторения сдвинуты на длину отпуска",
+			PluralMany: "📅 %d повторений сдвинуто на длину отпуска",
+		},
+		"comeback.title": {
+			PluralOne:  "👋 С возвращением! Накопилось %d повторение.",
+			PluralFew:  "👋 С возвращением! Накопилось %d повторения.",
+			PluralMany: "👋 С возвращением! Накопилось %d повторений.",
+		},
+		"comeback.more_days": {
+			PluralOne:  "… и ещё %d день",
+			PluralFew:  "… и ещё %d дня",
+			PluralMany: "… и ещё %d дней",
+		},
+		"comeback.accepted": {
+			PluralOne:  "✅ План принят: по %d странице в день, %s. Начни с /today",
+			PluralFew:  "✅ План принят: по %d страницы в день, %s. Начни с /today",
+			PluralMany: "✅ План принят: по %d страниц в день, %s. Начни с /today",
+		},
+	},
+}
@@ -17,19 +17,57 @@ type Repository interface {
 	UpdateOneNoteConfig(ctx context.Context, telegramID int64, config *OneNoteConfig) error
 	UpdateMaxPagesPerDay(ctx context.Context, telegramID int64, maxPages uint) error
 	UpdateUserTimezone(ctx context.Context, telegramID int64, timezone string) error
-	GetAllUsersWithReminders(ctx context.Context) ([]*User, error)
+	GetUserLanguage(ctx context.Context, telegramID int64) (string, error)
+	UpdateUserLanguage(ctx context.Context, telegramID int64, language string) error
 	RunInTx(ctx context.Context, fn func(Repository) error) error
 
+	GetReminderTimes(ctx context.Context, userID int64) ([]string, error)
+	ReplaceReminderTimes(ctx context.Context, userID int64, times []string) error
+	SetRemindersEnabled(ctx context.Context, userID int64, enabled bool) error
+	UpdateQuietHours(ctx context.Context, userID int64, start, end *string) error
+	GetReminderSchedules(ctx context.Context, userID int64) ([]*ReminderSchedule, error)
+	ClaimReminder(ctx context.Context, userID int64, localDate time.Time, reminderTime string, now, claimedUntil time.Time) (bool, error)
+	MarkReminderSent(ctx context.Context, userID int64, localDate time.Time, reminderTime string) error
+	DeleteReminderLog(ctx context.Context, userID int64, localDate time.Time, reminderTime string) error
+
+	AddUserSource(ctx context.Context, source *UserSource) error
+	GetUserSources(ctx context.Context, userID int64, onlyEnabled bool) ([]*UserSource, error)
+	SetUserSourceEnabled(ctx context.Context, userID, sourceID int64, enabled bool) error
+	UpdateUserSourcePriority(ctx context.Context, userID, sourceID int64, priority int) error
+	DeleteUserSource(ctx context.Context, userID, sourceID int64) error
+	UpdateUserSourceSyncCursor(ctx context.Context, userID, sourceID int64, cursor time.Time) error
+	UpdateUserSourceFullSync(ctx context.Context, userID, sourceID int64, fullSyncedAt time.Time) error
+	UpdateUserSourceNotebookName(ctx context.Context, userID, sourceID int64, notebookName string) error
+
+	GetChatState(ctx context.Context, chatID int64) (*ChatState, error)
+	SaveChatState(ctx context.Context, state *ChatState) error
+
+	SaveCallbackTokens(ctx context.Context, tokens []*CallbackToken) error
+	GetCallbackToken(ctx context.Context, userID int64, token string, now time.Time, forUpdate bool) (*CallbackToken, error)
+	DeletePageCallbackTokens(ctx context.Context, userID int64, pageID string, keepActions []string) error
+	DeleteExpiredCallbackTokens(ctx context.Context, userID int64, now time.Time) error
+
 	CreatePageReference(ctx context.Context, page *PageReference) error
 	GetPageReference(ctx context.Context, pageID string, userID int64) (*PageReference, error)
 	GetUserPagesInProgress(ctx context.Context, userID int64) ([]*PageReference, error)
 	DeleteUserPages(ctx context.Context, userID int64) error
 	UpsertPageReference(ctx context.Context, page *PageReference) error
+	UpsertPageReferences(ctx context.Context, pages []*PageReference) error
+	MarkPageReferencesDeleted(ctx context.Context, userID int64, pageIDs []string, deletedAt time.Time) error
+	MovePageReference(ctx context.Context, userID int64, oldPageID, newPageID string) error
+	ReplacePageItems(ctx context.Context, userID int64, pageID string, items []*PageItem) error
+	GetPageItems(ctx context.Context, userID int64, pageID string) ([]*PageItem, error)
+	GetPagesWithoutItems(ctx context.Context, userID int64, limit int) ([]string, error)
+	MarkPageItemsExtracted(ctx context.Context, userID int64, pageID string, extractedAt time.Time) error
 
 	CreateProgress(ctx context.Context, progress *UserProgress) error
 	GetProgress(ctx context.Context, userID int64, pageID string) (*UserProgress, error)
-	UpdateProgress(ctx context.Context, userID int64, pageID string, level string, repetitionCount int, lastReviewDate, nextReviewDate time.Time, intervalDays int, reviewedToday bool, passed bool) error
+	UpdateProgress(ctx context.Context, userID int64, pageID string, version int, level string, repetitionCount int, lastReviewDate, nextReviewDate time.Time, intervalDays int, reviewedToday bool, passed bool) (bool, error)
 	AddProgressHistory(ctx context.Context, userID int64, pageID string, history ProgressHistory) error
+	ReviewKeyExists(ctx context.Context, userID int64, reviewKey string) (bool, error)
+	GetLastProgressHistory(ctx context.Context, userID int64, pageID string) (*ProgressHistory, error)
+	GetProgressHistory(ctx context.Context, userID int64, pageID string) ([]*ProgressHistory, error)
+	MarkProgressHistoryUndone(ctx context.Context, userID, historyID int64, undoneAt time.Time) error
 	GetDuePagesToday(ctx context.Context, userID int64, endOfDayUTC time.Time) ([]*UserProgress, error)
 	GetAllProgressPageIDs(ctx context.Context, userID int64) ([]string, error)
 	GetPageIDsNotInProgress(ctx context.Context, userID int64, pageIDs []string) ([]string, error)
@@ -37,39 +75,113 @@ type Repository interface {
 	ResetReviewedTodayFlag(ctx context.Context, userID int64) error
 	GetLastReviewScore(ctx context.Context, userID int64, pageID string) (int, error)
 	DeleteProgress(ctx context.Context, userID int64, pageID string) error
+	GetProgressDueBetween(ctx context.Context, userID int64, fromUTC, toUTC time.Time) ([]*UserProgress, error)
+	UpdateNextReviewDate(ctx context.Context, userID int64, pageID string, nextReviewDate time.Time) error
+
+	GetDailyReviews(ctx context.Context, userID int64, timezone string, fromUTC time.Time) ([]*DailyReviews, error)
+	GetIntervalRetention(ctx context.Context, userID int64) ([]*IntervalRetention, error)
+	GetPagesPerInterval(ctx context.Context, userID int64) ([]*IntervalPages, error)
+	CountPassedPages(ctx context.Context, userID int64) (int, error)
+	GetUpcomingLoad(ctx context.Context, userID int64, timezone string, toUTC time.Time) ([]*DailyLoad, error)
+
+	GetReviewSession(ctx context.Context, userID int64, localDate time.Time) (*ReviewSession, error)
+	CreateReviewSession(ctx context.Context, session *ReviewSession) error
+	AddReviewSessionItems(ctx context.Context, sessionID int64, position int, pageIDs []string) error
+	SetReviewSessionItemResult(ctx context.Context, sessionID int64, pageID, result string, grade *int, reviewedAt time.Time) (bool, error)
+	ResetReviewSessionItemResult(ctx context.Context, sessionID int64, pageID string) error
+	UpdateReviewSessionStatus(ctx context.Context, sessionID int64, status string, finishedAt *time.Time) error
+	FinishReviewSessionsBefore(ctx context.Context, userID int64, localDate, finishedAt time.Time) error
 
 	UpdateUserActivity(ctx context.Context, userID int64, activityDate time.Time) error
 	SetUserPaused(ctx context.Context, userID int64, paused bool) error
-	GetUsersWithoutActivityAfter(ctx context.Context, afterTime time.Time, excludePaused bool) ([]*User, error)
-
-	ResetIntervalForPagesDueInMonth(ctx context.Context, userID int64, tomorrowUTC, monthFromNowUTC time.Time) error
+	UpdateVacation(ctx context.Context, userID int64, start, end *time.Time) error
 	UpdateLastCronProcessedAt(ctx context.Context, userID int64, processedAt time.Time) error
-	TryProcessDailyCronForUser(ctx context.Context, userID int64, startOfTodayUTC time.Time) (bool, error)
+
+	ScheduleJob(ctx context.Context, userID int64, jobType string, nextRunAt time.Time) error
+	ClaimJobs(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*Job, error)
+	FinishJob(ctx context.Context, job *Job) (bool, error)
+	AddJobRun(ctx context.Context, run *JobRun) error
+	DeleteJobRunsBefore(ctx context.Context, before time.Time) error
 }
 
 type Service interface {
-	RegisterUser(ctx context.Context, telegramID int64, username, level string) error
+	RegisterUser(ctx context.Context, telegramID int64, username, level, language string) error
 	GetUser(ctx context.Context, telegramID int64) (*User, error)
 	UserExists(ctx context.Context, telegramID int64) (bool, error)
 	UpdateUserLevel(ctx context.Context, telegramID int64, level string) error
-	GetAllUsersForReminders(ctx context.Context) ([]*User, error)
 
 	GetAuthURL(telegramID int64) string
 	ExchangeAuthCode(ctx context.Context, telegramID int64, code string) error
 
 	GetOneNoteNotebooks(ctx context.Context, telegramID int64) ([]onenote.Notebook, error)
 	GetOneNoteSections(ctx context.Context, telegramID int64, notebookID string) ([]onenote.Section, error)
+	GetOneNoteNotebookContents(ctx context.Context, telegramID int64, notebookID string) ([]onenote.SectionGroup, []onenote.Section, error)
+	GetOneNoteSectionGroupContents(ctx context.Context, telegramID int64, sectionGroupID string) ([]onenote.SectionGroup, []onenote.Section, error)
 	SaveOneNoteConfig(ctx context.Context, telegramID int64, notebookID, sectionID string) error
+	SyncPages(ctx context.Context, telegramID int64) (*SyncReport, error)
+	AddPageManually(ctx context.Context, telegramID int64, query string) (*PageReference, error)
+
+	GetChatState(ctx context.Context, chatID int64) (*ChatState, error)
+	SetChatState(ctx context.Context, chatID, telegramID int64, state string, data map[string]string) error
+
+	SaveCallbackTokens(ctx context.Context, telegramID int64, tokens []*CallbackToken) error
+	GetCallbackToken(ctx context.Context, telegramID int64, token string) (*CallbackToken, error)
+	ConsumeCallbackToken(ctx context.Context, telegramID int64, token string) (*CallbackToken, error)
+	AddSource(ctx context.Context, telegramID int64, notebookID, notebookName, sectionID, sectionName string) error
+	GetSources(ctx context.Context, telegramID int64) ([]*UserSource, error)
+	ToggleSource(ctx context.Context, telegramID, sourceID int64) error
+	MoveSourceUp(ctx context.Context, telegramID, sourceID int64) error
+	RemoveSource(ctx context.Context, telegramID, sourceID int64) error
 
 	GetDuePagesToday(ctx context.Context, telegramID int64) ([]*PageWithProgress, error)
+	StartReviewSession(ctx context.Context, telegramID int64) (*ReviewSession, []*PageWithProgress, error)
+	GetReviewSession(ctx context.Context, telegramID int64) (*ReviewSession, error)
+	GetStats(ctx context.Context, telegramID int64) (*UserStats, error)
 	GetUserAllPagesInProgress(ctx context.Context, telegramID int64) ([]*PageReference, error)
 	GetPageContent(ctx context.Context, telegramID int64, pageID string) (string, error)
-	UpdateReviewProgress(ctx context.Context, telegramID int64, pageID string, grade int) error
+	GetPageItems(ctx context.Context, telegramID int64, pageID string) ([]*PageItem, error)
+	UpdateReviewProgress(ctx context.Context, telegramID int64, submission ReviewSubmission) error
 	UpdateMaxPagesPerDay(ctx context.Context, telegramID int64, maxPages uint) error
 	UpdateUserTimezone(ctx context.Context, telegramID int64, timezone string) error
+	GetReminderTimes(ctx context.Context, telegramID int64) ([]string, error)
+	SetReminderTimes(ctx context.Context, telegramID int64, times []string) error
+	ToggleReminderTime(ctx context.Context, telegramID int64, reminderTime string) error
+	SetRemindersEnabled(ctx context.Context, telegramID int64, enabled bool) error
+	SetQuietHours(ctx context.Context, telegramID int64, start, end string) error
+	DisableQuietHours(ctx context.Context, telegramID int64) error
+	ClaimDueReminders(ctx context.Context, telegramID int64, now time.Time) ([]*DueReminder, error)
+	MarkRemindersSent(ctx context.Context, reminders []*DueReminder) error
+	ReleaseReminder(ctx context.Context, reminder *DueReminder) error
+	SetVacation(ctx context.Context, telegramID int64, start, end time.Time, spread bool) (int, error)
+	CancelVacation(ctx context.Context, telegramID int64) error
+	SuggestComebackPlan(ctx context.Context, telegramID int64) (*ComebackPlan, error)
+	GetComebackPlan(ctx context.Context, telegramID int64, pagesPerDay int) (*ComebackPlan, error)
+	ApplyComebackPlan(ctx context.Context, telegramID int64, pagesPerDay int, fingerprint string) (*ComebackPlan, error)
+	DismissComebackPlan(ctx context.Context, telegramID int64) error
+	GetUserLanguage(ctx context.Context, telegramID int64) (string, error)
+	UpdateUserLanguage(ctx context.Context, telegramID int64, language string) error
 	GetProgress(ctx context.Context, telegramID int64, pageID string) (*UserProgress, error)
 	GetLastReviewScore(ctx context.Context, telegramID int64, pageID string) (int, error)
+	GetPageHistory(ctx context.Context, telegramID int64, pageID string, grades []int) (*PageHistory, error)
 	SkipPage(ctx context.Context, userID int64, pageID string) error
-	RunDailyCron(ctx context.Context) error
+	UndoLastGrade(ctx context.Context, telegramID int64, pageID, reviewKey string) error
+
+	ClaimJobs(ctx context.Context, now time.Time) ([]*Job, error)
+	CompleteJob(ctx context.Context, job *Job, startedAt time.Time, runErr error) error
+	RunRollover(ctx context.Context, telegramID int64) error
+	CheckInactivity(ctx context.Context, telegramID int64) error
+	PruneJobHistory(ctx context.Context) error
 	PrepareMaterials(ctx context.Context, telegramID int64) error
 }
+
+// OneNoteClient — запросы сервиса к OneNote; реализуется onenote.Client
+type OneNoteClient interface {
+	GetNotebooks(accessToken string) ([]onenote.Notebook, error)
+	GetSections(accessToken, notebookID string) ([]onenote.Section, error)
+	GetSectionGroups(accessToken, notebookID string) ([]onenote.SectionGroup, error)
+	GetChildSectionGroups(accessToken, sectionGroupID string) ([]onenote.SectionGroup, error)
+	GetSectionGroupSections(accessToken, sectionGroupID string) ([]onenote.Section, error)
+	GetPages(accessToken, sectionID string) ([]onenote.Page, error)
+	GetPagesModifiedSince(accessToken, sectionID string, since time.Time) ([]onenote.Page, error)
+	GetPageHTML(accessToken, pageID string) (string, error)
+}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+)
 
 type User struct {
 	TelegramID     int64          `db:"telegram_id"`
@@ -9,20 +14,31 @@ type User struct {
 	OneNoteAuth    *OneNoteAuth   `db:"-"`
 	OneNoteConfig  *OneNoteConfig `db:"-"`
 	UseManualPages bool           `db:"use_manual_pages"`
-	ReminderTime   string         `db:"reminder_time"`
+	Language       string         `db:"language"`
 	CreatedAt      time.Time      `db:"created_at"`
 
+	// RemindersEnabled выключает все напоминания пользователя, не удаляя выбранное время
+	RemindersEnabled bool `db:"reminders_enabled"`
+	// QuietHoursStart и QuietHoursEnd — тихие часы "HH:MM" по местному времени, в которые напоминания не отправляются
+	QuietHoursStart *string `db:"quiet_hours_start"`
+	QuietHoursEnd   *string `db:"quiet_hours_end"`
+
+	// VacationStart и VacationEnd — отпуск по местным датам пользователя, оба дня включительно. Во время отпуска
+	// не приходят напоминания, не добавляются новые страницы и не действуют штрафы за неактивность.
+	VacationStart *time.Time `db:"vacation_start"`
+	VacationEnd   *time.Time `db:"vacation_end"`
+
 	AccessToken         *string    `db:"onenote_access_token"`
 	RefreshToken        *string    `db:"onenote_refresh_token"`
 	ExpiresAt           *time.Time `db:"onenote_expires_at"`
 	AuthCode            *string    `db:"onenote_auth_code"`
 	NotebookID          *string    `db:"onenote_notebook_id"`
 	SectionID           *string    `db:"onenote_section_id"`
-	MaxPagesPerDay         *uint      `db:"max_pages_per_day"`
-	IsPaused               *bool      `db:"is_paused"`
-	LastActivityDate       *time.Time `db:"last_activity_date"`
-	Timezone               *string    `db:"timezone"`
-	LastCronProcessedAt    *time.Time `db:"last_cron_processed_at"`
+	MaxPagesPerDay      *uint      `db:"max_pages_per_day"`
+	IsPaused            *bool      `db:"is_paused"`
+	LastActivityDate    *time.Time `db:"last_activity_date"`
+	Timezone            *string    `db:"timezone"`
+	LastCronProcessedAt *time.Time `db:"last_cron_processed_at"`
 }
 
 type OneNoteAuth struct {
@@ -36,36 +52,381 @@ type OneNoteConfig struct {
 	SectionID  string
 }
 
+// UserSource — подписка пользователя на секцию OneNote, у пользователя может быть несколько секций из разных книг
+type UserSource struct {
+	ID           int64      `db:"id"`
+	UserID       int64      `db:"user_id"`
+	NotebookID   string     `db:"notebook_id"`
+	SectionID    string     `db:"section_id"`
+	NotebookName string     `db:"notebook_name"`
+	SectionName  string     `db:"section_name"`
+	Enabled      bool       `db:"enabled"`
+	Priority     int        `db:"priority"`
+	SyncCursor   *time.Time `db:"sync_cursor"`
+	FullSyncedAt *time.Time `db:"full_synced_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// ReminderSchedule — время напоминания пользователя вместе с настройками, нужными планировщику
+type ReminderSchedule struct {
+	UserID          int64      `db:"user_id"`
+	ReminderTime    string     `db:"reminder_time"`
+	Language        string     `db:"language"`
+	Timezone        *string    `db:"timezone"`
+	QuietHoursStart *string    `db:"quiet_hours_start"`
+	QuietHoursEnd   *string    `db:"quiet_hours_end"`
+	VacationStart   *time.Time `db:"vacation_start"`
+	VacationEnd     *time.Time `db:"vacation_end"`
+}
+
+// DueReminder — напоминание, занятое для отправки на локальную дату пользователя
+type DueReminder struct {
+	UserID       int64
+	ReminderTime string
+	LocalDate    time.Time
+	Language     string
+}
+
+// Типы фоновых задач пользователя
+const (
+	// JobRollover — смена дня в полночь по местному времени: синхронизация, сброс отметок, новые страницы
+	JobRollover = "rollover"
+	// JobReminder — отправка напоминаний, запускается в ближайшее время напоминания
+	JobReminder = "reminder"
+	// JobInactivity — раз в день приостанавливает неактивного пользователя
+	JobInactivity = "inactivity"
+)
+
+// JobTypes — все типы задач, которые создаются для пользователя при регистрации
+var JobTypes = []string{JobRollover, JobReminder, JobInactivity}
+
+// Job — фоновая задача пользователя. Пока задача выполняется, она занята до LockedUntil,
+// после сбоя процесса её подхватит другой процесс.
+type Job struct {
+	ID          int64      `db:"id"`
+	UserID      int64      `db:"user_id"`
+	JobType     string     `db:"job_type"`
+	NextRunAt   time.Time  `db:"next_run_at"`
+	Attempts    int        `db:"attempts"`
+	LastError   *string    `db:"last_error"`
+	LockedUntil *time.Time `db:"locked_until"`
+}
+
+// Статусы запуска задачи в истории
+const (
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// JobRun — запись истории запусков задачи
+type JobRun struct {
+	ID         int64     `db:"id"`
+	JobID      int64     `db:"job_id"`
+	UserID     int64     `db:"user_id"`
+	JobType    string    `db:"job_type"`
+	Attempt    int       `db:"attempt"`
+	Status     string    `db:"status"`
+	Error      *string   `db:"error"`
+	StartedAt  time.Time `db:"started_at"`
+	FinishedAt time.Time `db:"finished_at"`
+}
+
 type PageReference struct {
 	PageID    string     `db:"page_id"`
 	UserID    int64      `db:"user_id"`
 	Title     string     `db:"title"`
 	Source    string     `db:"source"`
+	SectionID string     `db:"section_id"`
 	CreatedAt time.Time  `db:"created_at"`
 	UpdatedAt *time.Time `db:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+// SyncReport — результат синхронизации страниц с OneNote, списки содержат заголовки страниц
+type SyncReport struct {
+	Added   []string
+	Changed []string
+	Moved   []string
+	Removed []string
+}
+
+// PageItem — отдельное слово из словарной таблицы страницы, дочерняя запись page_references
+type PageItem struct {
+	ID          int64      `db:"id"`
+	UserID      int64      `db:"user_id"`
+	PageID      string     `db:"page_id"`
+	Position    int        `db:"position"`
+	Word        string     `db:"word"`
+	Translation string     `db:"translation"`
+	Example     string     `db:"example"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at"`
+}
+
+// Действия кнопок, ссылающихся на страницу через токен
+const (
+	CallbackActionShowPage = "show"
+	CallbackActionGrade    = "grade"
+	CallbackActionSkipPage = "skip"
+	CallbackActionUndo     = "undo"
+	CallbackActionHistory  = "history"
+)
+
+// CallbackToken — действие над страницей, на которое ссылается кнопка. Вместо позиции в списке,
+// который пересчитывается после каждой оценки, кнопка несёт токен, однозначно указывающий на пользователя, страницу и действие.
+type CallbackToken struct {
+	Token     string    `db:"token"`
+	UserID    int64     `db:"user_id"`
+	PageID    string    `db:"page_id"`
+	Action    string    `db:"action"`
+	Grade     int       `db:"grade"`
+	Version   int       `db:"version"`    // версия прогресса, показанная пользователю вместе с кнопкой оценки
+	ReviewKey string    `db:"review_key"` // ключ оценки, которую отменяет кнопка отмены
+	IssuedAt  time.Time `db:"issued_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// Состояния диалога с пользователем. Onboarding-состояния идут строго по порядку,
+// остальные ожидают от пользователя свободный текст определённого вида.
+const (
+	ChatStateIdle               = "idle"
+	ChatStateOnboardingLanguage = "onboarding_language"
+	ChatStateOnboardingLevel    = "onboarding_level"
+	ChatStateOnboardingMaxPages = "onboarding_max_pages"
+	ChatStateOnboardingTimezone = "onboarding_timezone"
+	ChatStateOnboardingAuthCode = "onboarding_auth_code"
+	ChatStateOnboardingSection  = "onboarding_section"
+	ChatStateAwaitingAuthCode   = "awaiting_auth_code"
+	ChatStateAwaitingManualPage = "awaiting_manual_page"
+	ChatStateAwaitingAnswer     = "awaiting_answer"
+)
+
+// ChatState — текущее состояние диалога в чате и данные шага (например, ID страницы, на которую ждём ответ)
+type ChatState struct {
+	ChatID    int64
+	UserID    int64
+	State     string
+	Data      map[string]string
+	UpdatedAt time.Time
 }
 
+// UserProgress — прогресс изучения страницы. В истории прогресса он хранится в jsonb под ключами json-тегов,
+// по ним же статистика читает состояние до оценки, поэтому ключи менять нельзя.
 type UserProgress struct {
-	UserID          int64     `db:"user_id"`
-	PageID          string    `db:"page_id"`
-	Level           string    `db:"level"`
-	RepetitionCount int       `db:"repetition_count"`
-	LastReviewDate  time.Time `db:"last_review_date"`
-	NextReviewDate  time.Time `db:"next_review_date"`
-	IntervalDays    int       `db:"interval_days"`
-	SuccessRate     int       `db:"success_rate"`
-	ReviewedToday   bool      `db:"reviewed_today"`
-	Passed          bool      `db:"passed"`
+	UserID          int64     `db:"user_id" json:"UserID"`
+	PageID          string    `db:"page_id" json:"PageID"`
+	Level           string    `db:"level" json:"Level"`
+	RepetitionCount int       `db:"repetition_count" json:"RepetitionCount"`
+	LastReviewDate  time.Time `db:"last_review_date" json:"LastReviewDate"`
+	NextReviewDate  time.Time `db:"next_review_date" json:"NextReviewDate"`
+	IntervalDays    int       `db:"interval_days" json:"IntervalDays"`
+	SuccessRate     int       `db:"success_rate" json:"SuccessRate"`
+	ReviewedToday   bool      `db:"reviewed_today" json:"ReviewedToday"`
+	Passed          bool      `db:"passed" json:"Passed"`
+	Version         int       `db:"version" json:"Version"` // растёт при каждой оценке, её отмене и переносе даты повторения
 }
 
+// Источники оценки в истории прогресса
+const (
+	ReviewSourceButton = "button"
+	ReviewSourceTyped  = "typed"
+)
+
+// ProgressHistory — событие оценки страницы. По состояниям до и после оценки историю можно воспроизвести
+// без user_progress; отменённые оценки (UndoneAt != nil) при воспроизведении пропускаются.
 type ProgressHistory struct {
+	ID    int64     `db:"id"`
 	Date  time.Time `db:"date"`
 	Score int       `db:"score"`
 	Mode  string    `db:"mode"`
 	Notes string    `db:"notes"`
+	// Before и After — прогресс страницы до и после оценки, по Before оценка отменяется. nil у старых записей.
+	Before *UserProgress `db:"-"`
+	After  *UserProgress `db:"-"`
+	// ResponseTime — время от показа страницы до оценки, nil — неизвестно
+	ResponseTime *time.Duration `db:"-"`
+	// Source — откуда пришла оценка: ReviewSourceButton или ReviewSourceTyped; пустой у старых записей
+	Source string `db:"source"`
+	// UndoneAt — когда оценка отменена, nil — оценка действует
+	UndoneAt *time.Time `db:"undone_at"`
+	// ReviewKey — ключ идемпотентности оценки, пустой у записей до его появления
+	ReviewKey string `db:"review_key"`
+}
+
+// PageHistory — история страницы для просмотра: действующие оценки по порядку и прогноз следующей оценки
+type PageHistory struct {
+	Page     *PageReference
+	Progress *UserProgress
+	// Events — действующие (не отменённые) оценки от первой к последней
+	Events []*ProgressHistory
+	// Lapses — сколько раз страница забывалась в AI режиме и возвращалась на первый интервал
+	Lapses int
+	// AIModeSince — когда страница перешла из режима чтения в AI режим, nil — ещё в режиме чтения или неизвестно
+	AIModeSince *time.Time
+	// Forecast — что будет после каждой из оценок, выставленной в день следующего повторения; пустой у изученной страницы
+	Forecast []*GradeForecast
+}
+
+// GradeForecast — прогноз прогресса страницы после оценки Grade
+type GradeForecast struct {
+	Grade        int
+	IntervalDays int
+	// NextReviewDate — местная дата следующего повторения в виде полуночи UTC
+	NextReviewDate time.Time
+	// Passed — после оценки страница станет изученной
+	Passed bool
+}
+
+// ReviewSubmission — оценка страницы, отправленная пользователем кнопкой или текстом
+type ReviewSubmission struct {
+	PageID string
+	Grade  int
+	// Version — версия прогресса, показанная пользователю вместе со страницей
+	Version int
+	// Key — ключ идемпотентности: callback data кнопки или ID сообщения с оценкой
+	Key string
+	// Source — ReviewSourceButton или ReviewSourceTyped
+	Source string
+	// ShownAt — когда страница была показана, nil — неизвестно
+	ShownAt *time.Time
 }
 
 type PageWithProgress struct {
 	Page     PageReference
 	Progress *UserProgress
 }
+
+// Статусы сессии повторения и результаты страниц в ней
+const (
+	ReviewSessionActive   = "active"
+	ReviewSessionFinished = "finished"
+
+	ReviewResultGraded  = "graded"
+	ReviewResultSkipped = "skipped"
+)
+
+// ReviewSession — сессия повторения на местную дату пользователя: очередь страниц на сегодня в порядке показа.
+// Сессия хранится в БД, поэтому после перезапуска бота повторение продолжается с того же места.
+type ReviewSession struct {
+	ID         int64      `db:"id"`
+	UserID     int64      `db:"user_id"`
+	LocalDate  time.Time  `db:"local_date"`
+	Status     string     `db:"status"`
+	CreatedAt  time.Time  `db:"created_at"`
+	FinishedAt *time.Time `db:"finished_at"`
+	Items      []*ReviewSessionItem
+}
+
+// ReviewSessionItem — страница в очереди сессии. Result nil — страница ещё не пройдена.
+type ReviewSessionItem struct {
+	SessionID  int64      `db:"session_id"`
+	Position   int        `db:"position"`
+	PageID     string     `db:"page_id"`
+	Result     *string    `db:"result"`
+	Grade      *int       `db:"grade"`
+	ReviewedAt *time.Time `db:"reviewed_at"`
+}
+
+// Next возвращает первую непройденную страницу, nil — все страницы пройдены
+func (s *ReviewSession) Next() *ReviewSessionItem {
+	for _, item := range s.Items {
+		if item.Result == nil {
+			return item
+		}
+	}
+	return nil
+}
+
+// Item возвращает страницу сессии по ID, nil — страницы в сессии нет
+func (s *ReviewSession) Item(pageID string) *ReviewSessionItem {
+	for _, item := range s.Items {
+		if item.PageID == pageID {
+			return item
+		}
+	}
+	return nil
+}
+
+// Done — сколько страниц сессии уже пройдено
+func (s *ReviewSession) Done() int {
+	done := 0
+	for _, item := range s.Items {
+		if item.Result != nil {
+			done++
+		}
+	}
+	return done
+}
+
+// ComebackPlan — план возвращения после перерыва: накопившиеся повторения, распределённые по дням
+// начиная с сегодняшнего, самые рискованные страницы идут первыми
+type ComebackPlan struct {
+	PagesPerDay int
+	Days        []*ComebackDay
+}
+
+// Fingerprint — короткий отпечаток плана: темп, даты и страницы по дням. Он передаётся с кнопкой принятия,
+// чтобы применился именно тот план, который видел пользователь.
+func (p *ComebackPlan) Fingerprint() string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d", p.PagesPerDay)
+	for _, day := range p.Days {
+		fmt.Fprintf(h, "|%s", day.Date.Format(time.DateOnly))
+		for _, pwp := range day.Pages {
+			fmt.Fprintf(h, ",%s", pwp.Page.PageID)
+		}
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// ComebackDay — повторения одного дня плана, Date — местная дата пользователя в виде полуночи UTC
+type ComebackDay struct {
+	Date  time.Time
+	Pages []*PageWithProgress
+}
+
+// UserStats — статистика обучения пользователя. Даты — местные даты пользователя в виде полуночи UTC.
+type UserStats struct {
+	// ReviewsPerDay — повторения за последние дни, включая сегодня и дни без повторений
+	ReviewsPerDay []*DailyReviews
+	// ScoreTrend — средняя оценка по неделям, последняя неделя заканчивается сегодня
+	ScoreTrend []*WeeklyScore
+	// Retention — доля вспомненных страниц (оценка выше 60) по интервалу перед оценкой
+	Retention []*IntervalRetention
+	// PagesPerInterval — страницы в изучении по текущему интервалу
+	PagesPerInterval []*IntervalPages
+	// Upcoming — нагрузка на ближайшие дни, просроченные повторения входят в сегодняшний день
+	Upcoming     []*DailyLoad
+	TotalReviews int
+	PassedPages  int
+	// Streak — сколько дней подряд, до сегодня или до вчера, пользователь что-то повторял
+	Streak int
+}
+
+type DailyReviews struct {
+	Date     time.Time `db:"day"`
+	Reviews  int       `db:"reviews"`
+	ScoreSum int       `db:"score_sum"`
+}
+
+type WeeklyScore struct {
+	WeekStart    time.Time
+	Reviews      int
+	AverageScore int
+}
+
+type IntervalRetention struct {
+	IntervalDays int `db:"interval_days"`
+	Reviews      int `db:"reviews"`
+	Remembered   int `db:"remembered"`
+}
+
+type IntervalPages struct {
+	IntervalDays int `db:"interval_days"`
+	Pages        int `db:"pages"`
+}
+
+type DailyLoad struct {
+	Date  time.Time `db:"day"`
+	Pages int       `db:"pages"`
+}
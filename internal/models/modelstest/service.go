@@ -0,0 +1,618 @@
+// Package modelstest содержит настраиваемую реализацию models.Service для тестов обработчиков
+package modelstest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/onenote"
+)
+
+var _ models.Service = (*Service)(nil)
+
+// Service — реализация models.Service для тестов. Метод вызывает одноимённое поле с суффиксом Func,
+// если оно задано, иначе возвращает нулевые значения. Все вызовы записываются по имени метода.
+type Service struct {
+	mu    sync.Mutex
+	calls []string
+
+	RegisterUserFunc    func(ctx context.Context, telegramID int64, username, level, language string) error
+	GetUserFunc         func(ctx context.Context, telegramID int64) (*models.User, error)
+	UserExistsFunc      func(ctx context.Context, telegramID int64) (bool, error)
+	UpdateUserLevelFunc func(ctx context.Context, telegramID int64, level string) error
+
+	GetAuthURLFunc       func(telegramID int64) string
+	ExchangeAuthCodeFunc func(ctx context.Context, telegramID int64, code string) error
+
+	GetOneNoteNotebooksFunc            func(ctx context.Context, telegramID int64) ([]onenote.Notebook, error)
+	GetOneNoteSectionsFunc             func(ctx context.Context, telegramID int64, notebookID string) ([]onenote.Section, error)
+	GetOneNoteNotebookContentsFunc     func(ctx context.Context, telegramID int64, notebookID string) ([]onenote.SectionGroup, []onenote.Section, error)
+	GetOneNoteSectionGroupContentsFunc func(ctx context.Context, telegramID int64, sectionGroupID string) ([]onenote.SectionGroup, []onenote.Section, error)
+	SaveOneNoteConfigFunc              func(ctx context.Context, telegramID int64, notebookID, sectionID string) error
+	SyncPagesFunc                      func(ctx context.Context, telegramID int64) (*models.SyncReport, error)
+	AddPageManuallyFunc                func(ctx context.Context, telegramID int64, query string) (*models.PageReference, error)
+
+	GetChatStateFunc func(ctx context.Context, chatID int64) (*models.ChatState, error)
+	SetChatStateFunc func(ctx context.Context, chatID, telegramID int64, state string, data map[string]string) error
+
+	SaveCallbackTokensFunc   func(ctx context.Context, telegramID int64, tokens []*models.CallbackToken) error
+	GetCallbackTokenFunc     func(ctx context.Context, telegramID int64, token string) (*models.CallbackToken, error)
+	ConsumeCallbackTokenFunc func(ctx context.Context, telegramID int64, token string) (*models.CallbackToken, error)
+	AddSourceFunc            func(ctx context.Context, telegramID int64, notebookID, notebookName, sectionID, sectionName string) error
+	GetSourcesFunc           func(ctx context.Context, telegramID int64) ([]*models.UserSource, error)
+	ToggleSourceFunc         func(ctx context.Context, telegramID, sourceID int64) error
+	MoveSourceUpFunc         func(ctx context.Context, telegramID, sourceID int64) error
+	RemoveSourceFunc         func(ctx context.Context, telegramID, sourceID int64) error
+
+	GetDuePagesTodayFunc          func(ctx context.Context, telegramID int64) ([]*models.PageWithProgress, error)
+	StartReviewSessionFunc        func(ctx context.Context, telegramID int64) (*models.ReviewSession, []*models.PageWithProgress, error)
+	GetReviewSessionFunc          func(ctx context.Context, telegramID int64) (*models.ReviewSession, error)
+	GetStatsFunc                  func(ctx context.Context, telegramID int64) (*models.UserStats, error)
+	GetUserAllPagesInProgressFunc func(ctx context.Context, telegramID int64) ([]*models.PageReference, error)
+	GetPageContentFunc            func(ctx context.Context, telegramID int64, pageID string) (string, error)
+	GetPageItemsFunc              func(ctx context.Context, telegramID int64, pageID string) ([]*models.PageItem, error)
+	UpdateReviewProgressFunc      func(ctx context.Context, telegramID int64, submission models.ReviewSubmission) error
+	UpdateMaxPagesPerDayFunc      func(ctx context.Context, telegramID int64, maxPages uint) error
+	UpdateUserTimezoneFunc        func(ctx context.Context, telegramID int64, timezone string) error
+	GetReminderTimesFunc          func(ctx context.Context, telegramID int64) ([]string, error)
+	SetReminderTimesFunc          func(ctx context.Context, telegramID int64, times []string) error
+	ToggleReminderTimeFunc        func(ctx context.Context, telegramID int64, reminderTime string) error
+	SetRemindersEnabledFunc       func(ctx context.Context, telegramID int64, enabled bool) error
+	SetQuietHoursFunc             func(ctx context.Context, telegramID int64, start, end string) error
+	DisableQuietHoursFunc         func(ctx context.Context, telegramID int64) error
+	ClaimDueRemindersFunc         func(ctx context.Context, telegramID int64, now time.Time) ([]*models.DueReminder, error)
+	MarkRemindersSentFunc         func(ctx context.Context, reminders []*models.DueReminder) error
+	ReleaseReminderFunc           func(ctx context.Context, reminder *models.DueReminder) error
+	SetVacationFunc               func(ctx context.Context, telegramID int64, start, end time.Time, spread bool) (int, error)
+	CancelVacationFunc            func(ctx context.Context, telegramID int64) error
+	SuggestComebackPlanFunc       func(ctx context.Context, telegramID int64) (*models.ComebackPlan, error)
+	GetComebackPlanFunc           func(ctx context.Context, telegramID int64, pagesPerDay int) (*models.ComebackPlan, error)
+	ApplyComebackPlanFunc         func(ctx context.Context, telegramID int64, pagesPerDay int, fingerprint string) (*models.ComebackPlan, error)
+	DismissComebackPlanFunc       func(ctx context.Context, telegramID int64) error
+	GetUserLanguageFunc           func(ctx context.Context, telegramID int64) (string, error)
+	UpdateUserLanguageFunc        func(ctx context.Context, telegramID int64, language string) error
+	GetProgressFunc               func(ctx context.Context, telegramID int64, pageID string) (*models.UserProgress, error)
+	GetLastReviewScoreFunc        func(ctx context.Context, telegramID int64, pageID string) (int, error)
+	GetPageHistoryFunc            func(ctx context.Context, telegramID int64, pageID string, grades []int) (*models.PageHistory, error)
+	SkipPageFunc                  func(ctx context.Context, userID int64, pageID string) error
+	UndoLastGradeFunc             func(ctx context.Context, telegramID int64, pageID, reviewKey string) error
+
+	ClaimJobsFunc        func(ctx context.Context, now time.Time) ([]*models.Job, error)
+	CompleteJobFunc      func(ctx context.Context, job *models.Job, startedAt time.Time, runErr error) error
+	RunRolloverFunc      func(ctx context.Context, telegramID int64) error
+	CheckInactivityFunc  func(ctx context.Context, telegramID int64) error
+	PruneJobHistoryFunc  func(ctx context.Context) error
+	PrepareMaterialsFunc func(ctx context.Context, telegramID int64) error
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func (s *Service) record(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, method)
+}
+
+// Calls возвращает имена вызванных методов в порядке вызова
+func (s *Service) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.calls)
+}
+
+// Count возвращает, сколько раз вызывался метод
+func (s *Service) Count(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, call := range s.calls {
+		if call == method {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset забывает записанные вызовы, настроенные функции сохраняются
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = nil
+}
+
+func (s *Service) RegisterUser(ctx context.Context, telegramID int64, username, level, language string) error {
+	s.record("RegisterUser")
+	if s.RegisterUserFunc != nil {
+		return s.RegisterUserFunc(ctx, telegramID, username, level, language)
+	}
+	return nil
+}
+
+func (s *Service) GetUser(ctx context.Context, telegramID int64) (*models.User, error) {
+	s.record("GetUser")
+	if s.GetUserFunc != nil {
+		return s.GetUserFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) UserExists(ctx context.Context, telegramID int64) (bool, error) {
+	s.record("UserExists")
+	if s.UserExistsFunc != nil {
+		return s.UserExistsFunc(ctx, telegramID)
+	}
+	return false, nil
+}
+
+func (s *Service) UpdateUserLevel(ctx context.Context, telegramID int64, level string) error {
+	s.record("UpdateUserLevel")
+	if s.UpdateUserLevelFunc != nil {
+		return s.UpdateUserLevelFunc(ctx, telegramID, level)
+	}
+	return nil
+}
+
+func (s *Service) GetAuthURL(telegramID int64) string {
+	s.record("GetAuthURL")
+	if s.GetAuthURLFunc != nil {
+		return s.GetAuthURLFunc(telegramID)
+	}
+	return ""
+}
+
+func (s *Service) ExchangeAuthCode(ctx context.Context, telegramID int64, code string) error {
+	s.record("ExchangeAuthCode")
+	if s.ExchangeAuthCodeFunc != nil {
+		return s.ExchangeAuthCodeFunc(ctx, telegramID, code)
+	}
+	return nil
+}
+
+func (s *Service) GetOneNoteNotebooks(ctx context.Context, telegramID int64) ([]onenote.Notebook, error) {
+	s.record("GetOneNoteNotebooks")
+	if s.GetOneNoteNotebooksFunc != nil {
+		return s.GetOneNoteNotebooksFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) GetOneNoteSections(ctx context.Context, telegramID int64, notebookID string) ([]onenote.Section, error) {
+	s.record("GetOneNoteSections")
+	if s.GetOneNoteSectionsFunc != nil {
+		return s.GetOneNoteSectionsFunc(ctx, telegramID, notebookID)
+	}
+	return nil, nil
+}
+
+func (s *Service) GetOneNoteNotebookContents(ctx context.Context, telegramID int64, notebookID string) ([]onenote.SectionGroup, []onenote.Section, error) {
+	s.record("GetOneNoteNotebookContents")
+	if s.GetOneNoteNotebookContentsFunc != nil {
+		return s.GetOneNoteNotebookContentsFunc(ctx, telegramID, notebookID)
+	}
+	return nil, nil, nil
+}
+
+func (s *Service) GetOneNoteSectionGroupContents(ctx context.Context, telegramID int64, sectionGroupID string) ([]onenote.SectionGroup, []onenote.Section, error) {
+	s.record("GetOneNoteSectionGroupContents")
+	if s.GetOneNoteSectionGroupContentsFunc != nil {
+		return s.GetOneNoteSectionGroupContentsFunc(ctx, telegramID, sectionGroupID)
+	}
+	return nil, nil, nil
+}
+
+func (s *Service) SaveOneNoteConfig(ctx context.Context, telegramID int64, notebookID, sectionID string) error {
+	s.record("SaveOneNoteConfig")
+	if s.SaveOneNoteConfigFunc != nil {
+		return s.SaveOneNoteConfigFunc(ctx, telegramID, notebookID, sectionID)
+	}
+	return nil
+}
+
+func (s *Service) SyncPages(ctx context.Context, telegramID int64) (*models.SyncReport, error) {
+	s.record("SyncPages")
+	if s.SyncPagesFunc != nil {
+		return s.SyncPagesFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) AddPageManually(ctx context.Context, telegramID int64, query string) (*models.PageReference, error) {
+	s.record("AddPageManually")
+	if s.AddPageManuallyFunc != nil {
+		return s.AddPageManuallyFunc(ctx, telegramID, query)
+	}
+	return nil, nil
+}
+
+func (s *Service) GetChatState(ctx context.Context, chatID int64) (*models.ChatState, error) {
+	s.record("GetChatState")
+	if s.GetChatStateFunc != nil {
+		return s.GetChatStateFunc(ctx, chatID)
+	}
+	return nil, nil
+}
+
+func (s *Service) SetChatState(ctx context.Context, chatID, telegramID int64, state string, data map[string]string) error {
+	s.record("SetChatState")
+	if s.SetChatStateFunc != nil {
+		return s.SetChatStateFunc(ctx, chatID, telegramID, state, data)
+	}
+	return nil
+}
+
+func (s *Service) SaveCallbackTokens(ctx context.Context, telegramID int64, tokens []*models.CallbackToken) error {
+	s.record("SaveCallbackTokens")
+	if s.SaveCallbackTokensFunc != nil {
+		return s.SaveCallbackTokensFunc(ctx, telegramID, tokens)
+	}
+	return nil
+}
+
+func (s *Service) GetCallbackToken(ctx context.Context, telegramID int64, token string) (*models.CallbackToken, error) {
+	s.record("GetCallbackToken")
+	if s.GetCallbackTokenFunc != nil {
+		return s.GetCallbackTokenFunc(ctx, telegramID, token)
+	}
+	return nil, nil
+}
+
+func (s *Service) ConsumeCallbackToken(ctx context.Context, telegramID int64, token string) (*models.CallbackToken, error) {
+	s.record("ConsumeCallbackToken")
+	if s.ConsumeCallbackTokenFunc != nil {
+		return s.ConsumeCallbackTokenFunc(ctx, telegramID, token)
+	}
+	return nil, nil
+}
+
+func (s *Service) AddSource(ctx context.Context, telegramID int64, notebookID, notebookName, sectionID, sectionName string) error {
+	s.record("AddSource")
+	if s.AddSourceFunc != nil {
+		return s.AddSourceFunc(ctx, telegramID, notebookID, notebookName, sectionID, sectionName)
+	}
+	return nil
+}
+
+func (s *Service) GetSources(ctx context.Context, telegramID int64) ([]*models.UserSource, error) {
+	s.record("GetSources")
+	if s.GetSourcesFunc != nil {
+		return s.GetSourcesFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) ToggleSource(ctx context.Context, telegramID, sourceID int64) error {
+	s.record("ToggleSource")
+	if s.ToggleSourceFunc != nil {
+		return s.ToggleSourceFunc(ctx, telegramID, sourceID)
+	}
+	return nil
+}
+
+func (s *Service) MoveSourceUp(ctx context.Context, telegramID, sourceID int64) error {
+	s.record("MoveSourceUp")
+	if s.MoveSourceUpFunc != nil {
+		return s.MoveSourceUpFunc(ctx, telegramID, sourceID)
+	}
+	return nil
+}
+
+func (s *Service) RemoveSource(ctx context.Context, telegramID, sourceID int64) error {
+	s.record("RemoveSource")
+	if s.RemoveSourceFunc != nil {
+		return s.RemoveSourceFunc(ctx, telegramID, sourceID)
+	}
+	return nil
+}
+
+func (s *Service) GetDuePagesToday(ctx context.Context, telegramID int64) ([]*models.PageWithProgress, error) {
+	s.record("GetDuePagesToday")
+	if s.GetDuePagesTodayFunc != nil {
+		return s.GetDuePagesTodayFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) StartReviewSession(ctx context.Context, telegramID int64) (*models.ReviewSession, []*models.PageWithProgress, error) {
+	s.record("StartReviewSession")
+	if s.StartReviewSessionFunc != nil {
+		return s.StartReviewSessionFunc(ctx, telegramID)
+	}
+	return nil, nil, nil
+}
+
+func (s *Service) GetReviewSession(ctx context.Context, telegramID int64) (*models.ReviewSession, error) {
+	s.record("GetReviewSession")
+	if s.GetReviewSessionFunc != nil {
+		return s.GetReviewSessionFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) GetStats(ctx context.Context, telegramID int64) (*models.UserStats, error) {
+	s.record("GetStats")
+	if s.GetStatsFunc != nil {
+		return s.GetStatsFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) GetUserAllPagesInProgress(ctx context.Context, telegramID int64) ([]*models.PageReference, error) {
+	s.record("GetUserAllPagesInProgress")
+	if s.GetUserAllPagesInProgressFunc != nil {
+		return s.GetUserAllPagesInProgressFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) GetPageContent(ctx context.Context, telegramID int64, pageID string) (string, error) {
+	s.record("GetPageContent")
+	if s.GetPageContentFunc != nil {
+		return s.GetPageContentFunc(ctx, telegramID, pageID)
+	}
+	return "", nil
+}
+
+func (s *Service) GetPageItems(ctx context.Context, telegramID int64, pageID string) ([]*models.PageItem, error) {
+	s.record("GetPageItems")
+	if s.GetPageItemsFunc != nil {
+		return s.GetPageItemsFunc(ctx, telegramID, pageID)
+	}
+	return nil, nil
+}
+
+func (s *Service) UpdateReviewProgress(ctx context.Context, telegramID int64, submission models.ReviewSubmission) error {
+	s.record("UpdateReviewProgress")
+	if s.UpdateReviewProgressFunc != nil {
+		return s.UpdateReviewProgressFunc(ctx, telegramID, submission)
+	}
+	return nil
+}
+
+func (s *Service) UpdateMaxPagesPerDay(ctx context.Context, telegramID int64, maxPages uint) error {
+	s.record("UpdateMaxPagesPerDay")
+	if s.UpdateMaxPagesPerDayFunc != nil {
+		return s.UpdateMaxPagesPerDayFunc(ctx, telegramID, maxPages)
+	}
+	return nil
+}
+
+func (s *Service) UpdateUserTimezone(ctx context.Context, telegramID int64, timezone string) error {
+	s.record("UpdateUserTimezone")
+	if s.UpdateUserTimezoneFunc != nil {
+		return s.UpdateUserTimezoneFunc(ctx, telegramID, timezone)
+	}
+	return nil
+}
+
+func (s *Service) GetReminderTimes(ctx context.Context, telegramID int64) ([]string, error) {
+	s.record("GetReminderTimes")
+	if s.GetReminderTimesFunc != nil {
+		return s.GetReminderTimesFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) SetReminderTimes(ctx context.Context, telegramID int64, times []string) error {
+	s.record("SetReminderTimes")
+	if s.SetReminderTimesFunc != nil {
+		return s.SetReminderTimesFunc(ctx, telegramID, times)
+	}
+	return nil
+}
+
+func (s *Service) ToggleReminderTime(ctx context.Context, telegramID int64, reminderTime string) error {
+	s.record("ToggleReminderTime")
+	if s.ToggleReminderTimeFunc != nil {
+		return s.ToggleReminderTimeFunc(ctx, telegramID, reminderTime)
+	}
+	return nil
+}
+
+func (s *Service) SetRemindersEnabled(ctx context.Context, telegramID int64, enabled bool) error {
+	s.record("SetRemindersEnabled")
+	if s.SetRemindersEnabledFunc != nil {
+		return s.SetRemindersEnabledFunc(ctx, telegramID, enabled)
+	}
+	return nil
+}
+
+func (s *Service) SetQuietHours(ctx context.Context, telegramID int64, start, end string) error {
+	s.record("SetQuietHours")
+	if s.SetQuietHoursFunc != nil {
+		return s.SetQuietHoursFunc(ctx, telegramID, start, end)
+	}
+	return nil
+}
+
+func (s *Service) DisableQuietHours(ctx context.Context, telegramID int64) error {
+	s.record("DisableQuietHours")
+	if s.DisableQuietHoursFunc != nil {
+		return s.DisableQuietHoursFunc(ctx, telegramID)
+	}
+	return nil
+}
+
+func (s *Service) ClaimDueReminders(ctx context.Context, telegramID int64, now time.Time) ([]*models.DueReminder, error) {
+	s.record("ClaimDueReminders")
+	if s.ClaimDueRemindersFunc != nil {
+		return s.ClaimDueRemindersFunc(ctx, telegramID, now)
+	}
+	return nil, nil
+}
+
+func (s *Service) MarkRemindersSent(ctx context.Context, reminders []*models.DueReminder) error {
+	s.record("MarkRemindersSent")
+	if s.MarkRemindersSentFunc != nil {
+		return s.MarkRemindersSentFunc(ctx, reminders)
+	}
+	return nil
+}
+
+func (s *Service) ReleaseReminder(ctx context.Context, reminder *models.DueReminder) error {
+	s.record("ReleaseReminder")
+	if s.ReleaseReminderFunc != nil {
+		return s.ReleaseReminderFunc(ctx, reminder)
+	}
+	return nil
+}
+
+func (s *Service) SetVacation(ctx context.Context, telegramID int64, start, end time.Time, spread bool) (int, error) {
+	s.record("SetVacation")
+	if s.SetVacationFunc != nil {
+		return s.SetVacationFunc(ctx, telegramID, start, end, spread)
+	}
+	return 0, nil
+}
+
+func (s *Service) CancelVacation(ctx context.Context, telegramID int64) error {
+	s.record("CancelVacation")
+	if s.CancelVacationFunc != nil {
+		return s.CancelVacationFunc(ctx, telegramID)
+	}
+	return nil
+}
+
+func (s *Service) SuggestComebackPlan(ctx context.Context, telegramID int64) (*models.ComebackPlan, error) {
+	s.record("SuggestComebackPlan")
+	if s.SuggestComebackPlanFunc != nil {
+		return s.SuggestComebackPlanFunc(ctx, telegramID)
+	}
+	return nil, nil
+}
+
+func (s *Service) GetComebackPlan(ctx context.Context, telegramID int64, pagesPerDay int) (*models.ComebackPlan, error) {
+	s.record("GetComebackPlan")
+	if s.GetComebackPlanFunc != nil {
+		return s.GetComebackPlanFunc(ctx, telegramID, pagesPerDay)
+	}
+	return nil, nil
+}
+
+func (s *Service) ApplyComebackPlan(ctx context.Context, telegramID int64, pagesPerDay int, fingerprint string) (*models.ComebackPlan, error) {
+	s.record("ApplyComebackPlan")
+	if s.ApplyComebackPlanFunc != nil {
+		return s.ApplyComebackPlanFunc(ctx, telegramID, pagesPerDay, fingerprint)
+	}
+	return nil, nil
+}
+
+func (s *Service) DismissComebackPlan(ctx context.Context, telegramID int64) error {
+	s.record("DismissComebackPlan")
+	if s.DismissComebackPlanFunc != nil {
+		return s.DismissComebackPlanFunc(ctx, telegramID)
+	}
+	return nil
+}
+
+func (s *Service) GetUserLanguage(ctx context.Context, telegramID int64) (string, error) {
+	s.record("GetUserLanguage")
+	if s.GetUserLanguageFunc != nil {
+		return s.GetUserLanguageFunc(ctx, telegramID)
+	}
+	return "", nil
+}
+
+func (s *Service) UpdateUserLanguage(ctx context.Context, telegramID int64, language string) error {
+	s.record("UpdateUserLanguage")
+	if s.UpdateUserLanguageFunc != nil {
+		return s.UpdateUserLanguageFunc(ctx, telegramID, language)
+	}
+	return nil
+}
+
+func (s *Service) GetProgress(ctx context.Context, telegramID int64, pageID string) (*models.UserProgress, error) {
+	s.record("GetProgress")
+	if s.GetProgressFunc != nil {
+		return s.GetProgressFunc(ctx, telegramID, pageID)
+	}
+	return nil, nil
+}
+
+func (s *Service) GetLastReviewScore(ctx context.Context, telegramID int64, pageID string) (int, error) {
+	s.record("GetLastReviewScore")
+	if s.GetLastReviewScoreFunc != nil {
+		return s.GetLastReviewScoreFunc(ctx, telegramID, pageID)
+	}
+	return 0, nil
+}
+
+func (s *Service) GetPageHistory(ctx context.Context, telegramID int64, pageID string, grades []int) (*models.PageHistory, error) {
+	s.record("GetPageHistory")
+	if s.GetPageHistoryFunc != nil {
+		return s.GetPageHistoryFunc(ctx, telegramID, pageID, grades)
+	}
+	return nil, nil
+}
+
+func (s *Service) SkipPage(ctx context.Context, userID int64, pageID string) error {
+	s.record("SkipPage")
+	if s.SkipPageFunc != nil {
+		return s.SkipPageFunc(ctx, userID, pageID)
+	}
+	return nil
+}
+
+func (s *Service) UndoLastGrade(ctx context.Context, telegramID int64, pageID, reviewKey string) error {
+	s.record("UndoLastGrade")
+	if s.UndoLastGradeFunc != nil {
+		return s.UndoLastGradeFunc(ctx, telegramID, pageID, reviewKey)
+	}
+	return nil
+}
+
+func (s *Service) ClaimJobs(ctx context.Context, now time.Time) ([]*models.Job, error) {
+	s.record("ClaimJobs")
+	if s.ClaimJobsFunc != nil {
+		return s.ClaimJobsFunc(ctx, now)
+	}
+	return nil, nil
+}
+
+func (s *Service) CompleteJob(ctx context.Context, job *models.Job, startedAt time.Time, runErr error) error {
+	s.record("CompleteJob")
+	if s.CompleteJobFunc != nil {
+		return s.CompleteJobFunc(ctx, job, startedAt, runErr)
+	}
+	return nil
+}
+
+func (s *Service) RunRollover(ctx context.Context, telegramID int64) error {
+	s.record("RunRollover")
+	if s.RunRolloverFunc != nil {
+		return s.RunRolloverFunc(ctx, telegramID)
+	}
+	return nil
+}
+
+func (s *Service) CheckInactivity(ctx context.Context, telegramID int64) error {
+	s.record("CheckInactivity")
+	if s.CheckInactivityFunc != nil {
+		return s.CheckInactivityFunc(ctx, telegramID)
+	}
+	return nil
+}
+
+func (s *Service) PruneJobHistory(ctx context.Context) error {
+	s.record("PruneJobHistory")
+	if s.PruneJobHistoryFunc != nil {
+		return s.PruneJobHistoryFunc(ctx)
+	}
+	return nil
+}
+
+func (s *Service) PrepareMaterials(ctx context.Context, telegramID int64) error {
+	s.record("PrepareMaterials")
+	if s.PrepareMaterialsFunc != nil {
+		return s.PrepareMaterialsFunc(ctx, telegramID)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+func (r Postgres) SaveCallbackTokens(ctx context.Context, tokens []*models.CallbackToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	query := r.psql.Insert("callback_tokens").
+		Columns("token", "user_id", "page_id", "action", "grade", "version", "review_key", "issued_at", "expires_at")
+
+	for _, token := range tokens {
+		query = query.Values(token.Token, token.UserID, token.PageID, token.Action, token.Grade, token.Version, token.ReviewKey, token.IssuedAt, token.ExpiresAt)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (count: %d): %w", len(tokens), err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("save callback tokens (user_id: %d, count: %d): %w", tokens[0].UserID, len(tokens), err)
+	}
+	return nil
+}
+
+// GetCallbackToken возвращает действующий токен пользователя или nil, если токена нет, он чужой или просрочен.
+// С forUpdate строка блокируется до конца транзакции, чтобы одноразовое действие забрал только один запрос
+func (r Postgres) GetCallbackToken(ctx context.Context, userID int64, token string, now time.Time, forUpdate bool) (*models.CallbackToken, error) {
+	query := `
+		SELECT token, user_id, page_id, action, grade, version, review_key, issued_at, expires_at
+		FROM callback_tokens
+		WHERE token = $1 AND user_id = $2 AND expires_at > $3
+	`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var tokens []*models.CallbackToken
+	if err := r.SelectContext(ctx, &tokens, query, token, userID, now); err != nil {
+		return nil, fmt.Errorf("get callback token (user_id: %d): %w", userID, err)
+	}
+
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return tokens[0], nil
+}
+
+// DeletePageCallbackTokens удаляет кнопки страницы, кроме кнопок с действиями keepActions
+func (r Postgres) DeletePageCallbackTokens(ctx context.Context, userID int64, pageID string, keepActions []string) error {
+	query := r.psql.Delete("callback_tokens").
+		Where("user_id = ? AND page_id = ?", userID, pageID)
+	if len(keepActions) > 0 {
+		query = query.Where(squirrel.NotEq{"action": keepActions})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("delete page callback tokens (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+	return nil
+}
+
+func (r Postgres) DeleteExpiredCallbackTokens(ctx context.Context, userID int64, now time.Time) error {
+	query := r.psql.Delete("callback_tokens").
+		Where("user_id = ? AND expires_at <= ?", userID, now)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("delete expired callback tokens (user_id: %d): %w", userID, err)
+	}
+	return nil
+}
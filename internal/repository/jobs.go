@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// ScheduleJob создаёт задачу пользователя или переносит её следующий запуск, счётчик неудачных попыток сбрасывается
+func (r Postgres) ScheduleJob(ctx context.Context, userID int64, jobType string, nextRunAt time.Time) error {
+	query := r.psql.Insert("jobs").
+		Columns("user_id", "job_type", "next_run_at", "updated_at").
+		Values(userID, jobType, nextRunAt, r.clock.Now()).
+		Suffix(`ON CONFLICT (user_id, job_type) DO UPDATE SET
+			next_run_at = EXCLUDED.next_run_at,
+			attempts = 0,
+			last_error = NULL,
+			updated_at = EXCLUDED.updated_at`)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, job_type: %s): %w", userID, jobType, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("schedule job (user_id: %d, job_type: %s): %w", userID, jobType, err)
+	}
+	return nil
+}
+
+// ClaimJobs занимает до limit задач, время запуска которых наступило, до lockedUntil.
+// FOR UPDATE SKIP LOCKED пропускает строки, которые в этот момент занимает другая реплика,
+// поэтому каждую задачу получает только один процесс.
+func (r Postgres) ClaimJobs(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*models.Job, error) {
+	query := `
+		UPDATE jobs SET locked_until = $1, updated_at = $2
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE next_run_at <= $2 AND (locked_until IS NULL OR locked_until <= $2)
+			ORDER BY next_run_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, job_type, next_run_at, attempts, last_error, locked_until
+	`
+
+	var jobs []*models.Job
+	if err := r.SelectContext(ctx, &jobs, query, lockedUntil, now, limit); err != nil {
+		return nil, fmt.Errorf("claim jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// FinishJob сохраняет следующий запуск и результат задачи и освобождает её, если процесс всё ещё её занимает.
+// false — аренда истекла и задачу уже занял другой процесс: его результат не перезаписывается.
+func (r Postgres) FinishJob(ctx context.Context, job *models.Job) (bool, error) {
+	query := r.psql.Update("jobs").
+		Set("next_run_at", job.NextRunAt).
+		Set("attempts", job.Attempts).
+		Set("last_error", job.LastError).
+		Set("locked_until", nil).
+		Set("updated_at", r.clock.Now()).
+		Where("id = ? AND locked_until = ?", job.ID, job.LockedUntil)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("build SQL query (job_id: %d): %w", job.ID, err)
+	}
+
+	res, err := r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("finish job (job_id: %d, user_id: %d, job_type: %s): %w", job.ID, job.UserID, job.JobType, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("get rows affected (job_id: %d): %w", job.ID, err)
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r Postgres) AddJobRun(ctx context.Context, run *models.JobRun) error {
+	query := r.psql.Insert("job_runs").
+		Columns("job_id", "user_id", "job_type", "attempt", "status", "error", "started_at", "finished_at").
+		Values(run.JobID, run.UserID, run.JobType, run.Attempt, run.Status, run.Error, run.StartedAt, run.FinishedAt)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (job_id: %d): %w", run.JobID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("add job run (job_id: %d, user_id: %d, job_type: %s): %w", run.JobID, run.UserID, run.JobType, err)
+	}
+	return nil
+}
+
+// DeleteJobRunsBefore удаляет историю запусков, завершённых раньше before
+func (r Postgres) DeleteJobRunsBefore(ctx context.Context, before time.Time) error {
+	query := r.psql.Delete("job_runs").
+		Where("finished_at < ?", before)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query: %w", err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("delete job runs before %v: %w", before, err)
+	}
+	return nil
+}
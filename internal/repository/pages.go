@@ -3,14 +3,16 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/romanzh1/master-english-srs/internal/models"
 )
 
 func (r Postgres) CreatePageReference(ctx context.Context, page *models.PageReference) error {
 	query := r.psql.Insert("page_references").
-		Columns("page_id", "user_id", "title", "source", "created_at", "updated_at").
-		Values(page.PageID, page.UserID, page.Title, page.Source, page.CreatedAt, page.UpdatedAt)
+		Columns("page_id", "user_id", "title", "source", "section_id", "created_at", "updated_at").
+		Values(page.PageID, page.UserID, page.Title, page.Source, page.SectionID, page.CreatedAt, page.UpdatedAt)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -26,7 +28,7 @@ func (r Postgres) CreatePageReference(ctx context.Context, page *models.PageRefe
 
 func (r Postgres) GetPageReference(ctx context.Context, pageID string, userID int64) (*models.PageReference, error) {
 	query := `
-		SELECT page_id, user_id, title, source, created_at, updated_at
+		SELECT page_id, user_id, title, source, COALESCE(section_id, '') AS section_id, created_at, updated_at, deleted_at
 		FROM page_references
 		WHERE page_id = $1 AND user_id = $2
 	`
@@ -41,7 +43,7 @@ func (r Postgres) GetPageReference(ctx context.Context, pageID string, userID in
 }
 
 func (r Postgres) GetUserPagesInProgress(ctx context.Context, userID int64) ([]*models.PageReference, error) {
-	query := `SELECT page_id, user_id, title, source, created_at, updated_at FROM page_references WHERE user_id = $1`
+	query := `SELECT page_id, user_id, title, source, COALESCE(section_id, '') AS section_id, created_at, updated_at, deleted_at FROM page_references WHERE user_id = $1`
 
 	var pages []*models.PageReference
 	err := r.SelectContext(ctx, &pages, query, userID)
@@ -70,18 +72,213 @@ func (r Postgres) DeleteUserPages(ctx context.Context, userID int64) error {
 
 func (r Postgres) UpsertPageReference(ctx context.Context, page *models.PageReference) error {
 	query := `
-		INSERT INTO page_references (page_id, user_id, title, source, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO page_references (page_id, user_id, title, source, section_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		ON CONFLICT (page_id, user_id) 
 		DO UPDATE SET 
 			title = EXCLUDED.title,
 			source = EXCLUDED.source,
-			updated_at = EXCLUDED.updated_at
+			section_id = EXCLUDED.section_id,
+			updated_at = EXCLUDED.updated_at,
+			deleted_at = NULL
 	`
 
-	_, err := r.ExecContext(ctx, query, page.PageID, page.UserID, page.Title, page.Source, page.CreatedAt, page.UpdatedAt)
+	_, err := r.ExecContext(ctx, query, page.PageID, page.UserID, page.Title, page.Source, page.SectionID, page.CreatedAt, page.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("upsert page reference (page_id: %s, user_id: %d, title: %s): %w", page.PageID, page.UserID, page.Title, err)
 	}
 	return nil
 }
+
+// pageReferencesBatchSize ограничивает число строк в одном INSERT, чтобы не упереться в лимит параметров PostgreSQL
+const pageReferencesBatchSize = 500
+
+// UpsertPageReferences сохраняет страницы пачками, сбрасывая отметку об удалении у найденных снова страниц
+// и отметку об извлечении слов, чтобы слова изменённых страниц извлеклись заново
+func (r Postgres) UpsertPageReferences(ctx context.Context, pages []*models.PageReference) error {
+	for start := 0; start < len(pages); start += pageReferencesBatchSize {
+		batch := pages[start:min(start+pageReferencesBatchSize, len(pages))]
+
+		query := r.psql.Insert("page_references").
+			Columns("page_id", "user_id", "title", "source", "section_id", "created_at", "updated_at", "deleted_at").
+			Suffix(`ON CONFLICT (page_id, user_id) DO UPDATE SET
+				title = EXCLUDED.title,
+				source = EXCLUDED.source,
+				section_id = EXCLUDED.section_id,
+				updated_at = EXCLUDED.updated_at,
+				deleted_at = EXCLUDED.deleted_at,
+				items_extracted_at = NULL`)
+
+		for _, page := range batch {
+			query = query.Values(page.PageID, page.UserID, page.Title, page.Source, page.SectionID, page.CreatedAt, page.UpdatedAt, page.DeletedAt)
+		}
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return fmt.Errorf("build SQL query (count: %d): %w", len(batch), err)
+		}
+
+		if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+			return fmt.Errorf("upsert page references (count: %d): %w", len(batch), err)
+		}
+	}
+
+	return nil
+}
+
+func (r Postgres) MarkPageReferencesDeleted(ctx context.Context, userID int64, pageIDs []string, deletedAt time.Time) error {
+	if len(pageIDs) == 0 {
+		return nil
+	}
+
+	query := r.psql.Update("page_references").
+		Set("deleted_at", deletedAt).
+		Where("user_id = ?", userID).
+		Where(squirrel.Eq{"page_id": pageIDs})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("mark page references deleted (user_id: %d, count: %d): %w", userID, len(pageIDs), err)
+	}
+	return nil
+}
+
+// MovePageReference переносит страницу, прогресс по ней, её места в сессиях повторения и кнопки на новый page_id.
+// История повторений и слова страницы переносятся каскадно через внешние ключи.
+func (r Postgres) MovePageReference(ctx context.Context, userID int64, oldPageID, newPageID string) error {
+	queries := []squirrel.UpdateBuilder{
+		r.psql.Update("page_references").
+			Set("page_id", newPageID).
+			Where("user_id = ? AND page_id = ?", userID, oldPageID),
+		r.psql.Update("user_progress").
+			Set("page_id", newPageID).
+			Where("user_id = ? AND page_id = ?", userID, oldPageID),
+		// У review_session_items нет user_id и внешнего ключа на страницу, поэтому сессии пользователя выбираются подзапросом
+		r.psql.Update("review_session_items").
+			Set("page_id", newPageID).
+			Where("page_id = ? AND session_id IN (SELECT id FROM review_sessions WHERE user_id = ?)", oldPageID, userID),
+		r.psql.Update("callback_tokens").
+			Set("page_id", newPageID).
+			Where("user_id = ? AND page_id = ?", userID, oldPageID),
+	}
+
+	for _, query := range queries {
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return fmt.Errorf("build SQL query (user_id: %d, page_id: %s): %w", userID, oldPageID, err)
+		}
+
+		if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+			return fmt.Errorf("move page reference (user_id: %d, old_page_id: %s, new_page_id: %s): %w", userID, oldPageID, newPageID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r Postgres) ReplacePageItems(ctx context.Context, userID int64, pageID string, items []*models.PageItem) error {
+	deleteQuery := r.psql.Delete("page_items").
+		Where("user_id = ? AND page_id = ?", userID, pageID)
+
+	if len(items) > 0 {
+		words := make([]string, 0, len(items))
+		for _, item := range items {
+			words = append(words, item.Word)
+		}
+		deleteQuery = deleteQuery.Where(squirrel.NotEq{"word": words})
+	}
+
+	sql, args, err := deleteQuery.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("delete stale page items (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	// Обновляем существующие слова по (user_id, page_id, word), чтобы id и связанная с ним статистика сохранялись
+	insertQuery := r.psql.Insert("page_items").
+		Columns("user_id", "page_id", "position", "word", "translation", "example", "created_at", "updated_at").
+		Suffix(`ON CONFLICT (user_id, page_id, word) DO UPDATE SET
+			position = EXCLUDED.position,
+			translation = EXCLUDED.translation,
+			example = EXCLUDED.example,
+			updated_at = EXCLUDED.updated_at`)
+
+	for _, item := range items {
+		insertQuery = insertQuery.Values(userID, pageID, item.Position, item.Word, item.Translation, item.Example, item.CreatedAt, item.UpdatedAt)
+	}
+
+	sql, args, err = insertQuery.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("upsert page items (user_id: %d, page_id: %s, count: %d): %w", userID, pageID, len(items), err)
+	}
+
+	return nil
+}
+
+func (r Postgres) GetPageItems(ctx context.Context, userID int64, pageID string) ([]*models.PageItem, error) {
+	query := `
+		SELECT id, user_id, page_id, position, word, translation, COALESCE(example, '') AS example, created_at, updated_at
+		FROM page_items
+		WHERE user_id = $1 AND page_id = $2
+		ORDER BY position ASC
+	`
+
+	var items []*models.PageItem
+	err := r.SelectContext(ctx, &items, query, userID, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("query page items (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	return items, nil
+}
+
+// GetPagesWithoutItems возвращает страницы, слова которых ещё не извлечены: новые и изменённые с прошлой синхронизации
+func (r Postgres) GetPagesWithoutItems(ctx context.Context, userID int64, limit int) ([]string, error) {
+	query := `
+		SELECT page_id
+		FROM page_references
+		WHERE user_id = $1 AND deleted_at IS NULL AND items_extracted_at IS NULL
+		ORDER BY created_at ASC, page_id ASC
+		LIMIT $2
+	`
+
+	var pageIDs []string
+	if err := r.SelectContext(ctx, &pageIDs, query, userID, limit); err != nil {
+		return nil, fmt.Errorf("query pages without items (user_id: %d): %w", userID, err)
+	}
+
+	return pageIDs, nil
+}
+
+func (r Postgres) MarkPageItemsExtracted(ctx context.Context, userID int64, pageID string, extractedAt time.Time) error {
+	query := r.psql.Update("page_references").
+		Set("items_extracted_at", extractedAt).
+		Where("user_id = ? AND page_id = ?", userID, pageID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("mark page items extracted (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	return nil
+}
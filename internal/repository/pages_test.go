@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// pagesTestUserID — пользователь, которого тесты страниц создают и удаляют в тестовой базе
+const pagesTestUserID int64 = -3000001
+
+// TestMovePageReference проверяет, что перенос страницы переписывает page_id во всех таблицах, которые на неё ссылаются:
+// page_references, user_progress, review_session_items и callback_tokens напрямую, progress_history и page_items каскадно
+func TestMovePageReference(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+
+	deleteTestUser(t, repo, pagesTestUserID)
+	t.Cleanup(func() { deleteTestUser(t, repo, pagesTestUserID) })
+
+	if err := repo.CreateUser(ctx, &models.User{TelegramID: pagesTestUserID, Username: "pages", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpsertPageReferences(ctx, []*models.PageReference{
+		{PageID: "old", UserID: pagesTestUserID, Title: "1 Travel", Source: "onenote", SectionID: "sec-a", CreatedAt: now},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateProgress(ctx, &models.UserProgress{UserID: pagesTestUserID, PageID: "old", IntervalDays: 3, NextReviewDate: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddProgressHistory(ctx, pagesTestUserID, "old", models.ProgressHistory{Date: now, Score: 90}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ReplacePageItems(ctx, pagesTestUserID, "old", []*models.PageItem{{UserID: pagesTestUserID, PageID: "old", Word: "journey", CreatedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	session := &models.ReviewSession{UserID: pagesTestUserID, LocalDate: now, Status: models.ReviewSessionActive, CreatedAt: now}
+	if err := repo.CreateReviewSession(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddReviewSessionItems(ctx, session.ID, 0, []string{"old"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveCallbackTokens(ctx, []*models.CallbackToken{
+		{Token: "pages-test", UserID: pagesTestUserID, PageID: "old", Action: models.CallbackActionShowPage, IssuedAt: now, ExpiresAt: now.Add(24 * time.Hour)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		return txRepo.MovePageReference(ctx, pagesTestUserID, "old", "new")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{"page_references", "SELECT page_id FROM page_references WHERE user_id = $1", []any{pagesTestUserID}},
+		{"user_progress", "SELECT page_id FROM user_progress WHERE user_id = $1", []any{pagesTestUserID}},
+		{"progress_history", "SELECT page_id FROM progress_history WHERE user_id = $1", []any{pagesTestUserID}},
+		{"page_items", "SELECT page_id FROM page_items WHERE user_id = $1", []any{pagesTestUserID}},
+		{"review_session_items", "SELECT page_id FROM review_session_items WHERE session_id = $1", []any{session.ID}},
+		{"callback_tokens", "SELECT page_id FROM callback_tokens WHERE user_id = $1", []any{pagesTestUserID}},
+	}
+	for _, table := range tables {
+		var pageIDs []string
+		if err := repo.SelectContext(ctx, &pageIDs, table.query, table.args...); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(pageIDs); got != "[new]" {
+			t.Errorf("%s page IDs = %s, want [new]", table.name, got)
+		}
+	}
+}
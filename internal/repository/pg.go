@@ -11,15 +11,18 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/pressly/goose/v3"
 	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils"
 )
 
 type Postgres struct {
 	db   *sqlx.DB
 	tx   *sqlx.Tx
 	psql squirrel.StatementBuilderType
+	// clock задаёт служебные отметки времени (updated_at, sent_at)
+	clock utils.Clock
 }
 
-func NewDB(dsn string, maxIdle, maxOpen int) (*Postgres, error) {
+func NewDB(dsn string, maxIdle, maxOpen int, clock utils.Clock) (*Postgres, error) {
 	db, err := sqlx.Connect("pgx", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("connect to database: %w", err)
@@ -38,7 +41,7 @@ func NewDB(dsn string, maxIdle, maxOpen int) (*Postgres, error) {
 
 	psql := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
 
-	return &Postgres{db: db, psql: psql}, nil
+	return &Postgres{db: db, psql: psql, clock: clock}, nil
 }
 
 func (r Postgres) Close() error {
@@ -68,9 +71,10 @@ func (r *Postgres) Begin() (*Postgres, error) {
 	}
 
 	return &Postgres{
-		db:   r.db,
-		tx:   tx,
-		psql: r.psql,
+		db:    r.db,
+		tx:    tx,
+		psql:  r.psql,
+		clock: r.clock,
 	}, nil
 }
 
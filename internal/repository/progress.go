@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -28,7 +29,7 @@ func (r Postgres) CreateProgress(ctx context.Context, progress *models.UserProgr
 
 func (r Postgres) GetProgress(ctx context.Context, userID int64, pageID string) (*models.UserProgress, error) {
 	query := `
-		SELECT user_id, page_id, level, repetition_count, last_review_date, next_review_date, interval_days, success_rate, reviewed_today, passed
+		SELECT user_id, page_id, level, repetition_count, last_review_date, next_review_date, interval_days, success_rate, reviewed_today, passed, version
 		FROM user_progress
 		WHERE user_id = $1 AND page_id = $2
 	`
@@ -42,7 +43,9 @@ func (r Postgres) GetProgress(ctx context.Context, userID int64, pageID string)
 	return &progress, nil
 }
 
-func (r Postgres) UpdateProgress(ctx context.Context, userID int64, pageID string, level string, repetitionCount int, lastReviewDate, nextReviewDate time.Time, intervalDays int, reviewedToday bool, passed bool) error {
+// UpdateProgress обновляет прогресс страницы, если его версия всё ещё version, и увеличивает версию.
+// Возвращает false, если прогресс успели изменить после чтения.
+func (r Postgres) UpdateProgress(ctx context.Context, userID int64, pageID string, version int, level string, repetitionCount int, lastReviewDate, nextReviewDate time.Time, intervalDays int, reviewedToday bool, passed bool) (bool, error) {
 	query := r.psql.Update("user_progress").
 		Set("level", level).
 		Set("repetition_count", repetitionCount).
@@ -51,24 +54,53 @@ func (r Postgres) UpdateProgress(ctx context.Context, userID int64, pageID strin
 		Set("interval_days", intervalDays).
 		Set("reviewed_today", reviewedToday).
 		Set("passed", passed).
-		Where("user_id = ? AND page_id = ?", userID, pageID)
+		Set("version", version+1).
+		Where("user_id = ? AND page_id = ? AND version = ?", userID, pageID, version)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf("build SQL query (user_id: %d, page_id: %s): %w", userID, pageID, err)
+		return false, fmt.Errorf("build SQL query (user_id: %d, page_id: %s): %w", userID, pageID, err)
 	}
 
-	_, err = r.ExecContext(ctx, sql, args...)
+	res, err := r.ExecContext(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("update progress (user_id: %d, page_id: %s, repetition_count: %d): %w", userID, pageID, repetitionCount, err)
+		return false, fmt.Errorf("update progress (user_id: %d, page_id: %s, repetition_count: %d): %w", userID, pageID, repetitionCount, err)
 	}
-	return nil
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("get rows affected (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+	return rowsAffected > 0, nil
 }
 
+// AddProgressHistory добавляет событие оценки в историю. Ошибка возвращается вызывающему: без записи истории
+// оценку нельзя ни отменить, ни воспроизвести, поэтому она откатывается вместе с транзакцией оценки.
 func (r Postgres) AddProgressHistory(ctx context.Context, userID int64, pageID string, history models.ProgressHistory) error {
+	before, err := marshalProgress(history.Before)
+	if err != nil {
+		return fmt.Errorf("marshal progress before (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	after, err := marshalProgress(history.After)
+	if err != nil {
+		return fmt.Errorf("marshal progress after (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	var responseMs, source, reviewKey any
+	if history.ResponseTime != nil {
+		responseMs = history.ResponseTime.Milliseconds()
+	}
+	if history.Source != "" {
+		source = history.Source
+	}
+	if history.ReviewKey != "" {
+		reviewKey = history.ReviewKey
+	}
+
 	query := r.psql.Insert("progress_history").
-		Columns("user_id", "page_id", "date", "score", "mode", "notes").
-		Values(userID, pageID, history.Date, history.Score, history.Mode, history.Notes)
+		Columns("user_id", "page_id", "date", "score", "mode", "notes", "progress_before", "progress_after", "response_ms", "source", "review_key").
+		Values(userID, pageID, history.Date, history.Score, history.Mode, history.Notes, before, after, responseMs, source, reviewKey)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -82,9 +114,168 @@ func (r Postgres) AddProgressHistory(ctx context.Context, userID int64, pageID s
 	return nil
 }
 
+// GetLastProgressHistory возвращает последнюю запись истории страницы, блокируя её до конца транзакции,
+// или nil, если страницу ещё не оценивали
+func (r Postgres) GetLastProgressHistory(ctx context.Context, userID int64, pageID string) (*models.ProgressHistory, error) {
+	query := `
+		SELECT ` + progressHistoryColumns + `
+		FROM progress_history
+		WHERE user_id = $1 AND page_id = $2
+		ORDER BY date DESC, id DESC
+		LIMIT 1
+		FOR UPDATE
+	`
+
+	var rows []progressHistoryRow
+	if err := r.SelectContext(ctx, &rows, query, userID, pageID); err != nil {
+		return nil, fmt.Errorf("get last progress history (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	history, err := rows[0].toModel()
+	if err != nil {
+		return nil, fmt.Errorf("decode progress history (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	return history, nil
+}
+
+// GetProgressHistory возвращает действующие (не отменённые) оценки страницы от первой к последней
+func (r Postgres) GetProgressHistory(ctx context.Context, userID int64, pageID string) ([]*models.ProgressHistory, error) {
+	query := `
+		SELECT ` + progressHistoryColumns + `
+		FROM progress_history
+		WHERE user_id = $1 AND page_id = $2 AND undone_at IS NULL
+		ORDER BY date ASC, id ASC
+	`
+
+	var rows []progressHistoryRow
+	if err := r.SelectContext(ctx, &rows, query, userID, pageID); err != nil {
+		return nil, fmt.Errorf("get progress history (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	events := make([]*models.ProgressHistory, 0, len(rows))
+	for _, row := range rows {
+		history, err := row.toModel()
+		if err != nil {
+			return nil, fmt.Errorf("decode progress history (user_id: %d, page_id: %s): %w", userID, pageID, err)
+		}
+		events = append(events, history)
+	}
+
+	return events, nil
+}
+
+// ReviewKeyExists сообщает, записана ли уже оценка с ключом идемпотентности reviewKey
+func (r Postgres) ReviewKeyExists(ctx context.Context, userID int64, reviewKey string) (bool, error) {
+	query := r.psql.Select("COUNT(*)").
+		From("progress_history").
+		Where("user_id = ? AND review_key = ?", userID, reviewKey)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+	}
+
+	var count int
+	if err := r.QueryRowxContext(ctx, sql, args...).Scan(&count); err != nil {
+		return false, fmt.Errorf("check review key exists (user_id: %d, review_key: %s): %w", userID, reviewKey, err)
+	}
+	return count > 0, nil
+}
+
+// MarkProgressHistoryUndone отмечает оценку historyID отменённой
+func (r Postgres) MarkProgressHistoryUndone(ctx context.Context, userID, historyID int64, undoneAt time.Time) error {
+	query := r.psql.Update("progress_history").
+		Set("undone_at", undoneAt).
+		Where("user_id = ? AND id = ?", userID, historyID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, history_id: %d): %w", userID, historyID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("mark progress history undone (user_id: %d, history_id: %d): %w", userID, historyID, err)
+	}
+	return nil
+}
+
+// progressHistoryColumns — колонки progress_history в порядке полей progressHistoryRow
+const progressHistoryColumns = "id, date, score, mode, notes, progress_before, progress_after, response_ms, source, undone_at, review_key"
+
+// progressHistoryRow — строка progress_history: у старых записей новые колонки пустые
+type progressHistoryRow struct {
+	ID         int64      `db:"id"`
+	Date       time.Time  `db:"date"`
+	Score      int        `db:"score"`
+	Mode       *string    `db:"mode"`
+	Notes      *string    `db:"notes"`
+	Before     []byte     `db:"progress_before"`
+	After      []byte     `db:"progress_after"`
+	ResponseMs *int64     `db:"response_ms"`
+	Source     *string    `db:"source"`
+	UndoneAt   *time.Time `db:"undone_at"`
+	ReviewKey  *string    `db:"review_key"`
+}
+
+func (row progressHistoryRow) toModel() (*models.ProgressHistory, error) {
+	history := &models.ProgressHistory{
+		ID:       row.ID,
+		Date:     row.Date,
+		Score:    row.Score,
+		UndoneAt: row.UndoneAt,
+	}
+	if row.Mode != nil {
+		history.Mode = *row.Mode
+	}
+	if row.Notes != nil {
+		history.Notes = *row.Notes
+	}
+	if row.Source != nil {
+		history.Source = *row.Source
+	}
+	if row.ReviewKey != nil {
+		history.ReviewKey = *row.ReviewKey
+	}
+	if row.ResponseMs != nil {
+		responseTime := time.Duration(*row.ResponseMs) * time.Millisecond
+		history.ResponseTime = &responseTime
+	}
+
+	if row.Before != nil {
+		if err := json.Unmarshal(row.Before, &history.Before); err != nil {
+			return nil, fmt.Errorf("unmarshal progress before (history_id: %d): %w", row.ID, err)
+		}
+	}
+	if row.After != nil {
+		if err := json.Unmarshal(row.After, &history.After); err != nil {
+			return nil, fmt.Errorf("unmarshal progress after (history_id: %d): %w", row.ID, err)
+		}
+	}
+
+	return history, nil
+}
+
+// marshalProgress готовит состояние прогресса для колонки jsonb, nil — NULL
+func marshalProgress(progress *models.UserProgress) (any, error) {
+	if progress == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
 func (r Postgres) GetDuePagesToday(ctx context.Context, userID int64, endOfDayUTC time.Time) ([]*models.UserProgress, error) {
 	query := `
-		SELECT user_id, page_id, level, repetition_count, last_review_date, next_review_date, interval_days, success_rate, reviewed_today, passed
+		SELECT user_id, page_id, level, repetition_count, last_review_date, next_review_date, interval_days, success_rate, reviewed_today, passed, version
 		FROM user_progress
 		WHERE user_id = $1 AND next_review_date < $2 AND reviewed_today = FALSE
 		ORDER BY next_review_date ASC
@@ -190,19 +381,22 @@ func (r Postgres) GetLastReviewScore(ctx context.Context, userID int64, pageID s
 	query := `
 		SELECT score
 		FROM progress_history
-		WHERE user_id = $1 AND page_id = $2
-		ORDER BY date DESC
+		WHERE user_id = $1 AND page_id = $2 AND undone_at IS NULL
+		ORDER BY date DESC, id DESC
 		LIMIT 1
 	`
 
-	var score int
-	err := r.GetContext(ctx, &score, query, userID, pageID)
-	if err != nil {
-		// Если записи нет, возвращаем 0
+	var scores []int
+	if err := r.SelectContext(ctx, &scores, query, userID, pageID); err != nil {
+		return 0, fmt.Errorf("get last review score (user_id: %d, page_id: %s): %w", userID, pageID, err)
+	}
+
+	// Если записи нет, возвращаем 0
+	if len(scores) == 0 {
 		return 0, nil
 	}
 
-	return score, nil
+	return scores[0], nil
 }
 
 func (r Postgres) DeleteProgress(ctx context.Context, userID int64, pageID string) error {
@@ -222,22 +416,40 @@ func (r Postgres) DeleteProgress(ctx context.Context, userID int64, pageID strin
 	return nil
 }
 
-func (r Postgres) ResetIntervalForPagesDueInMonth(ctx context.Context, userID int64, tomorrowUTC, monthFromNowUTC time.Time) error {
+// GetProgressDueBetween возвращает неизученные до конца страницы с датой повторения в [fromUTC, toUTC)
+func (r Postgres) GetProgressDueBetween(ctx context.Context, userID int64, fromUTC, toUTC time.Time) ([]*models.UserProgress, error) {
+	query := `
+		SELECT user_id, page_id, level, repetition_count, last_review_date, next_review_date, interval_days, success_rate, reviewed_today, passed, version
+		FROM user_progress
+		WHERE user_id = $1 AND next_review_date >= $2 AND next_review_date < $3 AND passed = FALSE
+		ORDER BY next_review_date ASC, page_id ASC
+	`
+
+	var progressList []*models.UserProgress
+	err := r.SelectContext(ctx, &progressList, query, userID, fromUTC, toUTC)
+	if err != nil {
+		return nil, fmt.Errorf("query progress due between (user_id: %d, from: %s, to: %s): %w", userID, fromUTC.Format(time.RFC3339), toUTC.Format(time.RFC3339), err)
+	}
+
+	return progressList, nil
+}
+
+// UpdateNextReviewDate переносит повторение страницы и увеличивает версию прогресса, чтобы оценка,
+// показанная до переноса, не применилась к перенесённой странице
+func (r Postgres) UpdateNextReviewDate(ctx context.Context, userID int64, pageID string, nextReviewDate time.Time) error {
 	query := r.psql.Update("user_progress").
-		Set("interval_days", 1).
-		Set("next_review_date", tomorrowUTC).
-		Where("user_id = ?", userID).
-		Where("next_review_date <= ?", monthFromNowUTC).
-		Where("passed = FALSE")
+		Set("next_review_date", nextReviewDate).
+		Set("version", squirrel.Expr("version + 1")).
+		Where("user_id = ? AND page_id = ?", userID, pageID)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+		return fmt.Errorf("build SQL query (user_id: %d, page_id: %s): %w", userID, pageID, err)
 	}
 
 	_, err = r.ExecContext(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf("reset interval for pages due in month (user_id: %d): %w", userID, err)
+		return fmt.Errorf("update next review date (user_id: %d, page_id: %s): %w", userID, pageID, err)
 	}
 
 	return nil
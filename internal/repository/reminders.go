@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+func (r Postgres) GetReminderTimes(ctx context.Context, userID int64) ([]string, error) {
+	query := r.psql.Select("reminder_time").
+		From("reminder_times").
+		Where("user_id = ?", userID).
+		OrderBy("reminder_time ASC")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+	}
+
+	var times []string
+	if err := r.SelectContext(ctx, &times, sql, args...); err != nil {
+		return nil, fmt.Errorf("get reminder times (user_id: %d): %w", userID, err)
+	}
+
+	return times, nil
+}
+
+// ReplaceReminderTimes заменяет все времена напоминаний пользователя, вызывается внутри транзакции
+func (r Postgres) ReplaceReminderTimes(ctx context.Context, userID int64, times []string) error {
+	deleteQuery := r.psql.Delete("reminder_times").
+		Where("user_id = ?", userID)
+
+	sql, args, err := deleteQuery.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("delete reminder times (user_id: %d): %w", userID, err)
+	}
+
+	if len(times) == 0 {
+		return nil
+	}
+
+	insertQuery := r.psql.Insert("reminder_times").
+		Columns("user_id", "reminder_time")
+
+	for _, reminderTime := range times {
+		insertQuery = insertQuery.Values(userID, reminderTime)
+	}
+
+	sql, args, err = insertQuery.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("insert reminder times (user_id: %d, count: %d): %w", userID, len(times), err)
+	}
+
+	return nil
+}
+
+func (r Postgres) SetRemindersEnabled(ctx context.Context, userID int64, enabled bool) error {
+	query := r.psql.Update("users").
+		Set("reminders_enabled", enabled).
+		Where("telegram_id = ?", userID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (telegram_id: %d): %w", userID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("set reminders enabled (telegram_id: %d, enabled: %v): %w", userID, enabled, err)
+	}
+	return nil
+}
+
+// UpdateQuietHours сохраняет тихие часы, nil в обоих полях их отключает
+func (r Postgres) UpdateQuietHours(ctx context.Context, userID int64, start, end *string) error {
+	query := r.psql.Update("users").
+		Set("quiet_hours_start", start).
+		Set("quiet_hours_end", end).
+		Where("telegram_id = ?", userID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (telegram_id: %d): %w", userID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("update quiet hours (telegram_id: %d): %w", userID, err)
+	}
+	return nil
+}
+
+// GetReminderSchedules возвращает времена напоминаний пользователя, пустой список — напоминания выключены
+func (r Postgres) GetReminderSchedules(ctx context.Context, userID int64) ([]*models.ReminderSchedule, error) {
+	query := `
+		SELECT rt.user_id, rt.reminder_time, u.language, u.timezone, u.quiet_hours_start, u.quiet_hours_end,
+		       u.vacation_start, u.vacation_end
+		FROM reminder_times rt
+		JOIN users u ON u.telegram_id = rt.user_id
+		WHERE rt.user_id = $1 AND u.reminders_enabled = TRUE
+		ORDER BY rt.reminder_time ASC
+	`
+
+	var schedules []*models.ReminderSchedule
+	if err := r.SelectContext(ctx, &schedules, query, userID); err != nil {
+		return nil, fmt.Errorf("get reminder schedules (user_id: %d): %w", userID, err)
+	}
+
+	return schedules, nil
+}
+
+// ClaimReminder занимает напоминание на локальную дату пользователя до claimedUntil. Напоминание, которое
+// занято, но не отправлено, а его аренда истекла к now, занимается заново.
+// Возвращает false, если напоминание уже отправлено или занято другим проходом или репликой.
+func (r Postgres) ClaimReminder(ctx context.Context, userID int64, localDate time.Time, reminderTime string, now, claimedUntil time.Time) (bool, error) {
+	query := r.psql.Insert("reminder_log").
+		Columns("user_id", "local_date", "reminder_time", "status", "claimed_until").
+		Values(userID, localDate, reminderTime, "pending", claimedUntil).
+		Suffix(`ON CONFLICT (user_id, local_date, reminder_time) DO UPDATE SET claimed_until = EXCLUDED.claimed_until
+			WHERE reminder_log.status = 'pending' AND reminder_log.claimed_until <= ?`, now)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("build SQL query (user_id: %d, reminder_time: %s): %w", userID, reminderTime, err)
+	}
+
+	result, err := r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("claim reminder (user_id: %d, reminder_time: %s): %w", userID, reminderTime, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("get rows affected (user_id: %d): %w", userID, err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// MarkReminderSent отмечает занятое напоминание отправленным, после этого оно больше не занимается
+func (r Postgres) MarkReminderSent(ctx context.Context, userID int64, localDate time.Time, reminderTime string) error {
+	query := r.psql.Update("reminder_log").
+		Set("status", "sent").
+		Set("sent_at", r.clock.Now()).
+		Set("claimed_until", nil).
+		Where("user_id = ? AND local_date = ? AND reminder_time = ?", userID, localDate, reminderTime)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, reminder_time: %s): %w", userID, reminderTime, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("mark reminder sent (user_id: %d, reminder_time: %s): %w", userID, reminderTime, err)
+	}
+	return nil
+}
+
+// DeleteReminderLog снимает занятие напоминания, чтобы напоминание отправилось на следующем проходе
+func (r Postgres) DeleteReminderLog(ctx context.Context, userID int64, localDate time.Time, reminderTime string) error {
+	query := r.psql.Delete("reminder_log").
+		Where("user_id = ? AND local_date = ? AND reminder_time = ?", userID, localDate, reminderTime)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, reminder_time: %s): %w", userID, reminderTime, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("delete reminder log (user_id: %d, reminder_time: %s): %w", userID, reminderTime, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// GetReviewSession возвращает сессию повторения пользователя на местную дату вместе со страницами или nil, если её нет
+func (r Postgres) GetReviewSession(ctx context.Context, userID int64, localDate time.Time) (*models.ReviewSession, error) {
+	query := `
+		SELECT id, user_id, local_date, status, created_at, finished_at
+		FROM review_sessions
+		WHERE user_id = $1 AND local_date = $2
+	`
+
+	var sessions []*models.ReviewSession
+	if err := r.SelectContext(ctx, &sessions, query, userID, localDate); err != nil {
+		return nil, fmt.Errorf("get review session (user_id: %d, local_date: %s): %w", userID, localDate.Format(time.DateOnly), err)
+	}
+
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	session := sessions[0]
+	itemsQuery := `
+		SELECT session_id, position, page_id, result, grade, reviewed_at
+		FROM review_session_items
+		WHERE session_id = $1
+		ORDER BY position ASC
+	`
+
+	if err := r.SelectContext(ctx, &session.Items, itemsQuery, session.ID); err != nil {
+		return nil, fmt.Errorf("get review session items (session_id: %d): %w", session.ID, err)
+	}
+
+	return session, nil
+}
+
+// CreateReviewSession создаёт сессию без страниц и записывает её ID в session.ID
+func (r Postgres) CreateReviewSession(ctx context.Context, session *models.ReviewSession) error {
+	query := r.psql.Insert("review_sessions").
+		Columns("user_id", "local_date", "status", "created_at").
+		Values(session.UserID, session.LocalDate, session.Status, session.CreatedAt).
+		Suffix("RETURNING id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d): %w", session.UserID, err)
+	}
+
+	if err := r.QueryRowxContext(ctx, sql, args...).Scan(&session.ID); err != nil {
+		return fmt.Errorf("create review session (user_id: %d, local_date: %s): %w", session.UserID, session.LocalDate.Format(time.DateOnly), err)
+	}
+
+	return nil
+}
+
+// AddReviewSessionItems добавляет страницы в конец очереди сессии начиная с позиции position
+func (r Postgres) AddReviewSessionItems(ctx context.Context, sessionID int64, position int, pageIDs []string) error {
+	if len(pageIDs) == 0 {
+		return nil
+	}
+
+	query := r.psql.Insert("review_session_items").
+		Columns("session_id", "position", "page_id")
+
+	for i, pageID := range pageIDs {
+		query = query.Values(sessionID, position+i, pageID)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (session_id: %d): %w", sessionID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("add review session items (session_id: %d, count: %d): %w", sessionID, len(pageIDs), err)
+	}
+
+	return nil
+}
+
+// SetReviewSessionItemResult записывает результат непройденной страницы сессии.
+// Возвращает false, если страница уже пройдена.
+func (r Postgres) SetReviewSessionItemResult(ctx context.Context, sessionID int64, pageID, result string, grade *int, reviewedAt time.Time) (bool, error) {
+	query := r.psql.Update("review_session_items").
+		Set("result", result).
+		Set("grade", grade).
+		Set("reviewed_at", reviewedAt).
+		Where("session_id = ? AND page_id = ? AND result IS NULL", sessionID, pageID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("build SQL query (session_id: %d, page_id: %s): %w", sessionID, pageID, err)
+	}
+
+	res, err := r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return false, fmt.Errorf("set review session item result (session_id: %d, page_id: %s): %w", sessionID, pageID, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("get rows affected (session_id: %d): %w", sessionID, err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ResetReviewSessionItemResult снова делает страницу сессии непройденной
+func (r Postgres) ResetReviewSessionItemResult(ctx context.Context, sessionID int64, pageID string) error {
+	query := r.psql.Update("review_session_items").
+		Set("result", nil).
+		Set("grade", nil).
+		Set("reviewed_at", nil).
+		Where("session_id = ? AND page_id = ?", sessionID, pageID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (session_id: %d, page_id: %s): %w", sessionID, pageID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("reset review session item result (session_id: %d, page_id: %s): %w", sessionID, pageID, err)
+	}
+
+	return nil
+}
+
+// UpdateReviewSessionStatus меняет статус сессии, finishedAt nil — сессия снова активна
+func (r Postgres) UpdateReviewSessionStatus(ctx context.Context, sessionID int64, status string, finishedAt *time.Time) error {
+	query := r.psql.Update("review_sessions").
+		Set("status", status).
+		Set("finished_at", finishedAt).
+		Where("id = ?", sessionID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (session_id: %d): %w", sessionID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("update review session status (session_id: %d, status: %s): %w", sessionID, status, err)
+	}
+
+	return nil
+}
+
+// FinishReviewSessionsBefore завершает незаконченные сессии прошлых дней
+func (r Postgres) FinishReviewSessionsBefore(ctx context.Context, userID int64, localDate, finishedAt time.Time) error {
+	query := r.psql.Update("review_sessions").
+		Set("status", models.ReviewSessionFinished).
+		Set("finished_at", finishedAt).
+		Where("user_id = ? AND local_date < ? AND status = ?", userID, localDate, models.ReviewSessionActive)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+	}
+
+	if _, err = r.ExecContext(ctx, sql, args...); err != nil {
+		return fmt.Errorf("finish review sessions before (user_id: %d, local_date: %s): %w", userID, localDate.Format(time.DateOnly), err)
+	}
+
+	return nil
+}
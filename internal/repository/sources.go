@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+func (r Postgres) AddUserSource(ctx context.Context, source *models.UserSource) error {
+	// Повторное добавление той же секции включает её обратно и обновляет названия, приоритет сохраняется
+	query := r.psql.Insert("user_sources").
+		Columns("user_id", "notebook_id", "section_id", "notebook_name", "section_name", "enabled", "priority", "created_at").
+		Values(source.UserID, source.NotebookID, source.SectionID, source.NotebookName, source.SectionName, source.Enabled, source.Priority, source.CreatedAt).
+		Suffix(`ON CONFLICT (user_id, section_id) DO UPDATE SET
+			notebook_id = EXCLUDED.notebook_id,
+			notebook_name = EXCLUDED.notebook_name,
+			section_name = EXCLUDED.section_name,
+			enabled = TRUE`)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, section_id: %s): %w", source.UserID, source.SectionID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("add user source (user_id: %d, notebook_id: %s, section_id: %s): %w", source.UserID, source.NotebookID, source.SectionID, err)
+	}
+	return nil
+}
+
+func (r Postgres) GetUserSources(ctx context.Context, userID int64, onlyEnabled bool) ([]*models.UserSource, error) {
+	query := `
+		SELECT id, user_id, notebook_id, section_id, COALESCE(notebook_name, '') AS notebook_name,
+		       COALESCE(section_name, '') AS section_name, enabled, priority, sync_cursor, full_synced_at, created_at
+		FROM user_sources
+		WHERE user_id = $1
+	`
+
+	if onlyEnabled {
+		query += " AND enabled = TRUE"
+	}
+
+	query += " ORDER BY priority ASC, id ASC"
+
+	var sources []*models.UserSource
+	if err := r.SelectContext(ctx, &sources, query, userID); err != nil {
+		return nil, fmt.Errorf("query user sources (user_id: %d): %w", userID, err)
+	}
+
+	return sources, nil
+}
+
+func (r Postgres) SetUserSourceEnabled(ctx context.Context, userID, sourceID int64, enabled bool) error {
+	query := r.psql.Update("user_sources").
+		Set("enabled", enabled).
+		Where("user_id = ? AND id = ?", userID, sourceID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("set user source enabled (user_id: %d, source_id: %d, enabled: %v): %w", userID, sourceID, enabled, err)
+	}
+	return nil
+}
+
+func (r Postgres) UpdateUserSourcePriority(ctx context.Context, userID, sourceID int64, priority int) error {
+	query := r.psql.Update("user_sources").
+		Set("priority", priority).
+		Where("user_id = ? AND id = ?", userID, sourceID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("update user source priority (user_id: %d, source_id: %d, priority: %d): %w", userID, sourceID, priority, err)
+	}
+	return nil
+}
+
+func (r Postgres) DeleteUserSource(ctx context.Context, userID, sourceID int64) error {
+	query := r.psql.Delete("user_sources").
+		Where("user_id = ? AND id = ?", userID, sourceID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("delete user source (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+	return nil
+}
+
+func (r Postgres) UpdateUserSourceSyncCursor(ctx context.Context, userID, sourceID int64, cursor time.Time) error {
+	query := r.psql.Update("user_sources").
+		Set("sync_cursor", cursor).
+		Where("user_id = ? AND id = ?", userID, sourceID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("update user source sync cursor (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+	return nil
+}
+
+func (r Postgres) UpdateUserSourceNotebookName(ctx context.Context, userID, sourceID int64, notebookName string) error {
+	query := r.psql.Update("user_sources").
+		Set("notebook_name", notebookName).
+		Where("user_id = ? AND id = ?", userID, sourceID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("update user source notebook name (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+	return nil
+}
+
+func (r Postgres) UpdateUserSourceFullSync(ctx context.Context, userID, sourceID int64, fullSyncedAt time.Time) error {
+	query := r.psql.Update("user_sources").
+		Set("full_synced_at", fullSyncedAt).
+		Where("user_id = ? AND id = ?", userID, sourceID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("update user source full sync (user_id: %d, source_id: %d): %w", userID, sourceID, err)
+	}
+	return nil
+}
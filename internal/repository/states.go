@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// GetChatState возвращает состояние диалога в чате или nil, если чат ещё не сохранял состояние
+func (r Postgres) GetChatState(ctx context.Context, chatID int64) (*models.ChatState, error) {
+	query := `SELECT chat_id, user_id, state, data, updated_at FROM chat_states WHERE chat_id = $1`
+
+	var rows []struct {
+		ChatID    int64     `db:"chat_id"`
+		UserID    int64     `db:"user_id"`
+		State     string    `db:"state"`
+		Data      []byte    `db:"data"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	if err := r.SelectContext(ctx, &rows, query, chatID); err != nil {
+		return nil, fmt.Errorf("get chat state (chat_id: %d): %w", chatID, err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	state := &models.ChatState{
+		ChatID:    rows[0].ChatID,
+		UserID:    rows[0].UserID,
+		State:     rows[0].State,
+		UpdatedAt: rows[0].UpdatedAt,
+	}
+
+	if err := json.Unmarshal(rows[0].Data, &state.Data); err != nil {
+		return nil, fmt.Errorf("unmarshal chat state data (chat_id: %d): %w", chatID, err)
+	}
+
+	return state, nil
+}
+
+func (r Postgres) SaveChatState(ctx context.Context, state *models.ChatState) error {
+	data, err := json.Marshal(state.Data)
+	if err != nil {
+		return fmt.Errorf("marshal chat state data (chat_id: %d): %w", state.ChatID, err)
+	}
+
+	if state.Data == nil {
+		data = []byte("{}")
+	}
+
+	query := r.psql.Insert("chat_states").
+		Columns("chat_id", "user_id", "state", "data", "updated_at").
+		Values(state.ChatID, state.UserID, state.State, string(data), state.UpdatedAt).
+		Suffix(`ON CONFLICT (chat_id) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			state = EXCLUDED.state,
+			data = EXCLUDED.data,
+			updated_at = EXCLUDED.updated_at`)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (chat_id: %d): %w", state.ChatID, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("save chat state (chat_id: %d, state: %s): %w", state.ChatID, state.State, err)
+	}
+	return nil
+}
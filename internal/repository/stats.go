@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// GetDailyReviews возвращает количество и сумму оценок по местным датам пользователя начиная с fromUTC.
+// Отменённые оценки не учитываются, дни без повторений не возвращаются.
+func (r Postgres) GetDailyReviews(ctx context.Context, userID int64, timezone string, fromUTC time.Time) ([]*models.DailyReviews, error) {
+	query := `
+		SELECT (date AT TIME ZONE $2)::date AS day, COUNT(*) AS reviews, SUM(score) AS score_sum
+		FROM progress_history
+		WHERE user_id = $1 AND date >= $3 AND undone_at IS NULL
+		GROUP BY day
+		ORDER BY day ASC
+	`
+
+	var days []*models.DailyReviews
+	if err := r.SelectContext(ctx, &days, query, userID, timezone, fromUTC); err != nil {
+		return nil, fmt.Errorf("get daily reviews (user_id: %d, timezone: %s): %w", userID, timezone, err)
+	}
+
+	return days, nil
+}
+
+// GetIntervalRetention возвращает по интервалу перед оценкой количество оценок и сколько из них выше 60.
+// Учитываются только записи истории с состоянием до оценки.
+func (r Postgres) GetIntervalRetention(ctx context.Context, userID int64) ([]*models.IntervalRetention, error) {
+	query := `
+		SELECT (progress_before->>'IntervalDays')::int AS interval_days,
+			COUNT(*) AS reviews,
+			COUNT(*) FILTER (WHERE score > 60) AS remembered
+		FROM progress_history
+		WHERE user_id = $1 AND undone_at IS NULL AND progress_before IS NOT NULL
+		GROUP BY interval_days
+		ORDER BY interval_days ASC
+	`
+
+	var retention []*models.IntervalRetention
+	if err := r.SelectContext(ctx, &retention, query, userID); err != nil {
+		return nil, fmt.Errorf("get interval retention (user_id: %d): %w", userID, err)
+	}
+
+	return retention, nil
+}
+
+// GetPagesPerInterval возвращает количество изучаемых (не пройденных) страниц по текущему интервалу
+func (r Postgres) GetPagesPerInterval(ctx context.Context, userID int64) ([]*models.IntervalPages, error) {
+	query := `
+		SELECT interval_days, COUNT(*) AS pages
+		FROM user_progress
+		WHERE user_id = $1 AND passed = FALSE
+		GROUP BY interval_days
+		ORDER BY interval_days ASC
+	`
+
+	var pages []*models.IntervalPages
+	if err := r.SelectContext(ctx, &pages, query, userID); err != nil {
+		return nil, fmt.Errorf("get pages per interval (user_id: %d): %w", userID, err)
+	}
+
+	return pages, nil
+}
+
+// CountPassedPages возвращает количество полностью изученных страниц
+func (r Postgres) CountPassedPages(ctx context.Context, userID int64) (int, error) {
+	query := r.psql.Select("COUNT(*)").
+		From("user_progress").
+		Where("user_id = ? AND passed = TRUE", userID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("build SQL query (user_id: %d): %w", userID, err)
+	}
+
+	var count int
+	if err := r.QueryRowxContext(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count passed pages (user_id: %d): %w", userID, err)
+	}
+	return count, nil
+}
+
+// GetUpcomingLoad возвращает количество повторений изучаемых страниц по местным датам до toUTC, включая просроченные
+func (r Postgres) GetUpcomingLoad(ctx context.Context, userID int64, timezone string, toUTC time.Time) ([]*models.DailyLoad, error) {
+	query := `
+		SELECT (next_review_date AT TIME ZONE $2)::date AS day, COUNT(*) AS pages
+		FROM user_progress
+		WHERE user_id = $1 AND passed = FALSE AND next_review_date < $3
+		GROUP BY day
+		ORDER BY day ASC
+	`
+
+	var load []*models.DailyLoad
+	if err := r.SelectContext(ctx, &load, query, userID, timezone, toUTC); err != nil {
+		return nil, fmt.Errorf("get upcoming load (user_id: %d, timezone: %s): %w", userID, timezone, err)
+	}
+
+	return load, nil
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils"
+)
+
+// statsTestUserID — пользователь, которого тесты создают и удаляют в тестовой базе
+const statsTestUserID int64 = -4900001
+
+// newTestRepository подключается к базе из TEST_DATABASE_URL и применяет миграции.
+// Без TEST_DATABASE_URL тест пропускается.
+func newTestRepository(t *testing.T) *Postgres {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	repo, err := NewDB(dsn, 2, 2, utils.SystemClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = repo.Close() })
+
+	if err = repo.Up("../../migrations"); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// deleteTestUser удаляет пользователя вместе с прогрессом и историей
+func deleteTestUser(t *testing.T, repo *Postgres, userID int64) {
+	t.Helper()
+
+	if _, err := repo.ExecContext(context.Background(), "DELETE FROM users WHERE telegram_id = $1", userID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedStats создаёт пользователя с прогрессом и историей оценок в Europe/Berlin (летом UTC+2)
+func seedStats(t *testing.T, repo *Postgres) {
+	t.Helper()
+	ctx := context.Background()
+
+	deleteTestUser(t, repo, statsTestUserID)
+	t.Cleanup(func() { deleteTestUser(t, repo, statsTestUserID) })
+
+	maxPagesPerDay := uint(2)
+	if err := repo.CreateUser(ctx, &models.User{TelegramID: statsTestUserID, Username: "stats", MaxPagesPerDay: &maxPagesPerDay, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	progress := []*models.UserProgress{
+		// 23:30 UTC — уже 11 июля по Берлину
+		{PageID: "page-1", IntervalDays: 1, NextReviewDate: time.Date(2026, 7, 10, 23, 30, 0, 0, time.UTC)},
+		{PageID: "page-2", IntervalDays: 3, NextReviewDate: time.Date(2026, 7, 11, 8, 0, 0, 0, time.UTC)},
+		{PageID: "page-3", IntervalDays: 3, NextReviewDate: time.Date(2026, 7, 12, 10, 0, 0, 0, time.UTC)},
+		{PageID: "page-4", IntervalDays: 7, NextReviewDate: time.Date(2026, 7, 20, 10, 0, 0, 0, time.UTC)},
+		{PageID: "page-5", IntervalDays: 30, NextReviewDate: time.Date(2026, 7, 11, 10, 0, 0, 0, time.UTC), Passed: true},
+	}
+	for _, p := range progress {
+		p.UserID = statsTestUserID
+		if err := repo.CreateProgress(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := []struct {
+		pageID string
+		date   time.Time
+		score  int
+		before *models.UserProgress
+	}{
+		{"page-1", time.Date(2026, 6, 20, 10, 0, 0, 0, time.UTC), 50, &models.UserProgress{IntervalDays: 1}},
+		// 22:30 UTC — уже 2 июля по Берлину
+		{"page-1", time.Date(2026, 7, 1, 22, 30, 0, 0, time.UTC), 80, &models.UserProgress{IntervalDays: 1}},
+		{"page-2", time.Date(2026, 7, 2, 10, 0, 0, 0, time.UTC), 40, &models.UserProgress{IntervalDays: 1}},
+		{"page-2", time.Date(2026, 7, 3, 10, 0, 0, 0, time.UTC), 90, &models.UserProgress{IntervalDays: 3}},
+		// Старая запись без состояния до оценки
+		{"page-3", time.Date(2026, 7, 3, 11, 0, 0, 0, time.UTC), 100, nil},
+		{"page-3", time.Date(2026, 7, 3, 12, 0, 0, 0, time.UTC), 10, &models.UserProgress{IntervalDays: 3}},
+	}
+	for _, h := range history {
+		if err := repo.AddProgressHistory(ctx, statsTestUserID, h.pageID, models.ProgressHistory{Date: h.date, Score: h.score, Before: h.before}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Последняя оценка page-3 отменена и нигде не учитывается
+	last, err := repo.GetLastProgressHistory(ctx, statsTestUserID, "page-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.MarkProgressHistoryUndone(ctx, statsTestUserID, last.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatsQueries(t *testing.T) {
+	repo := newTestRepository(t)
+	seedStats(t, repo)
+	ctx := context.Background()
+	timezone := "Europe/Berlin"
+	date := func(day time.Time) string { return day.Format("2006-01-02") }
+
+	t.Run("daily reviews", func(t *testing.T) {
+		days, err := repo.GetDailyReviews(ctx, statsTestUserID, timezone, time.Date(2026, 6, 30, 22, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []models.DailyReviews{
+			{Date: time.Date(2026, 7, 2, 0, 0, 0, 0, time.UTC), Reviews: 2, ScoreSum: 120},
+			{Date: time.Date(2026, 7, 3, 0, 0, 0, 0, time.UTC), Reviews: 2, ScoreSum: 190},
+		}
+		if len(days) != len(want) {
+			t.Fatalf("got %d days, want %d", len(days), len(want))
+		}
+		for i, day := range days {
+			if date(day.Date) != date(want[i].Date) || day.Reviews != want[i].Reviews || day.ScoreSum != want[i].ScoreSum {
+				t.Errorf("day %d = %s %d/%d, want %s %d/%d", i, date(day.Date), day.Reviews, day.ScoreSum, date(want[i].Date), want[i].Reviews, want[i].ScoreSum)
+			}
+		}
+	})
+
+	t.Run("interval retention", func(t *testing.T) {
+		retention, err := repo.GetIntervalRetention(ctx, statsTestUserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []models.IntervalRetention{
+			{IntervalDays: 1, Reviews: 3, Remembered: 1},
+			{IntervalDays: 3, Reviews: 1, Remembered: 1},
+		}
+		if len(retention) != len(want) {
+			t.Fatalf("got %d intervals, want %d", len(retention), len(want))
+		}
+		for i, interval := range retention {
+			if *interval != want[i] {
+				t.Errorf("interval %d = %+v, want %+v", i, *interval, want[i])
+			}
+		}
+	})
+
+	t.Run("pages per interval", func(t *testing.T) {
+		pages, err := repo.GetPagesPerInterval(ctx, statsTestUserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []models.IntervalPages{
+			{IntervalDays: 1, Pages: 1},
+			{IntervalDays: 3, Pages: 2},
+			{IntervalDays: 7, Pages: 1},
+		}
+		if len(pages) != len(want) {
+			t.Fatalf("got %d intervals, want %d", len(pages), len(want))
+		}
+		for i, interval := range pages {
+			if *interval != want[i] {
+				t.Errorf("interval %d = %+v, want %+v", i, *interval, want[i])
+			}
+		}
+	})
+
+	t.Run("passed pages", func(t *testing.T) {
+		count, err := repo.CountPassedPages(ctx, statsTestUserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("got %d passed pages, want 1", count)
+		}
+	})
+
+	t.Run("upcoming load", func(t *testing.T) {
+		load, err := repo.GetUpcomingLoad(ctx, statsTestUserID, timezone, time.Date(2026, 7, 14, 22, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []models.DailyLoad{
+			{Date: time.Date(2026, 7, 11, 0, 0, 0, 0, time.UTC), Pages: 2},
+			{Date: time.Date(2026, 7, 12, 0, 0, 0, 0, time.UTC), Pages: 1},
+		}
+		if len(load) != len(want) {
+			t.Fatalf("got %d days, want %d", len(load), len(want))
+		}
+		for i, day := range load {
+			if date(day.Date) != date(want[i].Date) || day.Pages != want[i].Pages {
+				t.Errorf("day %d = %s %d, want %s %d", i, date(day.Date), day.Pages, date(want[i].Date), want[i].Pages)
+			}
+		}
+	})
+}
+
+// Статистика читает интервал до оценки из jsonb по ключу IntervalDays, поэтому ключ не должен меняться
+func TestMarshalProgressKeepsIntervalDaysKey(t *testing.T) {
+	data, err := marshalProgress(&models.UserProgress{IntervalDays: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal([]byte(data.(string)), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if string(fields["IntervalDays"]) != "7" {
+		t.Errorf("progress JSON %s has no IntervalDays key", data)
+	}
+}
@@ -28,8 +28,8 @@ func populateOneNoteFields(user *models.User) {
 
 func (r Postgres) CreateUser(ctx context.Context, user *models.User) error {
 	query := r.psql.Insert("users").
-		Columns("telegram_id", "username", "level", "use_manual_pages", "reminder_time", "max_pages_per_day", "created_at").
-		Values(user.TelegramID, user.Username, user.Level, user.UseManualPages, user.ReminderTime, user.MaxPagesPerDay, user.CreatedAt)
+		Columns("telegram_id", "username", "level", "use_manual_pages", "max_pages_per_day", "created_at", "language").
+		Values(user.TelegramID, user.Username, user.Level, user.UseManualPages, user.MaxPagesPerDay, user.CreatedAt, user.Language)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -47,8 +47,9 @@ func (r Postgres) GetUser(ctx context.Context, telegramID int64) (*models.User,
 	query := `
 		SELECT telegram_id, username, level, onenote_access_token, onenote_refresh_token, 
 		       onenote_expires_at, onenote_auth_code, onenote_notebook_id, onenote_section_id, 
-		       use_manual_pages, reminder_time, max_pages_per_day, created_at,
-		       is_paused, last_activity_date, timezone, last_cron_processed_at
+		       use_manual_pages, max_pages_per_day, created_at,
+		       is_paused, last_activity_date, timezone, last_cron_processed_at, language,
+		       reminders_enabled, quiet_hours_start, quiet_hours_end, vacation_start, vacation_end
 		FROM users WHERE telegram_id = $1
 	`
 
@@ -150,28 +151,42 @@ func (r Postgres) UpdateOneNoteConfig(ctx context.Context, telegramID int64, con
 	return nil
 }
 
-func (r Postgres) GetAllUsersWithReminders(ctx context.Context) ([]*models.User, error) {
-	query := `
-		SELECT telegram_id, username, level, onenote_access_token, onenote_refresh_token, 
-		       onenote_expires_at, onenote_auth_code, onenote_notebook_id, onenote_section_id, 
-		       use_manual_pages, reminder_time, max_pages_per_day, created_at,
-		       is_paused, last_activity_date, timezone, last_cron_processed_at
-		FROM users
-	`
+// GetUserLanguage возвращает язык пользователя, пустая строка — пользователь не зарегистрирован
+func (r Postgres) GetUserLanguage(ctx context.Context, telegramID int64) (string, error) {
+	query := r.psql.Select("language").From("users").Where("telegram_id = ?", telegramID)
 
-	var dbUsers []models.User
-	if err := r.SelectContext(ctx, &dbUsers, query); err != nil {
-		return nil, fmt.Errorf("query users: %w", err)
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return "", fmt.Errorf("build SQL query (telegram_id: %d): %w", telegramID, err)
 	}
 
-	users := make([]*models.User, len(dbUsers))
-	for i := range dbUsers {
-		user := &dbUsers[i]
-		populateOneNoteFields(user)
-		users[i] = user
+	var languages []string
+	if err := r.SelectContext(ctx, &languages, sql, args...); err != nil {
+		return "", fmt.Errorf("get user language (telegram_id: %d): %w", telegramID, err)
 	}
 
-	return users, nil
+	if len(languages) == 0 {
+		return "", nil
+	}
+
+	return languages[0], nil
+}
+
+func (r Postgres) UpdateUserLanguage(ctx context.Context, telegramID int64, language string) error {
+	query := r.psql.Update("users").
+		Set("language", language).
+		Where("telegram_id = ?", telegramID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("build SQL query (telegram_id: %d, language: %s): %w", telegramID, language, err)
+	}
+
+	_, err = r.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("update user language (telegram_id: %d, language: %s): %w", telegramID, language, err)
+	}
+	return nil
 }
 
 func (r Postgres) UpdateMaxPagesPerDay(ctx context.Context, telegramID int64, maxPages uint) error {
@@ -242,35 +257,6 @@ func (r Postgres) SetUserPaused(ctx context.Context, userID int64, paused bool)
 	return nil
 }
 
-func (r Postgres) GetUsersWithoutActivityAfter(ctx context.Context, afterTime time.Time, excludePaused bool) ([]*models.User, error) {
-	query := `
-		SELECT telegram_id, username, level, onenote_access_token, onenote_refresh_token, 
-		       onenote_expires_at, onenote_auth_code, onenote_notebook_id, onenote_section_id, 
-		       use_manual_pages, reminder_time, max_pages_per_day, created_at,
-		       is_paused, last_activity_date, timezone, last_cron_processed_at
-		FROM users
-		WHERE (last_activity_date IS NULL OR last_activity_date < $1)
-	`
-
-	if excludePaused {
-		query += " AND is_paused = FALSE"
-	}
-
-	var dbUsers []models.User
-	if err := r.SelectContext(ctx, &dbUsers, query, afterTime); err != nil {
-		return nil, fmt.Errorf("get users without activity after %v: %w", afterTime, err)
-	}
-
-	users := make([]*models.User, len(dbUsers))
-	for i := range dbUsers {
-		user := &dbUsers[i]
-		populateOneNoteFields(user)
-		users[i] = user
-	}
-
-	return users, nil
-}
-
 func (r Postgres) UpdateLastCronProcessedAt(ctx context.Context, userID int64, processedAt time.Time) error {
 	query := r.psql.Update("users").
 		Set("last_cron_processed_at", processedAt).
@@ -288,28 +274,21 @@ func (r Postgres) UpdateLastCronProcessedAt(ctx context.Context, userID int64, p
 	return nil
 }
 
-func (r Postgres) TryProcessDailyCronForUser(ctx context.Context, userID int64, startOfTodayUTC time.Time) (bool, error) {
-	// Atomically update last_cron_processed_at only if it hasn't been processed today
-	// This ensures only one replica processes each user
+// UpdateVacation сохраняет даты отпуска, nil в обоих полях его отменяет
+func (r Postgres) UpdateVacation(ctx context.Context, userID int64, start, end *time.Time) error {
 	query := r.psql.Update("users").
-		Set("last_cron_processed_at", time.Now().UTC()).
-		Where("telegram_id = ?", userID).
-		Where("(last_cron_processed_at IS NULL OR last_cron_processed_at < ?)", startOfTodayUTC)
+		Set("vacation_start", start).
+		Set("vacation_end", end).
+		Where("telegram_id = ?", userID)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return false, fmt.Errorf("build SQL query (telegram_id: %d): %w", userID, err)
-	}
-
-	result, err := r.ExecContext(ctx, sql, args...)
-	if err != nil {
-		return false, fmt.Errorf("try process daily cron for user (telegram_id: %d): %w", userID, err)
+		return fmt.Errorf("build SQL query (telegram_id: %d): %w", userID, err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	_, err = r.ExecContext(ctx, sql, args...)
 	if err != nil {
-		return false, fmt.Errorf("get rows affected (telegram_id: %d): %w", userID, err)
+		return fmt.Errorf("update vacation (telegram_id: %d): %w", userID, err)
 	}
-
-	return rowsAffected > 0, nil
+	return nil
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// callbackTokenTTL — сколько живёт кнопка со ссылкой на страницу
+const callbackTokenTTL = 24 * time.Hour
+
+// SaveCallbackTokens сохраняет токены кнопок перед отправкой сообщения и заодно удаляет просроченные токены пользователя
+func (s *Service) SaveCallbackTokens(ctx context.Context, telegramID int64, tokens []*models.CallbackToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	now := s.clock.Now()
+	for _, token := range tokens {
+		token.UserID = telegramID
+		token.IssuedAt = now
+		token.ExpiresAt = now.Add(callbackTokenTTL)
+	}
+
+	err := s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if err := txRepo.DeleteExpiredCallbackTokens(ctx, telegramID, now); err != nil {
+			return err
+		}
+		return txRepo.SaveCallbackTokens(ctx, tokens)
+	})
+	if err != nil {
+		return fmt.Errorf("save callback tokens (telegram_id: %d): %w", telegramID, err)
+	}
+
+	return nil
+}
+
+// GetCallbackToken возвращает действие кнопки; nil означает, что кнопка устарела или чужая
+func (s *Service) GetCallbackToken(ctx context.Context, telegramID int64, token string) (*models.CallbackToken, error) {
+	callback, err := s.repo.GetCallbackToken(ctx, telegramID, token, s.clock.Now(), false)
+	if err != nil {
+		return nil, fmt.Errorf("get callback token (telegram_id: %d): %w", telegramID, err)
+	}
+
+	return callback, nil
+}
+
+// ConsumeCallbackToken атомарно забирает одноразовое действие над страницей (пропуск или отмену оценки): вместе с токеном
+// удаляются все остальные кнопки этой страницы, кроме показа и истории, поэтому повторное нажатие
+// (в том же или в другом сообщении) распознаётся как устаревшее. Кнопки оценки не забираются:
+// повторную оценку распознаёт UpdateReviewProgress по версии прогресса и ключу идемпотентности.
+// nil означает, что кнопка устарела или чужая.
+func (s *Service) ConsumeCallbackToken(ctx context.Context, telegramID int64, token string) (*models.CallbackToken, error) {
+	var consumed *models.CallbackToken
+
+	err := s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		callback, err := txRepo.GetCallbackToken(ctx, telegramID, token, s.clock.Now(), true)
+		if err != nil || callback == nil {
+			return err
+		}
+
+		keep := []string{models.CallbackActionShowPage, models.CallbackActionHistory}
+		if err := txRepo.DeletePageCallbackTokens(ctx, telegramID, callback.PageID, keep); err != nil {
+			return err
+		}
+
+		consumed = callback
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("consume callback token (telegram_id: %d): %w", telegramID, err)
+	}
+
+	return consumed, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+const (
+	// comebackBreak — перерыв, после которого накопившиеся повторения предлагается распределить по дням
+	comebackBreak = 3 * 24 * time.Hour
+	// MaxComebackPagesPerDay ограничивает темп, который можно выбрать в плане возвращения
+	MaxComebackPagesPerDay = 10
+)
+
+// ErrComebackPlanChanged — план изменился с тех пор, как его показали пользователю, например, часть страниц
+// уже повторена или наступил новый день. Такой план не применяется, пользователю нужно показать новый.
+var ErrComebackPlanChanged = errors.New("comeback plan changed")
+
+// SuggestComebackPlan предлагает план возвращения пользователю, который вернулся после перерыва или паузы
+// и у которого накопилось больше повторений, чем лимит страниц в день. nil — план не нужен.
+func (s *Service) SuggestComebackPlan(ctx context.Context, telegramID int64) (*models.ComebackPlan, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	now := s.clock.Now()
+	paused := user.IsPaused != nil && *user.IsPaused
+	lastActivity := lastActivityWithVacation(user, now)
+	if !paused && lastActivity != nil && !lastActivity.Before(now.Add(-comebackBreak)) {
+		return nil, nil
+	}
+
+	return s.buildComebackPlan(ctx, user, 0)
+}
+
+// GetComebackPlan распределяет накопившиеся повторения по pagesPerDay в день, 0 — по лимиту страниц пользователя.
+// nil — повторений не больше, чем помещается в один день.
+func (s *Service) GetComebackPlan(ctx context.Context, telegramID int64, pagesPerDay int) (*models.ComebackPlan, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	return s.buildComebackPlan(ctx, user, pagesPerDay)
+}
+
+// ApplyComebackPlan переносит повторения по плану с темпом pagesPerDay: страницы первого дня остаются на сегодня,
+// остальные получают дату своего дня. Интервалы не меняются. Принятие плана считается активностью и снимает паузу.
+// План строится заново и применяется, только если его отпечаток совпадает с fingerprint показанного плана,
+// иначе возвращается ErrComebackPlanChanged.
+func (s *Service) ApplyComebackPlan(ctx context.Context, telegramID int64, pagesPerDay int, fingerprint string) (*models.ComebackPlan, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	plan, err := s.buildComebackPlan(ctx, user, pagesPerDay)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan != nil && plan.Fingerprint() != fingerprint {
+		return nil, ErrComebackPlanChanged
+	}
+
+	timezone := userTimezone(user)
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if plan != nil {
+			for _, day := range plan.Days[1:] {
+				nextReview := localMidnight(day.Date, timezone).UTC()
+				for _, pwp := range day.Pages {
+					if err := txRepo.UpdateNextReviewDate(ctx, telegramID, pwp.Page.PageID, nextReview); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return s.markReturned(ctx, txRepo, user)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("apply comeback plan (telegram_id: %d, pages_per_day: %d): %w", telegramID, pagesPerDay, err)
+	}
+
+	return plan, nil
+}
+
+// DismissComebackPlan оставляет все накопившиеся повторения на сегодня, чтобы план больше не предлагался
+func (s *Service) DismissComebackPlan(ctx context.Context, telegramID int64) error {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		return s.markReturned(ctx, txRepo, user)
+	})
+	if err != nil {
+		return fmt.Errorf("dismiss comeback plan (telegram_id: %d): %w", telegramID, err)
+	}
+	return nil
+}
+
+// markReturned отмечает активность пользователя и снимает паузу, если он был приостановлен
+func (s *Service) markReturned(ctx context.Context, txRepo models.Repository, user *models.User) error {
+	if err := txRepo.UpdateUserActivity(ctx, user.TelegramID, s.clock.Now()); err != nil {
+		return err
+	}
+
+	if user.IsPaused != nil && *user.IsPaused {
+		return txRepo.SetUserPaused(ctx, user.TelegramID, false)
+	}
+	return nil
+}
+
+// buildComebackPlan ранжирует накопившиеся повторения по риску забыть страницу и раскладывает их по дням,
+// начиная с сегодняшнего: в день вместе с уже назначенными на него повторениями — не больше pagesPerDay.
+// Риск считается на начало сегодняшнего дня, поэтому в течение дня план не меняется, пока не меняются страницы.
+func (s *Service) buildComebackPlan(ctx context.Context, user *models.User, pagesPerDay int) (*models.ComebackPlan, error) {
+	if pagesPerDay <= 0 {
+		pagesPerDay = userMaxPagesPerDay(user)
+	}
+	pagesPerDay = min(pagesPerDay, MaxComebackPagesPerDay)
+
+	duePages, err := s.GetDuePagesToday(ctx, user.TelegramID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(duePages) <= pagesPerDay {
+		return nil, nil
+	}
+
+	timezone := userTimezone(user)
+	today := localDate(s.clock.Now(), timezone)
+	startOfToday := localMidnight(today, timezone)
+
+	risks := make(map[string]float64, len(duePages))
+	for _, pwp := range duePages {
+		lastScore, err := s.repo.GetLastReviewScore(ctx, user.TelegramID, pwp.Page.PageID)
+		if err != nil {
+			return nil, fmt.Errorf("get last review score (telegram_id: %d, page_id: %s): %w", user.TelegramID, pwp.Page.PageID, err)
+		}
+		risks[pwp.Page.PageID] = comebackRisk(pwp.Progress, lastScore, startOfToday)
+	}
+
+	ranked := slices.Clone(duePages)
+	slices.SortStableFunc(ranked, func(a, b *models.PageWithProgress) int {
+		return cmp.Compare(risks[b.Page.PageID], risks[a.Page.PageID])
+	})
+
+	// Сегодняшние повторения — это и есть накопившиеся страницы, поэтому нагрузка считается со следующего дня
+	load, err := s.getDailyLoad(ctx, user.TelegramID, timezone, today.AddDate(0, 0, 1), today.AddDate(0, 0, len(ranked)+1), pagesPerDay)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &models.ComebackPlan{PagesPerDay: pagesPerDay}
+	var current *models.ComebackDay
+	for _, pwp := range ranked {
+		day := today
+		if current != nil {
+			day = current.Date
+		}
+
+		// День, уже заполненный назначенными повторениями, пропускается
+		day = load.place(day)
+		if current == nil || !current.Date.Equal(day) {
+			current = &models.ComebackDay{Date: day}
+			plan.Days = append(plan.Days, current)
+		}
+		current.Pages = append(current.Pages, pwp)
+	}
+
+	return plan, nil
+}
+
+// comebackRisk оценивает риск забыть страницу: чем дольше она просрочена относительно своего интервала,
+// чем ниже последняя оценка и чем короче интервал, тем раньше её стоит повторить
+func comebackRisk(progress *models.UserProgress, lastScore int, now time.Time) float64 {
+	interval := float64(max(progress.IntervalDays, 1))
+	overdueDays := max(now.Sub(progress.NextReviewDate).Hours()/24, 0)
+	return overdueDays/interval + float64(100-lastScore)/100 + 1/interval
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/onenote"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+// comebackRepo — хранилище в памяти для плана возвращения: накопившиеся страницы с последними оценками,
+// уже назначенная на следующие дни нагрузка и перенесённые планом даты повторения
+type comebackRepo struct {
+	models.Repository
+
+	user     models.User
+	progress []*models.UserProgress
+	scores   map[string]int
+	load     []*models.DailyLoad
+	moved    map[string]time.Time
+}
+
+func (r *comebackRepo) GetUser(context.Context, int64) (*models.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *comebackRepo) GetUserSources(context.Context, int64, bool) ([]*models.UserSource, error) {
+	return []*models.UserSource{{ID: 1, SectionID: "sec-a", Enabled: true}}, nil
+}
+
+func (r *comebackRepo) GetDuePagesToday(_ context.Context, _ int64, endOfDayUTC time.Time) ([]*models.UserProgress, error) {
+	var due []*models.UserProgress
+	for _, progress := range r.progress {
+		if progress.NextReviewDate.Before(endOfDayUTC) {
+			copied := *progress
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+func (r *comebackRepo) GetLastReviewScore(_ context.Context, _ int64, pageID string) (int, error) {
+	return r.scores[pageID], nil
+}
+
+func (r *comebackRepo) GetUpcomingLoad(context.Context, int64, string, time.Time) ([]*models.DailyLoad, error) {
+	return r.load, nil
+}
+
+func (r *comebackRepo) RunInTx(_ context.Context, fn func(models.Repository) error) error {
+	return fn(r)
+}
+
+func (r *comebackRepo) UpdateNextReviewDate(_ context.Context, _ int64, pageID string, nextReviewDate time.Time) error {
+	r.moved[pageID] = nextReviewDate
+	return nil
+}
+
+func (r *comebackRepo) UpdateUserActivity(_ context.Context, _ int64, activityDate time.Time) error {
+	r.user.LastActivityDate = &activityDate
+	return nil
+}
+
+func (r *comebackRepo) SetUserPaused(_ context.Context, _ int64, paused bool) error {
+	r.user.IsPaused = &paused
+	return nil
+}
+
+// comebackNow — утро 10 июля по Берлину; пользователь не заходил с 1 июля и приостановлен
+var comebackNow = time.Date(2026, 7, 10, 8, 0, 0, 0, time.UTC)
+
+// newComebackService готовит пять накопившихся страниц. Риск на начало 10 июля по Берлину:
+// page-1 — 4 дня просрочки при интервале 1 и оценке 90: 4 + 0.1 + 1 = 5.1
+// page-2 — 7 дней при интервале 7 и оценке 40: 1 + 0.6 + 0.14 ≈ 1.74
+// page-3 — 3 дня при интервале 30 и оценке 100: 0.1 + 0 + 0.03 ≈ 0.13
+// page-4 — 3 дня при интервале 3 и оценке 20: 1 + 0.8 + 0.33 ≈ 2.13
+// page-5 — назначена на сегодня, интервал 14, оценка 70: 0 + 0.3 + 0.07 ≈ 0.37
+// 11 июля уже назначено одно повторение, 12 июля — два.
+func newComebackService(t *testing.T) (*Service, *comebackRepo, *clocktest.ManualClock) {
+	t.Helper()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	midnight := func(day int) time.Time {
+		return time.Date(2026, 7, day, 0, 0, 0, 0, berlin).UTC()
+	}
+
+	timezone := "Europe/Berlin"
+	token := "token"
+	expiresAt := comebackNow.AddDate(1, 0, 0)
+	lastActivity := time.Date(2026, 7, 1, 18, 0, 0, 0, time.UTC)
+	paused := true
+	repo := &comebackRepo{
+		user: models.User{
+			TelegramID:       1,
+			Timezone:         &timezone,
+			AccessToken:      &token,
+			RefreshToken:     &token,
+			ExpiresAt:        &expiresAt,
+			OneNoteConfig:    &models.OneNoteConfig{NotebookID: "notebook", SectionID: "sec-a"},
+			LastActivityDate: &lastActivity,
+			IsPaused:         &paused,
+		},
+		progress: []*models.UserProgress{
+			{PageID: "page-1", IntervalDays: 1, NextReviewDate: midnight(6)},
+			{PageID: "page-2", IntervalDays: 7, NextReviewDate: midnight(3)},
+			{PageID: "page-3", IntervalDays: 30, NextReviewDate: midnight(7)},
+			{PageID: "page-4", IntervalDays: 3, NextReviewDate: midnight(7)},
+			{PageID: "page-5", IntervalDays: 14, NextReviewDate: midnight(10)},
+		},
+		scores: map[string]int{"page-1": 90, "page-2": 40, "page-3": 100, "page-4": 20, "page-5": 70},
+		load: []*models.DailyLoad{
+			// Сегодняшние повторения — это сами накопившиеся страницы, в нагрузке они не учитываются
+			{Date: time.Date(2026, 7, 10, 0, 0, 0, 0, time.UTC), Pages: 5},
+			{Date: time.Date(2026, 7, 11, 0, 0, 0, 0, time.UTC), Pages: 1},
+			{Date: time.Date(2026, 7, 12, 0, 0, 0, 0, time.UTC), Pages: 2},
+		},
+		moved: make(map[string]time.Time),
+	}
+
+	var pages []onenote.Page
+	for i, progress := range repo.progress {
+		pages = append(pages, onenote.Page{ID: progress.PageID, Title: fmt.Sprintf("%d Topic", i+1)})
+	}
+	client := &syncOneNote{sections: map[string][]onenote.Page{"sec-a": pages}}
+
+	clock := clocktest.NewManualClock(comebackNow)
+	return NewService(repo, nil, client, clock, clocktest.FixedRand(0.5)), repo, clock
+}
+
+// planDays записывает план как «дата: страницы» по дням
+func planDays(plan *models.ComebackPlan) []string {
+	var days []string
+	for _, day := range plan.Days {
+		pages := make([]string, 0, len(day.Pages))
+		for _, pwp := range day.Pages {
+			pages = append(pages, pwp.Page.PageID)
+		}
+		days = append(days, fmt.Sprintf("%s: %v", day.Date.Format(time.DateOnly), pages))
+	}
+	return days
+}
+
+func TestComebackPlan(t *testing.T) {
+	tests := []struct {
+		name        string
+		pagesPerDay int
+		// want — дни плана, nil — план не нужен
+		want []string
+	}{
+		{
+			// Самые рискованные страницы идут первыми; 11 июля помещается одна страница, а заполненное 12 июля пропускается
+			name: "user limit",
+			want: []string{
+				"2026-07-10: [page-1 page-4]",
+				"2026-07-11: [page-2]",
+				"2026-07-13: [page-5 page-3]",
+			},
+		},
+		{
+			name:        "chosen pace",
+			pagesPerDay: 3,
+			want: []string{
+				"2026-07-10: [page-1 page-4 page-2]",
+				"2026-07-11: [page-5 page-3]",
+			},
+		},
+		{
+			name:        "fits into today",
+			pagesPerDay: 5,
+		},
+		{
+			// Темп выше MaxComebackPagesPerDay ограничивается, и все пять страниц помещаются в сегодня
+			name:        "pace above the maximum",
+			pagesPerDay: MaxComebackPagesPerDay + 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, _ := newComebackService(t)
+
+			plan, err := svc.GetComebackPlan(context.Background(), 1, tt.pagesPerDay)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.want == nil {
+				if plan != nil {
+					t.Fatalf("plan = %v, want no plan", planDays(plan))
+				}
+				return
+			}
+			if plan == nil {
+				t.Fatalf("no plan, want %v", tt.want)
+			}
+			if got := planDays(plan); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("plan = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyComebackPlan(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// change меняет страницы или время между показом плана и его принятием
+		change  func(repo *comebackRepo, clock *clocktest.ManualClock)
+		wantErr error
+	}{
+		{
+			name: "applied",
+		},
+		{
+			// Страницу повторили в другом сообщении: оставшиеся страницы раскладываются иначе
+			name: "page reviewed meanwhile",
+			change: func(repo *comebackRepo, _ *clocktest.ManualClock) {
+				repo.progress[3].NextReviewDate = comebackNow.AddDate(0, 0, 3)
+			},
+			wantErr: ErrComebackPlanChanged,
+		},
+		{
+			// Наступил новый день: даты плана сдвинулись
+			name: "next day",
+			change: func(_ *comebackRepo, clock *clocktest.ManualClock) {
+				clock.Advance(24 * time.Hour)
+			},
+			wantErr: ErrComebackPlanChanged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, clock := newComebackService(t)
+
+			shown, err := svc.SuggestComebackPlan(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if shown == nil {
+				t.Fatal("no plan suggested after the break")
+			}
+			if tt.change != nil {
+				tt.change(repo, clock)
+			}
+
+			applied, err := svc.ApplyComebackPlan(context.Background(), 1, shown.PagesPerDay, shown.Fingerprint())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				// Изменившийся план не применяется и не считается возвращением
+				if len(repo.moved) != 0 {
+					t.Errorf("pages moved by a changed plan: %v", repo.moved)
+				}
+				if !*repo.user.IsPaused {
+					t.Error("pause lifted by a changed plan")
+				}
+				return
+			}
+
+			if fmt.Sprint(planDays(applied)) != fmt.Sprint(planDays(shown)) {
+				t.Errorf("applied plan %v, want the shown plan %v", planDays(applied), planDays(shown))
+			}
+			// Страницы первого дня остаются на сегодня, остальные переносятся на местную полночь своего дня
+			want := map[string]time.Time{
+				"page-2": time.Date(2026, 7, 11, 0, 0, 0, 0, berlin).UTC(),
+				"page-5": time.Date(2026, 7, 13, 0, 0, 0, 0, berlin).UTC(),
+				"page-3": time.Date(2026, 7, 13, 0, 0, 0, 0, berlin).UTC(),
+			}
+			if len(repo.moved) != len(want) {
+				t.Errorf("moved %v, want %v", repo.moved, want)
+			}
+			for pageID, day := range want {
+				if got := repo.moved[pageID]; !got.Equal(day) {
+					t.Errorf("%s moved to %v, want %v", pageID, got, day)
+				}
+			}
+			if *repo.user.IsPaused || !repo.user.LastActivityDate.Equal(comebackNow) {
+				t.Errorf("paused = %v, activity = %v, want an active user returned now", *repo.user.IsPaused, repo.user.LastActivityDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service/srs"
+)
+
+// GetPageHistory собирает историю страницы: действующие оценки, срывы, переход в AI режим и прогноз
+// для каждой оценки из grades, выставленной в день следующего повторения (или сегодня, если страница уже ждёт повторения).
+// Страница, которая не изучается, — ErrPageNotFound.
+func (s *Service) GetPageHistory(ctx context.Context, telegramID int64, pageID string, grades []int) (*models.PageHistory, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	progress, err := s.repo.GetProgress(ctx, telegramID, pageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get progress (telegram_id: %d, page_id: %s): %w", telegramID, pageID, ErrPageNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get progress (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
+	}
+
+	page, err := s.repo.GetPageReference(ctx, pageID, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get page reference (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
+	}
+
+	events, err := s.repo.GetProgressHistory(ctx, telegramID, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("get progress history (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
+	}
+
+	history := &models.PageHistory{Page: page, Progress: progress, Events: events}
+
+	for _, event := range events {
+		status := srs.ConvertGradeToStatus(event.Score)
+		// Из режима чтения страница переходит в AI режим после первой оценки normal или easy
+		if event.Mode == srs.ModeReading && status.Remembered() && history.AIModeSince == nil {
+			date := event.Date
+			history.AIModeSince = &date
+		}
+		// В AI режиме forgot возвращает страницу на первый интервал
+		if event.Mode == srs.ModeStandard && status == srs.Forgot {
+			history.Lapses++
+		}
+	}
+
+	if progress.Passed {
+		return history, nil
+	}
+
+	timezone := userTimezone(user)
+	reviewAt := s.clock.Now()
+	if progress.NextReviewDate.After(reviewAt) {
+		reviewAt = progress.NextReviewDate
+	}
+
+	for _, grade := range grades {
+		schedule := scheduleReview(reviewAt, progress.IntervalDays, grade, timezone)
+		history.Forecast = append(history.Forecast, &models.GradeForecast{
+			Grade:          grade,
+			IntervalDays:   schedule.intervalDays,
+			NextReviewDate: localDate(schedule.nextReview, timezone),
+			Passed:         schedule.passed,
+		})
+	}
+
+	return history, nil
+}
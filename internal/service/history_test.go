@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/internal/service/srs"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+// historyRepo — хранилище в памяти для истории страницы: прогресс и действующие оценки одной страницы
+type historyRepo struct {
+	models.Repository
+
+	user     models.User
+	progress *models.UserProgress
+	events   []*models.ProgressHistory
+}
+
+func (r *historyRepo) GetUser(context.Context, int64) (*models.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *historyRepo) GetProgress(context.Context, int64, string) (*models.UserProgress, error) {
+	if r.progress == nil {
+		return nil, sql.ErrNoRows
+	}
+	progress := *r.progress
+	return &progress, nil
+}
+
+func (r *historyRepo) GetPageReference(_ context.Context, pageID string, userID int64) (*models.PageReference, error) {
+	return &models.PageReference{PageID: pageID, UserID: userID, Title: "1 Travel"}, nil
+}
+
+func (r *historyRepo) GetProgressHistory(context.Context, int64, string) ([]*models.ProgressHistory, error) {
+	return r.events, nil
+}
+
+func TestPageHistoryEvents(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 6, d, 10, 0, 0, 0, time.UTC)
+	}
+	event := func(d int, mode string, score int) *models.ProgressHistory {
+		return &models.ProgressHistory{Date: day(d), Mode: mode, Score: score}
+	}
+
+	tests := []struct {
+		name   string
+		events []*models.ProgressHistory
+		// wantAISince — день июня, когда страница перешла в AI режим, 0 — ещё в режиме чтения
+		wantAISince int
+		wantLapses  int
+	}{
+		{
+			name:   "no grades",
+			events: nil,
+		},
+		{
+			// Забытая в режиме чтения страница остаётся в нём, и это не срыв
+			name:   "still reading",
+			events: []*models.ProgressHistory{event(1, srs.ModeReading, 20), event(2, srs.ModeReading, 55)},
+		},
+		{
+			// Переход — первая оценка normal или easy в режиме чтения; оценка hard (40–60) его не даёт
+			name:        "reading to AI",
+			events:      []*models.ProgressHistory{event(1, srs.ModeReading, 60), event(2, srs.ModeReading, 61), event(3, srs.ModeStandard, 90)},
+			wantAISince: 2,
+		},
+		{
+			// Срыв — только forgot (меньше 40) в AI режиме; hard не возвращает на первый интервал
+			name: "lapses",
+			events: []*models.ProgressHistory{
+				event(1, srs.ModeReading, 85),
+				event(2, srs.ModeStandard, 39),
+				event(3, srs.ModeStandard, 40),
+				event(5, srs.ModeStandard, 0),
+				event(6, srs.ModeStandard, 75),
+			},
+			wantAISince: 1,
+			wantLapses:  2,
+		},
+		{
+			// Учитывается первый переход из режима чтения
+			name:        "first transition",
+			events:      []*models.ProgressHistory{event(1, srs.ModeReading, 70), event(4, srs.ModeReading, 95)},
+			wantAISince: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+			repo := &historyRepo{
+				progress: &models.UserProgress{PageID: "page-1", IntervalDays: 3, NextReviewDate: now, Passed: true},
+				events:   tt.events,
+			}
+			svc := NewService(repo, nil, nil, clocktest.NewManualClock(now), clocktest.FixedRand(0.5))
+
+			history, err := svc.GetPageHistory(context.Background(), 1, "page-1", []int{90})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if history.Lapses != tt.wantLapses {
+				t.Errorf("lapses = %d, want %d", history.Lapses, tt.wantLapses)
+			}
+			switch {
+			case tt.wantAISince == 0 && history.AIModeSince != nil:
+				t.Errorf("AI mode since %v, want still reading", history.AIModeSince)
+			case tt.wantAISince != 0 && (history.AIModeSince == nil || !history.AIModeSince.Equal(day(tt.wantAISince))):
+				t.Errorf("AI mode since %v, want %v", history.AIModeSince, day(tt.wantAISince))
+			}
+			// У изученной страницы прогноза нет
+			if len(history.Forecast) != 0 {
+				t.Errorf("forecast for a passed page: %d grades", len(history.Forecast))
+			}
+		})
+	}
+}
+
+func TestPageHistoryForecast(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grades := []int{90, 70, 50, 20}
+
+	tests := []struct {
+		name     string
+		progress *models.UserProgress
+		// want — интервал, местная дата следующего повторения и изученность после каждой из grades
+		want []string
+	}{
+		{
+			// Повторение ещё впереди, поэтому прогноз считается от 5 июля
+			name:     "review ahead",
+			progress: &models.UserProgress{IntervalDays: 7, NextReviewDate: time.Date(2026, 7, 5, 0, 0, 0, 0, berlin)},
+			want:     []string{"14 2026-07-19", "14 2026-07-19", "3 2026-07-08", "1 2026-07-06"},
+		},
+		{
+			// Страница просрочена, поэтому прогноз считается от сегодня
+			name:     "overdue",
+			progress: &models.UserProgress{IntervalDays: 3, NextReviewDate: time.Date(2026, 6, 20, 0, 0, 0, 0, berlin)},
+			want:     []string{"7 2026-07-08", "7 2026-07-08", "1 2026-07-02", "1 2026-07-02"},
+		},
+		{
+			// В режиме чтения normal и easy переводят страницу в AI режим, остальные оставляют в чтении
+			name:     "reading mode",
+			progress: &models.UserProgress{IntervalDays: 0, NextReviewDate: now},
+			want:     []string{"1 2026-07-02", "1 2026-07-02", "0 2026-07-02", "0 2026-07-02"},
+		},
+		{
+			// Успешное повторение на последнем интервале делает страницу изученной
+			name:     "last interval",
+			progress: &models.UserProgress{IntervalDays: 180, NextReviewDate: now},
+			want:     []string{"180 2026-12-28 passed", "180 2026-12-28 passed", "90 2026-09-29", "1 2026-07-02"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timezone := "Europe/Berlin"
+			tt.progress.PageID = "page-1"
+			repo := &historyRepo{user: models.User{TelegramID: 1, Timezone: &timezone}, progress: tt.progress}
+			svc := NewService(repo, nil, nil, clocktest.NewManualClock(now), clocktest.FixedRand(0.5))
+
+			history, err := svc.GetPageHistory(context.Background(), 1, "page-1", grades)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(history.Forecast) != len(grades) {
+				t.Fatalf("forecast has %d grades, want %d", len(history.Forecast), len(grades))
+			}
+			for i, forecast := range history.Forecast {
+				got := fmt.Sprintf("%d %s", forecast.IntervalDays, forecast.NextReviewDate.Format(time.DateOnly))
+				if forecast.Passed {
+					got += " passed"
+				}
+				if forecast.Grade != grades[i] || got != tt.want[i] {
+					t.Errorf("grade %d: %s, want grade %d: %s", forecast.Grade, got, grades[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPageHistoryNotInProgress(t *testing.T) {
+	svc := NewService(&historyRepo{}, nil, nil, clocktest.NewManualClock(time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)), clocktest.FixedRand(0.5))
+
+	_, err := svc.GetPageHistory(context.Background(), 1, "page-1", []int{90})
+	if !errors.Is(err, ErrPageNotFound) {
+		t.Errorf("error = %v, want ErrPageNotFound", err)
+	}
+}
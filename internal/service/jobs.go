@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// jobBatchSize — сколько задач процесс занимает за один запрос
+	jobBatchSize = 20
+	// jobLease — на сколько задача занимается процессом. Если процесс упал, не завершив задачу,
+	// после этого времени её выполнит другой процесс.
+	jobLease = 10 * time.Minute
+	// JobTimeout ограничивает один запуск задачи, чтобы зависшая задача не съела аренду остальных задач пачки
+	JobTimeout = 2 * time.Minute
+	// JobLeaseMargin — запас до конца аренды на завершение задачи: задача, которой до конца аренды осталось
+	// меньше, не запускается, а достаётся процессу, который займёт её после истечения аренды
+	JobLeaseMargin = 30 * time.Second
+	// maxJobAttempts — после стольких неудачных попыток подряд задача откладывается до следующего обычного запуска
+	maxJobAttempts = 5
+	// jobRetryBaseDelay и jobRetryMaxDelay задают экспоненциальную задержку повтора: 1, 2, 4, 8 минут и т. д.
+	jobRetryBaseDelay = time.Minute
+	jobRetryMaxDelay  = time.Hour
+	// jobHistoryRetention — сколько хранится история запусков задач
+	jobHistoryRetention = 30 * 24 * time.Hour
+)
+
+// ClaimJobs занимает задачи, время запуска которых наступило. Каждую задачу нужно завершить через CompleteJob.
+func (s *Service) ClaimJobs(ctx context.Context, now time.Time) ([]*models.Job, error) {
+	jobs, err := s.repo.ClaimJobs(ctx, now, now.Add(jobLease), jobBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("claim jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// CompleteJob записывает запуск в историю и назначает следующий: после успеха — обычный по типу задачи,
+// после ошибки — повтор с экспоненциальной задержкой
+func (s *Service) CompleteJob(ctx context.Context, job *models.Job, startedAt time.Time, runErr error) error {
+	finishedAt := s.clock.Now()
+
+	run := &models.JobRun{
+		JobID:      job.ID,
+		UserID:     job.UserID,
+		JobType:    job.JobType,
+		Attempt:    job.Attempts + 1,
+		Status:     models.JobRunSucceeded,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+	}
+
+	if runErr == nil {
+		job.Attempts = 0
+		job.LastError = nil
+		job.NextRunAt = s.nextJobRun(ctx, job, finishedAt)
+	} else {
+		message := runErr.Error()
+		run.Status = models.JobRunFailed
+		run.Error = &message
+
+		job.Attempts++
+		job.LastError = &message
+		if job.Attempts < maxJobAttempts {
+			job.NextRunAt = finishedAt.Add(jobRetryDelay(job.Attempts))
+		} else {
+			zap.S().Error("job failed too many times, postponed to the next regular run", zap.Error(runErr), zap.Int64("telegram_id", job.UserID), zap.String("job_type", job.JobType), zap.Int("attempts", job.Attempts))
+			job.Attempts = 0
+			job.NextRunAt = s.nextJobRun(ctx, job, finishedAt)
+		}
+	}
+
+	if err := s.repo.AddJobRun(ctx, run); err != nil {
+		zap.S().Error("add job run", zap.Error(err), zap.Int64("telegram_id", job.UserID), zap.String("job_type", job.JobType))
+	}
+
+	finished, err := s.repo.FinishJob(ctx, job)
+	if err != nil {
+		return fmt.Errorf("finish job (telegram_id: %d, job_type: %s): %w", job.UserID, job.JobType, err)
+	}
+
+	if !finished {
+		zap.S().Warn("job lease expired before completion, the job belongs to another process", zap.Int64("telegram_id", job.UserID), zap.String("job_type", job.JobType))
+	}
+
+	return nil
+}
+
+// PruneJobHistory удаляет историю запусков старше jobHistoryRetention
+func (s *Service) PruneJobHistory(ctx context.Context) error {
+	if err := s.repo.DeleteJobRunsBefore(ctx, s.clock.Now().Add(-jobHistoryRetention)); err != nil {
+		return fmt.Errorf("prune job history: %w", err)
+	}
+	return nil
+}
+
+// jobRetryDelay — задержка перед повтором после attempts неудачных попыток подряд
+func jobRetryDelay(attempts int) time.Duration {
+	delay := jobRetryBaseDelay
+	for i := 1; i < attempts && delay < jobRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, jobRetryMaxDelay)
+}
+
+// nextJobRun — время следующего обычного запуска: смена дня и проверка активности — в ближайшую полночь
+// по местному времени пользователя, напоминания — в ближайшее время напоминания
+func (s *Service) nextJobRun(ctx context.Context, job *models.Job, now time.Time) time.Time {
+	if job.JobType == models.JobReminder {
+		schedules, err := s.repo.GetReminderSchedules(ctx, job.UserID)
+		if err != nil {
+			zap.S().Error("get reminder schedules", zap.Error(err), zap.Int64("telegram_id", job.UserID))
+			return now.Add(jobRetryBaseDelay)
+		}
+
+		if next, ok := nextReminderRun(now, schedules); ok {
+			return next
+		}
+	}
+
+	user, err := s.repo.GetUser(ctx, job.UserID)
+	if err != nil {
+		zap.S().Error("get user", zap.Error(err), zap.Int64("telegram_id", job.UserID))
+		return now.Add(jobRetryBaseDelay)
+	}
+
+	return nextLocalMidnight(now, userTimezone(user))
+}
+
+// scheduleJob переносит следующий запуск задачи. Ошибка только логируется: задача всё равно
+// запустится в ранее назначенное время и пересчитает следующий запуск.
+func (s *Service) scheduleJob(ctx context.Context, telegramID int64, jobType string, at time.Time) {
+	if err := s.repo.ScheduleJob(ctx, telegramID, jobType, at); err != nil {
+		zap.S().Error("schedule job", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.String("job_type", jobType))
+	}
+}
+
+// RunRollover начинает новый день пользователя: синхронизирует страницы, сбрасывает отметки о повторении
+// и добавляет новые страницы в изучение. Повторный запуск в тот же день ничего не делает.
+func (s *Service) RunRollover(ctx context.Context, telegramID int64) error {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	if user.OneNoteConfig == nil {
+		return nil
+	}
+
+	if user.IsPaused != nil && *user.IsPaused {
+		zap.S().Info("skipping paused user in rollover", zap.Int64("telegram_id", telegramID))
+		return nil
+	}
+
+	startOfTodayInTz, err := utils.StartOfDayInTimezone(s.clock.Now(), userTimezone(user))
+	if err != nil {
+		return fmt.Errorf("get start of today in timezone (telegram_id: %d): %w", telegramID, err)
+	}
+
+	if user.LastCronProcessedAt != nil && !user.LastCronProcessedAt.Before(startOfTodayInTz.UTC()) {
+		return nil
+	}
+
+	if _, err := s.syncPagesInternal(ctx, telegramID); err != nil {
+		if _, ok := err.(*AuthRequiredError); ok {
+			zap.S().Warn("auth required for rollover", zap.Int64("telegram_id", telegramID))
+			return nil
+		}
+		zap.S().Warn("failed to sync pages in rollover", zap.Error(err), zap.Int64("telegram_id", telegramID))
+	}
+
+	if err := s.repo.ResetReviewedTodayFlag(ctx, telegramID); err != nil {
+		return fmt.Errorf("reset reviewed today flag (telegram_id: %d): %w", telegramID, err)
+	}
+
+	if err := s.addPagesToLearning(ctx, telegramID); err != nil {
+		return fmt.Errorf("add pages to learning (telegram_id: %d): %w", telegramID, err)
+	}
+
+	// Отметка ставится последней: если шаг выше упал, повтор задачи выполнит смену дня заново
+	if err := s.repo.UpdateLastCronProcessedAt(ctx, telegramID, s.clock.Now()); err != nil {
+		return fmt.Errorf("update last cron processed at (telegram_id: %d): %w", telegramID, err)
+	}
+
+	return nil
+}
+
+// CheckInactivity приостанавливает пользователя без активности неделю. Интервалы не сбрасываются:
+// накопившиеся повторения вернувшийся пользователь распределяет планом возвращения (SuggestComebackPlan).
+func (s *Service) CheckInactivity(ctx context.Context, telegramID int64) error {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	if user.IsPaused != nil && *user.IsPaused {
+		return nil
+	}
+
+	nowUTC := s.clock.Now()
+	// Дни отпуска не считаются неактивностью
+	lastActivity := lastActivityWithVacation(user, nowUTC)
+	if lastActivity == nil || lastActivity.Before(nowUTC.AddDate(0, 0, -7)) {
+		return s.pauseInactiveUser(ctx, user)
+	}
+
+	return nil
+}
+
+// pauseInactiveUser приостанавливает неактивного пользователя, если в today уже набралось максимальное количество страниц
+func (s *Service) pauseInactiveUser(ctx context.Context, user *models.User) error {
+	if user.OneNoteConfig == nil {
+		return nil
+	}
+
+	maxPagesPerDay := uint(2)
+	if user.MaxPagesPerDay != nil {
+		maxPagesPerDay = *user.MaxPagesPerDay
+	}
+
+	startOfDayInTz, err := utils.StartOfDayInTimezone(s.clock.Now(), userTimezone(user))
+	if err != nil {
+		return fmt.Errorf("get start of day in timezone (telegram_id: %d): %w", user.TelegramID, err)
+	}
+
+	duePagesToday, err := s.repo.GetDuePagesToday(ctx, user.TelegramID, utils.AddDays(startOfDayInTz, 1).UTC())
+	if err != nil {
+		return fmt.Errorf("get due pages today (telegram_id: %d): %w", user.TelegramID, err)
+	}
+
+	if len(duePagesToday) < int(maxPagesPerDay) {
+		return nil
+	}
+
+	if err := s.repo.SetUserPaused(ctx, user.TelegramID, true); err != nil {
+		return fmt.Errorf("set user paused (telegram_id: %d): %w", user.TelegramID, err)
+	}
+	zap.S().Info("user paused due to inactivity and max pages in today reached", zap.Int64("telegram_id", user.TelegramID), zap.Int("due_pages_today", len(duePagesToday)), zap.Uint("max_pages_per_day", maxPagesPerDay))
+
+	return nil
+}
+
+// userTimezone — таймзона пользователя, UTC если она не задана
+func userTimezone(user *models.User) string {
+	if user.Timezone != nil && *user.Timezone != "" {
+		return *user.Timezone
+	}
+	return "UTC"
+}
+
+// nextLocalMidnight — ближайшая полночь после now по таймзоне, при неизвестной таймзоне — по UTC
+func nextLocalMidnight(now time.Time, timezone string) time.Time {
+	startOfDay, err := utils.StartOfDayInTimezone(now, timezone)
+	if err != nil {
+		startOfDay = utils.StartOfDay(now.UTC())
+	}
+	return utils.AddDays(startOfDay, 1).UTC()
+}
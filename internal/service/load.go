@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// dailyLoad — сколько повторений уже назначено на каждую местную дату пользователя. По ней переносимые страницы
+// раскладываются так, чтобы вместе с уже назначенными в день приходилось не больше limit повторений.
+type dailyLoad struct {
+	limit int
+	pages map[string]int
+}
+
+// getDailyLoad загружает назначенные повторения на местные даты с from до to (местные даты в виде полуночи UTC,
+// to не включительно). Повторения до from не учитываются: это просроченные страницы или те, что переносятся.
+func (s *Service) getDailyLoad(ctx context.Context, telegramID int64, timezone string, from, to time.Time, limit int) (*dailyLoad, error) {
+	load, err := s.repo.GetUpcomingLoad(ctx, telegramID, timezone, localMidnight(to, timezone).UTC())
+	if err != nil {
+		return nil, fmt.Errorf("get upcoming load (telegram_id: %d): %w", telegramID, err)
+	}
+
+	daily := &dailyLoad{limit: max(limit, 1), pages: make(map[string]int, len(load))}
+	for _, day := range load {
+		if !day.Date.Before(from) {
+			daily.pages[day.Date.Format(VacationDateLayout)] = day.Pages
+		}
+	}
+	return daily, nil
+}
+
+// place назначает страницу на первый день не раньше day, в котором ещё есть место, и возвращает этот день
+func (l *dailyLoad) place(day time.Time) time.Time {
+	for l.pages[day.Format(VacationDateLayout)] >= l.limit {
+		day = day.AddDate(0, 0, 1)
+	}
+	l.pages[day.Format(VacationDateLayout)]++
+	return day
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils"
+)
+
+const (
+	// DefaultReminderTime — время напоминания нового пользователя
+	DefaultReminderTime = "09:00"
+	// MaxReminderTimes ограничивает количество напоминаний в день
+	MaxReminderTimes = 6
+	// reminderClaimLease — на сколько напоминание занимается для отправки. Аренда не короче одного запуска задачи
+	// (JobTimeout), поэтому работающий процесс успевает отправить напоминание, и короче аренды задачи, поэтому
+	// процесс, занявший задачу после падения предыдущего, застаёт аренду напоминания истёкшей и отправляет его.
+	reminderClaimLease = JobTimeout
+)
+
+// ErrNoReminderTimes — попытка убрать последнее время напоминания
+var ErrNoReminderTimes = fmt.Errorf("%w: no reminder times", ErrInvalidSetting)
+
+func (s *Service) GetReminderTimes(ctx context.Context, telegramID int64) ([]string, error) {
+	times, err := s.repo.GetReminderTimes(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get reminder times (telegram_id: %d): %w", telegramID, err)
+	}
+	return times, nil
+}
+
+// SetReminderTimes заменяет время напоминаний: значения проверяются, повторы убираются, список сортируется
+func (s *Service) SetReminderTimes(ctx context.Context, telegramID int64, times []string) error {
+	normalized := make([]string, 0, len(times))
+	for _, value := range times {
+		reminderTime, err := NormalizeReminderTime(value)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, reminderTime)
+	}
+
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	if len(normalized) == 0 {
+		return ErrNoReminderTimes
+	}
+	if len(normalized) > MaxReminderTimes {
+		return fmt.Errorf("%w: %d reminder times", ErrInvalidSetting, len(normalized))
+	}
+
+	err := s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		return txRepo.ReplaceReminderTimes(ctx, telegramID, normalized)
+	})
+	if err != nil {
+		return fmt.Errorf("replace reminder times (telegram_id: %d): %w", telegramID, err)
+	}
+
+	s.rescheduleReminders(ctx, telegramID)
+	return nil
+}
+
+// ToggleReminderTime добавляет время напоминания или убирает уже выбранное. Последнее время убрать нельзя,
+// для этого напоминания выключаются целиком.
+func (s *Service) ToggleReminderTime(ctx context.Context, telegramID int64, reminderTime string) error {
+	reminderTime, err := NormalizeReminderTime(reminderTime)
+	if err != nil {
+		return err
+	}
+
+	times, err := s.GetReminderTimes(ctx, telegramID)
+	if err != nil {
+		return err
+	}
+
+	if i := slices.Index(times, reminderTime); i >= 0 {
+		times = slices.Delete(times, i, i+1)
+	} else {
+		times = append(times, reminderTime)
+	}
+
+	return s.SetReminderTimes(ctx, telegramID, times)
+}
+
+func (s *Service) SetRemindersEnabled(ctx context.Context, telegramID int64, enabled bool) error {
+	if err := s.repo.SetRemindersEnabled(ctx, telegramID, enabled); err != nil {
+		return fmt.Errorf("set reminders enabled (telegram_id: %d, enabled: %v): %w", telegramID, enabled, err)
+	}
+
+	s.rescheduleReminders(ctx, telegramID)
+	return nil
+}
+
+// SetQuietHours задаёт тихие часы по местному времени. Интервал может переходить через полночь, например 22:00-08:00.
+func (s *Service) SetQuietHours(ctx context.Context, telegramID int64, start, end string) error {
+	start, err := NormalizeReminderTime(start)
+	if err != nil {
+		return err
+	}
+
+	end, err = NormalizeReminderTime(end)
+	if err != nil {
+		return err
+	}
+
+	if start == end {
+		return fmt.Errorf("%w: empty quiet hours %s-%s", ErrInvalidSetting, start, end)
+	}
+
+	if err := s.repo.UpdateQuietHours(ctx, telegramID, &start, &end); err != nil {
+		return fmt.Errorf("update quiet hours (telegram_id: %d, start: %s, end: %s): %w", telegramID, start, end, err)
+	}
+
+	s.rescheduleReminders(ctx, telegramID)
+	return nil
+}
+
+func (s *Service) DisableQuietHours(ctx context.Context, telegramID int64) error {
+	if err := s.repo.UpdateQuietHours(ctx, telegramID, nil, nil); err != nil {
+		return fmt.Errorf("disable quiet hours (telegram_id: %d): %w", telegramID, err)
+	}
+
+	s.rescheduleReminders(ctx, telegramID)
+	return nil
+}
+
+// rescheduleReminders запускает задачу напоминаний сразу, чтобы она пересчитала следующий запуск по новым настройкам.
+// Уже отправленные сегодня напоминания не повторятся благодаря reminder_log.
+func (s *Service) rescheduleReminders(ctx context.Context, telegramID int64) {
+	s.scheduleJob(ctx, telegramID, models.JobReminder, s.clock.Now())
+}
+
+// ClaimDueReminders находит напоминания пользователя, время отправки которых наступило по его местному времени,
+// и занимает каждое в reminder_log на местную дату, на которую оно назначено, на reminderClaimLease.
+// Вызывающий должен отметить отправленные напоминания через MarkRemindersSent или вернуть неотправленные
+// через ReleaseReminder.
+//
+// Занятое напоминание не вернётся ни этому, ни другому процессу, пока не истечёт аренда. Если процесс упал между
+// занятием и отправкой, следующий запуск задачи займёт напоминание заново, поэтому оно не пропадает.
+// Повторно напоминание приходит, только если отправка прошла, а отметка об отправке не записалась.
+func (s *Service) ClaimDueReminders(ctx context.Context, telegramID int64, now time.Time) ([]*models.DueReminder, error) {
+	schedules, err := s.repo.GetReminderSchedules(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get reminder schedules (telegram_id: %d): %w", telegramID, err)
+	}
+
+	var due []*models.DueReminder
+	for _, schedule := range schedules {
+		local, err := scheduleLocalTime(now, schedule)
+		if err != nil {
+			return nil, err
+		}
+
+		if inQuietHours(local, schedule.QuietHoursStart, schedule.QuietHoursEnd) {
+			continue
+		}
+
+		for _, day := range reminderDays(local) {
+			delivery, ok := reminderDelivery(day, schedule)
+			if !ok || !reminderDue(local, delivery) {
+				continue
+			}
+
+			localDate := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+			if vacationCovers(localDate, schedule.VacationStart, schedule.VacationEnd) {
+				continue
+			}
+			claimed, err := s.repo.ClaimReminder(ctx, schedule.UserID, localDate, schedule.ReminderTime, now, now.Add(reminderClaimLease))
+			if err != nil {
+				return nil, fmt.Errorf("claim reminder (telegram_id: %d, reminder_time: %s): %w", telegramID, schedule.ReminderTime, err)
+			}
+
+			if !claimed {
+				continue
+			}
+
+			due = append(due, &models.DueReminder{
+				UserID:       schedule.UserID,
+				ReminderTime: schedule.ReminderTime,
+				LocalDate:    localDate,
+				Language:     schedule.Language,
+			})
+		}
+	}
+
+	return due, nil
+}
+
+// MarkRemindersSent отмечает занятые напоминания отправленными, после этого они не занимаются повторно
+func (s *Service) MarkRemindersSent(ctx context.Context, reminders []*models.DueReminder) error {
+	for _, reminder := range reminders {
+		if err := s.repo.MarkReminderSent(ctx, reminder.UserID, reminder.LocalDate, reminder.ReminderTime); err != nil {
+			return fmt.Errorf("mark reminder sent (telegram_id: %d, reminder_time: %s): %w", reminder.UserID, reminder.ReminderTime, err)
+		}
+	}
+	return nil
+}
+
+// ReleaseReminder снимает отметку с напоминания, которое не удалось отправить, чтобы следующий проход повторил его
+func (s *Service) ReleaseReminder(ctx context.Context, reminder *models.DueReminder) error {
+	if err := s.repo.DeleteReminderLog(ctx, reminder.UserID, reminder.LocalDate, reminder.ReminderTime); err != nil {
+		return fmt.Errorf("release reminder (telegram_id: %d, reminder_time: %s): %w", reminder.UserID, reminder.ReminderTime, err)
+	}
+	return nil
+}
+
+// nextReminderRun — ближайшее время после now, когда нужно проверить напоминания: следующее время отправки
+// напоминания с учётом тихих часов. false — напоминаний нет.
+func nextReminderRun(now time.Time, schedules []*models.ReminderSchedule) (time.Time, bool) {
+	var next time.Time
+	consider := func(candidate time.Time) {
+		if candidate.After(now) && (next.IsZero() || candidate.Before(next)) {
+			next = candidate
+		}
+	}
+
+	for _, schedule := range schedules {
+		local, err := scheduleLocalTime(now, schedule)
+		if err != nil {
+			continue
+		}
+
+		for _, day := range reminderDays(local) {
+			if delivery, ok := reminderDelivery(day, schedule); ok {
+				consider(delivery)
+			}
+		}
+	}
+
+	if next.IsZero() {
+		return time.Time{}, false
+	}
+	return next.UTC(), true
+}
+
+// nextOccurrence — ближайшее после local наступление времени "HH:MM" в таймзоне local
+func nextOccurrence(local time.Time, clock string) (time.Time, bool) {
+	parsed, err := time.Parse(reminderTimeLayout, clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	occurrence := time.Date(local.Year(), local.Month(), local.Day(), parsed.Hour(), parsed.Minute(), 0, 0, local.Location())
+	if !occurrence.After(local) {
+		occurrence = time.Date(local.Year(), local.Month(), local.Day()+1, parsed.Hour(), parsed.Minute(), 0, 0, local.Location())
+	}
+	return occurrence, true
+}
+
+// scheduleLocalTime переводит now в таймзону пользователя из расписания напоминаний
+func scheduleLocalTime(now time.Time, schedule *models.ReminderSchedule) (time.Time, error) {
+	timezone := "UTC"
+	if schedule.Timezone != nil && *schedule.Timezone != "" {
+		timezone = *schedule.Timezone
+	}
+
+	local, err := utils.ToUserTimezone(now, timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("convert to user timezone (telegram_id: %d, timezone: %s): %w", schedule.UserID, timezone, err)
+	}
+	return local, nil
+}
+
+// reminderDays — местные дни, напоминания которых могут приходиться на время около local: вчерашнее напоминание,
+// отложенное до конца тихих часов или пропущенное перед полуночью, приходит сегодня, а сегодняшние могут уже пройти
+func reminderDays(local time.Time) []time.Time {
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	return []time.Time{today.AddDate(0, 0, -1), today, today.AddDate(0, 0, 1)}
+}
+
+// reminderDelivery — когда отправляется напоминание, назначенное на местный день day: в своё время,
+// а если оно попадает в тихие часы — когда они заканчиваются, возможно уже на следующий день
+func reminderDelivery(day time.Time, schedule *models.ReminderSchedule) (time.Time, bool) {
+	parsed, err := time.Parse(reminderTimeLayout, schedule.ReminderTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	scheduled := time.Date(day.Year(), day.Month(), day.Day(), parsed.Hour(), parsed.Minute(), 0, 0, day.Location())
+	if !inQuietHours(scheduled, schedule.QuietHoursStart, schedule.QuietHoursEnd) {
+		return scheduled, true
+	}
+	return nextOccurrence(scheduled, *schedule.QuietHoursEnd)
+}
+
+// reminderDue сообщает, наступило ли время отправки напоминания и не кончился ли местный день, в который оно
+// приходится: напоминание, пропущенное из-за простоя, приходит позже в тот же день, но не переносится на следующий
+func reminderDue(local, delivery time.Time) bool {
+	return !local.Before(delivery) && local.Before(utils.AddDays(delivery, 1))
+}
+
+// inQuietHours сообщает, попадает ли местное время в тихие часы [start, end), интервал может переходить через полночь
+func inQuietHours(local time.Time, start, end *string) bool {
+	if start == nil || end == nil {
+		return false
+	}
+
+	startTime, err := time.Parse(reminderTimeLayout, *start)
+	if err != nil {
+		return false
+	}
+	endTime, err := time.Parse(reminderTimeLayout, *end)
+	if err != nil {
+		return false
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	from := startTime.Hour()*60 + startTime.Minute()
+	to := endTime.Hour()*60 + endTime.Minute()
+
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to
+}
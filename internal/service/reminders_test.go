@@ -0,0 +1,136 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+func newReminderSchedule(reminderTime, quietStart, quietEnd string) *models.ReminderSchedule {
+	timezone := "Europe/Moscow"
+	schedule := &models.ReminderSchedule{UserID: 1, ReminderTime: reminderTime, Timezone: &timezone}
+	if quietStart != "" {
+		schedule.QuietHoursStart = &quietStart
+		schedule.QuietHoursEnd = &quietEnd
+	}
+	return schedule
+}
+
+func TestReminderDelivery(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, moscow)
+
+	tests := []struct {
+		name     string
+		schedule *models.ReminderSchedule
+		want     time.Time
+	}{
+		{
+			name:     "outside quiet hours",
+			schedule: newReminderSchedule("09:00", "22:00", "08:00"),
+			want:     time.Date(2026, 3, 10, 9, 0, 0, 0, moscow),
+		},
+		{
+			name:     "quiet hours end long after the reminder",
+			schedule: newReminderSchedule("06:00", "22:00", "10:00"),
+			want:     time.Date(2026, 3, 10, 10, 0, 0, 0, moscow),
+		},
+		{
+			name:     "quiet hours end the next day",
+			schedule: newReminderSchedule("23:00", "22:00", "08:00"),
+			want:     time.Date(2026, 3, 11, 8, 0, 0, 0, moscow),
+		},
+		{
+			name:     "quiet hours within a day",
+			schedule: newReminderSchedule("13:00", "12:00", "17:30"),
+			want:     time.Date(2026, 3, 10, 17, 30, 0, 0, moscow),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := reminderDelivery(day, tt.schedule)
+			if !ok || !got.Equal(tt.want) {
+				t.Fatalf("reminderDelivery = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+// TestReminderDueAfterLongQuietHours проверяет, что напоминание в тихие часы, которые заканчиваются через несколько
+// часов после его времени, приходит в конце тихих часов, а вчерашнее напоминание на следующий день не переносится
+func TestReminderDueAfterLongQuietHours(t *testing.T) {
+	schedule := newReminderSchedule("06:00", "22:00", "10:00")
+	// 10:00 по Москве
+	quietEnd := time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC)
+
+	next, ok := nextReminderRun(quietEnd.Add(-5*time.Hour), []*models.ReminderSchedule{schedule})
+	if !ok || !next.Equal(quietEnd) {
+		t.Fatalf("nextReminderRun = %v, %v, want %v", next, ok, quietEnd)
+	}
+
+	local, err := scheduleLocalTime(quietEnd.Add(time.Minute), schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var due []time.Time
+	for _, day := range reminderDays(local) {
+		if delivery, ok := reminderDelivery(day, schedule); ok && reminderDue(local, delivery) {
+			due = append(due, day)
+		}
+	}
+	if len(due) != 1 || due[0].Day() != 10 {
+		t.Fatalf("due days = %v, want only 10 March", due)
+	}
+}
+
+// TestReminderDeliveredAfterCrash проверяет, что напоминание, занятое процессом, который упал до отправки,
+// отправляет следующий запуск задачи после истечения её аренды, и только один раз
+func TestReminderDeliveredAfterCrash(t *testing.T) {
+	// 07:00 по Берлину, напоминание на 07:00 приходится на тихие часы и отправляется в 08:00
+	start := time.Date(2026, 7, 1, 5, 0, 0, 0, time.UTC)
+	delivery := time.Date(2026, 7, 1, 6, 0, 0, 0, time.UTC)
+	sim := newSimulation(t, start, "Europe/Berlin", 1)
+	sim.run(delivery)
+
+	// Процесс занимает задачу и напоминание и падает, не отправив его и не завершив задачу
+	jobs, err := sim.svc.ClaimJobs(sim.ctx, delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].JobType != models.JobReminder {
+		t.Fatalf("claimed jobs %v, want the reminder job", jobs)
+	}
+	claimed, err := sim.svc.ClaimDueReminders(sim.ctx, simUserID, delivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 {
+		t.Fatalf("claimed %d reminders, want 1", len(claimed))
+	}
+
+	sim.run(time.Date(2026, 7, 1, 21, 0, 0, 0, time.UTC))
+
+	var morning []simDelivery
+	for _, d := range sim.deliveries {
+		if d.reminder.ReminderTime == "07:00" {
+			morning = append(morning, d)
+		}
+	}
+	if len(morning) != 1 {
+		t.Fatalf("morning reminder delivered %d times, want 1", len(morning))
+	}
+
+	// Задачу снова занимают на первом шаге симуляции после истечения её аренды
+	if leaseEnd := delivery.Add(jobLease); morning[0].local.Before(leaseEnd) || !morning[0].local.Before(leaseEnd.Add(simTick)) {
+		t.Errorf("morning reminder delivered at %v, want the first tick after %v", morning[0].local, leaseEnd)
+	}
+	if date := morning[0].reminder.LocalDate.Format(time.DateOnly); date != "2026-07-01" {
+		t.Errorf("morning reminder delivered for %s, want 2026-07-01", date)
+	}
+}
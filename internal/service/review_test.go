@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+// reviewRepo — хранилище в памяти для оценки и отмены оценки страниц. RunInTx работает как транзакция: если функция
+// вернула ошибку, прогресс, история, сегодняшняя сессия и активность пользователя возвращаются к состоянию до её вызова.
+type reviewRepo struct {
+	models.Repository
+
+	user     models.User
+	progress map[string]*models.UserProgress
+	history  []*reviewEvent
+	session  *models.ReviewSession
+	// addHistoryErr — ошибка, которую возвращает AddProgressHistory
+	addHistoryErr error
+	// resetItemErr — ошибка, которую возвращает ResetReviewSessionItemResult
+	resetItemErr error
+}
+
+// reviewEvent — запись истории прогресса страницы
+type reviewEvent struct {
+	pageID string
+	models.ProgressHistory
+}
+
+func newReviewRepo(pages ...*models.UserProgress) *reviewRepo {
+	timezone := "Europe/Berlin"
+	repo := &reviewRepo{
+		user:     models.User{TelegramID: 1, Timezone: &timezone},
+		progress: make(map[string]*models.UserProgress),
+	}
+	for _, page := range pages {
+		repo.progress[page.PageID] = page
+	}
+	return repo
+}
+
+func (r *reviewRepo) GetUser(context.Context, int64) (*models.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *reviewRepo) RunInTx(_ context.Context, fn func(models.Repository) error) error {
+	user := r.user
+	progress := make(map[string]*models.UserProgress, len(r.progress))
+	for pageID, p := range r.progress {
+		copied := *p
+		progress[pageID] = &copied
+	}
+	history := make([]*reviewEvent, 0, len(r.history))
+	for _, event := range r.history {
+		copied := *event
+		history = append(history, &copied)
+	}
+	session := copySession(r.session)
+
+	if err := fn(r); err != nil {
+		r.user, r.progress, r.history, r.session = user, progress, history, session
+		return err
+	}
+	return nil
+}
+
+func copySession(session *models.ReviewSession) *models.ReviewSession {
+	if session == nil {
+		return nil
+	}
+
+	copied := *session
+	copied.Items = make([]*models.ReviewSessionItem, 0, len(session.Items))
+	for _, item := range session.Items {
+		copiedItem := *item
+		copied.Items = append(copied.Items, &copiedItem)
+	}
+	return &copied
+}
+
+func (r *reviewRepo) UpdateUserActivity(_ context.Context, _ int64, activityDate time.Time) error {
+	r.user.LastActivityDate = &activityDate
+	return nil
+}
+
+func (r *reviewRepo) SetUserPaused(_ context.Context, _ int64, paused bool) error {
+	r.user.IsPaused = &paused
+	return nil
+}
+
+func (r *reviewRepo) GetProgress(_ context.Context, _ int64, pageID string) (*models.UserProgress, error) {
+	progress := *r.progress[pageID]
+	return &progress, nil
+}
+
+func (r *reviewRepo) UpdateProgress(_ context.Context, _ int64, pageID string, version int, level string, repetitionCount int, lastReviewDate, nextReviewDate time.Time, intervalDays int, reviewedToday bool, passed bool) (bool, error) {
+	progress := r.progress[pageID]
+	if progress.Version != version {
+		return false, nil
+	}
+
+	progress.Level = level
+	progress.RepetitionCount = repetitionCount
+	progress.LastReviewDate = lastReviewDate
+	progress.NextReviewDate = nextReviewDate
+	progress.IntervalDays = intervalDays
+	progress.ReviewedToday = reviewedToday
+	progress.Passed = passed
+	progress.Version++
+	return true, nil
+}
+
+func (r *reviewRepo) AddProgressHistory(_ context.Context, _ int64, pageID string, history models.ProgressHistory) error {
+	if r.addHistoryErr != nil {
+		return r.addHistoryErr
+	}
+
+	history.ID = int64(len(r.history) + 1)
+	r.history = append(r.history, &reviewEvent{pageID: pageID, ProgressHistory: history})
+	return nil
+}
+
+func (r *reviewRepo) ReviewKeyExists(_ context.Context, _ int64, reviewKey string) (bool, error) {
+	for _, event := range r.history {
+		if event.ReviewKey == reviewKey {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *reviewRepo) GetLastProgressHistory(_ context.Context, _ int64, pageID string) (*models.ProgressHistory, error) {
+	for i := len(r.history) - 1; i >= 0; i-- {
+		if r.history[i].pageID == pageID {
+			history := r.history[i].ProgressHistory
+			return &history, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *reviewRepo) MarkProgressHistoryUndone(_ context.Context, _ int64, historyID int64, undoneAt time.Time) error {
+	for _, event := range r.history {
+		if event.ID == historyID {
+			event.UndoneAt = &undoneAt
+		}
+	}
+	return nil
+}
+
+func (r *reviewRepo) GetReviewSession(context.Context, int64, time.Time) (*models.ReviewSession, error) {
+	return copySession(r.session), nil
+}
+
+func (r *reviewRepo) SetReviewSessionItemResult(_ context.Context, _ int64, pageID, result string, grade *int, reviewedAt time.Time) (bool, error) {
+	item := r.session.Item(pageID)
+	if item.Result != nil {
+		return false, nil
+	}
+
+	item.Result, item.Grade, item.ReviewedAt = &result, grade, &reviewedAt
+	return true, nil
+}
+
+func (r *reviewRepo) ResetReviewSessionItemResult(_ context.Context, _ int64, pageID string) error {
+	if r.resetItemErr != nil {
+		return r.resetItemErr
+	}
+
+	item := r.session.Item(pageID)
+	item.Result, item.Grade, item.ReviewedAt = nil, nil, nil
+	return nil
+}
+
+func (r *reviewRepo) UpdateReviewSessionStatus(_ context.Context, _ int64, status string, finishedAt *time.Time) error {
+	r.session.Status, r.session.FinishedAt = status, finishedAt
+	return nil
+}
+
+// errHistoryWrite — сбой записи истории посреди транзакции оценки
+var errHistoryWrite = errors.New("connection reset")
+
+func TestUpdateReviewProgress(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	page := func() *models.UserProgress {
+		return &models.UserProgress{UserID: 1, PageID: "page-1", IntervalDays: 3, NextReviewDate: now.Add(-time.Hour), Version: 4}
+	}
+	graded := models.ReviewSubmission{PageID: "page-1", Grade: 90, Version: 4, Key: "t_first", Source: models.ReviewSourceButton}
+
+	tests := []struct {
+		name string
+		// prepare готовит хранилище, например, записывает первую оценку
+		prepare     func(t *testing.T, svc *Service, repo *reviewRepo)
+		submission  models.ReviewSubmission
+		wantErr     error
+		wantVersion int
+		wantHistory int
+	}{
+		{
+			name:        "graded",
+			submission:  graded,
+			wantVersion: 5,
+			wantHistory: 1,
+		},
+		{
+			// Повторная доставка той же кнопки после оценки: версия уже другая, но это повтор, а не конфликт
+			name: "duplicate key",
+			prepare: func(t *testing.T, svc *Service, _ *reviewRepo) {
+				if err := svc.UpdateReviewProgress(context.Background(), 1, graded); err != nil {
+					t.Fatal(err)
+				}
+			},
+			submission:  graded,
+			wantErr:     ErrDuplicateReview,
+			wantVersion: 5,
+			wantHistory: 1,
+		},
+		{
+			// Другая кнопка, показанная до первой оценки
+			name: "stale version",
+			prepare: func(t *testing.T, svc *Service, _ *reviewRepo) {
+				if err := svc.UpdateReviewProgress(context.Background(), 1, graded); err != nil {
+					t.Fatal(err)
+				}
+			},
+			submission:  models.ReviewSubmission{PageID: "page-1", Grade: 20, Version: 4, Key: "t_second", Source: models.ReviewSourceButton},
+			wantErr:     ErrReviewConflict,
+			wantVersion: 5,
+			wantHistory: 1,
+		},
+		{
+			name: "history write fails",
+			prepare: func(_ *testing.T, _ *Service, repo *reviewRepo) {
+				repo.addHistoryErr = errHistoryWrite
+			},
+			submission:  graded,
+			wantErr:     errHistoryWrite,
+			wantVersion: 4,
+			wantHistory: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newReviewRepo(page())
+			svc := NewService(repo, nil, nil, clocktest.NewManualClock(now), clocktest.FixedRand(0.5))
+			if tt.prepare != nil {
+				tt.prepare(t, svc, repo)
+			}
+			before := *repo.progress["page-1"]
+			activity := repo.user.LastActivityDate
+
+			err := svc.UpdateReviewProgress(context.Background(), 1, tt.submission)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			after := repo.progress["page-1"]
+			if after.Version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", after.Version, tt.wantVersion)
+			}
+			if len(repo.history) != tt.wantHistory {
+				t.Errorf("history has %d events, want %d", len(repo.history), tt.wantHistory)
+			}
+			if err != nil {
+				// Отклонённая оценка не меняет ни прогресс, ни активность пользователя
+				if *after != before {
+					t.Errorf("progress changed by a rejected grade: %+v, want %+v", *after, before)
+				}
+				if repo.user.LastActivityDate != activity {
+					t.Error("user activity changed by a rejected grade")
+				}
+			}
+		})
+	}
+}
+
+// TestUpdateReviewProgressRecordsHistory проверяет, что запись истории хранит ключ, источник и прогресс до и после оценки
+func TestUpdateReviewProgressRecordsHistory(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	shownAt := now.Add(-12 * time.Second)
+	repo := newReviewRepo(&models.UserProgress{UserID: 1, PageID: "page-1", IntervalDays: 3, NextReviewDate: now.Add(-time.Hour), Version: 4})
+	svc := NewService(repo, nil, nil, clocktest.NewManualClock(now), clocktest.FixedRand(0.5))
+
+	submission := models.ReviewSubmission{PageID: "page-1", Grade: 90, Version: 4, Key: "t_first", Source: models.ReviewSourceTyped, ShownAt: &shownAt}
+	if err := svc.UpdateReviewProgress(context.Background(), 1, submission); err != nil {
+		t.Fatal(err)
+	}
+
+	event := repo.history[0]
+	if event.ReviewKey != "t_first" || event.Source != models.ReviewSourceTyped || event.Score != 90 {
+		t.Errorf("history event %+v does not match the submission", event.ProgressHistory)
+	}
+	if event.ResponseTime == nil || *event.ResponseTime != 12*time.Second {
+		t.Errorf("response time = %v, want 12s", event.ResponseTime)
+	}
+	if event.Before == nil || event.Before.Version != 4 || event.After == nil || *event.After != *repo.progress["page-1"] {
+		t.Errorf("history before/after = %+v/%+v, want version 4 and the stored progress", event.Before, event.After)
+	}
+	if !repo.progress["page-1"].ReviewedToday {
+		t.Error("page is not marked reviewed today")
+	}
+}
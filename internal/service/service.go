@@ -3,6 +3,7 @@ package service
 import (
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"slices"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/romanzh1/master-english-srs/internal/i18n"
 	"github.com/romanzh1/master-english-srs/internal/models"
 	"github.com/romanzh1/master-english-srs/internal/service/srs"
 	"github.com/romanzh1/master-english-srs/pkg/onenote"
@@ -20,18 +22,30 @@ import (
 type Service struct {
 	repo          models.Repository
 	authService   *onenote.AuthService
-	oneNoteClient *onenote.Client
+	oneNoteClient models.OneNoteClient
+	// clock и rng задают текущее время и случайный выбор, чтобы в тестах смену дней и интервалы можно было прокручивать
+	clock utils.Clock
+	rng   utils.Rand
 }
 
-func NewService(repo models.Repository, authService *onenote.AuthService, oneNoteClient *onenote.Client) *Service {
+func NewService(repo models.Repository, authService *onenote.AuthService, oneNoteClient models.OneNoteClient, clock utils.Clock, rng utils.Rand) *Service {
 	return &Service{
 		repo:          repo,
 		authService:   authService,
 		oneNoteClient: oneNoteClient,
+		clock:         clock,
+		rng:           rng,
 	}
 }
 
-func (s *Service) RegisterUser(ctx context.Context, telegramID int64, username, level string) error {
+func (s *Service) RegisterUser(ctx context.Context, telegramID int64, username, level, language string) error {
+	if err := ValidateLevel(level); err != nil {
+		return err
+	}
+	if !i18n.Supported(language) {
+		language = i18n.DefaultLanguage
+	}
+
 	exists, err := s.repo.UserExists(ctx, telegramID)
 	if err != nil {
 		return fmt.Errorf("check user exists (telegram_id: %d): %w", telegramID, err)
@@ -43,13 +57,14 @@ func (s *Service) RegisterUser(ctx context.Context, telegramID int64, username,
 
 	defaultTimezone := "UTC"
 	user := &models.User{
-		TelegramID:     telegramID,
-		Username:       username,
-		Level:          level,
-		UseManualPages: false,
-		ReminderTime:   "09:00",
-		CreatedAt:      utils.NowUTC(),
-		Timezone:       &defaultTimezone,
+		TelegramID:       telegramID,
+		Username:         username,
+		Level:            level,
+		UseManualPages:   false,
+		RemindersEnabled: true,
+		Language:         language,
+		CreatedAt:        s.clock.Now(),
+		Timezone:         &defaultTimezone,
 	}
 
 	maxPagesPerDay := uint(2)
@@ -57,8 +72,26 @@ func (s *Service) RegisterUser(ctx context.Context, telegramID int64, username,
 		user.MaxPagesPerDay = &maxPagesPerDay
 	}
 
-	if err := s.repo.CreateUser(ctx, user); err != nil {
-		return fmt.Errorf("create user (telegram_id: %d, username: %s): %w", telegramID, username, err)
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if err := txRepo.CreateUser(ctx, user); err != nil {
+			return fmt.Errorf("create user: %w", err)
+		}
+
+		if err := txRepo.ReplaceReminderTimes(ctx, telegramID, []string{DefaultReminderTime}); err != nil {
+			return fmt.Errorf("set default reminder time: %w", err)
+		}
+
+		// Задачи запускаются сразу и сами назначают себе следующий запуск
+		for _, jobType := range models.JobTypes {
+			if err := txRepo.ScheduleJob(ctx, telegramID, jobType, user.CreatedAt); err != nil {
+				return fmt.Errorf("schedule %s job: %w", jobType, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("register user (telegram_id: %d, username: %s): %w", telegramID, username, err)
 	}
 
 	return nil
@@ -73,6 +106,10 @@ func (s *Service) UserExists(ctx context.Context, telegramID int64) (bool, error
 }
 
 func (s *Service) UpdateUserLevel(ctx context.Context, telegramID int64, level string) error {
+	if err := ValidateLevel(level); err != nil {
+		return err
+	}
+
 	return s.repo.UpdateUserLevel(ctx, telegramID, level)
 }
 
@@ -90,7 +127,7 @@ func (s *Service) ExchangeAuthCode(ctx context.Context, telegramID int64, code s
 	auth := &models.OneNoteAuth{
 		AccessToken:  tokenResp.AccessToken,
 		RefreshToken: tokenResp.RefreshToken,
-		ExpiresAt:    utils.NowUTC().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+		ExpiresAt:    s.clock.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
 	}
 
 	if err := s.repo.UpdateOneNoteAuth(ctx, telegramID, auth); err != nil {
@@ -120,7 +157,7 @@ func (s *Service) getValidAccessToken(ctx context.Context, telegramID int64) (st
 
 	// Проверяем, не истёк ли токен (с запасом в 5 минут)
 	expiresAt := *user.ExpiresAt
-	if time.Until(expiresAt) > 5*time.Minute {
+	if expiresAt.Sub(s.clock.Now()) > 5*time.Minute {
 		return *user.AccessToken, nil
 	}
 
@@ -166,7 +203,7 @@ func (s *Service) updateTokens(ctx context.Context, telegramID int64, tokenResp
 	auth := &models.OneNoteAuth{
 		AccessToken:  tokenResp.AccessToken,
 		RefreshToken: tokenResp.RefreshToken,
-		ExpiresAt:    utils.NowUTC().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+		ExpiresAt:    s.clock.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
 	}
 
 	if err := s.repo.UpdateOneNoteAuth(ctx, telegramID, auth); err != nil {
@@ -215,69 +252,60 @@ func (s *Service) GetOneNoteSections(ctx context.Context, telegramID int64, note
 	return sections, err
 }
 
-func (s *Service) SaveOneNoteConfig(ctx context.Context, telegramID int64, notebookID, sectionID string) error {
-	config := &models.OneNoteConfig{
-		NotebookID: notebookID,
-		SectionID:  sectionID,
-	}
-
-	if err := s.repo.UpdateOneNoteConfig(ctx, telegramID, config); err != nil {
-		return fmt.Errorf("save OneNote config (telegram_id: %d, notebook_id: %s): %w", telegramID, notebookID, err)
-	}
-
-	return nil
-}
-
-func (s *Service) syncPagesInternal(ctx context.Context, telegramID int64) error {
-	user, err := s.repo.GetUser(ctx, telegramID)
-	if err != nil {
-		return fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
-	}
+// GetOneNoteNotebookContents возвращает группы секций и секции верхнего уровня книги
+func (s *Service) GetOneNoteNotebookContents(ctx context.Context, telegramID int64, notebookID string) ([]onenote.SectionGroup, []onenote.Section, error) {
+	var groups []onenote.SectionGroup
+	var sections []onenote.Section
 
-	if user.OneNoteConfig == nil {
-		return fmt.Errorf("onenote not configured (telegram_id: %d)", telegramID)
-	}
+	err := s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
+		resultGroups, err := s.oneNoteClient.GetSectionGroups(accessToken, notebookID)
+		if err != nil {
+			return fmt.Errorf("get section groups (telegram_id: %d, notebook_id: %s): %w", telegramID, notebookID, err)
+		}
 
-	var pages []onenote.Page
-	err = s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
-		result, err := s.oneNoteClient.GetPages(accessToken, user.OneNoteConfig.SectionID)
+		resultSections, err := s.oneNoteClient.GetSections(accessToken, notebookID)
 		if err != nil {
-			return fmt.Errorf("get pages (telegram_id: %d, section_id: %s): %w", telegramID, user.OneNoteConfig.SectionID, err)
+			return fmt.Errorf("get sections (telegram_id: %d, notebook_id: %s): %w", telegramID, notebookID, err)
 		}
-		pages = result
+
+		groups, sections = resultGroups, resultSections
 		return nil
 	})
 
-	if err != nil {
-		return err
-	}
+	return groups, sections, err
+}
 
-	for _, page := range pages {
-		if strings.Contains(page.Title, "*") || !hasPageNumber(page.Title) {
-			continue
-		}
+// GetOneNoteSectionGroupContents возвращает вложенные группы секций и секции группы
+func (s *Service) GetOneNoteSectionGroupContents(ctx context.Context, telegramID int64, sectionGroupID string) ([]onenote.SectionGroup, []onenote.Section, error) {
+	var groups []onenote.SectionGroup
+	var sections []onenote.Section
 
-		var updatedAt *time.Time
-		if page.LastModifiedDateTime != "" {
-			parsed, err := time.Parse(time.RFC3339, page.LastModifiedDateTime)
-			if err == nil {
-				updatedAt = &parsed
-			}
+	err := s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
+		resultGroups, err := s.oneNoteClient.GetChildSectionGroups(accessToken, sectionGroupID)
+		if err != nil {
+			return fmt.Errorf("get child section groups (telegram_id: %d, section_group_id: %s): %w", telegramID, sectionGroupID, err)
 		}
 
-		pageRef := &models.PageReference{
-			PageID:    page.ID,
-			UserID:    telegramID,
-			Title:     page.Title,
-			Source:    "onenote",
-			CreatedAt: utils.NowUTC(),
-			UpdatedAt: updatedAt,
+		resultSections, err := s.oneNoteClient.GetSectionGroupSections(accessToken, sectionGroupID)
+		if err != nil {
+			return fmt.Errorf("get section group sections (telegram_id: %d, section_group_id: %s): %w", telegramID, sectionGroupID, err)
 		}
 
-		if err := s.repo.UpsertPageReference(ctx, pageRef); err != nil {
-			zap.S().Error("upsert page reference", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.String("page_id", page.ID))
-			continue
-		}
+		groups, sections = resultGroups, resultSections
+		return nil
+	})
+
+	return groups, sections, err
+}
+
+func (s *Service) SaveOneNoteConfig(ctx context.Context, telegramID int64, notebookID, sectionID string) error {
+	config := &models.OneNoteConfig{
+		NotebookID: notebookID,
+		SectionID:  sectionID,
+	}
+
+	if err := s.repo.UpdateOneNoteConfig(ctx, telegramID, config); err != nil {
+		return fmt.Errorf("save OneNote config (telegram_id: %d, notebook_id: %s): %w", telegramID, notebookID, err)
 	}
 
 	return nil
@@ -334,12 +362,12 @@ func (s *Service) GetDuePagesToday(ctx context.Context, telegramID int64) ([]*mo
 	}
 
 	// Calculate end of day in user's timezone, then convert to UTC for database query
-	startOfDayInTz, err := utils.StartOfTodayInTimezone(timezone)
+	startOfDayInTz, err := utils.StartOfDayInTimezone(s.clock.Now(), timezone)
 	if err != nil {
 		return nil, fmt.Errorf("get start of day in timezone (telegram_id: %d, timezone: %s): %w", telegramID, timezone, err)
 	}
 
-	endOfDayUTC := startOfDayInTz.AddDate(0, 0, 1).UTC()
+	endOfDayUTC := utils.AddDays(startOfDayInTz, 1).UTC()
 
 	progressList, err := s.repo.GetDuePagesToday(ctx, telegramID, endOfDayUTC)
 	if err != nil {
@@ -350,25 +378,18 @@ func (s *Service) GetDuePagesToday(ctx context.Context, telegramID int64) ([]*mo
 		return []*models.PageWithProgress{}, nil
 	}
 
-	var onenotePages []onenote.Page
-	err = s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
-		result, err := s.oneNoteClient.GetPages(accessToken, user.OneNoteConfig.SectionID)
-		if err != nil {
-			return fmt.Errorf("get pages (telegram_id: %d, section_id: %s): %w", telegramID, user.OneNoteConfig.SectionID, err)
-		}
-		onenotePages = result
-		return nil
-	})
+	onenotePages, err := s.getSourcePages(ctx, telegramID)
 	if err != nil {
 		return nil, fmt.Errorf("get onenote pages (telegram_id: %d): %w", telegramID, err)
 	}
 
-	pageMap := make(map[string]onenote.Page, len(onenotePages))
+	pageMap := make(map[string]sourcePage, len(onenotePages))
 	for _, page := range onenotePages {
 		pageMap[page.ID] = page
 	}
 
 	result := make([]*models.PageWithProgress, 0, len(progressList))
+	priorities := make(map[string]int, len(progressList))
 	for _, progress := range progressList {
 		page, ok := pageMap[progress.PageID]
 		if !ok {
@@ -393,15 +414,21 @@ func (s *Service) GetDuePagesToday(ctx context.Context, telegramID int64) ([]*mo
 				UserID:    telegramID,
 				Title:     page.Title,
 				Source:    "onenote",
-				CreatedAt: time.Now(),
+				SectionID: page.Source.SectionID,
+				CreatedAt: s.clock.Now(),
 				UpdatedAt: updatedAt,
 			},
 			Progress: progress,
 		}
 		result = append(result, pwp)
+		priorities[page.ID] = page.Source.Priority
 	}
 
 	slices.SortFunc(result, func(a, b *models.PageWithProgress) int {
+		if c := cmp.Compare(priorities[a.Page.PageID], priorities[b.Page.PageID]); c != 0 {
+			return c
+		}
+
 		numA := extractPageNumber(a.Page.Title)
 		numB := extractPageNumber(b.Page.Title)
 		if numA != numB {
@@ -424,15 +451,7 @@ func (s *Service) GetUserAllPagesInProgress(ctx context.Context, telegramID int6
 		return nil, fmt.Errorf("onenote not configured (telegram_id: %d)", telegramID)
 	}
 
-	var onenotePages []onenote.Page
-	err = s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
-		result, err := s.oneNoteClient.GetPages(accessToken, user.OneNoteConfig.SectionID)
-		if err != nil {
-			return fmt.Errorf("get pages (telegram_id: %d, section_id: %s): %w", telegramID, user.OneNoteConfig.SectionID, err)
-		}
-		onenotePages = result
-		return nil
-	})
+	onenotePages, err := s.getSourcePages(ctx, telegramID)
 	if err != nil {
 		return nil, fmt.Errorf("get onenote pages (telegram_id: %d): %w", telegramID, err)
 	}
@@ -448,6 +467,7 @@ func (s *Service) GetUserAllPagesInProgress(ctx context.Context, telegramID int6
 	}
 
 	result := make([]*models.PageReference, 0, len(onenotePages))
+	priorities := make(map[string]int, len(onenotePages))
 	for _, page := range onenotePages {
 		if page.ID == "" {
 			continue
@@ -474,19 +494,25 @@ func (s *Service) GetUserAllPagesInProgress(ctx context.Context, telegramID int6
 			UserID:    telegramID,
 			Title:     page.Title,
 			Source:    "onenote",
-			CreatedAt: utils.NowUTC(),
+			SectionID: page.Source.SectionID,
+			CreatedAt: s.clock.Now(),
 			UpdatedAt: updatedAt,
 		}
 
-		if err := s.repo.UpsertPageReference(ctx, pageRef); err != nil {
-			zap.S().Error("upsert page reference", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.String("page_id", page.ID))
-			// Продолжаем, даже если не удалось сохранить в БД
-		}
-
 		result = append(result, pageRef)
+		priorities[page.ID] = page.Source.Priority
+	}
+
+	if err := s.repo.UpsertPageReferences(ctx, result); err != nil {
+		zap.S().Error("upsert page references", zap.Error(err), zap.Int64("telegram_id", telegramID))
+		// Продолжаем, даже если не удалось сохранить в БД
 	}
 
 	slices.SortFunc(result, func(a, b *models.PageReference) int {
+		if c := cmp.Compare(priorities[a.PageID], priorities[b.PageID]); c != 0 {
+			return c
+		}
+
 		numA := extractPageNumber(a.Title)
 		numB := extractPageNumber(b.Title)
 		return cmp.Compare(numA, numB)
@@ -537,99 +563,213 @@ func (s *Service) GetPageContent(ctx context.Context, telegramID int64, pageID s
 	var content string
 
 	err := s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
-		result, err := s.oneNoteClient.GetPageContent(accessToken, pageID)
+		result, err := s.oneNoteClient.GetPageHTML(accessToken, pageID)
 		if err != nil {
 			return fmt.Errorf("get page content (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
 		}
 		content = result
 		return nil
 	})
+	if err != nil {
+		return "", err
+	}
 
-	return content, err
+	return onenote.RenderHTML(content), nil
 }
 
-func (s *Service) UpdateReviewProgress(ctx context.Context, telegramID int64, pageID string, grade int) error {
-	progress, err := s.repo.GetProgress(ctx, telegramID, pageID)
+// savePageItems сохраняет слова из словарных таблиц страницы как дочерние записи page_references
+// и отмечает, что слова страницы извлечены
+func (s *Service) savePageItems(ctx context.Context, telegramID int64, pageID string, entries []onenote.VocabularyEntry) error {
+	nowUTC := s.clock.Now()
+	items := make([]*models.PageItem, 0, len(entries))
+	for i, entry := range entries {
+		items = append(items, &models.PageItem{
+			UserID:      telegramID,
+			PageID:      pageID,
+			Position:    i,
+			Word:        entry.Word,
+			Translation: entry.Translation,
+			Example:     entry.Example,
+			CreatedAt:   nowUTC,
+			UpdatedAt:   &nowUTC,
+		})
+	}
+
+	err := s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if err := txRepo.ReplacePageItems(ctx, telegramID, pageID, items); err != nil {
+			return err
+		}
+		return txRepo.MarkPageItemsExtracted(ctx, telegramID, pageID, nowUTC)
+	})
 	if err != nil {
-		return fmt.Errorf("get progress (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
+		return fmt.Errorf("replace page items (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
 	}
 
-	// Проверяем, приостановлен ли пользователь, чтобы возобновить его при активности
-	user, err := s.repo.GetUser(ctx, telegramID)
+	return nil
+}
+
+func (s *Service) GetPageItems(ctx context.Context, telegramID int64, pageID string) ([]*models.PageItem, error) {
+	items, err := s.repo.GetPageItems(ctx, telegramID, pageID)
 	if err != nil {
-		return fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+		return nil, fmt.Errorf("get page items (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
 	}
 
-	status := srs.ConvertGradeToStatus(grade)
+	return items, nil
+}
 
-	timezone := "UTC"
-	if user.Timezone != nil && *user.Timezone != "" {
-		timezone = *user.Timezone
-	}
+var (
+	// ErrDuplicateReview — оценка с этим ключом уже записана: кнопку нажали дважды или Telegram доставил её повторно
+	ErrDuplicateReview = errors.New("duplicate review")
+	// ErrReviewConflict — прогресс страницы изменился после её показа, например, её уже оценили другой кнопкой
+	ErrReviewConflict = errors.New("review conflict")
+)
+
+// reviewSchedule — прогресс страницы после оценки
+type reviewSchedule struct {
+	nextReview   time.Time
+	intervalDays int
+	// mode — режим, в котором выставлена оценка: srs.ModeReading или srs.ModeStandard
+	mode   string
+	passed bool
+}
 
-	var nextReview time.Time
-	var newInterval int
-	var historyMode string
+// scheduleReview рассчитывает следующее повторение страницы с интервалом intervalDays после оценки grade в момент now
+func scheduleReview(now time.Time, intervalDays, grade int, timezone string) reviewSchedule {
+	status := srs.ConvertGradeToStatus(grade)
+	var schedule reviewSchedule
 
 	// Режим чтения (IntervalDays == 0): пользователь только читает слова
-	if progress.IntervalDays == 0 {
-		statusStr := string(status)
-		if statusStr == "normal" || statusStr == "easy" {
+	if intervalDays == 0 {
+		if status.Remembered() {
 			// Пользователь помнит слова → переход к AI режиму завтра
-			nextReview, newInterval = srs.GetNextDayReviewDate(timezone)
+			schedule.nextReview, schedule.intervalDays = srs.GetNextDayReviewDate(now, timezone)
 		} else {
 			// Пользователь не помнит слова → остаёмся в режиме чтения, повтор завтра
-			nextReview, newInterval = srs.GetNextDayReadingMode(timezone)
+			schedule.nextReview, schedule.intervalDays = srs.GetNextDayReadingMode(now, timezone)
 		}
-		historyMode = "reading"
+		schedule.mode = srs.ModeReading
 	} else {
 		// AI режим: стандартные SRS интервалы
-		nextReview, newInterval = srs.CalculateNextReviewDate(progress.IntervalDays, status, timezone)
-		historyMode = "standard"
+		schedule.nextReview, schedule.intervalDays = srs.CalculateNextReviewDate(now, intervalDays, status, timezone)
+		schedule.mode = srs.ModeStandard
 	}
 
 	// Определяем флаг passed: страница считается изученной, если она уже была на последнем интервале (180 дней)
 	// и при текущем прохождении статус = "easy" или "normal" (успешное прохождение последнего интервала)
-	passed := false
-	statusStr := string(status)
-	if progress.IntervalDays == 180 && (statusStr == "easy" || statusStr == "normal") {
-		passed = true
+	if intervalDays == 180 && status.Remembered() {
+		schedule.passed = true
 	}
 
-	nowUTC := utils.NowUTC()
-	history := models.ProgressHistory{
-		Date:  nowUTC,
-		Score: grade,
-		Mode:  historyMode,
-		Notes: "",
-	}
+	return schedule
+}
 
-	if err := s.repo.UpdateProgress(ctx, telegramID, pageID, progress.Level, progress.RepetitionCount, nowUTC, nextReview, newInterval, true, passed); err != nil {
-		return fmt.Errorf("update progress (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
+// UpdateReviewProgress применяет оценку страницы в одной транзакции: прогресс, запись истории со снимком
+// прогресса до оценки, активность пользователя и результат в сегодняшней сессии. Оценка применяется, только если
+// версия прогресса совпадает с показанной пользователю; повторная оценка с тем же ключом возвращает ErrDuplicateReview.
+func (s *Service) UpdateReviewProgress(ctx context.Context, telegramID int64, submission models.ReviewSubmission) error {
+	pageID := submission.PageID
+	grade := submission.Grade
+
+	// Проверяем, приостановлен ли пользователь, чтобы возобновить его при активности
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
 	}
 
-	if err := s.repo.AddProgressHistory(ctx, telegramID, pageID, history); err != nil {
-		zap.S().Error("add progress history", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.String("page_id", pageID))
+	timezone := "UTC"
+	if user.Timezone != nil && *user.Timezone != "" {
+		timezone = *user.Timezone
 	}
 
-	if err := s.repo.UpdateUserActivity(ctx, telegramID, nowUTC); err != nil {
-		zap.S().Error("update user activity", zap.Error(err), zap.Int64("telegram_id", telegramID))
+	nowUTC := s.clock.Now()
+
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if submission.Key != "" {
+			duplicate, err := txRepo.ReviewKeyExists(ctx, telegramID, submission.Key)
+			if err != nil {
+				return err
+			}
+			if duplicate {
+				return ErrDuplicateReview
+			}
+		}
+
+		progress, err := txRepo.GetProgress(ctx, telegramID, pageID)
+		if err != nil {
+			return err
+		}
+
+		if progress.Version != submission.Version {
+			return ErrReviewConflict
+		}
+
+		schedule := scheduleReview(nowUTC, progress.IntervalDays, grade, timezone)
+
+		updated, err := txRepo.UpdateProgress(ctx, telegramID, pageID, progress.Version, progress.Level, progress.RepetitionCount, nowUTC, schedule.nextReview, schedule.intervalDays, true, schedule.passed)
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			// Одновременная оценка с тем же ключом успела записаться первой — это повтор, а не конфликт
+			if submission.Key != "" {
+				duplicate, err := txRepo.ReviewKeyExists(ctx, telegramID, submission.Key)
+				if err != nil {
+					return err
+				}
+				if duplicate {
+					return ErrDuplicateReview
+				}
+			}
+			return ErrReviewConflict
+		}
+
+		after := *progress
+		after.LastReviewDate = nowUTC
+		after.NextReviewDate = schedule.nextReview
+		after.IntervalDays = schedule.intervalDays
+		after.ReviewedToday = true
+		after.Passed = schedule.passed
+		after.Version = progress.Version + 1
+
+		history := models.ProgressHistory{
+			Date:      nowUTC,
+			Score:     grade,
+			Mode:      schedule.mode,
+			Notes:     "",
+			Before:    progress,
+			After:     &after,
+			Source:    submission.Source,
+			ReviewKey: submission.Key,
+		}
+
+		if submission.ShownAt != nil && nowUTC.After(*submission.ShownAt) {
+			responseTime := nowUTC.Sub(*submission.ShownAt)
+			history.ResponseTime = &responseTime
+		}
+
+		if err := txRepo.AddProgressHistory(ctx, telegramID, pageID, history); err != nil {
+			return err
+		}
+
+		// Активность снимает паузу приостановленного пользователя
+		if err := s.markReturned(ctx, txRepo, user); err != nil {
+			return err
+		}
+
+		return s.recordReviewResult(ctx, txRepo, user, pageID, models.ReviewResultGraded, &grade)
+	})
+	if err != nil {
+		return fmt.Errorf("update review progress (telegram_id: %d, page_id: %s, grade: %d): %w", telegramID, pageID, grade, err)
 	}
 
-	// Если пользователь был приостановлен, возобновляем его
 	if user.IsPaused != nil && *user.IsPaused {
-		if err := s.resumeUserOnActivity(ctx, telegramID); err != nil {
-			zap.S().Error("resume user on activity", zap.Error(err), zap.Int64("telegram_id", telegramID))
-		}
+		zap.S().Info("user resumed due to activity", zap.Int64("telegram_id", telegramID))
 	}
 
 	return nil
 }
 
-func (s *Service) GetAllUsersForReminders(ctx context.Context) ([]*models.User, error) {
-	return s.repo.GetAllUsersWithReminders(ctx)
-}
-
 func (s *Service) GetProgress(ctx context.Context, telegramID int64, pageID string) (*models.UserProgress, error) {
 	return s.repo.GetProgress(ctx, telegramID, pageID)
 }
@@ -639,6 +779,10 @@ func (s *Service) GetLastReviewScore(ctx context.Context, telegramID int64, page
 }
 
 func (s *Service) UpdateMaxPagesPerDay(ctx context.Context, telegramID int64, maxPages uint) error {
+	if err := ValidateMaxPagesPerDay(maxPages); err != nil {
+		return err
+	}
+
 	if err := s.repo.UpdateMaxPagesPerDay(ctx, telegramID, maxPages); err != nil {
 		return fmt.Errorf("update max pages per day (telegram_id: %d, max_pages: %d): %w", telegramID, maxPages, err)
 	}
@@ -647,10 +791,50 @@ func (s *Service) UpdateMaxPagesPerDay(ctx context.Context, telegramID int64, ma
 }
 
 func (s *Service) UpdateUserTimezone(ctx context.Context, telegramID int64, timezone string) error {
+	if err := ValidateTimezone(timezone); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
 	if err := s.repo.UpdateUserTimezone(ctx, telegramID, timezone); err != nil {
 		return fmt.Errorf("update user timezone (telegram_id: %d, timezone: %s): %w", telegramID, timezone, err)
 	}
 
+	// Полночь и время напоминаний зависят от таймзоны, поэтому задачи переназначаются
+	nowUTC := s.clock.Now()
+	midnight := nextLocalMidnight(nowUTC, timezone)
+	s.scheduleJob(ctx, telegramID, models.JobInactivity, midnight)
+
+	// Если в новой таймзоне уже наступил следующий день, его смена дня выполняется сразу, иначе день пропустился бы.
+	// При переезде на запад местная дата не меняется или уходит назад, и смена дня ждёт полуночи по новому времени.
+	rollover := midnight
+	if localDate(nowUTC, timezone).After(localDate(nowUTC, userTimezone(user))) {
+		rollover = nowUTC
+	}
+	s.scheduleJob(ctx, telegramID, models.JobRollover, rollover)
+	s.rescheduleReminders(ctx, telegramID)
+
+	return nil
+}
+
+// GetUserLanguage возвращает язык пользователя, пустая строка — пользователь не зарегистрирован
+func (s *Service) GetUserLanguage(ctx context.Context, telegramID int64) (string, error) {
+	return s.repo.GetUserLanguage(ctx, telegramID)
+}
+
+func (s *Service) UpdateUserLanguage(ctx context.Context, telegramID int64, language string) error {
+	if !i18n.Supported(language) {
+		return fmt.Errorf("%w: %s", i18n.ErrUnsupportedLanguage, language)
+	}
+
+	if err := s.repo.UpdateUserLanguage(ctx, telegramID, language); err != nil {
+		return fmt.Errorf("update user language (telegram_id: %d, language: %s): %w", telegramID, language, err)
+	}
+
 	return nil
 }
 
@@ -670,6 +854,11 @@ func (s *Service) addPagesToLearning(ctx context.Context, telegramID int64) erro
 		return nil
 	}
 
+	if OnVacation(user, s.clock.Now()) {
+		zap.S().Info("user is on vacation, skipping add pages to learning", zap.Int64("telegram_id", telegramID))
+		return nil
+	}
+
 	maxPagesPerDay := uint(2)
 	if user.MaxPagesPerDay != nil {
 		maxPagesPerDay = *user.MaxPagesPerDay
@@ -683,12 +872,12 @@ func (s *Service) addPagesToLearning(ctx context.Context, telegramID int64) erro
 
 	// Calculate end of day in user's timezone, then convert to UTC for database query
 	var endOfDayUTC time.Time
-	startOfDayInTz, err := utils.StartOfTodayInTimezone(timezone)
+	startOfDayInTz, err := utils.StartOfDayInTimezone(s.clock.Now(), timezone)
 	if err != nil {
 		zap.S().Error("get start of day in timezone", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.String("timezone", timezone))
-		endOfDayUTC = utils.StartOfTodayUTC().AddDate(0, 0, 1)
+		endOfDayUTC = utils.AddDays(s.clock.Now(), 1)
 	} else {
-		endOfDayUTC = startOfDayInTz.AddDate(0, 0, 1).UTC()
+		endOfDayUTC = utils.AddDays(startOfDayInTz, 1).UTC()
 	}
 
 	duePagesToday, err := s.repo.GetDuePagesToday(ctx, telegramID, endOfDayUTC)
@@ -700,31 +889,26 @@ func (s *Service) addPagesToLearning(ctx context.Context, telegramID int64) erro
 		return nil
 	}
 
-	pagesToAdd := srs.CalculatePagesToAdd(maxPagesPerDay)
-
-	var onenotePages []onenote.Page
-	err = s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
-		result, err := s.oneNoteClient.GetPages(accessToken, user.OneNoteConfig.SectionID)
-		if err != nil {
-			return fmt.Errorf("get pages (telegram_id: %d, section_id: %s): %w", telegramID, user.OneNoteConfig.SectionID, err)
-		}
-
-		onenotePages = result
+	pagesToAdd := srs.CalculatePagesToAdd(maxPagesPerDay, s.rng)
 
-		return nil
-	})
+	onenotePages, err := s.getSourcePages(ctx, telegramID)
 	if err != nil {
 		return fmt.Errorf("get onenote pages (telegram_id: %d): %w", telegramID, err)
 	}
 
-	availablePages := make([]onenote.Page, 0, len(onenotePages))
+	availablePages := make([]sourcePage, 0, len(onenotePages))
 	for _, page := range onenotePages {
 		if !strings.Contains(page.Title, "*") && hasPageNumber(page.Title) {
 			availablePages = append(availablePages, page)
 		}
 	}
 
-	slices.SortFunc(availablePages, func(a, b onenote.Page) int {
+	// Новые страницы берутся сначала из источников с более высоким приоритетом, внутри источника — по номеру страницы
+	slices.SortFunc(availablePages, func(a, b sourcePage) int {
+		if c := cmp.Compare(a.Source.Priority, b.Source.Priority); c != 0 {
+			return c
+		}
+
 		numA := extractPageNumber(a.Title)
 		numB := extractPageNumber(b.Title)
 		return cmp.Compare(numA, numB)
@@ -744,15 +928,16 @@ func (s *Service) addPagesToLearning(ctx context.Context, telegramID int64) erro
 		notInProgress = notInProgress[:pagesToAdd]
 	}
 
+	nowUTC := s.clock.Now()
 	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
 		for _, pageID := range notInProgress {
-			nextReview, interval := srs.GetInitialReviewDate(timezone)
+			nextReview, interval := srs.GetInitialReviewDate(nowUTC, timezone)
 			progress := &models.UserProgress{
 				UserID:          telegramID,
 				PageID:          pageID,
 				Level:           user.Level,
 				RepetitionCount: 0,
-				LastReviewDate:  utils.NowUTC().AddDate(0, 0, -1),
+				LastReviewDate:  nowUTC.AddDate(0, 0, -1),
 				NextReviewDate:  nextReview,
 				IntervalDays:    interval,
 				SuccessRate:     0,
@@ -786,7 +971,7 @@ func (s *Service) PrepareMaterials(ctx context.Context, telegramID int64) error
 		return fmt.Errorf("onenote not configured (telegram_id: %d)", telegramID)
 	}
 
-	err = s.syncPagesInternal(ctx, telegramID)
+	_, err = s.syncPagesInternal(ctx, telegramID)
 	if err != nil {
 		if _, ok := err.(*AuthRequiredError); ok {
 			return err
@@ -801,165 +986,124 @@ func (s *Service) PrepareMaterials(ctx context.Context, telegramID int64) error
 	return nil
 }
 
-func (s *Service) SkipPage(ctx context.Context, userID int64, pageID string) error {
-	if err := s.repo.DeleteProgress(ctx, userID, pageID); err != nil {
-		return fmt.Errorf("delete progress (telegram_id: %d, page_id: %s): %w", userID, pageID, err)
-	}
-	return nil
-}
-
-func (s *Service) RunDailyCron(ctx context.Context) error {
-	zap.S().Info("running daily cron")
+var (
+	// ErrPageNotFound — в подключённых секциях нет страницы, подходящей под запрос пользователя
+	ErrPageNotFound = errors.New("page not found")
+	// ErrPageAlreadyInProgress — найденная страница уже изучается
+	ErrPageAlreadyInProgress = errors.New("page already in progress")
+)
 
-	users, err := s.repo.GetAllUsersWithReminders(ctx)
+// AddPageManually добавляет в изучение страницу, выбранную пользователем по номеру или части заголовка,
+// независимо от дневного лимита новых страниц
+func (s *Service) AddPageManually(ctx context.Context, telegramID int64, query string) (*models.PageReference, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
 	if err != nil {
-		return fmt.Errorf("get all users: %w", err)
+		return nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
 	}
 
-	for _, user := range users {
-		if user.OneNoteConfig == nil {
-			continue
-		}
-
-		// Проверяем, приостановлен ли пользователь
-		if user.IsPaused != nil && *user.IsPaused {
-			zap.S().Info("skipping paused user in daily cron", zap.Int64("telegram_id", user.TelegramID))
-			continue
-		}
-
-		// Определяем таймзону пользователя
-		timezone := "UTC"
-		if user.Timezone != nil && *user.Timezone != "" {
-			timezone = *user.Timezone
-		}
+	if user.OneNoteConfig == nil {
+		return nil, fmt.Errorf("onenote not configured (telegram_id: %d)", telegramID)
+	}
 
-		// Вычисляем начало сегодняшнего дня в таймзоне пользователя и конвертируем в UTC
-		startOfTodayInTz, err := utils.StartOfTodayInTimezone(timezone)
-		if err != nil {
-			zap.S().Warn("failed to get start of today in timezone", zap.Error(err), zap.Int64("telegram_id", user.TelegramID), zap.String("timezone", timezone))
-			continue
-		}
-		startOfTodayUTC := startOfTodayInTz.UTC()
+	pages, err := s.getSourcePages(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get onenote pages (telegram_id: %d): %w", telegramID, err)
+	}
 
-		processed, err := s.repo.TryProcessDailyCronForUser(ctx, user.TelegramID, startOfTodayUTC)
-		if err != nil {
-			zap.S().Error("try process daily cron for user", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
-			continue
-		}
+	query = strings.TrimSpace(query)
+	number, numErr := strconv.Atoi(query)
 
-		if !processed {
+	var found *sourcePage
+	for i, page := range pages {
+		if strings.Contains(page.Title, "*") || !hasPageNumber(page.Title) {
 			continue
 		}
 
-		err = s.syncPagesInternal(ctx, user.TelegramID)
-		if err != nil {
-			if _, ok := err.(*AuthRequiredError); ok {
-				zap.S().Warn("auth required for daily cron", zap.Int64("telegram_id", user.TelegramID))
-				continue
+		// Номер страницы совпадает точно, иначе ищем по вхождению в заголовок
+		if numErr == nil {
+			if extractPageNumber(page.Title) == number {
+				found = &pages[i]
+				break
 			}
-			zap.S().Warn("failed to sync pages in daily cron", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
-		}
-
-		if err := s.repo.ResetReviewedTodayFlag(ctx, user.TelegramID); err != nil {
-			zap.S().Error("reset reviewed today flag in daily cron", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
+			continue
 		}
 
-		if err := s.addPagesToLearning(ctx, user.TelegramID); err != nil {
-			zap.S().Error("add pages to learning in daily cron", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
-			continue
+		if strings.Contains(strings.ToLower(page.Title), strings.ToLower(query)) {
+			found = &pages[i]
+			break
 		}
 	}
 
-	// Проверяем пользователей без активности неделю и приостанавливаем их
-	if err := s.checkAndPauseInactiveUsers(ctx); err != nil {
-		zap.S().Error("check and pause inactive users", zap.Error(err))
+	if found == nil {
+		return nil, fmt.Errorf("find page (telegram_id: %d, query: %s): %w", telegramID, query, ErrPageNotFound)
 	}
 
-	// Проверяем пользователей без активности месяц и сбрасываем интервалы
-	if err := s.checkAndResetIntervals(ctx); err != nil {
-		zap.S().Error("check and reset intervals", zap.Error(err))
+	notInProgress, err := s.repo.GetPageIDsNotInProgress(ctx, telegramID, []string{found.ID})
+	if err != nil {
+		return nil, fmt.Errorf("get page IDs not in progress (telegram_id: %d): %w", telegramID, err)
 	}
 
-	return nil
-}
-
-func (s *Service) checkAndPauseInactiveUsers(ctx context.Context) error {
-	weekAgo := utils.NowUTC().AddDate(0, 0, -7)
-	users, err := s.repo.GetUsersWithoutActivityAfter(ctx, weekAgo, true)
-	if err != nil {
-		return fmt.Errorf("get users without activity for week: %w", err)
+	if len(notInProgress) == 0 {
+		return nil, fmt.Errorf("add page (telegram_id: %d, page_id: %s): %w", telegramID, found.ID, ErrPageAlreadyInProgress)
 	}
 
-	for _, user := range users {
-		if user.OneNoteConfig == nil {
-			continue
-		}
+	timezone := "UTC"
+	if user.Timezone != nil && *user.Timezone != "" {
+		timezone = *user.Timezone
+	}
 
-		maxPagesPerDay := uint(2)
-		if user.MaxPagesPerDay != nil {
-			maxPagesPerDay = *user.MaxPagesPerDay
-		}
+	pageRef := &models.PageReference{
+		PageID:    found.ID,
+		UserID:    telegramID,
+		Title:     found.Title,
+		Source:    "onenote",
+		SectionID: found.Source.SectionID,
+		CreatedAt: s.clock.Now(),
+	}
 
-		timezone := "UTC"
-		if user.Timezone != nil && *user.Timezone != "" {
-			timezone = *user.Timezone
-		}
+	nextReview, interval := srs.GetInitialReviewDate(s.clock.Now(), timezone)
+	progress := &models.UserProgress{
+		UserID:          telegramID,
+		PageID:          found.ID,
+		Level:           user.Level,
+		RepetitionCount: 0,
+		LastReviewDate:  s.clock.Now().AddDate(0, 0, -1),
+		NextReviewDate:  nextReview,
+		IntervalDays:    interval,
+	}
 
-		// Calculate end of day in user's timezone, then convert to UTC for database query
-		var endOfDayUTC time.Time
-		startOfDayInTz, err := utils.StartOfTodayInTimezone(timezone)
-		if err != nil {
-			zap.S().Error("get start of day in timezone", zap.Error(err), zap.Int64("telegram_id", user.TelegramID), zap.String("timezone", timezone))
-			endOfDayUTC = utils.StartOfTodayUTC().AddDate(0, 0, 1)
-		} else {
-			endOfDayUTC = startOfDayInTz.AddDate(0, 0, 1).UTC()
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if err := txRepo.UpsertPageReference(ctx, pageRef); err != nil {
+			return fmt.Errorf("upsert page reference: %w", err)
 		}
 
-		duePagesToday, err := s.repo.GetDuePagesToday(ctx, user.TelegramID, endOfDayUTC)
-		if err != nil {
-			zap.S().Error("get due pages today", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
-			continue
+		if err := txRepo.CreateProgress(ctx, progress); err != nil {
+			return fmt.Errorf("create progress: %w", err)
 		}
 
-		// Приостанавливаем пользователя только если он неактивен неделю И количество страниц в today достигло максимума
-		if len(duePagesToday) >= int(maxPagesPerDay) {
-			if err := s.repo.SetUserPaused(ctx, user.TelegramID, true); err != nil {
-				zap.S().Error("set user paused", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
-				continue
-			}
-			zap.S().Info("user paused due to inactivity and max pages in today reached", zap.Int64("telegram_id", user.TelegramID), zap.Int("due_pages_today", len(duePagesToday)), zap.Uint("max_pages_per_day", maxPagesPerDay))
-		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("add page manually (telegram_id: %d, page_id: %s): %w", telegramID, found.ID, err)
 	}
 
-	return nil
+	return pageRef, nil
 }
 
-func (s *Service) checkAndResetIntervals(ctx context.Context) error {
-	monthAgo := utils.NowUTC().AddDate(0, 0, -30)
-	users, err := s.repo.GetUsersWithoutActivityAfter(ctx, monthAgo, false)
+func (s *Service) SkipPage(ctx context.Context, userID int64, pageID string) error {
+	user, err := s.repo.GetUser(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("get users without activity for month: %w", err)
+		return fmt.Errorf("get user (telegram_id: %d): %w", userID, err)
 	}
 
-	today := utils.StartOfTodayUTC()
-	tomorrowUTC := today.AddDate(0, 0, 1)
-	monthFromNowUTC := today.AddDate(0, 0, 30)
-
-	for _, user := range users {
-		if err := s.repo.ResetIntervalForPagesDueInMonth(ctx, user.TelegramID, tomorrowUTC, monthFromNowUTC); err != nil {
-			zap.S().Error("reset interval for pages due in month", zap.Error(err), zap.Int64("telegram_id", user.TelegramID))
-			continue
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if err := txRepo.DeleteProgress(ctx, userID, pageID); err != nil {
+			return err
 		}
-		zap.S().Info("intervals reset for inactive user", zap.Int64("telegram_id", user.TelegramID))
-	}
-
-	return nil
-}
-
-func (s *Service) resumeUserOnActivity(ctx context.Context, userID int64) error {
-	if err := s.repo.SetUserPaused(ctx, userID, false); err != nil {
-		return fmt.Errorf("resume user on activity (telegram_id: %d): %w", userID, err)
+		return s.recordReviewResult(ctx, txRepo, user, pageID, models.ReviewResultSkipped, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("skip page (telegram_id: %d, page_id: %s): %w", userID, pageID, err)
 	}
-	zap.S().Info("user resumed due to activity", zap.Int64("telegram_id", userID))
 	return nil
 }
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// StartReviewSession возвращает сессию повторения на сегодня вместе с очередью страниц на сегодня.
+// Первый вызов за день создаёт сессию из очереди, следующие продолжают её: новые страницы дописываются в конец,
+// а непройденные страницы, которых больше нет в очереди, помечаются пропущенными. nil — повторять нечего.
+func (s *Service) StartReviewSession(ctx context.Context, telegramID int64) (*models.ReviewSession, []*models.PageWithProgress, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	duePages, err := s.GetDuePagesToday(ctx, telegramID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := s.clock.Now()
+	today := localDate(now, userTimezone(user))
+
+	session, err := s.repo.GetReviewSession(ctx, telegramID, today)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if session == nil && len(duePages) == 0 {
+		return nil, duePages, nil
+	}
+
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if session == nil {
+			if err := txRepo.FinishReviewSessionsBefore(ctx, telegramID, today, now); err != nil {
+				return err
+			}
+
+			session = &models.ReviewSession{
+				UserID:    telegramID,
+				LocalDate: today,
+				Status:    models.ReviewSessionActive,
+				CreatedAt: now,
+			}
+			if err := txRepo.CreateReviewSession(ctx, session); err != nil {
+				return err
+			}
+		}
+
+		due := make(map[string]bool, len(duePages))
+		for _, pwp := range duePages {
+			due[pwp.Page.PageID] = true
+		}
+
+		pending := 0
+		for _, item := range session.Items {
+			if item.Result != nil {
+				continue
+			}
+			if due[item.PageID] {
+				pending++
+				continue
+			}
+			// Страница ушла из очереди: её удалили из источника или исключили из изучения
+			if _, err := txRepo.SetReviewSessionItemResult(ctx, session.ID, item.PageID, models.ReviewResultSkipped, nil, now); err != nil {
+				return err
+			}
+		}
+
+		var added []string
+		for _, pwp := range duePages {
+			if session.Item(pwp.Page.PageID) == nil {
+				added = append(added, pwp.Page.PageID)
+			}
+		}
+		if err := txRepo.AddReviewSessionItems(ctx, session.ID, len(session.Items), added); err != nil {
+			return err
+		}
+		pending += len(added)
+
+		switch {
+		case pending == 0 && session.Status == models.ReviewSessionActive:
+			return txRepo.UpdateReviewSessionStatus(ctx, session.ID, models.ReviewSessionFinished, &now)
+		case pending > 0 && session.Status == models.ReviewSessionFinished:
+			return txRepo.UpdateReviewSessionStatus(ctx, session.ID, models.ReviewSessionActive, nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("start review session (telegram_id: %d): %w", telegramID, err)
+	}
+
+	session, err = s.repo.GetReviewSession(ctx, telegramID, today)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return session, duePages, nil
+}
+
+// GetReviewSession возвращает сессию повторения на сегодня без её обновления, nil — сессия сегодня не начиналась
+func (s *Service) GetReviewSession(ctx context.Context, telegramID int64) (*models.ReviewSession, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	return s.repo.GetReviewSession(ctx, telegramID, localDate(s.clock.Now(), userTimezone(user)))
+}
+
+// recordReviewResult отмечает страницу пройденной в сегодняшней сессии и завершает сессию после последней страницы.
+// Страницы вне сессии и уже пройденные страницы не меняются. Вызывается внутри транзакции оценки или пропуска.
+func (s *Service) recordReviewResult(ctx context.Context, txRepo models.Repository, user *models.User, pageID, result string, grade *int) error {
+	now := s.clock.Now()
+	session, err := txRepo.GetReviewSession(ctx, user.TelegramID, localDate(now, userTimezone(user)))
+	if err != nil {
+		return err
+	}
+
+	if session == nil {
+		return nil
+	}
+
+	item := session.Item(pageID)
+	if item == nil || item.Result != nil {
+		return nil
+	}
+
+	updated, err := txRepo.SetReviewSessionItemResult(ctx, session.ID, pageID, result, grade, now)
+	if err != nil || !updated {
+		return err
+	}
+
+	if session.Done()+1 == len(session.Items) {
+		return txRepo.UpdateReviewSessionStatus(ctx, session.ID, models.ReviewSessionFinished, &now)
+	}
+	return nil
+}
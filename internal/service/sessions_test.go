@@ -0,0 +1,223 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/onenote"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+// sessionRepo — reviewRepo с очередью страниц на сегодня и созданием сессии повторения.
+// Сессия одна: сессия другой местной даты для GetReviewSession не существует.
+type sessionRepo struct {
+	*reviewRepo
+}
+
+func newSessionRepo(pages ...*models.UserProgress) *sessionRepo {
+	repo := &sessionRepo{reviewRepo: newReviewRepo(pages...)}
+	token := "token"
+	expiresAt := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.user.AccessToken, repo.user.RefreshToken, repo.user.ExpiresAt = &token, &token, &expiresAt
+	repo.user.OneNoteConfig = &models.OneNoteConfig{NotebookID: "notebook", SectionID: "sec-a"}
+	return repo
+}
+
+// RunInTx передаёт в функцию sessionRepo, а откат состояния берёт у reviewRepo
+func (r *sessionRepo) RunInTx(ctx context.Context, fn func(models.Repository) error) error {
+	return r.reviewRepo.RunInTx(ctx, func(models.Repository) error {
+		return fn(r)
+	})
+}
+
+func (r *sessionRepo) GetUserSources(context.Context, int64, bool) ([]*models.UserSource, error) {
+	return []*models.UserSource{{ID: 1, SectionID: "sec-a", Enabled: true}}, nil
+}
+
+func (r *sessionRepo) GetDuePagesToday(_ context.Context, _ int64, endOfDayUTC time.Time) ([]*models.UserProgress, error) {
+	var due []*models.UserProgress
+	for _, progress := range r.progress {
+		if progress.NextReviewDate.Before(endOfDayUTC) && !progress.ReviewedToday {
+			copied := *progress
+			due = append(due, &copied)
+		}
+	}
+	slices.SortFunc(due, func(a, b *models.UserProgress) int {
+		return cmp.Compare(a.PageID, b.PageID)
+	})
+	return due, nil
+}
+
+func (r *sessionRepo) DeleteProgress(_ context.Context, _ int64, pageID string) error {
+	delete(r.progress, pageID)
+	return nil
+}
+
+func (r *sessionRepo) GetReviewSession(_ context.Context, _ int64, localDate time.Time) (*models.ReviewSession, error) {
+	if r.session == nil || !r.session.LocalDate.Equal(localDate) {
+		return nil, nil
+	}
+	return copySession(r.session), nil
+}
+
+func (r *sessionRepo) FinishReviewSessionsBefore(_ context.Context, _ int64, localDate, finishedAt time.Time) error {
+	if r.session != nil && r.session.LocalDate.Before(localDate) && r.session.Status == models.ReviewSessionActive {
+		r.session.Status, r.session.FinishedAt = models.ReviewSessionFinished, &finishedAt
+	}
+	return nil
+}
+
+func (r *sessionRepo) CreateReviewSession(_ context.Context, session *models.ReviewSession) error {
+	session.ID = 1
+	if r.session != nil {
+		session.ID = r.session.ID + 1
+	}
+	r.session = copySession(session)
+	return nil
+}
+
+func (r *sessionRepo) AddReviewSessionItems(_ context.Context, sessionID int64, position int, pageIDs []string) error {
+	for i, pageID := range pageIDs {
+		r.session.Items = append(r.session.Items, &models.ReviewSessionItem{SessionID: sessionID, Position: position + i, PageID: pageID})
+	}
+	return nil
+}
+
+// sessionState записывает сессию как статус и страницы с результатами, «-» — страница не пройдена
+func sessionState(session *models.ReviewSession) string {
+	if session == nil {
+		return "no session"
+	}
+
+	items := make([]string, 0, len(session.Items))
+	for _, item := range session.Items {
+		result := "-"
+		if item.Result != nil {
+			result = *item.Result
+			if item.Grade != nil {
+				result += fmt.Sprint(*item.Grade)
+			}
+		}
+		items = append(items, item.PageID+":"+result)
+	}
+	return fmt.Sprintf("%s %v", session.Status, items)
+}
+
+// TestReviewSession проходит сегодняшнюю сессию целиком: очередь, продолжение с того же места,
+// пропуск, новые и ушедшие из очереди страницы, завершение и следующий день
+func TestReviewSession(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	due := func(pageID string) *models.UserProgress {
+		return &models.UserProgress{UserID: 1, PageID: pageID, IntervalDays: 3, NextReviewDate: now.Add(-time.Hour), Version: 1}
+	}
+	// Очередь упорядочена по номерам в заголовках, а не по ID страниц
+	titles := map[string]string{"page-a": "3 Travel", "page-b": "1 Food", "page-c": "2 Work", "page-d": "4 Sport"}
+	pages := func(pageIDs ...string) []onenote.Page {
+		var result []onenote.Page
+		for _, pageID := range pageIDs {
+			result = append(result, onenote.Page{ID: pageID, Title: titles[pageID]})
+		}
+		return result
+	}
+
+	repo := newSessionRepo(due("page-a"), due("page-b"), due("page-c"))
+	client := &syncOneNote{sections: map[string][]onenote.Page{"sec-a": pages("page-a", "page-b", "page-c", "page-d")}}
+	clock := clocktest.NewManualClock(now)
+	svc := NewService(repo, nil, client, clock, clocktest.FixedRand(0.5))
+	ctx := context.Background()
+
+	start := func(t *testing.T, want string, wantNext string) *models.ReviewSession {
+		t.Helper()
+		session, _, err := svc.StartReviewSession(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sessionState(session); got != want {
+			t.Errorf("session = %s, want %s", got, want)
+		}
+		next := ""
+		if session != nil && session.Next() != nil {
+			next = session.Next().PageID
+		}
+		if next != wantNext {
+			t.Errorf("next page = %q, want %q", next, wantNext)
+		}
+		return session
+	}
+	grade := func(t *testing.T, pageID string, score int) {
+		t.Helper()
+		submission := models.ReviewSubmission{PageID: pageID, Grade: score, Version: repo.progress[pageID].Version, Key: "t_" + pageID, Source: models.ReviewSourceButton}
+		if err := svc.UpdateReviewProgress(ctx, 1, submission); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("start", func(t *testing.T) {
+		start(t, "active [page-b:- page-c:- page-a:-]", "page-b")
+	})
+
+	t.Run("resume where the user left off", func(t *testing.T) {
+		grade(t, "page-b", 90)
+		clock.Advance(time.Hour)
+		session := start(t, "active [page-b:graded90 page-c:- page-a:-]", "page-c")
+		if session.ID != 1 || session.Done() != 1 {
+			t.Errorf("session %d with %d done, want the same session 1 with 1 done", session.ID, session.Done())
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		if err := svc.SkipPage(ctx, 1, "page-c"); err != nil {
+			t.Fatal(err)
+		}
+		start(t, "active [page-b:graded90 page-c:skipped page-a:-]", "page-a")
+	})
+
+	t.Run("queue changes", func(t *testing.T) {
+		// Новая страница дописывается в конец, а страница, ушедшая из очереди, считается пропущенной
+		repo.progress["page-d"] = due("page-d")
+		delete(repo.progress, "page-a")
+		start(t, "active [page-b:graded90 page-c:skipped page-a:skipped page-d:-]", "page-d")
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		grade(t, "page-d", 50)
+		if repo.session.Status != models.ReviewSessionFinished || repo.session.FinishedAt == nil || !repo.session.FinishedAt.Equal(clock.Now()) {
+			t.Errorf("session status %s finished at %v, want finished now", repo.session.Status, repo.session.FinishedAt)
+		}
+		// Повторный запуск в тот же день показывает завершённую сессию, а не начинает новую
+		start(t, "finished [page-b:graded90 page-c:skipped page-a:skipped page-d:graded50]", "")
+	})
+
+	t.Run("next day", func(t *testing.T) {
+		clock.Set(time.Date(2026, 7, 2, 8, 0, 0, 0, time.UTC))
+		for _, progress := range repo.progress {
+			progress.ReviewedToday = false
+			progress.NextReviewDate = clock.Now().Add(-time.Hour)
+		}
+		session := start(t, "active [page-b:- page-d:-]", "page-b")
+		if session.ID == 1 {
+			t.Error("yesterday's session continued on the next day")
+		}
+	})
+}
+
+// TestStartReviewSessionWithoutDuePages проверяет, что без страниц на сегодня сессия не создаётся
+func TestStartReviewSessionWithoutDuePages(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	repo := newSessionRepo(&models.UserProgress{UserID: 1, PageID: "page-a", NextReviewDate: now.AddDate(0, 0, 3)})
+	client := &syncOneNote{sections: map[string][]onenote.Page{"sec-a": {{ID: "page-a", Title: "1 Travel"}}}}
+	svc := NewService(repo, nil, client, clocktest.NewManualClock(now), clocktest.FixedRand(0.5))
+
+	session, duePages, err := svc.StartReviewSession(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session != nil || len(duePages) != 0 || repo.session != nil {
+		t.Errorf("session = %s with %d due pages, want no session", sessionState(session), len(duePages))
+	}
+}
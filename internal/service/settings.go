@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrInvalidSetting — значение настройки пользователя не прошло проверку
+var ErrInvalidSetting = errors.New("invalid setting")
+
+// Levels — уровни владения языком, которые можно выбрать
+var Levels = []string{"A1", "A2", "B1", "B2", "C1"}
+
+const (
+	// MinMaxPagesPerDay и MaxMaxPagesPerDay ограничивают лимит страниц на повторение в день
+	MinMaxPagesPerDay = 2
+	MaxMaxPagesPerDay = 4
+)
+
+// reminderTimeLayout — формат времени напоминания, "HH:MM"
+const reminderTimeLayout = "15:04"
+
+func ValidateLevel(level string) error {
+	if !slices.Contains(Levels, level) {
+		return fmt.Errorf("%w: level %q", ErrInvalidSetting, level)
+	}
+	return nil
+}
+
+func ValidateMaxPagesPerDay(maxPages uint) error {
+	if maxPages < MinMaxPagesPerDay || maxPages > MaxMaxPagesPerDay {
+		return fmt.Errorf("%w: max pages per day %d", ErrInvalidSetting, maxPages)
+	}
+	return nil
+}
+
+func ValidateTimezone(timezone string) error {
+	if timezone == "" {
+		return fmt.Errorf("%w: empty timezone", ErrInvalidSetting)
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("%w: timezone %q: %w", ErrInvalidSetting, timezone, err)
+	}
+	return nil
+}
+
+// NormalizeReminderTime проверяет время в формате "HH:MM" и приводит его к этому виду: "9:00" становится "09:00"
+func NormalizeReminderTime(value string) (string, error) {
+	parsed, err := time.Parse(reminderTimeLayout, value)
+	if err != nil {
+		return "", fmt.Errorf("%w: reminder time %q", ErrInvalidSetting, value)
+	}
+	return parsed.Format(reminderTimeLayout), nil
+}
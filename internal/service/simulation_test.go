@@ -0,0 +1,542 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+const (
+	simUserID = 1
+	// simTick — шаг симуляции: все времена напоминаний, полночи и переходы часов в сценариях кратны ему
+	simTick = 15 * time.Minute
+	// simReviewHour — в этот час по местному времени пользователь каждый день повторяет все страницы
+	simReviewHour = 10
+)
+
+// simRepo — хранилище в памяти для симуляции. Реализованы только методы, которые вызывают смена дня,
+// напоминания, проверка активности и оценка страниц; вызов любого другого метода паникует.
+type simRepo struct {
+	models.Repository
+
+	user      models.User
+	times     []string
+	progress  map[string]*models.UserProgress
+	jobs      map[string]*models.Job
+	nextJobID int64
+	reminders map[string]*simReminder
+	keys      map[string]bool
+	// rollovers — местные даты и таймзоны, в которых выполнилась смена дня
+	rollovers []simDay
+}
+
+// simDay — местная дата в таймзоне
+type simDay struct {
+	timezone string
+	date     time.Time
+}
+
+func (d simDay) String() string {
+	return d.date.Format(time.DateOnly) + " " + d.timezone
+}
+
+func (r *simRepo) GetUser(_ context.Context, _ int64) (*models.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *simRepo) UpdateUserTimezone(_ context.Context, _ int64, timezone string) error {
+	r.user.Timezone = &timezone
+	return nil
+}
+
+func (r *simRepo) RunInTx(_ context.Context, fn func(models.Repository) error) error {
+	return fn(r)
+}
+
+func (r *simRepo) UpdateUserActivity(_ context.Context, _ int64, activityDate time.Time) error {
+	r.user.LastActivityDate = &activityDate
+	return nil
+}
+
+func (r *simRepo) SetUserPaused(_ context.Context, _ int64, paused bool) error {
+	r.user.IsPaused = &paused
+	return nil
+}
+
+func (r *simRepo) UpdateLastCronProcessedAt(_ context.Context, _ int64, processedAt time.Time) error {
+	r.user.LastCronProcessedAt = &processedAt
+	timezone := userTimezone(&r.user)
+	r.rollovers = append(r.rollovers, simDay{timezone: timezone, date: localDate(processedAt, timezone)})
+	return nil
+}
+
+func (r *simRepo) GetUserSources(context.Context, int64, bool) ([]*models.UserSource, error) {
+	return nil, nil
+}
+
+func (r *simRepo) GetPageIDsNotInProgress(context.Context, int64, []string) ([]string, error) {
+	return nil, nil
+}
+
+func (r *simRepo) ResetReviewedTodayFlag(context.Context, int64) error {
+	for _, progress := range r.progress {
+		progress.ReviewedToday = false
+	}
+	return nil
+}
+
+func (r *simRepo) GetDuePagesToday(_ context.Context, _ int64, endOfDayUTC time.Time) ([]*models.UserProgress, error) {
+	var due []*models.UserProgress
+	for _, progress := range r.progress {
+		if progress.NextReviewDate.Before(endOfDayUTC) && !progress.ReviewedToday {
+			page := *progress
+			due = append(due, &page)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b *models.UserProgress) int {
+		if c := a.NextReviewDate.Compare(b.NextReviewDate); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.PageID, b.PageID)
+	})
+	return due, nil
+}
+
+func (r *simRepo) GetProgress(_ context.Context, _ int64, pageID string) (*models.UserProgress, error) {
+	progress := *r.progress[pageID]
+	return &progress, nil
+}
+
+func (r *simRepo) UpdateProgress(_ context.Context, _ int64, pageID string, version int, level string, repetitionCount int, lastReviewDate, nextReviewDate time.Time, intervalDays int, reviewedToday bool, passed bool) (bool, error) {
+	progress := r.progress[pageID]
+	if progress.Version != version {
+		return false, nil
+	}
+
+	progress.Level = level
+	progress.RepetitionCount = repetitionCount
+	progress.LastReviewDate = lastReviewDate
+	progress.NextReviewDate = nextReviewDate
+	progress.IntervalDays = intervalDays
+	progress.ReviewedToday = reviewedToday
+	progress.Passed = passed
+	progress.Version++
+	return true, nil
+}
+
+func (r *simRepo) AddProgressHistory(_ context.Context, _ int64, _ string, history models.ProgressHistory) error {
+	r.keys[history.ReviewKey] = true
+	return nil
+}
+
+func (r *simRepo) ReviewKeyExists(_ context.Context, _ int64, reviewKey string) (bool, error) {
+	return r.keys[reviewKey], nil
+}
+
+func (r *simRepo) GetReviewSession(context.Context, int64, time.Time) (*models.ReviewSession, error) {
+	return nil, nil
+}
+
+func (r *simRepo) GetReminderSchedules(_ context.Context, userID int64) ([]*models.ReminderSchedule, error) {
+	if !r.user.RemindersEnabled {
+		return nil, nil
+	}
+
+	schedules := make([]*models.ReminderSchedule, 0, len(r.times))
+	for _, reminderTime := range r.times {
+		schedules = append(schedules, &models.ReminderSchedule{
+			UserID:          userID,
+			ReminderTime:    reminderTime,
+			Language:        r.user.Language,
+			Timezone:        r.user.Timezone,
+			QuietHoursStart: r.user.QuietHoursStart,
+			QuietHoursEnd:   r.user.QuietHoursEnd,
+			VacationStart:   r.user.VacationStart,
+			VacationEnd:     r.user.VacationEnd,
+		})
+	}
+	return schedules, nil
+}
+
+// simReminder — строка reminder_log
+type simReminder struct {
+	sent         bool
+	claimedUntil time.Time
+}
+
+func (r *simRepo) ClaimReminder(_ context.Context, _ int64, localDate time.Time, reminderTime string, now, claimedUntil time.Time) (bool, error) {
+	key := localDate.Format(time.DateOnly) + " " + reminderTime
+	if reminder, ok := r.reminders[key]; ok && (reminder.sent || reminder.claimedUntil.After(now)) {
+		return false, nil
+	}
+	r.reminders[key] = &simReminder{claimedUntil: claimedUntil}
+	return true, nil
+}
+
+func (r *simRepo) MarkReminderSent(_ context.Context, _ int64, localDate time.Time, reminderTime string) error {
+	r.reminders[localDate.Format(time.DateOnly)+" "+reminderTime].sent = true
+	return nil
+}
+
+func (r *simRepo) DeleteReminderLog(_ context.Context, _ int64, localDate time.Time, reminderTime string) error {
+	delete(r.reminders, localDate.Format(time.DateOnly)+" "+reminderTime)
+	return nil
+}
+
+func (r *simRepo) ScheduleJob(_ context.Context, userID int64, jobType string, nextRunAt time.Time) error {
+	job, ok := r.jobs[jobType]
+	if !ok {
+		r.nextJobID++
+		job = &models.Job{ID: r.nextJobID, UserID: userID, JobType: jobType}
+		r.jobs[jobType] = job
+	}
+
+	job.NextRunAt = nextRunAt
+	job.Attempts = 0
+	job.LastError = nil
+	return nil
+}
+
+func (r *simRepo) ClaimJobs(_ context.Context, now, lockedUntil time.Time, limit int) ([]*models.Job, error) {
+	var claimed []*models.Job
+	for _, jobType := range models.JobTypes {
+		job, ok := r.jobs[jobType]
+		if !ok || job.NextRunAt.After(now) || (job.LockedUntil != nil && job.LockedUntil.After(now)) || len(claimed) == limit {
+			continue
+		}
+
+		until := lockedUntil
+		job.LockedUntil = &until
+		copied := *job
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *simRepo) FinishJob(_ context.Context, job *models.Job) (bool, error) {
+	stored := r.jobs[job.JobType]
+	if stored.LockedUntil == nil || job.LockedUntil == nil || !stored.LockedUntil.Equal(*job.LockedUntil) {
+		return false, nil
+	}
+
+	stored.NextRunAt = job.NextRunAt
+	stored.Attempts = job.Attempts
+	stored.LastError = job.LastError
+	stored.LockedUntil = nil
+	return true, nil
+}
+
+func (r *simRepo) AddJobRun(context.Context, *models.JobRun) error {
+	return nil
+}
+
+// simDelivery — напоминание, отправленное симуляцией
+type simDelivery struct {
+	reminder *models.DueReminder
+	// local — местное время отправки в таймзоне пользователя на момент отправки
+	local time.Time
+}
+
+// simSwitch — смена таймзоны пользователем в момент at
+type simSwitch struct {
+	at       time.Time
+	timezone string
+}
+
+// simulation прогоняет сервис по ManualClock с шагом simTick: выполняет фоновые задачи так же, как обработчик,
+// и каждый день в simReviewHour повторяет все страницы, проверяя расписание по ходу
+type simulation struct {
+	t     *testing.T
+	ctx   context.Context
+	clock *clocktest.ManualClock
+	repo  *simRepo
+	svc   *Service
+
+	deliveries []simDelivery
+	// days — все местные даты, которые прошла симуляция, по порядку
+	days    []time.Time
+	reviews int
+}
+
+func newSimulation(t *testing.T, start time.Time, timezone string, pages int) *simulation {
+	t.Helper()
+
+	quietStart, quietEnd := "22:00", "08:00"
+	maxPagesPerDay := uint(3)
+	repo := &simRepo{
+		user: models.User{
+			TelegramID:       simUserID,
+			Language:         "en",
+			OneNoteConfig:    &models.OneNoteConfig{},
+			RemindersEnabled: true,
+			QuietHoursStart:  &quietStart,
+			QuietHoursEnd:    &quietEnd,
+			MaxPagesPerDay:   &maxPagesPerDay,
+			LastActivityDate: &start,
+			Timezone:         &timezone,
+		},
+		// 07:00 приходится на тихие часы и переносится на 08:00
+		times:     []string{"07:00", "20:00"},
+		progress:  make(map[string]*models.UserProgress),
+		jobs:      make(map[string]*models.Job),
+		reminders: make(map[string]*simReminder),
+		keys:      make(map[string]bool),
+	}
+
+	firstReview := localMidnight(localDate(start, timezone), timezone).UTC()
+	for i := range pages {
+		pageID := fmt.Sprintf("page-%d", i+1)
+		repo.progress[pageID] = &models.UserProgress{
+			UserID:         simUserID,
+			PageID:         pageID,
+			LastReviewDate: firstReview.AddDate(0, 0, -1),
+			NextReviewDate: firstReview,
+		}
+	}
+
+	for _, jobType := range models.JobTypes {
+		repo.jobs[jobType] = &models.Job{ID: int64(len(repo.jobs) + 1), UserID: simUserID, JobType: jobType, NextRunAt: start}
+	}
+	repo.nextJobID = int64(len(repo.jobs))
+
+	clock := clocktest.NewManualClock(start)
+	return &simulation{
+		t:     t,
+		ctx:   context.Background(),
+		clock: clock,
+		repo:  repo,
+		svc:   NewService(repo, nil, nil, clock, clocktest.FixedRand(0.5)),
+	}
+}
+
+// run двигает время до end, меняя таймзону в моменты switches
+func (sim *simulation) run(end time.Time, switches ...simSwitch) {
+	sim.t.Helper()
+
+	for now := sim.clock.Now(); now.Before(end); now = sim.clock.Now() {
+		for _, sw := range switches {
+			if sw.at.Equal(now) {
+				if err := sim.svc.UpdateUserTimezone(sim.ctx, simUserID, sw.timezone); err != nil {
+					sim.t.Fatalf("switch timezone to %s: %v", sw.timezone, err)
+				}
+			}
+		}
+
+		today := localDate(now, sim.timezone())
+		if len(sim.days) == 0 || !sim.days[len(sim.days)-1].Equal(today) {
+			sim.days = append(sim.days, today)
+		}
+
+		sim.runJobs(now)
+
+		if local := now.In(sim.location()); local.Hour() == simReviewHour && local.Minute() == 0 {
+			sim.reviewDuePages(now)
+		}
+
+		sim.clock.Advance(simTick)
+	}
+}
+
+// runJobs выполняет наступившие фоновые задачи так же, как обработчик
+func (sim *simulation) runJobs(now time.Time) {
+	sim.t.Helper()
+
+	jobs, err := sim.svc.ClaimJobs(sim.ctx, now)
+	if err != nil {
+		sim.t.Fatalf("claim jobs: %v", err)
+	}
+
+	for _, job := range jobs {
+		var runErr error
+		switch job.JobType {
+		case models.JobRollover:
+			runErr = sim.svc.RunRollover(sim.ctx, job.UserID)
+		case models.JobReminder:
+			var due []*models.DueReminder
+			due, runErr = sim.svc.ClaimDueReminders(sim.ctx, job.UserID, now)
+			for _, reminder := range due {
+				sim.deliveries = append(sim.deliveries, simDelivery{reminder: reminder, local: now.In(sim.location())})
+			}
+			if runErr == nil {
+				runErr = sim.svc.MarkRemindersSent(sim.ctx, due)
+			}
+		case models.JobInactivity:
+			runErr = sim.svc.CheckInactivity(sim.ctx, job.UserID)
+		}
+
+		if runErr != nil {
+			sim.t.Fatalf("run %s job at %v: %v", job.JobType, now, runErr)
+		}
+		if err := sim.svc.CompleteJob(sim.ctx, job, now, nil); err != nil {
+			sim.t.Fatalf("complete %s job at %v: %v", job.JobType, now, err)
+		}
+	}
+}
+
+// reviewDuePages оценивает все страницы на сегодня и проверяет, что ни одна не просрочена, смена дня
+// сегодня уже прошла, а следующее повторение назначено на местную полночь через интервал страницы
+func (sim *simulation) reviewDuePages(now time.Time) {
+	sim.t.Helper()
+
+	timezone := sim.timezone()
+	today := localDate(now, timezone)
+	if !sim.rolledOver(today) {
+		sim.t.Errorf("review on %s before the rollover of that day", today.Format(time.DateOnly))
+	}
+
+	due, err := sim.repo.GetDuePagesToday(sim.ctx, simUserID, nextLocalMidnight(now, timezone))
+	if err != nil {
+		sim.t.Fatalf("get due pages: %v", err)
+	}
+
+	// Оценки чередуются, чтобы страницы и продвигались по интервалам, и забывались
+	grades := []int{90, 90, 70, 50, 20}
+	for i, progress := range due {
+		if dueDate := localDate(progress.NextReviewDate, timezone); dueDate.Before(today) {
+			sim.t.Errorf("%s due on %s reviewed late on %s", progress.PageID, dueDate.Format(time.DateOnly), today.Format(time.DateOnly))
+		}
+
+		submission := models.ReviewSubmission{
+			PageID:  progress.PageID,
+			Grade:   grades[(i+len(sim.days))%len(grades)],
+			Version: progress.Version,
+			Key:     fmt.Sprintf("%s-%s", progress.PageID, now.Format(time.RFC3339)),
+			Source:  models.ReviewSourceButton,
+		}
+		if err := sim.svc.UpdateReviewProgress(sim.ctx, simUserID, submission); err != nil {
+			sim.t.Fatalf("review %s at %v: %v", progress.PageID, now, err)
+		}
+		sim.reviews++
+
+		after := sim.repo.progress[progress.PageID]
+		days := max(after.IntervalDays, 1)
+		if want := localMidnight(today.AddDate(0, 0, days), timezone).UTC(); !after.NextReviewDate.Equal(want) {
+			sim.t.Errorf("%s reviewed on %s with interval %d: next review %v, want %v", progress.PageID, today.Format(time.DateOnly), after.IntervalDays, after.NextReviewDate, want)
+		}
+	}
+}
+
+// rolledOver сообщает, прошла ли смена дня на местную дату date в какой-либо таймзоне
+func (sim *simulation) rolledOver(date time.Time) bool {
+	return slices.ContainsFunc(sim.repo.rollovers, func(day simDay) bool {
+		return day.date.Equal(date)
+	})
+}
+
+func (sim *simulation) timezone() string {
+	return userTimezone(&sim.repo.user)
+}
+
+func (sim *simulation) location() *time.Location {
+	loc, err := time.LoadLocation(sim.timezone())
+	if err != nil {
+		sim.t.Fatal(err)
+	}
+	return loc
+}
+
+// check проверяет итог симуляции: смена дня не повторяется в одну и ту же местную дату, каждое напоминание
+// каждого полного дня приходит ровно один раз в своё местное время, пользователь не приостановлен
+func (sim *simulation) check() {
+	sim.t.Helper()
+
+	seen := make(map[simDay]bool)
+	for _, day := range sim.repo.rollovers {
+		if seen[day] {
+			sim.t.Errorf("rollover ran twice on %s", day)
+		}
+		seen[day] = true
+	}
+
+	wantLocal := map[string]string{"07:00": "08:00", "20:00": "20:00"}
+	delivered := make(map[string]int)
+	for _, delivery := range sim.deliveries {
+		reminder := delivery.reminder
+		key := reminder.LocalDate.Format(time.DateOnly) + " " + reminder.ReminderTime
+		delivered[key]++
+
+		if got := delivery.local.Format(reminderTimeLayout); got != wantLocal[reminder.ReminderTime] {
+			sim.t.Errorf("reminder %s delivered at %s local time, want %s", key, got, wantLocal[reminder.ReminderTime])
+		}
+		if !localDate(delivery.local, delivery.local.Location().String()).Equal(reminder.LocalDate) {
+			sim.t.Errorf("reminder %s delivered on %s", key, delivery.local.Format(time.DateTime))
+		}
+	}
+
+	// Первый и последний день симуляции неполные
+	for _, day := range sim.days[1 : len(sim.days)-1] {
+		for _, reminderTime := range sim.repo.times {
+			key := day.Format(time.DateOnly) + " " + reminderTime
+			if delivered[key] != 1 {
+				sim.t.Errorf("reminder %s delivered %d times, want once", key, delivered[key])
+			}
+		}
+	}
+
+	if sim.reviews == 0 {
+		sim.t.Error("no reviews in the simulation")
+	}
+	if sim.repo.user.IsPaused != nil && *sim.repo.user.IsPaused {
+		sim.t.Error("active user paused")
+	}
+}
+
+// TestSimulation прогоняет недели повторений, смен дня и напоминаний через переходы на летнее и зимнее время
+// и смены таймзоны пользователем
+func TestSimulation(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		start    time.Time
+		end      time.Time
+		switches []simSwitch
+	}{
+		{
+			// Переход на летнее время 29 марта в 02:00
+			name:     "Berlin spring forward",
+			timezone: "Europe/Berlin",
+			start:    time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 4, 20, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// Берлин переходит на зимнее время 25 октября, пользователь переезжает в Нью-Йорк 28 октября в 13:00
+			// по Берлину, где зимнее время наступает 1 ноября
+			name:     "Berlin fall back and switch west to New York",
+			timezone: "Europe/Berlin",
+			start:    time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 11, 23, 0, 0, 0, 0, time.UTC),
+			switches: []simSwitch{{at: time.Date(2026, 10, 28, 12, 0, 0, 0, time.UTC), timezone: "America/New_York"}},
+		},
+		{
+			// 14 мая в 23:30 по Берлину в Токио уже 15 мая: смена дня 15 мая не должна пропасть
+			name:     "switch east to Tokyo past local midnight",
+			timezone: "Europe/Berlin",
+			start:    time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 5, 25, 0, 0, 0, 0, time.UTC),
+			switches: []simSwitch{{at: time.Date(2026, 5, 14, 21, 30, 0, 0, time.UTC), timezone: "Asia/Tokyo"}},
+		},
+		{
+			// В Сантьяго время переводится в полночь: 5 апреля полночь наступает дважды, 6 сентября её нет
+			name:     "Santiago midnight transitions",
+			timezone: "America/Santiago",
+			start:    time.Date(2026, 3, 23, 6, 0, 0, 0, time.UTC),
+			end:      time.Date(2026, 9, 21, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newSimulation(t, tt.start, tt.timezone, 6)
+			sim.run(tt.end, tt.switches...)
+			sim.check()
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/onenote"
+	"go.uber.org/zap"
+)
+
+// sourcePage — страница OneNote вместе с источником (секцией), из которого она получена
+type sourcePage struct {
+	onenote.Page
+	Source *models.UserSource
+}
+
+// getSourcePages получает страницы из всех включённых источников пользователя в порядке их приоритета
+func (s *Service) getSourcePages(ctx context.Context, telegramID int64) ([]sourcePage, error) {
+	sources, err := s.repo.GetUserSources(ctx, telegramID, true)
+	if err != nil {
+		return nil, fmt.Errorf("get user sources (telegram_id: %d): %w", telegramID, err)
+	}
+
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	var pages []sourcePage
+	err = s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
+		pages = pages[:0]
+		seen := make(map[string]bool)
+
+		for _, source := range sources {
+			result, err := s.oneNoteClient.GetPages(accessToken, source.SectionID)
+			if err != nil {
+				return fmt.Errorf("get pages (telegram_id: %d, section_id: %s): %w", telegramID, source.SectionID, err)
+			}
+
+			for _, page := range result {
+				// Одна и та же страница не может прийти из двух секций, но защищаемся от дублей при переносе
+				if seen[page.ID] {
+					continue
+				}
+				seen[page.ID] = true
+				pages = append(pages, sourcePage{Page: page, Source: source})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+func (s *Service) AddSource(ctx context.Context, telegramID int64, notebookID, notebookName, sectionID, sectionName string) error {
+	// Без названия книги источник в /sources не отличить от секции с тем же именем из другой книги
+	if notebookName == "" {
+		names, err := s.notebookNames(ctx, telegramID)
+		if err != nil {
+			return err
+		}
+		notebookName = names[notebookID]
+	}
+
+	sources, err := s.repo.GetUserSources(ctx, telegramID, false)
+	if err != nil {
+		return fmt.Errorf("get user sources (telegram_id: %d): %w", telegramID, err)
+	}
+
+	// Новая секция добавляется в конец списка приоритетов
+	priority := 0
+	for _, source := range sources {
+		priority = max(priority, source.Priority+1)
+	}
+
+	source := &models.UserSource{
+		UserID:       telegramID,
+		NotebookID:   notebookID,
+		SectionID:    sectionID,
+		NotebookName: notebookName,
+		SectionName:  sectionName,
+		Enabled:      true,
+		Priority:     priority,
+		CreatedAt:    s.clock.Now(),
+	}
+
+	if err := s.repo.AddUserSource(ctx, source); err != nil {
+		return fmt.Errorf("add source (telegram_id: %d, section_id: %s): %w", telegramID, sectionID, err)
+	}
+
+	return nil
+}
+
+func (s *Service) GetSources(ctx context.Context, telegramID int64) ([]*models.UserSource, error) {
+	sources, err := s.repo.GetUserSources(ctx, telegramID, false)
+	if err != nil {
+		return nil, fmt.Errorf("get user sources (telegram_id: %d): %w", telegramID, err)
+	}
+
+	s.fillNotebookNames(ctx, telegramID, sources)
+
+	return sources, nil
+}
+
+// fillNotebookNames дописывает названия книг источникам, сохранённым без них.
+// Ошибки OneNote не мешают показать список, название дозапишется при следующем просмотре
+func (s *Service) fillNotebookNames(ctx context.Context, telegramID int64, sources []*models.UserSource) {
+	var unnamed []*models.UserSource
+	for _, source := range sources {
+		if source.NotebookName == "" {
+			unnamed = append(unnamed, source)
+		}
+	}
+	if len(unnamed) == 0 {
+		return
+	}
+
+	names, err := s.notebookNames(ctx, telegramID)
+	if err != nil {
+		zap.S().Warn("get notebook names", zap.Error(err), zap.Int64("telegram_id", telegramID))
+		return
+	}
+
+	for _, source := range unnamed {
+		name := names[source.NotebookID]
+		if name == "" {
+			continue
+		}
+		if err := s.repo.UpdateUserSourceNotebookName(ctx, telegramID, source.ID, name); err != nil {
+			zap.S().Error("update source notebook name", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.Int64("source_id", source.ID))
+			continue
+		}
+		source.NotebookName = name
+	}
+}
+
+// notebookNames возвращает названия книг пользователя по их ID
+func (s *Service) notebookNames(ctx context.Context, telegramID int64) (map[string]string, error) {
+	notebooks, err := s.GetOneNoteNotebooks(ctx, telegramID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(notebooks))
+	for _, notebook := range notebooks {
+		names[notebook.ID] = notebook.DisplayName
+	}
+	return names, nil
+}
+
+func (s *Service) ToggleSource(ctx context.Context, telegramID, sourceID int64) error {
+	source, err := s.findSource(ctx, telegramID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SetUserSourceEnabled(ctx, telegramID, sourceID, !source.Enabled); err != nil {
+		return fmt.Errorf("toggle source (telegram_id: %d, source_id: %d): %w", telegramID, sourceID, err)
+	}
+
+	return nil
+}
+
+// MoveSourceUp поднимает источник на одну позицию вверх и перенумеровывает приоритеты всех источников
+func (s *Service) MoveSourceUp(ctx context.Context, telegramID, sourceID int64) error {
+	return s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		sources, err := txRepo.GetUserSources(ctx, telegramID, false)
+		if err != nil {
+			return fmt.Errorf("get user sources (telegram_id: %d): %w", telegramID, err)
+		}
+
+		index := -1
+		for i, source := range sources {
+			if source.ID == sourceID {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			return fmt.Errorf("source not found (telegram_id: %d, source_id: %d)", telegramID, sourceID)
+		}
+
+		if index > 0 {
+			sources[index-1], sources[index] = sources[index], sources[index-1]
+		}
+
+		for i, source := range sources {
+			if source.Priority == i {
+				continue
+			}
+			if err := txRepo.UpdateUserSourcePriority(ctx, telegramID, source.ID, i); err != nil {
+				return fmt.Errorf("update source priority (telegram_id: %d, source_id: %d): %w", telegramID, source.ID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Service) RemoveSource(ctx context.Context, telegramID, sourceID int64) error {
+	if _, err := s.findSource(ctx, telegramID, sourceID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteUserSource(ctx, telegramID, sourceID); err != nil {
+		return fmt.Errorf("remove source (telegram_id: %d, source_id: %d): %w", telegramID, sourceID, err)
+	}
+
+	return nil
+}
+
+func (s *Service) findSource(ctx context.Context, telegramID, sourceID int64) (*models.UserSource, error) {
+	sources, err := s.repo.GetUserSources(ctx, telegramID, false)
+	if err != nil {
+		return nil, fmt.Errorf("get user sources (telegram_id: %d): %w", telegramID, err)
+	}
+
+	for _, source := range sources {
+		if source.ID == sourceID {
+			return source, nil
+		}
+	}
+
+	return nil, fmt.Errorf("source not found (telegram_id: %d, source_id: %d)", telegramID, sourceID)
+}
@@ -1,7 +1,6 @@
 package srs
 
 import (
-	"math/rand"
 	"slices"
 	"time"
 
@@ -12,117 +11,96 @@ import (
 type Grade string
 
 const (
-	forgot Grade = "forgot"
-	easy   Grade = "easy"
-	normal Grade = "normal"
-	hard   Grade = "hard"
+	Forgot Grade = "forgot"
+	Easy   Grade = "easy"
+	Normal Grade = "normal"
+	Hard   Grade = "hard"
+)
+
+// Remembered reports whether the grade counts as a successful review: easy or normal
+func (g Grade) Remembered() bool {
+	return g == Easy || g == Normal
+}
+
+// Review modes stored in progress_history.mode
+const (
+	// ModeReading is used while the page is only being read (interval 0)
+	ModeReading = "reading"
+	// ModeStandard is the AI mode with the standard SRS intervals
+	ModeStandard = "standard"
 )
 
 var defaultIntervals = []int{1, 3, 7, 14, 30, 90, 180}
 
-func CalculateNextReviewDate(currentIntervalDays int, success Grade, timezone string) (time.Time, int) {
+func CalculateNextReviewDate(now time.Time, currentIntervalDays int, success Grade, timezone string) (time.Time, int) {
 	interval := slices.Index(defaultIntervals, currentIntervalDays)
 
 	// Если интервал не найден, используем первый интервал как fallback
 	if interval == -1 {
 		zap.L().Error("Interval not found, using default", zap.Int("requested_days", currentIntervalDays))
-		return calculateInterval(defaultIntervals[0], timezone)
+		return calculateInterval(now, defaultIntervals[0], timezone)
 	}
 
 	switch success {
-	case forgot:
-		return calculateInterval(defaultIntervals[0], timezone)
-	case easy, normal:
+	case Forgot:
+		return calculateInterval(now, defaultIntervals[0], timezone)
+	case Easy, Normal:
 		if interval == len(defaultIntervals)-1 {
-			return calculateInterval(defaultIntervals[interval], timezone)
+			return calculateInterval(now, defaultIntervals[interval], timezone)
 		}
 
-		return calculateInterval(defaultIntervals[interval+1], timezone)
-	case hard:
+		return calculateInterval(now, defaultIntervals[interval+1], timezone)
+	case Hard:
 		if interval == 0 {
-			return calculateInterval(defaultIntervals[interval], timezone)
+			return calculateInterval(now, defaultIntervals[interval], timezone)
 		}
 
-		return calculateInterval(defaultIntervals[interval-1], timezone)
+		return calculateInterval(now, defaultIntervals[interval-1], timezone)
 	}
 
-	return calculateInterval(defaultIntervals[interval]+1, timezone)
+	return calculateInterval(now, defaultIntervals[interval]+1, timezone)
 }
 
-func calculateInterval(interval int, timezone string) (time.Time, int) {
-	// Convert to user's timezone to get "today" in their timezone
-	var startOfDayInTz time.Time
-	var err error
-	if timezone != "" {
-		startOfDayInTz, err = utils.StartOfTodayInTimezone(timezone)
-		if err != nil {
-			zap.L().Warn("Failed to get start of day in timezone, using UTC", zap.String("timezone", timezone), zap.Error(err))
-			startOfDayInTz = utils.StartOfTodayUTC()
-		}
-	} else {
-		startOfDayInTz = utils.StartOfTodayUTC()
-	}
-
+func calculateInterval(now time.Time, interval int, timezone string) (time.Time, int) {
 	// Add interval days in user's timezone
-	t := startOfDayInTz.AddDate(0, 0, interval)
+	t := utils.AddDays(startOfDay(now, timezone), interval)
 
 	// Convert back to UTC for database storage
 	return t.UTC(), interval
 }
 
-// GetInitialReviewDate returns today's date with interval 0 (reading mode)
-func GetInitialReviewDate(timezone string) (time.Time, int) {
-	var startOfDayInTz time.Time
-	var err error
-	if timezone != "" {
-		startOfDayInTz, err = utils.StartOfTodayInTimezone(timezone)
-		if err != nil {
-			zap.L().Warn("Failed to get start of day in timezone, using UTC", zap.String("timezone", timezone), zap.Error(err))
-			startOfDayInTz = utils.StartOfTodayUTC()
-		}
-	} else {
-		startOfDayInTz = utils.StartOfTodayUTC()
+// startOfDay returns the start of the day containing now in the user's timezone, in UTC if the timezone is unknown
+func startOfDay(now time.Time, timezone string) time.Time {
+	if timezone == "" {
+		return utils.StartOfDay(now.UTC())
 	}
 
+	startOfDayInTz, err := utils.StartOfDayInTimezone(now, timezone)
+	if err != nil {
+		zap.L().Warn("Failed to get start of day in timezone, using UTC", zap.String("timezone", timezone), zap.Error(err))
+		return utils.StartOfDay(now.UTC())
+	}
+
+	return startOfDayInTz
+}
+
+// GetInitialReviewDate returns today's date with interval 0 (reading mode)
+func GetInitialReviewDate(now time.Time, timezone string) (time.Time, int) {
 	// Convert back to UTC for database storage
-	return startOfDayInTz.UTC(), 0
+	return startOfDay(now, timezone).UTC(), 0
 }
 
 // GetNextDayReviewDate returns tomorrow's date with interval 1 (transition to AI mode)
-func GetNextDayReviewDate(timezone string) (time.Time, int) {
-	var startOfDayInTz time.Time
-	var err error
-	if timezone != "" {
-		startOfDayInTz, err = utils.StartOfTodayInTimezone(timezone)
-		if err != nil {
-			zap.L().Warn("Failed to get start of day in timezone, using UTC", zap.String("timezone", timezone), zap.Error(err))
-			startOfDayInTz = utils.StartOfTodayUTC()
-		}
-	} else {
-		startOfDayInTz = utils.StartOfTodayUTC()
-	}
-
-	tomorrow := startOfDayInTz.AddDate(0, 0, 1)
+func GetNextDayReviewDate(now time.Time, timezone string) (time.Time, int) {
+	tomorrow := utils.AddDays(startOfDay(now, timezone), 1)
 
 	// Convert back to UTC for database storage
 	return tomorrow.UTC(), 1
 }
 
 // GetNextDayReadingMode returns tomorrow's date with interval 0 (stay in reading mode)
-func GetNextDayReadingMode(timezone string) (time.Time, int) {
-	var startOfDayInTz time.Time
-	var err error
-	if timezone != "" {
-		startOfDayInTz, err = utils.StartOfTodayInTimezone(timezone)
-		if err != nil {
-			zap.L().Warn("Failed to get start of day in timezone, using UTC", zap.String("timezone", timezone), zap.Error(err))
-			startOfDayInTz = utils.StartOfTodayUTC()
-		}
-	} else {
-		startOfDayInTz = utils.StartOfTodayUTC()
-	}
-
-	tomorrow := startOfDayInTz.AddDate(0, 0, 1)
+func GetNextDayReadingMode(now time.Time, timezone string) (time.Time, int) {
+	tomorrow := utils.AddDays(startOfDay(now, timezone), 1)
 
 	// Convert back to UTC for database storage
 	return tomorrow.UTC(), 0
@@ -132,7 +110,8 @@ func GetNextDayReadingMode(timezone string) (time.Time, int) {
 // maxPagesPerDay = 2 → returns 1
 // maxPagesPerDay = 3 → randomly returns 1 (60%) or 2 (40%)
 // maxPagesPerDay = 4 → returns 2
-func CalculatePagesToAdd(maxPagesPerDay uint) int {
+// rng decides the random case, pass a fixed source to make it deterministic
+func CalculatePagesToAdd(maxPagesPerDay uint, rng utils.Rand) int {
 	switch maxPagesPerDay {
 	case 0:
 		return 1
@@ -142,7 +121,7 @@ func CalculatePagesToAdd(maxPagesPerDay uint) int {
 		return 1
 	case 3:
 		// 60% chance for 1 page, 40% chance for 2 pages
-		if rand.Float32() < 0.6 {
+		if rng.Float32() < 0.6 {
 			return 1
 		}
 		return 2
@@ -161,11 +140,11 @@ func CalculatePagesToAdd(maxPagesPerDay uint) int {
 // <40% → forgot
 func ConvertGradeToStatus(grade int) Grade {
 	if grade > 80 {
-		return easy
+		return Easy
 	} else if grade > 60 {
-		return normal
+		return Normal
 	} else if grade >= 40 {
-		return hard
+		return Hard
 	}
-	return forgot
+	return Forgot
 }
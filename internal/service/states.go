@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// GetChatState возвращает состояние диалога в чате, для нового чата — ChatStateIdle
+func (s *Service) GetChatState(ctx context.Context, chatID int64) (*models.ChatState, error) {
+	state, err := s.repo.GetChatState(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get chat state (chat_id: %d): %w", chatID, err)
+	}
+
+	if state == nil {
+		return &models.ChatState{ChatID: chatID, State: models.ChatStateIdle}, nil
+	}
+
+	return state, nil
+}
+
+func (s *Service) SetChatState(ctx context.Context, chatID, telegramID int64, state string, data map[string]string) error {
+	chatState := &models.ChatState{
+		ChatID:    chatID,
+		UserID:    telegramID,
+		State:     state,
+		Data:      data,
+		UpdatedAt: s.clock.Now(),
+	}
+
+	if err := s.repo.SaveChatState(ctx, chatState); err != nil {
+		return fmt.Errorf("set chat state (chat_id: %d, telegram_id: %d, state: %s): %w", chatID, telegramID, state, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+const (
+	// statsHistoryDays — за сколько дней читается история повторений для серии и динамики оценок
+	statsHistoryDays = 365
+	// StatsDays — за сколько последних дней показываются повторения по дням
+	StatsDays = 14
+	// StatsWeeks — за сколько недель показывается средняя оценка
+	StatsWeeks = 8
+	// StatsUpcomingDays — на сколько дней вперёд показывается нагрузка
+	StatsUpcomingDays = 7
+)
+
+// GetStats собирает статистику обучения пользователя по его местным датам
+func (s *Service) GetStats(ctx context.Context, telegramID int64) (*models.UserStats, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	timezone := userTimezone(user)
+	today := localDate(s.clock.Now(), timezone)
+	stats := &models.UserStats{}
+
+	from := localMidnight(today.AddDate(0, 0, -statsHistoryDays+1), timezone).UTC()
+	days, err := s.repo.GetDailyReviews(ctx, telegramID, timezone, from)
+	if err != nil {
+		return nil, fmt.Errorf("get daily reviews (telegram_id: %d): %w", telegramID, err)
+	}
+
+	byDate := make(map[string]*models.DailyReviews, len(days))
+	for _, day := range days {
+		byDate[day.Date.Format(VacationDateLayout)] = day
+		stats.TotalReviews += day.Reviews
+	}
+	reviewsOn := func(date string) *models.DailyReviews {
+		if day, ok := byDate[date]; ok {
+			return day
+		}
+		return &models.DailyReviews{}
+	}
+
+	for i := StatsDays - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i)
+		day := reviewsOn(date.Format(VacationDateLayout))
+		stats.ReviewsPerDay = append(stats.ReviewsPerDay, &models.DailyReviews{Date: date, Reviews: day.Reviews, ScoreSum: day.ScoreSum})
+	}
+
+	for week := StatsWeeks - 1; week >= 0; week-- {
+		weekStart := today.AddDate(0, 0, -7*week-6)
+		score := &models.WeeklyScore{WeekStart: weekStart}
+		scoreSum := 0
+		for i := range 7 {
+			day := reviewsOn(weekStart.AddDate(0, 0, i).Format(VacationDateLayout))
+			score.Reviews += day.Reviews
+			scoreSum += day.ScoreSum
+		}
+		if score.Reviews > 0 {
+			score.AverageScore = scoreSum / score.Reviews
+		}
+		stats.ScoreTrend = append(stats.ScoreTrend, score)
+	}
+
+	// Серия не прерывается, пока пользователь ещё не повторял сегодня
+	streakDay := today
+	if reviewsOn(today.Format(VacationDateLayout)).Reviews == 0 {
+		streakDay = today.AddDate(0, 0, -1)
+	}
+	for reviewsOn(streakDay.Format(VacationDateLayout)).Reviews > 0 {
+		stats.Streak++
+		streakDay = streakDay.AddDate(0, 0, -1)
+	}
+
+	stats.Retention, err = s.repo.GetIntervalRetention(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get interval retention (telegram_id: %d): %w", telegramID, err)
+	}
+
+	stats.PagesPerInterval, err = s.repo.GetPagesPerInterval(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get pages per interval (telegram_id: %d): %w", telegramID, err)
+	}
+
+	stats.PassedPages, err = s.repo.CountPassedPages(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("count passed pages (telegram_id: %d): %w", telegramID, err)
+	}
+
+	to := localMidnight(today.AddDate(0, 0, StatsUpcomingDays), timezone).UTC()
+	load, err := s.repo.GetUpcomingLoad(ctx, telegramID, timezone, to)
+	if err != nil {
+		return nil, fmt.Errorf("get upcoming load (telegram_id: %d): %w", telegramID, err)
+	}
+
+	for i := range StatsUpcomingDays {
+		stats.Upcoming = append(stats.Upcoming, &models.DailyLoad{Date: today.AddDate(0, 0, i)})
+	}
+	for _, day := range load {
+		// Просроченные повторения ждут пользователя сегодня
+		index := max(int(day.Date.Sub(today).Hours()/24), 0)
+		if index < len(stats.Upcoming) {
+			stats.Upcoming[index].Pages += day.Pages
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/onenote"
+	"go.uber.org/zap"
+)
+
+// SyncPages синхронизирует страницы всех включённых источников пользователя и возвращает отчёт об изменениях
+func (s *Service) SyncPages(ctx context.Context, telegramID int64) (*models.SyncReport, error) {
+	return s.syncPagesInternal(ctx, telegramID)
+}
+
+// syncPagesInternal сверяет список страниц OneNote с page_references.
+// Записываются только новые, восстановленные, перенесённые и изменённые после курсора синхронизации страницы,
+// пропавшие из подписанных секций помечаются удалёнными. Страница, перенесённая между секциями,
+// в OneNote получает новый ID, поэтому новая страница с тем же заголовком, что и пропавшая, считается переносом,
+// и прогресс вместе с историей переезжает на новый ID. Если заголовок повторяется у нескольких пропавших
+// или новых страниц, перенос не угадывается: новые страницы добавляются, пропавшие помечаются удалёнными.
+// Между полными сверками (fullSyncInterval) из OneNote запрашиваются только страницы, изменённые после курсора.
+func (s *Service) syncPagesInternal(ctx context.Context, telegramID int64) (*models.SyncReport, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	if user.OneNoteConfig == nil {
+		return nil, fmt.Errorf("onenote not configured (telegram_id: %d)", telegramID)
+	}
+
+	// Курсор фиксируется до запроса к OneNote, чтобы правки во время синхронизации попали в следующую
+	syncStartedAt := s.clock.Now()
+
+	sources, err := s.repo.GetUserSources(ctx, telegramID, true)
+	if err != nil {
+		return nil, fmt.Errorf("get user sources (telegram_id: %d): %w", telegramID, err)
+	}
+
+	report := &models.SyncReport{}
+
+	// Без включённых секций сверять не с чем, иначе все страницы оказались бы удалёнными
+	if len(sources) == 0 {
+		return report, nil
+	}
+
+	existingPages, err := s.repo.GetUserPagesInProgress(ctx, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("get page references (telegram_id: %d): %w", telegramID, err)
+	}
+
+	existing := make(map[string]*models.PageReference, len(existingPages))
+	for _, page := range existingPages {
+		existing[page.PageID] = page
+	}
+
+	// Секции сверяются полным списком все вместе: страница с тем же ID может переехать в соседнюю секцию
+	// и не попасть в список изменённых, а по полному списку старой секции оказалась бы удалённой
+	full := slices.ContainsFunc(sources, func(source *models.UserSource) bool {
+		return needsFullSync(source, syncStartedAt)
+	})
+
+	pages, err := s.getSyncPages(ctx, telegramID, sources, full)
+	if err != nil {
+		return nil, err
+	}
+
+	// Незнакомая страница может быть перенесённой, а найти пропавший оригинал можно только по полному списку
+	if !full && hasUnknownPages(pages, existing) {
+		full = true
+		pages, err = s.getSyncPages(ctx, telegramID, sources, full)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	syncedSections := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		syncedSections[source.SectionID] = true
+	}
+
+	var upserts []*models.PageReference
+	var added []*models.PageReference
+	listed := make(map[string]bool, len(pages))
+
+	for _, page := range pages {
+		if !isSyncedTitle(page.Title) {
+			continue
+		}
+		listed[page.ID] = true
+
+		var updatedAt *time.Time
+		if page.LastModifiedDateTime != "" {
+			parsed, err := time.Parse(time.RFC3339, page.LastModifiedDateTime)
+			if err == nil {
+				updatedAt = &parsed
+			}
+		}
+
+		pageRef := &models.PageReference{
+			PageID:    page.ID,
+			UserID:    telegramID,
+			Title:     page.Title,
+			Source:    "onenote",
+			SectionID: page.Source.SectionID,
+			CreatedAt: syncStartedAt,
+			UpdatedAt: updatedAt,
+		}
+
+		old, ok := existing[page.ID]
+		switch {
+		case !ok || old.DeletedAt != nil:
+			added = append(added, pageRef)
+		case old.SectionID != "" && old.SectionID != pageRef.SectionID:
+			report.Moved = append(report.Moved, page.Title)
+		case old.Title != page.Title || isModifiedSince(updatedAt, old.UpdatedAt, page.Source.SyncCursor):
+			report.Changed = append(report.Changed, page.Title)
+		default:
+			continue
+		}
+
+		upserts = append(upserts, pageRef)
+	}
+
+	// Пропавшими считаются только страницы секций, которые реально синхронизировались в этот раз,
+	// и только по полному списку: в списке изменённых страниц нет неизменённых
+	removed := make(map[string]*models.PageReference)
+	for _, page := range existingPages {
+		if !full || page.DeletedAt != nil || listed[page.PageID] {
+			continue
+		}
+		if page.SectionID != "" && !syncedSections[page.SectionID] {
+			continue
+		}
+		removed[page.PageID] = page
+	}
+
+	removedByTitle := make(map[string][]*models.PageReference, len(removed))
+	for _, page := range removed {
+		title := normalizeTitle(page.Title)
+		removedByTitle[title] = append(removedByTitle[title], page)
+	}
+
+	newByTitle := make(map[string]int, len(added))
+	for _, page := range added {
+		if existing[page.PageID] == nil {
+			newByTitle[normalizeTitle(page.Title)]++
+		}
+	}
+
+	moves := make(map[string]string)
+	for _, page := range added {
+		title := normalizeTitle(page.Title)
+		// Перенос засчитывается, только когда заголовок однозначно связывает одну пропавшую и одну новую страницу
+		if candidates := removedByTitle[title]; len(candidates) == 1 && newByTitle[title] == 1 && existing[page.PageID] == nil {
+			old := candidates[0]
+			moves[old.PageID] = page.PageID
+			delete(removed, old.PageID)
+			report.Moved = append(report.Moved, page.Title)
+			continue
+		}
+		report.Added = append(report.Added, page.Title)
+	}
+
+	removedIDs := make([]string, 0, len(removed))
+	for _, page := range removed {
+		removedIDs = append(removedIDs, page.PageID)
+		report.Removed = append(report.Removed, page.Title)
+	}
+
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		for oldPageID, newPageID := range moves {
+			if err := txRepo.MovePageReference(ctx, telegramID, oldPageID, newPageID); err != nil {
+				return fmt.Errorf("move page reference (telegram_id: %d, page_id: %s): %w", telegramID, oldPageID, err)
+			}
+		}
+
+		if err := txRepo.UpsertPageReferences(ctx, upserts); err != nil {
+			return fmt.Errorf("upsert page references (telegram_id: %d): %w", telegramID, err)
+		}
+
+		if err := txRepo.MarkPageReferencesDeleted(ctx, telegramID, removedIDs, syncStartedAt); err != nil {
+			return fmt.Errorf("mark page references deleted (telegram_id: %d): %w", telegramID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		if err := s.repo.UpdateUserSourceSyncCursor(ctx, telegramID, source.ID, syncStartedAt); err != nil {
+			zap.S().Error("update sync cursor", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.Int64("source_id", source.ID))
+		}
+		if !full {
+			continue
+		}
+		if err := s.repo.UpdateUserSourceFullSync(ctx, telegramID, source.ID, syncStartedAt); err != nil {
+			zap.S().Error("update full sync time", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.Int64("source_id", source.ID))
+		}
+	}
+
+	s.extractPageItems(ctx, telegramID)
+
+	zap.S().Info("pages synced",
+		zap.Int64("telegram_id", telegramID),
+		zap.Int("added", len(report.Added)),
+		zap.Int("changed", len(report.Changed)),
+		zap.Int("moved", len(report.Moved)),
+		zap.Int("removed", len(report.Removed)),
+		zap.Bool("full", full))
+
+	return report, nil
+}
+
+// fullSyncInterval — как часто секция сверяется полным списком страниц. Только полный список показывает
+// удалённые и перенесённые страницы, между сверками хватает страниц, изменённых после курсора
+const fullSyncInterval = 24 * time.Hour
+
+// getSyncPages получает страницы включённых источников для синхронизации:
+// полный список при full, иначе только страницы, изменённые после курсора секции
+func (s *Service) getSyncPages(ctx context.Context, telegramID int64, sources []*models.UserSource, full bool) ([]sourcePage, error) {
+	var pages []sourcePage
+	err := s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
+		pages = pages[:0]
+		seen := make(map[string]bool)
+
+		for _, source := range sources {
+			var result []onenote.Page
+			var err error
+			if full {
+				result, err = s.oneNoteClient.GetPages(accessToken, source.SectionID)
+			} else {
+				result, err = s.oneNoteClient.GetPagesModifiedSince(accessToken, source.SectionID, *source.SyncCursor)
+			}
+			if err != nil {
+				return fmt.Errorf("get pages (telegram_id: %d, section_id: %s): %w", telegramID, source.SectionID, err)
+			}
+
+			for _, page := range result {
+				if seen[page.ID] {
+					continue
+				}
+				seen[page.ID] = true
+				pages = append(pages, sourcePage{Page: page, Source: source})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+// needsFullSync проверяет, пора ли сверить секцию полным списком страниц
+func needsFullSync(source *models.UserSource, now time.Time) bool {
+	if source.SyncCursor == nil || source.FullSyncedAt == nil {
+		return true
+	}
+	return now.Sub(*source.FullSyncedAt) >= fullSyncInterval
+}
+
+// hasUnknownPages проверяет, есть ли среди полученных страниц те, которых ещё нет в page_references
+func hasUnknownPages(pages []sourcePage, existing map[string]*models.PageReference) bool {
+	for _, page := range pages {
+		if isSyncedTitle(page.Title) && existing[page.ID] == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isSyncedTitle проверяет, участвует ли страница в обучении: черновики со звёздочкой и страницы без номера пропускаются
+func isSyncedTitle(title string) bool {
+	return !strings.Contains(title, "*") && hasPageNumber(title)
+}
+
+// maxItemExtractionsPerSync — сколько страниц за одну синхронизацию загружается для извлечения слов,
+// остальные страницы дождутся следующей синхронизации
+const maxItemExtractionsPerSync = 20
+
+// extractPageItems извлекает слова из словарных таблиц новых и изменённых страниц.
+// Ошибки не прерывают синхронизацию: страница останется без отметки и обработается в следующий раз
+func (s *Service) extractPageItems(ctx context.Context, telegramID int64) {
+	pageIDs, err := s.repo.GetPagesWithoutItems(ctx, telegramID, maxItemExtractionsPerSync)
+	if err != nil {
+		zap.S().Error("get pages without items", zap.Error(err), zap.Int64("telegram_id", telegramID))
+		return
+	}
+
+	for _, pageID := range pageIDs {
+		var content string
+		err := s.withAuthRetry(ctx, telegramID, func(accessToken string) error {
+			result, err := s.oneNoteClient.GetPageHTML(accessToken, pageID)
+			if err != nil {
+				return fmt.Errorf("get page content (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
+			}
+			content = result
+			return nil
+		})
+		if err != nil {
+			// OneNote недоступен или токен отозван — остальные страницы упадут так же
+			zap.S().Error("fetch page for items", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.String("page_id", pageID))
+			return
+		}
+
+		if err := s.savePageItems(ctx, telegramID, pageID, onenote.ExtractVocabulary(content)); err != nil {
+			zap.S().Error("save page items", zap.Error(err), zap.Int64("telegram_id", telegramID), zap.String("page_id", pageID))
+		}
+	}
+}
+
+// isModifiedSince проверяет, изменялась ли страница после курсора синхронизации источника
+// и после сохранённой у нас даты изменения
+func isModifiedSince(lastModified, stored, cursor *time.Time) bool {
+	if lastModified == nil {
+		return false
+	}
+	if cursor != nil && !lastModified.After(*cursor) {
+		return false
+	}
+	return stored == nil || lastModified.After(*stored)
+}
+
+func normalizeTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
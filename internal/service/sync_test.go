@@ -0,0 +1,420 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/onenote"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+// syncOneNote — OneNote в памяти: страницы подписанных секций и счётчики полных и частичных запросов
+type syncOneNote struct {
+	models.OneNoteClient
+
+	sections map[string][]onenote.Page
+	// fullRequests и deltaRequests — сколько раз запрашивались полный список и изменённые после курсора страницы
+	fullRequests  int
+	deltaRequests int
+}
+
+func (c *syncOneNote) GetPages(_, sectionID string) ([]onenote.Page, error) {
+	c.fullRequests++
+	return c.sections[sectionID], nil
+}
+
+func (c *syncOneNote) GetPagesModifiedSince(_, sectionID string, since time.Time) ([]onenote.Page, error) {
+	c.deltaRequests++
+
+	var pages []onenote.Page
+	for _, page := range c.sections[sectionID] {
+		modified, err := time.Parse(time.RFC3339, page.LastModifiedDateTime)
+		if err == nil && !modified.Before(since) {
+			pages = append(pages, page)
+		}
+	}
+	return pages, nil
+}
+
+// syncRepo — хранилище в памяти для синхронизации: источники, page_references и страницы с прогрессом
+type syncRepo struct {
+	models.Repository
+
+	user    models.User
+	sources []*models.UserSource
+	pages   map[string]*models.PageReference
+	// progress — страницы, по которым есть прогресс; при переносе прогресс переезжает на новый ID
+	progress map[string]bool
+	moves    map[string]string
+}
+
+func newSyncRepo(now time.Time, sources []*models.UserSource, pages ...*models.PageReference) *syncRepo {
+	token := "token"
+	expiresAt := now.AddDate(1, 0, 0)
+	repo := &syncRepo{
+		user: models.User{
+			TelegramID:    1,
+			AccessToken:   &token,
+			RefreshToken:  &token,
+			ExpiresAt:     &expiresAt,
+			OneNoteConfig: &models.OneNoteConfig{NotebookID: "notebook", SectionID: sources[0].SectionID},
+		},
+		sources:  sources,
+		pages:    make(map[string]*models.PageReference),
+		progress: make(map[string]bool),
+		moves:    make(map[string]string),
+	}
+	for _, page := range pages {
+		page.UserID = 1
+		repo.pages[page.PageID] = page
+		repo.progress[page.PageID] = true
+	}
+	return repo
+}
+
+func (r *syncRepo) GetUser(context.Context, int64) (*models.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *syncRepo) RunInTx(_ context.Context, fn func(models.Repository) error) error {
+	return fn(r)
+}
+
+func (r *syncRepo) GetUserSources(context.Context, int64, bool) ([]*models.UserSource, error) {
+	sources := make([]*models.UserSource, 0, len(r.sources))
+	for _, source := range r.sources {
+		copied := *source
+		sources = append(sources, &copied)
+	}
+	return sources, nil
+}
+
+func (r *syncRepo) UpdateUserSourceSyncCursor(_ context.Context, _, sourceID int64, cursor time.Time) error {
+	r.source(sourceID).SyncCursor = &cursor
+	return nil
+}
+
+func (r *syncRepo) UpdateUserSourceFullSync(_ context.Context, _, sourceID int64, fullSyncedAt time.Time) error {
+	r.source(sourceID).FullSyncedAt = &fullSyncedAt
+	return nil
+}
+
+func (r *syncRepo) source(sourceID int64) *models.UserSource {
+	for _, source := range r.sources {
+		if source.ID == sourceID {
+			return source
+		}
+	}
+	return nil
+}
+
+func (r *syncRepo) GetUserPagesInProgress(context.Context, int64) ([]*models.PageReference, error) {
+	pages := make([]*models.PageReference, 0, len(r.pages))
+	for _, page := range r.pages {
+		copied := *page
+		pages = append(pages, &copied)
+	}
+	return pages, nil
+}
+
+func (r *syncRepo) MovePageReference(_ context.Context, _ int64, oldPageID, newPageID string) error {
+	page := r.pages[oldPageID]
+	delete(r.pages, oldPageID)
+	page.PageID = newPageID
+	r.pages[newPageID] = page
+
+	r.progress[newPageID] = r.progress[oldPageID]
+	delete(r.progress, oldPageID)
+	r.moves[oldPageID] = newPageID
+	return nil
+}
+
+func (r *syncRepo) UpsertPageReferences(_ context.Context, pages []*models.PageReference) error {
+	for _, page := range pages {
+		copied := *page
+		r.pages[page.PageID] = &copied
+	}
+	return nil
+}
+
+func (r *syncRepo) MarkPageReferencesDeleted(_ context.Context, _ int64, pageIDs []string, deletedAt time.Time) error {
+	for _, pageID := range pageIDs {
+		r.pages[pageID].DeletedAt = &deletedAt
+	}
+	return nil
+}
+
+func (r *syncRepo) GetPagesWithoutItems(context.Context, int64, int) ([]string, error) {
+	return nil, nil
+}
+
+func oneNotePage(id, title string, modified time.Time) onenote.Page {
+	return onenote.Page{ID: id, Title: title, LastModifiedDateTime: modified.Format(time.RFC3339)}
+}
+
+func TestSyncPagesReconciles(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	edited := now.Add(-48 * time.Hour)
+	// Обе секции подписаны и ещё ни разу не сверялись, поэтому синхронизация идёт по полному списку
+	sources := func() []*models.UserSource {
+		return []*models.UserSource{{ID: 1, SectionID: "sec-a", Enabled: true}, {ID: 2, SectionID: "sec-b", Enabled: true}}
+	}
+	page := func(id, title, sectionID string) *models.PageReference {
+		return &models.PageReference{PageID: id, Title: title, Source: "onenote", SectionID: sectionID, UpdatedAt: &edited}
+	}
+
+	tests := []struct {
+		name     string
+		existing []*models.PageReference
+		oneNote  map[string][]onenote.Page
+		want     models.SyncReport
+		// wantMoves — старый ID → новый ID страниц, прогресс которых переехал
+		wantMoves map[string]string
+		// wantDeleted — страницы, помеченные удалёнными
+		wantDeleted []string
+		check       func(t *testing.T, repo *syncRepo)
+	}{
+		{
+			name:     "rename",
+			existing: []*models.PageReference{page("p1", "1 Travel", "sec-a")},
+			oneNote:  map[string][]onenote.Page{"sec-a": {oneNotePage("p1", "1 Trips", edited)}},
+			want:     models.SyncReport{Changed: []string{"1 Trips"}},
+			check: func(t *testing.T, repo *syncRepo) {
+				if title := repo.pages["p1"].Title; title != "1 Trips" {
+					t.Errorf("title = %q, want the new title", title)
+				}
+			},
+		},
+		{
+			// OneNote выдаёт странице, перенесённой в другую секцию, новый ID, и прогресс переезжает на него
+			name:      "move between subscribed sections",
+			existing:  []*models.PageReference{page("p1", "1 Travel", "sec-a")},
+			oneNote:   map[string][]onenote.Page{"sec-b": {oneNotePage("p2", "1  travel", edited)}},
+			want:      models.SyncReport{Moved: []string{"1  travel"}},
+			wantMoves: map[string]string{"p1": "p2"},
+			check: func(t *testing.T, repo *syncRepo) {
+				if moved := repo.pages["p2"]; moved == nil || moved.SectionID != "sec-b" || moved.DeletedAt != nil {
+					t.Errorf("moved page = %+v, want an active page in sec-b", moved)
+				}
+				if !repo.progress["p2"] || repo.progress["p1"] {
+					t.Error("progress did not move to the new page ID")
+				}
+			},
+		},
+		{
+			name:     "move keeping the page ID",
+			existing: []*models.PageReference{page("p1", "1 Travel", "sec-a")},
+			oneNote:  map[string][]onenote.Page{"sec-b": {oneNotePage("p1", "1 Travel", edited)}},
+			want:     models.SyncReport{Moved: []string{"1 Travel"}},
+			check: func(t *testing.T, repo *syncRepo) {
+				if sectionID := repo.pages["p1"].SectionID; sectionID != "sec-b" {
+					t.Errorf("section = %s, want sec-b", sectionID)
+				}
+			},
+		},
+		{
+			// Пропавшая страница помечается удалённой, а страница отписанной секции не сверяется и остаётся как есть
+			name: "delete marking",
+			existing: []*models.PageReference{
+				page("p1", "1 Travel", "sec-a"),
+				page("p2", "2 Food", "sec-a"),
+				page("p9", "9 Old", "sec-unsubscribed"),
+			},
+			oneNote:     map[string][]onenote.Page{"sec-a": {oneNotePage("p1", "1 Travel", edited)}},
+			want:        models.SyncReport{Removed: []string{"2 Food"}},
+			wantDeleted: []string{"p2"},
+		},
+		{
+			// Страница, помеченная удалённой, снова появилась в OneNote: запись снова активна и попадает в добавленные
+			name:     "restore deleted page",
+			existing: []*models.PageReference{{PageID: "p1", Title: "1 Travel", Source: "onenote", SectionID: "sec-a", UpdatedAt: &edited, DeletedAt: &edited}},
+			oneNote:  map[string][]onenote.Page{"sec-a": {oneNotePage("p1", "1 Travel", edited)}},
+			want:     models.SyncReport{Added: []string{"1 Travel"}},
+			check: func(t *testing.T, repo *syncRepo) {
+				if repo.pages["p1"].DeletedAt != nil {
+					t.Error("restored page is still marked deleted")
+				}
+			},
+		},
+		{
+			// Две новые страницы с заголовком пропавшей: перенос не угадывается
+			name:     "ambiguous new titles",
+			existing: []*models.PageReference{page("p1", "1 Travel", "sec-a")},
+			oneNote: map[string][]onenote.Page{
+				"sec-b": {oneNotePage("p2", "1 Travel", edited), oneNotePage("p3", "1 Travel", edited)},
+			},
+			want:        models.SyncReport{Added: []string{"1 Travel", "1 Travel"}, Removed: []string{"1 Travel"}},
+			wantDeleted: []string{"p1"},
+		},
+		{
+			// Две пропавшие страницы с заголовком новой: перенос не угадывается
+			name:     "ambiguous removed titles",
+			existing: []*models.PageReference{page("p1", "1 Travel", "sec-a"), page("p2", "1 Travel", "sec-a")},
+			oneNote: map[string][]onenote.Page{
+				"sec-b": {oneNotePage("p3", "1 Travel", edited)},
+			},
+			want:        models.SyncReport{Added: []string{"1 Travel"}, Removed: []string{"1 Travel", "1 Travel"}},
+			wantDeleted: []string{"p1", "p2"},
+		},
+		{
+			// Черновики со звёздочкой и страницы без номера в обучении не участвуют
+			name: "skipped titles",
+			oneNote: map[string][]onenote.Page{
+				"sec-a": {oneNotePage("p1", "1 Travel*", edited), oneNotePage("p2", "Notes", edited)},
+			},
+			want: models.SyncReport{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newSyncRepo(now, sources(), tt.existing...)
+			svc := NewService(repo, nil, &syncOneNote{sections: tt.oneNote}, clocktest.NewManualClock(now), clocktest.FixedRand(0.5))
+
+			report, err := svc.SyncPages(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, list := range []struct {
+				name      string
+				got, want []string
+			}{
+				{"added", report.Added, tt.want.Added},
+				{"changed", report.Changed, tt.want.Changed},
+				{"moved", report.Moved, tt.want.Moved},
+				{"removed", report.Removed, tt.want.Removed},
+			} {
+				slices.Sort(list.got)
+				if !slices.Equal(list.got, list.want) {
+					t.Errorf("%s = %q, want %q", list.name, list.got, list.want)
+				}
+			}
+
+			if len(repo.moves) != len(tt.wantMoves) {
+				t.Errorf("moves = %v, want %v", repo.moves, tt.wantMoves)
+			}
+			for oldPageID, newPageID := range tt.wantMoves {
+				if repo.moves[oldPageID] != newPageID {
+					t.Errorf("moves = %v, want %v", repo.moves, tt.wantMoves)
+				}
+			}
+
+			var deleted []string
+			for pageID, page := range repo.pages {
+				if page.DeletedAt != nil {
+					deleted = append(deleted, pageID)
+					if !page.DeletedAt.Equal(now) {
+						t.Errorf("page %s deleted at %v, want the sync start %v", pageID, page.DeletedAt, now)
+					}
+				}
+			}
+			slices.Sort(deleted)
+			if !slices.Equal(deleted, tt.wantDeleted) {
+				t.Errorf("deleted pages = %v, want %v", deleted, tt.wantDeleted)
+			}
+
+			if tt.check != nil {
+				tt.check(t, repo)
+			}
+		})
+	}
+}
+
+// TestSyncPagesCursor проверяет, что между полными сверками запрашиваются только страницы, изменённые после курсора
+func TestSyncPagesCursor(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	cursor := now.Add(-time.Hour)
+	fullSyncedAt := now.Add(-fullSyncInterval / 2)
+	stale := now.Add(-48 * time.Hour)
+	edited := now.Add(-10 * time.Minute)
+
+	newSync := func(pages []onenote.Page) (*Service, *syncRepo, *syncOneNote, *clocktest.ManualClock) {
+		repo := newSyncRepo(now,
+			[]*models.UserSource{{ID: 1, SectionID: "sec-a", Enabled: true, SyncCursor: &cursor, FullSyncedAt: &fullSyncedAt}},
+			&models.PageReference{PageID: "p1", Title: "1 Travel", Source: "onenote", SectionID: "sec-a", UpdatedAt: &stale},
+			&models.PageReference{PageID: "p2", Title: "2 Food", Source: "onenote", SectionID: "sec-a", UpdatedAt: &stale},
+		)
+		client := &syncOneNote{sections: map[string][]onenote.Page{"sec-a": pages}}
+		clock := clocktest.NewManualClock(now)
+		return NewService(repo, nil, client, clock, clocktest.FixedRand(0.5)), repo, client, clock
+	}
+
+	t.Run("delta", func(t *testing.T) {
+		// p2 пропала из OneNote, но список изменённых страниц этого не показывает: удаление ждёт полной сверки
+		svc, repo, client, _ := newSync([]onenote.Page{oneNotePage("p1", "1 Travel", edited)})
+
+		report, err := svc.SyncPages(context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if client.deltaRequests != 1 || client.fullRequests != 0 {
+			t.Errorf("requests: %d delta, %d full, want only one delta", client.deltaRequests, client.fullRequests)
+		}
+		if !slices.Equal(report.Changed, []string{"1 Travel"}) || len(report.Removed) != 0 {
+			t.Errorf("report = %+v, want only 1 Travel changed", report)
+		}
+		source := repo.sources[0]
+		if !source.SyncCursor.Equal(now) {
+			t.Errorf("sync cursor = %v, want %v", source.SyncCursor, now)
+		}
+		if !source.FullSyncedAt.Equal(fullSyncedAt) {
+			t.Errorf("full sync time moved to %v by a delta sync", source.FullSyncedAt)
+		}
+	})
+
+	t.Run("unchanged before cursor", func(t *testing.T) {
+		// Страница, изменённая до курсора, уже учтена прошлой синхронизацией и не пишется заново
+		svc, _, _, _ := newSync([]onenote.Page{oneNotePage("p1", "1 Travel", cursor.Add(-time.Minute)), oneNotePage("p2", "2 Food", stale)})
+
+		report, err := svc.SyncPages(context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Changed)+len(report.Added)+len(report.Moved)+len(report.Removed) != 0 {
+			t.Errorf("report = %+v, want no changes", report)
+		}
+	})
+
+	t.Run("unknown page", func(t *testing.T) {
+		// Незнакомая страница может быть перенесённой, поэтому синхронизация переходит на полный список
+		svc, repo, client, _ := newSync([]onenote.Page{oneNotePage("p1", "1 Travel", stale), oneNotePage("p3", "2 Food", edited)})
+
+		report, err := svc.SyncPages(context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if client.fullRequests != 1 {
+			t.Errorf("full requests = %d, want a fallback to the full list", client.fullRequests)
+		}
+		if !slices.Equal(report.Moved, []string{"2 Food"}) || repo.moves["p2"] != "p3" {
+			t.Errorf("report = %+v, moves = %v, want 2 Food moved from p2 to p3", report, repo.moves)
+		}
+		if !repo.sources[0].FullSyncedAt.Equal(now) {
+			t.Errorf("full sync time = %v, want %v", repo.sources[0].FullSyncedAt, now)
+		}
+	})
+
+	t.Run("full sync due", func(t *testing.T) {
+		svc, repo, client, clock := newSync([]onenote.Page{oneNotePage("p1", "1 Travel", stale)})
+		clock.Advance(fullSyncInterval)
+
+		report, err := svc.SyncPages(context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if client.fullRequests != 1 || client.deltaRequests != 0 {
+			t.Errorf("requests: %d delta, %d full, want only one full", client.deltaRequests, client.fullRequests)
+		}
+		if !slices.Equal(report.Removed, []string{"2 Food"}) || repo.pages["p2"].DeletedAt == nil {
+			t.Errorf("report = %+v, want 2 Food removed", report)
+		}
+	})
+}
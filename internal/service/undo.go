@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+)
+
+// UndoWindow — сколько после оценки её ещё можно отменить
+const UndoWindow = 10 * time.Minute
+
+var (
+	// ErrNothingToUndo — оценку нельзя отменить: её не было, она уже отменена или после неё страницу оценили снова
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrUndoExpired — с момента оценки прошло больше UndoWindow
+	ErrUndoExpired = errors.New("undo window expired")
+)
+
+// UndoLastGrade отменяет оценку страницы с ключом reviewKey, если она последняя: возвращает прогресс к состоянию
+// до оценки, отмечает запись истории отменённой и снова делает страницу непройденной в сегодняшней сессии.
+// Если после этой оценки страницу оценили снова, отменять нечего: кнопка отмены старого сообщения
+// не должна откатывать оценку, которую пользователь не отменял. Всё выполняется в одной транзакции
+// под блокировкой записи истории, поэтому повторная или параллельная отмена получает ErrNothingToUndo.
+func (s *Service) UndoLastGrade(ctx context.Context, telegramID int64, pageID, reviewKey string) error {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	now := s.clock.Now()
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		history, err := txRepo.GetLastProgressHistory(ctx, telegramID, pageID)
+		if err != nil {
+			return err
+		}
+
+		if history == nil || history.UndoneAt != nil || history.Before == nil || reviewKey == "" || history.ReviewKey != reviewKey {
+			return ErrNothingToUndo
+		}
+		if now.Sub(history.Date) > UndoWindow {
+			return ErrUndoExpired
+		}
+
+		current, err := txRepo.GetProgress(ctx, telegramID, pageID)
+		if err != nil {
+			return err
+		}
+
+		// Версия не откатывается вместе с прогрессом, иначе кнопки оценки, показанные до отмены, снова стали бы действительными
+		previous := history.Before
+		updated, err := txRepo.UpdateProgress(ctx, telegramID, pageID, current.Version, previous.Level, previous.RepetitionCount, previous.LastReviewDate, previous.NextReviewDate, previous.IntervalDays, previous.ReviewedToday, previous.Passed)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrReviewConflict
+		}
+
+		if err := txRepo.MarkProgressHistoryUndone(ctx, telegramID, history.ID, now); err != nil {
+			return err
+		}
+
+		return s.reopenSessionItem(ctx, txRepo, user, pageID)
+	})
+	if err != nil {
+		return fmt.Errorf("undo last grade (telegram_id: %d, page_id: %s): %w", telegramID, pageID, err)
+	}
+
+	return nil
+}
+
+// reopenSessionItem снова делает страницу непройденной в сегодняшней сессии и возобновляет завершённую сессию
+func (s *Service) reopenSessionItem(ctx context.Context, txRepo models.Repository, user *models.User, pageID string) error {
+	session, err := txRepo.GetReviewSession(ctx, user.TelegramID, localDate(s.clock.Now(), userTimezone(user)))
+	if err != nil {
+		return err
+	}
+
+	if session == nil || session.Item(pageID) == nil {
+		return nil
+	}
+
+	if err := txRepo.ResetReviewSessionItemResult(ctx, session.ID, pageID); err != nil {
+		return err
+	}
+
+	if session.Status == models.ReviewSessionFinished {
+		return txRepo.UpdateReviewSessionStatus(ctx, session.ID, models.ReviewSessionActive, nil)
+	}
+	return nil
+}
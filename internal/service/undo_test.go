@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+// errSessionWrite — сбой записи сессии посреди транзакции отмены
+var errSessionWrite = errors.New("connection reset")
+
+func TestUndoLastGrade(t *testing.T) {
+	now := time.Date(2026, 7, 1, 8, 0, 0, 0, time.UTC)
+	first := models.ReviewSubmission{PageID: "page-1", Grade: 90, Version: 4, Key: "t_first", Source: models.ReviewSourceButton}
+
+	tests := []struct {
+		name string
+		// prepare выполняется после оценки first и перед отменой
+		prepare   func(t *testing.T, svc *Service, repo *reviewRepo, clock *clocktest.ManualClock)
+		reviewKey string
+		wantErr   error
+		// wantUndone — оценка first отменена: прогресс вернулся к состоянию до неё, а страница снова не пройдена
+		wantUndone bool
+	}{
+		{
+			name:       "undone",
+			reviewKey:  "t_first",
+			wantUndone: true,
+		},
+		{
+			// Повторное нажатие той же кнопки отмены ничего не меняет
+			name: "repeat undo",
+			prepare: func(t *testing.T, svc *Service, _ *reviewRepo, _ *clocktest.ManualClock) {
+				if err := svc.UndoLastGrade(context.Background(), 1, "page-1", "t_first"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			reviewKey:  "t_first",
+			wantErr:    ErrNothingToUndo,
+			wantUndone: true,
+		},
+		{
+			// Кнопка отмены старого сообщения не откатывает более позднюю оценку страницы
+			name: "later grade",
+			prepare: func(t *testing.T, svc *Service, _ *reviewRepo, _ *clocktest.ManualClock) {
+				later := models.ReviewSubmission{PageID: "page-1", Grade: 20, Version: 5, Key: "t_later", Source: models.ReviewSourceButton}
+				if err := svc.UpdateReviewProgress(context.Background(), 1, later); err != nil {
+					t.Fatal(err)
+				}
+			},
+			reviewKey: "t_first",
+			wantErr:   ErrNothingToUndo,
+		},
+		{
+			// Кнопка отмены, выданная до появления ключа в токене
+			name:      "no review key",
+			reviewKey: "",
+			wantErr:   ErrNothingToUndo,
+		},
+		{
+			name: "expired",
+			prepare: func(_ *testing.T, _ *Service, _ *reviewRepo, clock *clocktest.ManualClock) {
+				clock.Advance(UndoWindow + time.Second)
+			},
+			reviewKey: "t_first",
+			wantErr:   ErrUndoExpired,
+		},
+		{
+			// Сбой на последнем шаге откатывает и прогресс, и отметку отмены в истории
+			name: "session write fails",
+			prepare: func(_ *testing.T, _ *Service, repo *reviewRepo, _ *clocktest.ManualClock) {
+				repo.resetItemErr = errSessionWrite
+			},
+			reviewKey: "t_first",
+			wantErr:   errSessionWrite,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := &models.UserProgress{UserID: 1, PageID: "page-1", IntervalDays: 3, NextReviewDate: now.Add(-time.Hour), Version: 4}
+			repo := newReviewRepo(original)
+			repo.session = &models.ReviewSession{ID: 1, UserID: 1, Status: models.ReviewSessionActive, Items: []*models.ReviewSessionItem{{SessionID: 1, PageID: "page-1"}}}
+			clock := clocktest.NewManualClock(now)
+			svc := NewService(repo, nil, nil, clock, clocktest.FixedRand(0.5))
+
+			if err := svc.UpdateReviewProgress(context.Background(), 1, first); err != nil {
+				t.Fatal(err)
+			}
+			graded := *repo.progress["page-1"]
+			if repo.session.Status != models.ReviewSessionFinished {
+				t.Fatalf("session status after the grade = %s, want finished", repo.session.Status)
+			}
+			if tt.prepare != nil {
+				tt.prepare(t, svc, repo, clock)
+			}
+			before := *repo.progress["page-1"]
+			beforeSession := copySession(repo.session)
+
+			err := svc.UndoLastGrade(context.Background(), 1, "page-1", tt.reviewKey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			after := repo.progress["page-1"]
+			firstEvent := repo.history[0]
+			if tt.wantUndone {
+				// Прогресс возвращается к состоянию до оценки, а версия растёт дальше, чтобы старые кнопки оценки остались устаревшими
+				restored := *original
+				restored.Version = graded.Version + 1
+				if *after != restored {
+					t.Errorf("progress = %+v, want %+v", *after, restored)
+				}
+				if firstEvent.UndoneAt == nil {
+					t.Error("history event is not marked undone")
+				}
+				if item := repo.session.Item("page-1"); item.Result != nil || repo.session.Status != models.ReviewSessionActive {
+					t.Errorf("session item result %v, status %s, want the page reopened in an active session", item.Result, repo.session.Status)
+				}
+				return
+			}
+
+			if *after != before {
+				t.Errorf("progress changed by a rejected undo: %+v, want %+v", *after, before)
+			}
+			if firstEvent.UndoneAt != nil {
+				t.Error("history event marked undone by a rejected undo")
+			}
+			if item := repo.session.Item("page-1"); item.Result == nil || repo.session.Status != beforeSession.Status {
+				t.Errorf("session changed by a rejected undo: result %v, status %s", item.Result, repo.session.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils"
+)
+
+const (
+	// VacationDateLayout — формат дат отпуска, в котором они показываются пользователю
+	VacationDateLayout = "2006-01-02"
+	// MaxVacationDays ограничивает длину отпуска
+	MaxVacationDays = 90
+)
+
+// vacationDateLayouts — форматы, в которых принимаются даты отпуска
+var vacationDateLayouts = []string{VacationDateLayout, "02.01.2006"}
+
+// ParseVacationDate разбирает дату "2006-01-02" или "02.01.2006" и возвращает полночь этой даты в UTC
+func ParseVacationDate(value string) (time.Time, error) {
+	for _, layout := range vacationDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: vacation date %q", ErrInvalidSetting, value)
+}
+
+// SetVacation назначает отпуск с start по end (местные даты, включительно) и переносит повторения, выпадающие
+// на отпуск, на дни после него. Без spread они занимают первые дни после отпуска, со spread каждое сдвигается
+// на длину отпуска, сохраняя промежутки между повторениями. В обоих случаях в день вместе с уже назначенными
+// повторениями приходится не больше лимита страниц, лишние переходят на следующий день.
+// Возвращает количество перенесённых страниц.
+func (s *Service) SetVacation(ctx context.Context, telegramID int64, start, end time.Time, spread bool) (int, error) {
+	user, err := s.repo.GetUser(ctx, telegramID)
+	if err != nil {
+		return 0, fmt.Errorf("get user (telegram_id: %d): %w", telegramID, err)
+	}
+
+	timezone := userTimezone(user)
+	today := localDate(s.clock.Now(), timezone)
+
+	if end.Before(start) {
+		return 0, fmt.Errorf("%w: vacation ends before it starts", ErrInvalidSetting)
+	}
+	if start.Before(today) {
+		return 0, fmt.Errorf("%w: vacation starts in the past", ErrInvalidSetting)
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+	if days > MaxVacationDays {
+		return 0, fmt.Errorf("%w: vacation of %d days", ErrInvalidSetting, days)
+	}
+
+	windowStart := localMidnight(start, timezone)
+	windowEnd := localMidnight(end.AddDate(0, 0, 1), timezone)
+
+	// Отпуск с сегодняшнего дня забирает и просроченные страницы: иначе они встретят пользователя после отпуска
+	from := windowStart.UTC()
+	if !start.After(today) {
+		from = time.Time{}
+	}
+
+	pages, err := s.repo.GetProgressDueBetween(ctx, telegramID, from, windowEnd.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("get progress due in vacation (telegram_id: %d): %w", telegramID, err)
+	}
+
+	// Дальше этого дня страницы уходят, только если все дни до него уже заполнены
+	firstDay := end.AddDate(0, 0, 1)
+	load, err := s.getDailyLoad(ctx, telegramID, timezone, firstDay, firstDay.AddDate(0, 0, days+len(pages)), userMaxPagesPerDay(user))
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if err := txRepo.UpdateVacation(ctx, telegramID, &start, &end); err != nil {
+			return err
+		}
+
+		day := firstDay
+		for _, progress := range pages {
+			if spread {
+				day = localDate(progress.NextReviewDate, timezone).AddDate(0, 0, days)
+				if day.Before(firstDay) {
+					day = firstDay
+				}
+			}
+			day = load.place(day)
+
+			nextReview := localMidnight(day, timezone).UTC()
+			if err := txRepo.UpdateNextReviewDate(ctx, telegramID, progress.PageID, nextReview); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("set vacation (telegram_id: %d): %w", telegramID, err)
+	}
+
+	return len(pages), nil
+}
+
+// CancelVacation отменяет отпуск. Перенесённые повторения остаются на новых датах. Отмена считается активностью,
+// иначе проверка неактивности сразу после отмены отпуска приостановила бы пользователя.
+func (s *Service) CancelVacation(ctx context.Context, telegramID int64) error {
+	err := s.repo.RunInTx(ctx, func(txRepo models.Repository) error {
+		if err := txRepo.UpdateVacation(ctx, telegramID, nil, nil); err != nil {
+			return err
+		}
+		return txRepo.UpdateUserActivity(ctx, telegramID, s.clock.Now())
+	})
+	if err != nil {
+		return fmt.Errorf("cancel vacation (telegram_id: %d): %w", telegramID, err)
+	}
+	return nil
+}
+
+// OnVacation сообщает, идёт ли у пользователя отпуск в момент now по его местной дате
+func OnVacation(user *models.User, now time.Time) bool {
+	return vacationCovers(localDate(now, userTimezone(user)), user.VacationStart, user.VacationEnd)
+}
+
+// VacationPlanned сообщает, есть ли у пользователя отпуск, который ещё не закончился к моменту now
+func VacationPlanned(user *models.User, now time.Time) bool {
+	if user.VacationStart == nil || user.VacationEnd == nil {
+		return false
+	}
+	return !user.VacationEnd.Before(localDate(now, userTimezone(user)))
+}
+
+// vacationCovers сообщает, попадает ли местная дата date в отпуск [start, end]
+func vacationCovers(date time.Time, start, end *time.Time) bool {
+	if start == nil || end == nil {
+		return false
+	}
+	return !date.Before(*start) && !date.After(*end)
+}
+
+// lastActivityWithVacation — последняя активность пользователя, в которой дни начавшегося отпуска считаются активными
+func lastActivityWithVacation(user *models.User, now time.Time) *time.Time {
+	if user.VacationStart == nil || user.VacationEnd == nil {
+		return user.LastActivityDate
+	}
+
+	timezone := userTimezone(user)
+	if localMidnight(*user.VacationStart, timezone).After(now) {
+		return user.LastActivityDate
+	}
+
+	vacationActivity := localMidnight(user.VacationEnd.AddDate(0, 0, 1), timezone).UTC()
+	if vacationActivity.After(now) {
+		vacationActivity = now
+	}
+
+	if user.LastActivityDate != nil && user.LastActivityDate.After(vacationActivity) {
+		return user.LastActivityDate
+	}
+	return &vacationActivity
+}
+
+// userMaxPagesPerDay — лимит страниц в день пользователя, 2 если он не задан
+func userMaxPagesPerDay(user *models.User) int {
+	if user.MaxPagesPerDay != nil && *user.MaxPagesPerDay > 0 {
+		return int(*user.MaxPagesPerDay)
+	}
+	return 2
+}
+
+// localDate — местная дата now в таймзоне пользователя в виде полуночи UTC, как даты хранятся в колонках date
+func localDate(now time.Time, timezone string) time.Time {
+	local, err := utils.ToUserTimezone(now, timezone)
+	if err != nil {
+		local = now.UTC()
+	}
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// localMidnight — начало местной даты date в таймзоне пользователя, при неизвестной таймзоне — в UTC
+func localMidnight(date time.Time, timezone string) time.Time {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return utils.StartOfDay(time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, loc))
+}
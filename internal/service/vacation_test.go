@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romanzh1/master-english-srs/internal/models"
+	"github.com/romanzh1/master-english-srs/pkg/utils/clocktest"
+)
+
+// vacationRepo — хранилище в памяти для SetVacation: страницы с датами повторения и уже назначенная нагрузка
+type vacationRepo struct {
+	models.Repository
+
+	user  models.User
+	pages []*models.UserProgress
+	load  []*models.DailyLoad
+	moved map[string]time.Time
+}
+
+func (r *vacationRepo) GetUser(context.Context, int64) (*models.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *vacationRepo) GetProgressDueBetween(_ context.Context, _ int64, fromUTC, toUTC time.Time) ([]*models.UserProgress, error) {
+	var due []*models.UserProgress
+	for _, progress := range r.pages {
+		if !progress.NextReviewDate.Before(fromUTC) && progress.NextReviewDate.Before(toUTC) {
+			due = append(due, progress)
+		}
+	}
+	return due, nil
+}
+
+func (r *vacationRepo) GetUpcomingLoad(context.Context, int64, string, time.Time) ([]*models.DailyLoad, error) {
+	return r.load, nil
+}
+
+func (r *vacationRepo) RunInTx(_ context.Context, fn func(models.Repository) error) error {
+	return fn(r)
+}
+
+func (r *vacationRepo) UpdateVacation(_ context.Context, _ int64, start, end *time.Time) error {
+	r.user.VacationStart, r.user.VacationEnd = start, end
+	return nil
+}
+
+func (r *vacationRepo) UpdateNextReviewDate(_ context.Context, _ int64, pageID string, nextReviewDate time.Time) error {
+	r.moved[pageID] = nextReviewDate
+	return nil
+}
+
+func TestSetVacationFillsDaysUpToLimit(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(day int) time.Time {
+		return time.Date(2026, 7, day, 0, 0, 0, 0, time.UTC)
+	}
+	midnight := func(day int) time.Time {
+		return time.Date(2026, 7, day, 0, 0, 0, 0, berlin).UTC()
+	}
+
+	tests := []struct {
+		name   string
+		spread bool
+		want   map[string]int
+	}{
+		{
+			// Первые дни после отпуска заполняются по порядку: 6 июля уже есть одно повторение, 7 июля — два
+			name: "first days after the vacation",
+			want: map[string]int{"page-1": 6, "page-2": 8, "page-3": 8, "page-4": 9},
+		},
+		{
+			// Каждое повторение сдвигается на пять дней отпуска и уходит дальше, только если день заполнен
+			name:   "shifted by the vacation length",
+			spread: true,
+			want:   map[string]int{"page-1": 6, "page-2": 8, "page-3": 8, "page-4": 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timezone := "Europe/Berlin"
+			maxPagesPerDay := uint(2)
+			repo := &vacationRepo{
+				user: models.User{TelegramID: 1, Timezone: &timezone, MaxPagesPerDay: &maxPagesPerDay},
+				pages: []*models.UserProgress{
+					{PageID: "page-1", NextReviewDate: midnight(1)},
+					{PageID: "page-2", NextReviewDate: midnight(1)},
+					{PageID: "page-3", NextReviewDate: midnight(3)},
+					{PageID: "page-4", NextReviewDate: midnight(5)},
+				},
+				// Повторения внутри отпуска переносятся сами и в нагрузке не учитываются
+				load: []*models.DailyLoad{
+					{Date: date(1), Pages: 2},
+					{Date: date(3), Pages: 1},
+					{Date: date(5), Pages: 1},
+					{Date: date(6), Pages: 1},
+					{Date: date(7), Pages: 2},
+				},
+				moved: make(map[string]time.Time),
+			}
+
+			svc := NewService(repo, nil, nil, clocktest.NewManualClock(time.Date(2026, 6, 20, 9, 0, 0, 0, time.UTC)), clocktest.FixedRand(0.5))
+			shifted, err := svc.SetVacation(context.Background(), 1, date(1), date(5), tt.spread)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if shifted != len(repo.pages) {
+				t.Fatalf("shifted %d pages, want %d", shifted, len(repo.pages))
+			}
+
+			for pageID, day := range tt.want {
+				if got := repo.moved[pageID]; !got.Equal(midnight(day)) {
+					t.Errorf("%s moved to %v, want %v", pageID, got, midnight(day))
+				}
+			}
+		})
+	}
+}
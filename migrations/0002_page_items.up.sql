@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS page_items (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    page_id varchar(255) NOT NULL,
+    position integer NOT NULL,
+    word text NOT NULL,
+    translation text NOT NULL,
+    example text,
+    created_at timestamptz DEFAULT NOW(),
+    updated_at timestamptz,
+    UNIQUE (user_id, page_id, word),
+    FOREIGN KEY (page_id, user_id) REFERENCES page_references (page_id, user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_page_items_page ON page_items (user_id, page_id, position);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_page_items_page;
+
+DROP TABLE IF EXISTS page_items;
@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_sources (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    notebook_id varchar(255) NOT NULL,
+    section_id varchar(255) NOT NULL,
+    notebook_name text,
+    section_name text,
+    enabled boolean NOT NULL DEFAULT TRUE,
+    priority integer NOT NULL DEFAULT 0,
+    created_at timestamptz DEFAULT NOW(),
+    UNIQUE (user_id, section_id),
+    FOREIGN KEY (user_id) REFERENCES users (telegram_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sources_user ON user_sources (user_id, priority);
+
+ALTER TABLE page_references ADD COLUMN IF NOT EXISTS section_id varchar(255);
+
+-- Переносим текущую секцию каждого пользователя в список источников
+INSERT INTO user_sources (user_id, notebook_id, section_id)
+SELECT telegram_id, onenote_notebook_id, onenote_section_id
+FROM users
+WHERE onenote_notebook_id IS NOT NULL AND onenote_notebook_id <> ''
+  AND onenote_section_id IS NOT NULL AND onenote_section_id <> ''
+ON CONFLICT (user_id, section_id) DO NOTHING;
+
+UPDATE page_references p
+SET section_id = u.onenote_section_id
+FROM users u
+WHERE p.user_id = u.telegram_id AND p.section_id IS NULL AND u.onenote_section_id <> '';
+
+-- +goose Down
+ALTER TABLE page_references DROP COLUMN IF EXISTS section_id;
+
+DROP INDEX IF EXISTS idx_user_sources_user;
+
+DROP TABLE IF EXISTS user_sources;
@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE page_references ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+ALTER TABLE user_sources ADD COLUMN IF NOT EXISTS sync_cursor timestamptz;
+
+-- Перенос страницы (новый page_id при перемещении между секциями) должен переносить историю и слова
+ALTER TABLE progress_history DROP CONSTRAINT IF EXISTS progress_history_user_id_page_id_fkey;
+ALTER TABLE progress_history ADD CONSTRAINT progress_history_user_id_page_id_fkey
+    FOREIGN KEY (user_id, page_id) REFERENCES user_progress (user_id, page_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE page_items DROP CONSTRAINT IF EXISTS page_items_page_id_user_id_fkey;
+ALTER TABLE page_items ADD CONSTRAINT page_items_page_id_user_id_fkey
+    FOREIGN KEY (page_id, user_id) REFERENCES page_references (page_id, user_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- +goose Down
+ALTER TABLE page_items DROP CONSTRAINT IF EXISTS page_items_page_id_user_id_fkey;
+ALTER TABLE page_items ADD CONSTRAINT page_items_page_id_user_id_fkey
+    FOREIGN KEY (page_id, user_id) REFERENCES page_references (page_id, user_id) ON DELETE CASCADE;
+
+ALTER TABLE progress_history DROP CONSTRAINT IF EXISTS progress_history_user_id_page_id_fkey;
+ALTER TABLE progress_history ADD CONSTRAINT progress_history_user_id_page_id_fkey
+    FOREIGN KEY (user_id, page_id) REFERENCES user_progress (user_id, page_id) ON DELETE CASCADE;
+
+ALTER TABLE user_sources DROP COLUMN IF EXISTS sync_cursor;
+
+ALTER TABLE page_references DROP COLUMN IF EXISTS deleted_at;
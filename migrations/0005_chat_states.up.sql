@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS chat_states (
+    chat_id bigint PRIMARY KEY,
+    user_id bigint NOT NULL,
+    state varchar(64) NOT NULL,
+    data jsonb NOT NULL DEFAULT '{}',
+    updated_at timestamptz DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS chat_states;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language varchar(8) NOT NULL DEFAULT 'ru';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS language;
@@ -0,0 +1,43 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reminders_enabled boolean NOT NULL DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_start varchar(5) NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_end varchar(5) NULL;
+
+CREATE TABLE IF NOT EXISTS reminder_times (
+    user_id bigint NOT NULL,
+    reminder_time varchar(5) NOT NULL,
+    PRIMARY KEY (user_id, reminder_time),
+    FOREIGN KEY (user_id) REFERENCES users (telegram_id) ON DELETE CASCADE
+);
+
+INSERT INTO reminder_times (user_id, reminder_time)
+SELECT telegram_id, reminder_time FROM users WHERE reminder_time IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS reminder_time;
+
+-- Отправленные напоминания: строка занимает напоминание на локальную дату пользователя,
+-- поэтому перезапуск и несколько реплик не отправляют его повторно
+CREATE TABLE IF NOT EXISTS reminder_log (
+    user_id bigint NOT NULL,
+    local_date date NOT NULL,
+    reminder_time varchar(5) NOT NULL,
+    sent_at timestamptz DEFAULT NOW(),
+    PRIMARY KEY (user_id, local_date, reminder_time),
+    FOREIGN KEY (user_id) REFERENCES users (telegram_id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS reminder_log;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reminder_time varchar(10) DEFAULT '09:00';
+
+UPDATE users u SET reminder_time = r.reminder_time
+FROM (SELECT user_id, MIN(reminder_time) AS reminder_time FROM reminder_times GROUP BY user_id) r
+WHERE r.user_id = u.telegram_id;
+
+DROP TABLE IF EXISTS reminder_times;
+
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_end;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_start;
+ALTER TABLE users DROP COLUMN IF EXISTS reminders_enabled;
@@ -0,0 +1,44 @@
+-- +goose Up
+-- Фоновые задачи пользователя: у каждого пользователя одна задача каждого типа со временем следующего запуска
+CREATE TABLE IF NOT EXISTS jobs (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    job_type varchar(32) NOT NULL,
+    next_run_at timestamptz NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text NULL,
+    locked_until timestamptz NULL,
+    updated_at timestamptz DEFAULT NOW(),
+    UNIQUE (user_id, job_type),
+    FOREIGN KEY (user_id) REFERENCES users (telegram_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_next_run_at ON jobs (next_run_at);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id bigserial PRIMARY KEY,
+    job_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    job_type varchar(32) NOT NULL,
+    attempt integer NOT NULL,
+    status varchar(16) NOT NULL,
+    error text NULL,
+    started_at timestamptz NOT NULL,
+    finished_at timestamptz NOT NULL,
+    FOREIGN KEY (job_id) REFERENCES jobs (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_user_id ON job_runs (user_id, started_at);
+CREATE INDEX IF NOT EXISTS idx_job_runs_finished_at ON job_runs (finished_at);
+
+-- Задачи существующих пользователей запускаются сразу: смена дня защищена last_cron_processed_at,
+-- напоминания — reminder_log, поэтому повторной обработки за сегодня не будет
+INSERT INTO jobs (user_id, job_type, next_run_at)
+SELECT u.telegram_id, t.job_type, NOW()
+FROM users u
+CROSS JOIN (VALUES ('rollover'), ('reminder'), ('inactivity')) AS t (job_type)
+ON CONFLICT (user_id, job_type) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS job_runs;
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Отпуск пользователя: даты по его местному времени, оба дня включительно
+ALTER TABLE users ADD COLUMN IF NOT EXISTS vacation_start date NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS vacation_end date NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS vacation_end;
+ALTER TABLE users DROP COLUMN IF EXISTS vacation_start;
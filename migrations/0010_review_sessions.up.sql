@@ -0,0 +1,28 @@
+-- +goose Up
+-- Сессия повторения на местную дату пользователя: очередь страниц на сегодня и результат по каждой
+CREATE TABLE IF NOT EXISTS review_sessions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    local_date date NOT NULL,
+    status varchar(16) NOT NULL DEFAULT 'active',
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    finished_at timestamptz NULL,
+    UNIQUE (user_id, local_date),
+    FOREIGN KEY (user_id) REFERENCES users (telegram_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS review_session_items (
+    session_id bigint NOT NULL,
+    position integer NOT NULL,
+    page_id varchar(255) NOT NULL,
+    result varchar(16) NULL,
+    grade integer NULL,
+    reviewed_at timestamptz NULL,
+    PRIMARY KEY (session_id, position),
+    UNIQUE (session_id, page_id),
+    FOREIGN KEY (session_id) REFERENCES review_sessions (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS review_session_items;
+DROP TABLE IF EXISTS review_sessions;
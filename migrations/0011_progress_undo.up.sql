@@ -0,0 +1,8 @@
+-- +goose Up
+-- Состояние user_progress до оценки, чтобы ошибочную оценку можно было отменить
+ALTER TABLE progress_history ADD COLUMN IF NOT EXISTS previous_progress jsonb NULL;
+ALTER TABLE progress_history ADD COLUMN IF NOT EXISTS undone_at timestamptz NULL;
+
+-- +goose Down
+ALTER TABLE progress_history DROP COLUMN IF EXISTS undone_at;
+ALTER TABLE progress_history DROP COLUMN IF EXISTS previous_progress;
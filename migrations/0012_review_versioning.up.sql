@@ -0,0 +1,12 @@
+-- +goose Up
+-- Версия прогресса для оптимистичной блокировки: оценка применяется, только если прогресс не менялся с показа страницы
+ALTER TABLE user_progress ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 0;
+
+-- Ключ идемпотентности оценки: повторная доставка той же кнопки или сообщения не оценивает страницу второй раз
+ALTER TABLE progress_history ADD COLUMN IF NOT EXISTS review_key varchar(64) NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_progress_history_review_key ON progress_history (user_id, review_key) WHERE review_key IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_progress_history_review_key;
+ALTER TABLE progress_history DROP COLUMN IF EXISTS review_key;
+ALTER TABLE user_progress DROP COLUMN IF EXISTS version;
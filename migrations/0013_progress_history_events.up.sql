@@ -0,0 +1,24 @@
+-- +goose Up
+-- Несколько оценок страницы в одну и ту же секунду не должны конфликтовать по первичному ключу
+ALTER TABLE progress_history DROP CONSTRAINT IF EXISTS progress_history_pkey;
+ALTER TABLE progress_history ADD COLUMN IF NOT EXISTS id bigserial;
+ALTER TABLE progress_history ADD PRIMARY KEY (id);
+CREATE INDEX IF NOT EXISTS idx_progress_history_page ON progress_history (user_id, page_id, date);
+
+-- Полное состояние прогресса до и после оценки, время ответа и источник оценки
+ALTER TABLE progress_history RENAME COLUMN previous_progress TO progress_before;
+ALTER TABLE progress_history ADD COLUMN IF NOT EXISTS progress_after jsonb NULL;
+ALTER TABLE progress_history ADD COLUMN IF NOT EXISTS response_ms bigint NULL;
+ALTER TABLE progress_history ADD COLUMN IF NOT EXISTS source varchar(16) NULL;
+
+-- +goose Down
+ALTER TABLE progress_history DROP COLUMN IF EXISTS source;
+ALTER TABLE progress_history DROP COLUMN IF EXISTS response_ms;
+ALTER TABLE progress_history DROP COLUMN IF EXISTS progress_after;
+ALTER TABLE progress_history RENAME COLUMN progress_before TO previous_progress;
+
+-- Старый ключ восстановится, только если в истории нет двух оценок страницы с одинаковым временем
+DROP INDEX IF EXISTS idx_progress_history_page;
+ALTER TABLE progress_history DROP CONSTRAINT IF EXISTS progress_history_pkey;
+ALTER TABLE progress_history DROP COLUMN IF EXISTS id;
+ALTER TABLE progress_history ADD PRIMARY KEY (user_id, page_id, date);
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Слова страницы извлекаются при синхронизации; NULL — страница новая или изменилась и слова нужно извлечь заново
+ALTER TABLE page_references ADD COLUMN IF NOT EXISTS items_extracted_at timestamptz;
+
+-- +goose Down
+ALTER TABLE page_references DROP COLUMN IF EXISTS items_extracted_at;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Время последней сверки секции полным списком страниц; между сверками запрашиваются только изменённые страницы
+ALTER TABLE user_sources ADD COLUMN IF NOT EXISTS full_synced_at timestamptz;
+
+-- +goose Down
+ALTER TABLE user_sources DROP COLUMN IF EXISTS full_synced_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Токены кнопок со ссылкой на страницу: хранятся в базе, чтобы кнопки работали после перезапуска и на любой реплике
+CREATE TABLE IF NOT EXISTS callback_tokens (
+    token varchar(16) PRIMARY KEY,
+    user_id bigint NOT NULL,
+    page_id varchar(255) NOT NULL,
+    action varchar(16) NOT NULL,
+    grade integer NOT NULL DEFAULT 0,
+    version integer NOT NULL DEFAULT 0,
+    issued_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (telegram_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_callback_tokens_page ON callback_tokens (user_id, page_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_callback_tokens_page;
+
+DROP TABLE IF EXISTS callback_tokens;
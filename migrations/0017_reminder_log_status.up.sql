@@ -0,0 +1,12 @@
+-- +goose Up
+-- Напоминание сначала занимается (pending) на время аренды claimed_until и отмечается отправленным (sent) после
+-- отправки. Занятое напоминание, аренда которого истекла (процесс упал до отправки), занимается заново.
+ALTER TABLE reminder_log ADD COLUMN IF NOT EXISTS status varchar(16) NOT NULL DEFAULT 'sent';
+ALTER TABLE reminder_log ADD COLUMN IF NOT EXISTS claimed_until timestamptz NULL;
+ALTER TABLE reminder_log ALTER COLUMN sent_at DROP DEFAULT;
+
+-- +goose Down
+ALTER TABLE reminder_log ALTER COLUMN sent_at SET DEFAULT NOW();
+DELETE FROM reminder_log WHERE status <> 'sent';
+ALTER TABLE reminder_log DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE reminder_log DROP COLUMN IF EXISTS status;
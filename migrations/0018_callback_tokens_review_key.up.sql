@@ -0,0 +1,7 @@
+-- +goose Up
+-- Кнопка отмены ссылается на конкретную оценку по её ключу идемпотентности, чтобы старое сообщение
+-- не отменило более позднюю оценку той же страницы
+ALTER TABLE callback_tokens ADD COLUMN IF NOT EXISTS review_key varchar(64) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE callback_tokens DROP COLUMN IF EXISTS review_key;
@@ -0,0 +1,170 @@
+// Package chart рисует простые столбчатые диаграммы в PNG только средствами стандартной библиотеки.
+// Текст ограничен глифами встроенного растрового шрифта 3x5 (цифры и несколько символов),
+// поэтому заголовки и легенды пишутся в сообщении, к которому приложена картинка.
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+const (
+	width       = 720
+	panelHeight = 220
+	padding     = 24
+	// labelArea — место под столбцами для подписей категорий
+	labelArea = 22
+	// valueArea — место над самым высоким столбцом для подписи его значения
+	valueArea = 18
+	// glyphScale — размер точки растрового шрифта в пикселях
+	glyphScale = 2
+)
+
+var (
+	background = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	axis       = color.RGBA{R: 180, G: 180, B: 180, A: 255}
+	textColor  = color.RGBA{R: 60, G: 60, B: 60, A: 255}
+)
+
+// Цвета палитры для панелей
+var (
+	Blue   = color.RGBA{R: 66, G: 133, B: 244, A: 255}
+	Green  = color.RGBA{R: 52, G: 168, B: 83, A: 255}
+	Orange = color.RGBA{R: 251, G: 140, B: 0, A: 255}
+	Purple = color.RGBA{R: 142, G: 68, B: 173, A: 255}
+)
+
+// Panel — одна столбчатая диаграмма. Labels и ValueLabels могут содержать только цифры и символы % . - / : +,
+// остальные символы пропускаются. Пустая подпись значения скрывает подпись этого столбца.
+type Panel struct {
+	Labels      []string
+	Values      []float64
+	ValueLabels []string
+	// Max — значение столбца во всю высоту, 0 — наибольшее из значений
+	Max   float64
+	Color color.RGBA
+}
+
+// Render рисует панели одну под другой и кодирует картинку в PNG
+func Render(panels []Panel) ([]byte, error) {
+	if len(panels) == 0 {
+		return nil, fmt.Errorf("no panels to render")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, panelHeight*len(panels)))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+	for i, panel := range panels {
+		if len(panel.Labels) != len(panel.Values) {
+			return nil, fmt.Errorf("panel %d: %d labels for %d values", i, len(panel.Labels), len(panel.Values))
+		}
+		if panel.ValueLabels != nil && len(panel.ValueLabels) != len(panel.Values) {
+			return nil, fmt.Errorf("panel %d: %d value labels for %d values", i, len(panel.ValueLabels), len(panel.Values))
+		}
+		drawPanel(img, image.Rect(0, panelHeight*i, width, panelHeight*(i+1)), panel)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func drawPanel(img *image.RGBA, area image.Rectangle, panel Panel) {
+	plot := image.Rect(area.Min.X+padding, area.Min.Y+padding+valueArea, area.Max.X-padding, area.Max.Y-padding-labelArea)
+
+	fill(img, image.Rect(plot.Min.X, plot.Max.Y, plot.Max.X, plot.Max.Y+1), axis)
+
+	if len(panel.Values) == 0 {
+		return
+	}
+
+	maxValue := panel.Max
+	if maxValue <= 0 {
+		for _, value := range panel.Values {
+			maxValue = max(maxValue, value)
+		}
+	}
+
+	slot := plot.Dx() / len(panel.Values)
+	barWidth := max(slot*7/10, 1)
+
+	for i, value := range panel.Values {
+		center := plot.Min.X + slot*i + slot/2
+
+		barHeight := 0
+		if maxValue > 0 && value > 0 {
+			barHeight = max(int(float64(plot.Dy())*min(value/maxValue, 1)), 1)
+		}
+
+		top := plot.Max.Y - barHeight
+		fill(img, image.Rect(center-barWidth/2, top, center-barWidth/2+barWidth, plot.Max.Y), panel.Color)
+
+		if panel.ValueLabels != nil && panel.ValueLabels[i] != "" {
+			drawText(img, panel.ValueLabels[i], center, top-glyphHeight*glyphScale-4)
+		}
+		drawText(img, panel.Labels[i], center, plot.Max.Y+6)
+	}
+}
+
+func fill(img *image.RGBA, rect image.Rectangle, c color.RGBA) {
+	draw.Draw(img, rect, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+// glyphs — растровый шрифт 3x5: каждая строка — 3 бита, старший бит — левая точка
+var glyphs = map[rune][glyphHeight]uint8{
+	'0': {0b111, 0b101, 0b101, 0b101, 0b111},
+	'1': {0b010, 0b110, 0b010, 0b010, 0b111},
+	'2': {0b111, 0b001, 0b111, 0b100, 0b111},
+	'3': {0b111, 0b001, 0b111, 0b001, 0b111},
+	'4': {0b101, 0b101, 0b111, 0b001, 0b001},
+	'5': {0b111, 0b100, 0b111, 0b001, 0b111},
+	'6': {0b111, 0b100, 0b111, 0b101, 0b111},
+	'7': {0b111, 0b001, 0b010, 0b010, 0b010},
+	'8': {0b111, 0b101, 0b111, 0b101, 0b111},
+	'9': {0b111, 0b101, 0b111, 0b001, 0b111},
+	'%': {0b101, 0b001, 0b010, 0b100, 0b101},
+	'.': {0b000, 0b000, 0b000, 0b000, 0b010},
+	'-': {0b000, 0b000, 0b111, 0b000, 0b000},
+	'/': {0b001, 0b001, 0b010, 0b100, 0b100},
+	':': {0b000, 0b010, 0b000, 0b010, 0b000},
+	'+': {0b000, 0b010, 0b111, 0b010, 0b000},
+}
+
+// drawText рисует текст по центру centerX, верх текста — на y
+func drawText(img *image.RGBA, text string, centerX, y int) {
+	var runes []rune
+	for _, r := range text {
+		if _, ok := glyphs[r]; ok {
+			runes = append(runes, r)
+		}
+	}
+
+	advance := (glyphWidth + 1) * glyphScale
+	x := centerX - (len(runes)*advance-glyphScale)/2
+
+	for _, r := range runes {
+		glyph := glyphs[r]
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row]&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				dotX := x + col*glyphScale
+				dotY := y + row*glyphScale
+				fill(img, image.Rect(dotX, dotY, dotX+glyphScale, dotY+glyphScale), textColor)
+			}
+		}
+		x += advance
+	}
+}
@@ -0,0 +1,114 @@
+package chart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// decode проверяет, что Render вернул корректный PNG нужного размера
+func decode(t *testing.T, data []byte, panels int) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	if got, want := img.Bounds(), image.Rect(0, 0, width, panelHeight*panels); got != want {
+		t.Fatalf("image bounds = %v, want %v", got, want)
+	}
+	return img
+}
+
+// countColor считает пиксели цвета c
+func countColor(img image.Image, c color.RGBA) int {
+	count := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if color.RGBAModel.Convert(img.At(x, y)) == c {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestRenderRejectsMismatchedPanels(t *testing.T) {
+	tests := []struct {
+		name   string
+		panels []Panel
+	}{
+		{
+			name: "no panels",
+		},
+		{
+			name:   "labels",
+			panels: []Panel{{Labels: []string{"1", "2"}, Values: []float64{1, 2, 3}}},
+		},
+		{
+			// Ошибка во второй панели, первая корректна
+			name: "value labels",
+			panels: []Panel{
+				{Labels: []string{"1"}, Values: []float64{1}},
+				{Labels: []string{"1", "2"}, Values: []float64{1, 2}, ValueLabels: []string{"1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Render(tt.panels)
+			if err == nil {
+				t.Errorf("no error, got %d bytes", len(data))
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		panels []Panel
+		// wantBars — есть ли на картинке столбцы цвета панели
+		wantBars bool
+	}{
+		{
+			name: "bars",
+			panels: []Panel{
+				{Labels: []string{"1", "2", "3"}, Values: []float64{3, 0, 7}, ValueLabels: []string{"3", "", "7"}, Color: Blue},
+				{Labels: []string{"1", "2", "3"}, Values: []float64{50, 80, 120}, Max: 100, Color: Green},
+			},
+			wantBars: true,
+		},
+		{
+			// Без ненулевых значений масштаб не из чего посчитать: рисуется только ось
+			name:   "all zero",
+			panels: []Panel{{Labels: []string{"1", "2"}, Values: []float64{0, 0}, ValueLabels: []string{"0", "0"}, Color: Orange}},
+		},
+		{
+			name:   "empty panel",
+			panels: []Panel{{Color: Purple}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Render(tt.panels)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img := decode(t, data, len(tt.panels))
+
+			for i, panel := range tt.panels {
+				if bars := countColor(img, panel.Color) > 0; bars != tt.wantBars {
+					t.Errorf("panel %d has bars = %v, want %v", i, bars, tt.wantBars)
+				}
+			}
+			if countColor(img, axis) == 0 {
+				t.Error("no axis drawn")
+			}
+		})
+	}
+}
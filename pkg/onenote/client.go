@@ -5,8 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
-	"strings"
+	neturl "net/url"
 	"time"
 )
 
@@ -46,15 +45,122 @@ func (c *Client) GetSections(accessToken, notebookID string) ([]Section, error)
 	return response.Value, nil
 }
 
+func (c *Client) GetSectionGroups(accessToken, notebookID string) ([]SectionGroup, error) {
+	url := fmt.Sprintf("%s/me/onenote/notebooks/%s/sectionGroups", graphAPIBase, notebookID)
+
+	var response SectionGroupsResponse
+	if err := c.makeRequest(accessToken, url, &response); err != nil {
+		return nil, fmt.Errorf("get section groups (notebook_id: %s): %w", notebookID, err)
+	}
+
+	return response.Value, nil
+}
+
+func (c *Client) GetChildSectionGroups(accessToken, sectionGroupID string) ([]SectionGroup, error) {
+	url := fmt.Sprintf("%s/me/onenote/sectionGroups/%s/sectionGroups", graphAPIBase, sectionGroupID)
+
+	var response SectionGroupsResponse
+	if err := c.makeRequest(accessToken, url, &response); err != nil {
+		return nil, fmt.Errorf("get child section groups (section_group_id: %s): %w", sectionGroupID, err)
+	}
+
+	return response.Value, nil
+}
+
+func (c *Client) GetSectionGroupSections(accessToken, sectionGroupID string) ([]Section, error) {
+	url := fmt.Sprintf("%s/me/onenote/sectionGroups/%s/sections", graphAPIBase, sectionGroupID)
+
+	var response SectionsResponse
+	if err := c.makeRequest(accessToken, url, &response); err != nil {
+		return nil, fmt.Errorf("get section group sections (section_group_id: %s): %w", sectionGroupID, err)
+	}
+
+	return response.Value, nil
+}
+
+// GetAllSections returns every section of the notebook including sections nested in section groups at any depth.
+// Path of each section is set to the names of the groups it is nested in.
+func (c *Client) GetAllSections(accessToken, notebookID string) ([]Section, error) {
+	sections, err := c.GetSections(accessToken, notebookID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := c.GetSectionGroups(accessToken, notebookID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		nested, err := c.collectGroupSections(accessToken, group, nil)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, nested...)
+	}
+
+	return sections, nil
+}
+
+func (c *Client) collectGroupSections(accessToken string, group SectionGroup, parentPath []string) ([]Section, error) {
+	path := append(append([]string(nil), parentPath...), group.DisplayName)
+
+	sections, err := c.GetSectionGroupSections(accessToken, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sections {
+		sections[i].Path = path
+	}
+
+	children, err := c.GetChildSectionGroups(accessToken, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children {
+		nested, err := c.collectGroupSections(accessToken, child, path)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, nested...)
+	}
+
+	return sections, nil
+}
+
 func (c *Client) GetPages(accessToken, sectionID string) ([]Page, error) {
 	url := fmt.Sprintf("%s/me/onenote/sections/%s/pages?$select=id,title,lastModifiedDateTime,createdDateTime&$top=100", graphAPIBase, sectionID)
 
+	pages, err := c.listPages(accessToken, url)
+	if err != nil {
+		return nil, fmt.Errorf("get pages (section_id: %s): %w", sectionID, err)
+	}
+	return pages, nil
+}
+
+// GetPagesModifiedSince returns only the section pages modified at or after since.
+// Deleted pages are not reported, so callers still need GetPages to detect removals.
+func (c *Client) GetPagesModifiedSince(accessToken, sectionID string, since time.Time) ([]Page, error) {
+	filter := neturl.QueryEscape("lastModifiedDateTime ge " + since.UTC().Format(time.RFC3339))
+	url := fmt.Sprintf("%s/me/onenote/sections/%s/pages?$select=id,title,lastModifiedDateTime,createdDateTime&$top=100&$filter=%s", graphAPIBase, sectionID, filter)
+
+	pages, err := c.listPages(accessToken, url)
+	if err != nil {
+		return nil, fmt.Errorf("get modified pages (section_id: %s): %w", sectionID, err)
+	}
+	return pages, nil
+}
+
+// listPages follows @odata.nextLink until all pages of the listing are fetched.
+func (c *Client) listPages(accessToken, url string) ([]Page, error) {
 	var allPages []Page
 
 	for url != "" {
 		var response PagesResponse
 		if err := c.makeRequest(accessToken, url, &response); err != nil {
-			return nil, fmt.Errorf("get pages (section_id: %s): %w", sectionID, err)
+			return nil, err
 		}
 
 		allPages = append(allPages, response.Value...)
@@ -65,6 +171,15 @@ func (c *Client) GetPages(accessToken, sectionID string) ([]Page, error) {
 }
 
 func (c *Client) GetPageContent(accessToken, pageID string) (string, error) {
+	content, err := c.GetPageHTML(accessToken, pageID)
+	if err != nil {
+		return "", err
+	}
+
+	return RenderHTML(content), nil
+}
+
+func (c *Client) GetPageHTML(accessToken, pageID string) (string, error) {
 	url := fmt.Sprintf("%s/me/onenote/pages/%s/content", graphAPIBase, pageID)
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -90,8 +205,7 @@ func (c *Client) GetPageContent(accessToken, pageID string) (string, error) {
 		return "", fmt.Errorf("read response body (page_id: %s): %w", pageID, err)
 	}
 
-	content := c.extractTextFromHTML(string(bodyBytes))
-	return content, nil
+	return string(bodyBytes), nil
 }
 
 func (c *Client) makeRequest(accessToken, url string, result interface{}) error {
@@ -119,25 +233,3 @@ func (c *Client) makeRequest(accessToken, url string, result interface{}) error
 
 	return nil
 }
-
-func (c *Client) extractTextFromHTML(html string) string {
-	re := regexp.MustCompile(`<[^>]*>`)
-	text := re.ReplaceAllString(html, "")
-
-	text = strings.ReplaceAll(text, "&nbsp;", " ")
-	text = strings.ReplaceAll(text, "&amp;", "&")
-	text = strings.ReplaceAll(text, "&lt;", "<")
-	text = strings.ReplaceAll(text, "&gt;", ">")
-	text = strings.ReplaceAll(text, "&quot;", "\"")
-
-	lines := strings.Split(text, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" {
-			cleanLines = append(cleanLines, trimmed)
-		}
-	}
-
-	return strings.Join(cleanLines, "\n")
-}
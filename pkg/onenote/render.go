@@ -0,0 +1,458 @@
+package onenote
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// RenderHTML converts OneNote page HTML into text formatted for Telegram HTML parse mode.
+// Structure is preserved: paragraphs and headings become lines, lists keep their bullets,
+// two-column tables become "word — translation" pairs and wider tables become aligned rows.
+// Emphasis is mapped to the tags Telegram supports, all other markup is dropped and
+// the resulting text is escaped.
+func RenderHTML(content string) string {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return escapeText(content)
+	}
+
+	r := &renderer{}
+	root := findElement(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+	r.blocks(root, "")
+
+	return strings.TrimRight(r.out.String(), "\n")
+}
+
+type renderer struct {
+	out       strings.Builder
+	marker    string
+	needBlank bool
+}
+
+func (r *renderer) blocks(n *html.Node, indent string) {
+	var inline strings.Builder
+	flush := func() {
+		r.writeLine(indent, inline.String())
+		inline.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.ElementNode && c.DataAtom == atom.Br:
+			flush()
+		case isBlock(c):
+			flush()
+			r.block(c, indent)
+		default:
+			r.inline(&inline, c)
+		}
+	}
+	flush()
+}
+
+func (r *renderer) block(n *html.Node, indent string) {
+	switch n.DataAtom {
+	case atom.Head, atom.Script, atom.Style, atom.Title:
+		return
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		var b strings.Builder
+		r.inlineChildren(&b, n)
+		r.blank()
+		r.writeLine(indent, wrapTag("b", "", b.String()))
+		r.blank()
+	case atom.Ul, atom.Ol:
+		r.list(n, indent)
+	case atom.Table:
+		r.blank()
+		r.table(n, indent)
+		r.blank()
+	case atom.Pre:
+		text := strings.Trim(textContent(n), "\n")
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		r.blank()
+		r.writeRaw(indent, "<pre>"+escapeText(text)+"</pre>")
+		r.blank()
+	case atom.Hr:
+		r.blank()
+	default:
+		r.blocks(n, indent)
+	}
+}
+
+func (r *renderer) list(n *html.Node, indent string) {
+	ordered := n.DataAtom == atom.Ol
+	number := 1
+	if start, err := strconv.Atoi(getAttr(n, "start")); err == nil && ordered {
+		number = start
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+
+		if c.DataAtom != atom.Li {
+			// Nested list without a wrapping <li>, OneNote sometimes emits levels this way
+			if c.DataAtom == atom.Ul || c.DataAtom == atom.Ol {
+				r.list(c, indent+"   ")
+			}
+			continue
+		}
+
+		marker := "• "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+
+		r.marker = indent + marker
+		r.blocks(c, indent+strings.Repeat(" ", utf8.RuneCountInString(marker)))
+		r.marker = ""
+	}
+}
+
+type tableCell struct {
+	html   string
+	plain  string
+	header bool
+}
+
+func (r *renderer) table(n *html.Node, indent string) {
+	var rows [][]tableCell
+	columns := 0
+
+	for _, tr := range findRows(n) {
+		var row []tableCell
+		empty := true
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || (c.DataAtom != atom.Td && c.DataAtom != atom.Th) {
+				continue
+			}
+
+			cell := renderCell(c)
+			if cell.plain != "" {
+				empty = false
+			}
+			row = append(row, cell)
+		}
+
+		if empty {
+			continue
+		}
+
+		rows = append(rows, row)
+		columns = max(columns, len(row))
+	}
+
+	switch {
+	case columns == 0:
+		return
+	case columns <= 2:
+		for _, row := range rows {
+			parts := make([]string, 0, len(row))
+			header := true
+			for _, cell := range row {
+				if cell.html != "" {
+					parts = append(parts, cell.html)
+				}
+				header = header && cell.header
+			}
+
+			line := strings.Join(parts, " — ")
+			if header {
+				line = wrapTag("b", "", line)
+			}
+			r.writeLine(indent, line)
+		}
+	default:
+		r.writeRaw(indent, "<pre>"+escapeText(alignRows(rows, columns))+"</pre>")
+	}
+}
+
+func renderCell(n *html.Node) tableCell {
+	cr := &renderer{}
+	cr.blocks(n, "")
+
+	lines := strings.Split(strings.TrimRight(cr.out.String(), "\n"), "\n")
+	parts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+
+	return tableCell{
+		html:   strings.Join(parts, "; "),
+		plain:  collapseSpaces(strings.TrimSpace(textContent(n))),
+		header: n.DataAtom == atom.Th,
+	}
+}
+
+func alignRows(rows [][]tableCell, columns int) string {
+	widths := make([]int, columns)
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell.plain))
+		}
+	}
+
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]string, columns)
+		for i := range columns {
+			value := ""
+			if i < len(row) {
+				value = row[i].plain
+			}
+			cells[i] = value + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(value))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, " | "), " "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (r *renderer) inlineChildren(b *strings.Builder, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (c.DataAtom == atom.Br || isBlock(c)) {
+			b.WriteString(" ")
+			r.inlineChildren(b, c)
+			b.WriteString(" ")
+			continue
+		}
+		r.inline(b, c)
+	}
+}
+
+func (r *renderer) inline(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(escapeText(collapseSpaces(n.Data)))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Img, atom.Object:
+		return
+	}
+
+	var inner strings.Builder
+	r.inlineChildren(&inner, n)
+	content := inner.String()
+
+	switch n.DataAtom {
+	case atom.B, atom.Strong:
+		content = wrapTag("b", "", content)
+	case atom.I, atom.Em, atom.Cite:
+		content = wrapTag("i", "", content)
+	case atom.U, atom.Ins:
+		content = wrapTag("u", "", content)
+	case atom.S, atom.Strike, atom.Del:
+		content = wrapTag("s", "", content)
+	case atom.Code, atom.Kbd, atom.Samp:
+		content = wrapTag("code", "", escapeText(collapseSpaces(textContent(n))))
+	case atom.A:
+		href := getAttr(n, "href")
+		if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
+			content = wrapTag("a", ` href="`+escapeAttr(href)+`"`, content)
+		}
+	case atom.Span:
+		content = applyStyle(getAttr(n, "style"), content)
+	}
+
+	b.WriteString(content)
+}
+
+// applyStyle maps OneNote inline styles (spans with font-weight and similar) onto Telegram tags
+func applyStyle(style, content string) string {
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if style == "" {
+		return content
+	}
+
+	if strings.Contains(style, "font-weight:bold") || strings.Contains(style, "font-weight:700") {
+		content = wrapTag("b", "", content)
+	}
+	if strings.Contains(style, "font-style:italic") {
+		content = wrapTag("i", "", content)
+	}
+	if strings.Contains(style, "underline") {
+		content = wrapTag("u", "", content)
+	}
+	if strings.Contains(style, "line-through") {
+		content = wrapTag("s", "", content)
+	}
+
+	return content
+}
+
+// wrapTag wraps content into a tag, keeping surrounding whitespace outside and skipping empty content
+func wrapTag(name, attrs, content string) string {
+	core := strings.TrimSpace(content)
+	if core == "" {
+		return content
+	}
+
+	lead := content[:strings.Index(content, core)]
+	trail := content[len(lead)+len(core):]
+
+	return lead + "<" + name + attrs + ">" + core + "</" + name + ">" + trail
+}
+
+func (r *renderer) writeLine(indent, text string) {
+	text = collapseSpaces(strings.TrimSpace(text))
+	if text == "" {
+		return
+	}
+	r.writeRaw(indent, text)
+}
+
+func (r *renderer) writeRaw(indent, text string) {
+	if r.needBlank && r.out.Len() > 0 {
+		r.out.WriteString("\n")
+	}
+	r.needBlank = false
+
+	prefix := indent
+	if r.marker != "" {
+		prefix = r.marker
+		r.marker = ""
+	}
+
+	r.out.WriteString(prefix)
+	r.out.WriteString(text)
+	r.out.WriteString("\n")
+}
+
+func (r *renderer) blank() {
+	r.needBlank = true
+}
+
+func isBlock(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Blockquote,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Li, atom.Table, atom.Tr, atom.Pre, atom.Hr,
+		atom.Head, atom.Script, atom.Style, atom.Title:
+		return true
+	}
+
+	return false
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// findRows returns rows of the table without descending into nested tables
+func findRows(table *html.Node) []*html.Node {
+	var rows []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+
+			switch c.DataAtom {
+			case atom.Tr:
+				rows = append(rows, c)
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(c)
+			}
+		}
+	}
+	walk(table)
+
+	return rows
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Br {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textContent(c))
+	}
+
+	return b.String()
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// collapseSpaces replaces every run of whitespace (including non-breaking spaces) with a single space
+func collapseSpaces(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == ' ' {
+			space = true
+			continue
+		}
+
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+
+	if space {
+		b.WriteByte(' ')
+	}
+
+	return b.String()
+}
+
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	return s
+}
+
+func escapeAttr(s string) string {
+	return strings.ReplaceAll(escapeText(s), `"`, "&quot;")
+}
@@ -0,0 +1,36 @@
+package onenote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name string
+	}{
+		{name: "tables"},
+		{name: "lists"},
+		{name: "entities"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", "render", tt.name+".html"))
+			if err != nil {
+				t.Fatalf("read input: %v", err)
+			}
+
+			checkGolden(t, filepath.Join("render", tt.name), RenderHTML(string(input)))
+		})
+	}
+}
+
+func TestRenderHTMLPlainTextIsEscaped(t *testing.T) {
+	got := RenderHTML("a < b & c")
+	want := "a &lt; b &amp; c"
+	if got != want {
+		t.Errorf("RenderHTML() = %q, want %q", got, want)
+	}
+}
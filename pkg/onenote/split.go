@@ -0,0 +1,172 @@
+package onenote
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitHTML splits Telegram HTML text into chunks no longer than limit UTF-16 code units,
+// which is how Telegram measures message length. Chunks are cut at line breaks when possible,
+// then at spaces, and never inside a tag or an entity. Tags left open at a cut are closed
+// at the end of the chunk and reopened at the start of the next one.
+func SplitHTML(text string, limit int) []string {
+	if utf16Len(text) <= limit {
+		return []string{text}
+	}
+
+	tokens := tokenizeHTML(text)
+
+	var chunks []string
+	var open []htmlTag
+	i := 0
+
+	for i < len(tokens) {
+		var b strings.Builder
+		size := 0
+		stack := append([]htmlTag(nil), open...)
+		for _, t := range stack {
+			b.WriteString(t.open)
+			size += utf16Len(t.open)
+		}
+
+		type cutPoint struct {
+			index int
+			len   int
+			stack []htmlTag
+		}
+		var lastLine, lastSpace cutPoint
+
+		j := i
+		for ; j < len(tokens); j++ {
+			tok := tokens[j]
+			next := tok.apply(stack)
+			if j > i && size+tok.size+closingLen(next) > limit {
+				break
+			}
+
+			b.WriteString(tok.text)
+			size += tok.size
+			stack = next
+
+			switch tok.text {
+			case "\n":
+				lastLine = cutPoint{index: j + 1, len: b.Len(), stack: stack}
+			case " ":
+				lastSpace = cutPoint{index: j + 1, len: b.Len(), stack: stack}
+			}
+		}
+
+		cut := cutPoint{index: j, len: b.Len(), stack: stack}
+		if j < len(tokens) {
+			if lastLine.index > i {
+				cut = lastLine
+			} else if lastSpace.index > i {
+				cut = lastSpace
+			}
+		}
+
+		chunk := strings.TrimRight(b.String()[:cut.len], " \n")
+		for k := len(cut.stack) - 1; k >= 0; k-- {
+			chunk += "</" + cut.stack[k].name + ">"
+		}
+		chunks = append(chunks, chunk)
+
+		open = cut.stack
+		i = cut.index
+		for i < len(tokens) && (tokens[i].text == "\n" || tokens[i].text == " ") {
+			i++
+		}
+	}
+
+	return chunks
+}
+
+type htmlTag struct {
+	name string
+	open string
+}
+
+type htmlToken struct {
+	text    string
+	size    int
+	tag     string
+	opening bool
+	closing bool
+}
+
+func (t htmlToken) apply(stack []htmlTag) []htmlTag {
+	switch {
+	case t.opening:
+		next := make([]htmlTag, len(stack), len(stack)+1)
+		copy(next, stack)
+		return append(next, htmlTag{name: t.tag, open: t.text})
+	case t.closing:
+		for k := len(stack) - 1; k >= 0; k-- {
+			if stack[k].name == t.tag {
+				return stack[:k:k]
+			}
+		}
+	}
+	return stack
+}
+
+func closingLen(stack []htmlTag) int {
+	size := 0
+	for _, t := range stack {
+		size += len(t.name) + 3
+	}
+	return size
+}
+
+// tokenizeHTML breaks text into tags, entities and single runes
+func tokenizeHTML(text string) []htmlToken {
+	var tokens []htmlToken
+
+	for len(text) > 0 {
+		switch {
+		case text[0] == '<':
+			end := strings.IndexByte(text, '>')
+			if end < 0 {
+				end = len(text) - 1
+			}
+			raw := text[:end+1]
+			tok := htmlToken{text: raw, size: utf16Len(raw)}
+			name := strings.TrimPrefix(strings.Trim(raw, "<>"), "/")
+			if idx := strings.IndexAny(name, " \t\n"); idx >= 0 {
+				name = name[:idx]
+			}
+			tok.tag = strings.ToLower(name)
+			tok.closing = strings.HasPrefix(raw, "</")
+			tok.opening = !tok.closing
+			tokens = append(tokens, tok)
+			text = text[end+1:]
+		case text[0] == '&':
+			end := strings.IndexByte(text, ';')
+			if end < 0 || end > 10 {
+				end = 0
+			}
+			raw := text[:end+1]
+			tokens = append(tokens, htmlToken{text: raw, size: 1})
+			text = text[end+1:]
+		default:
+			_, n := utf8.DecodeRuneInString(text)
+			raw := text[:n]
+			tokens = append(tokens, htmlToken{text: raw, size: utf16Len(raw)})
+			text = text[n:]
+		}
+	}
+
+	return tokens
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
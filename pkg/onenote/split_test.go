@@ -0,0 +1,120 @@
+package onenote
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// telegramLimit is the Telegram message length limit in UTF-16 code units of the parsed text
+const telegramLimit = 4096
+
+func TestSplitHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+	}{
+		{
+			name:  "short",
+			text:  "<b>short</b> text",
+			limit: telegramLimit,
+		},
+		{
+			name:  "lines",
+			text:  repeatLines(300, func(i int) string { return fmt.Sprintf("Line %d: <b>bold</b> and plain text", i) }),
+			limit: telegramLimit,
+		},
+		{
+			name:  "open_tags",
+			text:  "<b>Title</b>\n<i>" + strings.Repeat("word <u>under</u> <a href=\"https://example.com/?a=1&amp;b=2\">link</a> ", 150) + "</i>",
+			limit: telegramLimit,
+		},
+		{
+			name:  "emoji",
+			text:  strings.Repeat("🎉 слово ", 700),
+			limit: telegramLimit,
+		},
+		{
+			name:  "entities",
+			text:  "<pre>" + strings.Repeat("a &lt; b &amp;&amp; c &gt; d ", 300) + "</pre>",
+			limit: telegramLimit,
+		},
+		{
+			name:  "small_limit",
+			text:  "<b>bold <i>nested italic text</i> tail</b>\nnext line with <a href=\"https://example.com\">a link</a>",
+			limit: 40,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitHTML(tt.text, tt.limit)
+
+			for i, chunk := range chunks {
+				if size := parsedLen(chunk); size > tt.limit {
+					t.Errorf("chunk %d is %d UTF-16 units long after parsing, limit %d", i, size, tt.limit)
+				}
+				if open := openTags(chunk); len(open) > 0 {
+					t.Errorf("chunk %d leaves tags open: %v", i, open)
+				}
+			}
+
+			if got, want := visibleText(strings.Join(chunks, "")), visibleText(tt.text); got != want {
+				t.Errorf("chunks lose or duplicate text:\n got %q\nwant %q", got, want)
+			}
+
+			var golden strings.Builder
+			for i, chunk := range chunks {
+				fmt.Fprintf(&golden, "===== chunk %d (%d UTF-16 units after parsing) =====\n%s\n", i+1, parsedLen(chunk), chunk)
+			}
+			checkGolden(t, filepath.Join("split", tt.name), golden.String())
+		})
+	}
+}
+
+func repeatLines(n int, line func(i int) string) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = line(i + 1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parsedLen is the length Telegram checks against the limit: tags are dropped and an entity is one character
+func parsedLen(text string) int {
+	size := 0
+	for _, tok := range tokenizeHTML(text) {
+		if !tok.opening && !tok.closing {
+			size += tok.size
+		}
+	}
+	return size
+}
+
+// openTags returns the tags left open at the end of text
+func openTags(text string) []string {
+	var stack []htmlTag
+	for _, tok := range tokenizeHTML(text) {
+		stack = tok.apply(stack)
+	}
+
+	names := make([]string, 0, len(stack))
+	for _, tag := range stack {
+		names = append(names, tag.name)
+	}
+	return names
+}
+
+// visibleText drops tags and whitespace, which chunks are allowed to add or trim at cuts
+func visibleText(text string) string {
+	var b strings.Builder
+	for _, tok := range tokenizeHTML(text) {
+		if tok.opening || tok.closing || strings.TrimSpace(tok.text) == "" {
+			continue
+		}
+		b.WriteString(tok.text)
+	}
+	return b.String()
+}
@@ -16,6 +16,17 @@ type SectionsResponse struct {
 type Section struct {
 	ID          string `json:"id"`
 	DisplayName string `json:"displayName"`
+	// Path holds display names of the section groups containing the section, filled by GetAllSections
+	Path []string `json:"-"`
+}
+
+type SectionGroupsResponse struct {
+	Value []SectionGroup `json:"value"`
+}
+
+type SectionGroup struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
 }
 
 type PagesResponse struct {
@@ -0,0 +1,186 @@
+package onenote
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// VocabularyEntry is a single word extracted from a vocabulary table of a page
+type VocabularyEntry struct {
+	Word        string
+	Translation string
+	Example     string
+}
+
+// ExtractVocabulary finds vocabulary tables (English | Russian, optionally with a third example column)
+// in OneNote page HTML and returns their rows as entries in page order.
+// The first line of the English cell is the word, the first line of the Russian cell is the translation,
+// remaining lines of both cells and the third column become the example.
+// Header rows and duplicate words are skipped.
+func ExtractVocabulary(content string) []VocabularyEntry {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	var entries []VocabularyEntry
+	seen := make(map[string]bool)
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Table {
+			for _, entry := range extractTableEntries(n) {
+				key := strings.ToLower(entry.Word)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				entries = append(entries, entry)
+			}
+			return
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	return entries
+}
+
+func extractTableEntries(table *html.Node) []VocabularyEntry {
+	var rows [][][]string
+	for _, tr := range findRows(table) {
+		var row [][]string
+		header := true
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || (c.DataAtom != atom.Td && c.DataAtom != atom.Th) {
+				continue
+			}
+			header = header && c.DataAtom == atom.Th
+			row = append(row, cellLines(c))
+		}
+
+		if header || len(row) < 2 || len(row) > 3 || len(row[0]) == 0 || len(row[1]) == 0 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Detect which column holds English by the majority of rows
+	var latinFirst, cyrillicFirst int
+	for _, row := range rows {
+		left, right := scriptOf(row[0][0]), scriptOf(row[1][0])
+		switch {
+		case left == unicode.Latin && right == unicode.Cyrillic:
+			latinFirst++
+		case left == unicode.Cyrillic && right == unicode.Latin:
+			cyrillicFirst++
+		}
+	}
+
+	if latinFirst == 0 && cyrillicFirst == 0 {
+		return nil
+	}
+	swap := cyrillicFirst > latinFirst
+
+	entries := make([]VocabularyEntry, 0, len(rows))
+	for i, row := range rows {
+		wordCell, translationCell := row[0], row[1]
+		if swap {
+			wordCell, translationCell = translationCell, wordCell
+		}
+
+		// The first row may be a header like "English | Russian" written without <th>
+		if i == 0 && isHeaderRow(wordCell[0], translationCell[0]) {
+			continue
+		}
+
+		var examples []string
+		examples = append(examples, wordCell[1:]...)
+		examples = append(examples, translationCell[1:]...)
+		if len(row) == 3 {
+			examples = append(examples, row[2]...)
+		}
+
+		entries = append(entries, VocabularyEntry{
+			Word:        wordCell[0],
+			Translation: translationCell[0],
+			Example:     strings.Join(examples, "; "),
+		})
+	}
+
+	return entries
+}
+
+// cellLines returns the non-empty lines of a table cell, paragraphs and line breaks separate lines
+func cellLines(n *html.Node) []string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.DataAtom == atom.Br:
+			b.WriteString("\n")
+		case n.Type == html.ElementNode:
+			block := isBlock(n)
+			if block {
+				b.WriteString("\n")
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+			if block {
+				b.WriteString("\n")
+			}
+		}
+	}
+	walk(n)
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		line = strings.TrimSpace(collapseSpaces(line))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// scriptOf returns the dominant script of the text: Latin, Cyrillic or nil if there are no letters
+func scriptOf(text string) *unicode.RangeTable {
+	var latin, cyrillic int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		}
+	}
+
+	switch {
+	case latin == 0 && cyrillic == 0:
+		return nil
+	case latin >= cyrillic:
+		return unicode.Latin
+	default:
+		return unicode.Cyrillic
+	}
+}
+
+func isHeaderRow(word, translation string) bool {
+	word, translation = strings.ToLower(word), strings.ToLower(translation)
+	return (word == "english" || word == "word" || word == "английский" || word == "слово") &&
+		(translation == "russian" || translation == "translation" || translation == "русский" || translation == "перевод")
+}
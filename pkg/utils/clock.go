@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Clock returns the current time. Code that depends on "now" takes a Clock
+// so that day rollover, DST and inactivity logic can run against simulated time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock, it returns the current time in UTC
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Rand is a source of random numbers
+type Rand interface {
+	// Float32 returns a number in [0.0, 1.0)
+	Float32() float32
+}
+
+// SystemRand uses the global math/rand source, which is safe for concurrent use
+type SystemRand struct{}
+
+func (SystemRand) Float32() float32 {
+	return rand.Float32()
+}
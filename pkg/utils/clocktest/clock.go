@@ -0,0 +1,44 @@
+// Package clocktest provides controllable utils.Clock and utils.Rand implementations for tests
+package clocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is a clock that only moves when told to, for fast-forwarding simulated days and weeks
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now.UTC()}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to now
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now.UTC()
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// FixedRand always returns the same number, making random choices deterministic
+type FixedRand float32
+
+func (r FixedRand) Float32() float32 {
+	return float32(r)
+}
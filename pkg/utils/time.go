@@ -2,16 +2,24 @@ package utils
 
 import "time"
 
+// StartOfDay returns the first instant of the day of t in the location of t. When clocks jump forward
+// at midnight the day has no 00:00 and starts at the moment of the jump.
 func StartOfDay(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if start.Day() != t.Day() {
+		// time.Date resolved the missing midnight to the last hour of the previous day
+		_, start = start.ZoneBounds()
+	}
+	return start
 }
 
-func TruncateToMinutes(t time.Time) time.Time {
-	return t.Truncate(time.Minute)
+// AddDays returns the start of the day that is days calendar days after the day of t, in the location of t
+func AddDays(t time.Time, days int) time.Time {
+	return StartOfDay(time.Date(t.Year(), t.Month(), t.Day()+days, 12, 0, 0, 0, t.Location()))
 }
 
-func NowUTC() time.Time {
-	return time.Now().UTC()
+func TruncateToMinutes(t time.Time) time.Time {
+	return t.Truncate(time.Minute)
 }
 
 func ToUserTimezone(t time.Time, timezone string) (time.Time, error) {
@@ -31,27 +39,5 @@ func StartOfDayInTimezone(t time.Time, timezone string) (time.Time, error) {
 	if err != nil {
 		return t, err
 	}
-	tInTz := t.In(loc)
-	return time.Date(tInTz.Year(), tInTz.Month(), tInTz.Day(), 0, 0, 0, 0, loc), nil
-}
-
-func StartOfTodayUTC() time.Time {
-	return StartOfDay(NowUTC())
-}
-
-func StartOfTodayInTimezone(timezone string) (time.Time, error) {
-	return StartOfDayInTimezone(NowUTC(), timezone)
-}
-
-// IsFirstHourOfDayInTimezone checks if it's the first hour of the day (00:00-00:59) in the specified timezone
-func IsFirstHourOfDayInTimezone(timezone string) (bool, error) {
-	if timezone == "" {
-		return false, nil
-	}
-	loc, err := time.LoadLocation(timezone)
-	if err != nil {
-		return false, err
-	}
-	now := time.Now().In(loc)
-	return now.Hour() == 0, nil
+	return StartOfDay(t.In(loc)), nil
 }